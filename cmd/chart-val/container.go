@@ -2,22 +2,40 @@
 package main
 
 import (
+	"context"
 	"fmt"
 	"log/slog"
+	"net/http"
 	"strings"
+	"time"
 
 	gogithub "github.com/google/go-github/v68/github"
 
+	adminapi "github.com/nathantilsley/chart-val/internal/diff/adapters/admin_api"
+	artifactfs "github.com/nathantilsley/chart-val/internal/diff/adapters/artifact_store/filesystem"
+	artifacts3 "github.com/nathantilsley/chart-val/internal/diff/adapters/artifact_store/s3"
+	chatnotify "github.com/nathantilsley/chart-val/internal/diff/adapters/chat_notify"
 	dyffdiff "github.com/nathantilsley/chart-val/internal/diff/adapters/dyff_diff"
 	argoenv "github.com/nathantilsley/chart-val/internal/diff/adapters/environment_config/argo"
+	"github.com/nathantilsley/chart-val/internal/diff/adapters/environment_config/combined"
 	fsenv "github.com/nathantilsley/chart-val/internal/diff/adapters/environment_config/filesystem"
+	fluxenv "github.com/nathantilsley/chart-val/internal/diff/adapters/environment_config/flux"
+	helmfileenv "github.com/nathantilsley/chart-val/internal/diff/adapters/environment_config/helmfile"
+	fanout "github.com/nathantilsley/chart-val/internal/diff/adapters/fan_out"
 	githubin "github.com/nathantilsley/chart-val/internal/diff/adapters/github_in"
 	githubout "github.com/nathantilsley/chart-val/internal/diff/adapters/github_out"
 	helmcli "github.com/nathantilsley/chart-val/internal/diff/adapters/helm_cli"
+	jsonfile "github.com/nathantilsley/chart-val/internal/diff/adapters/json_file"
 	linediff "github.com/nathantilsley/chart-val/internal/diff/adapters/line_diff"
 	prfiles "github.com/nathantilsley/chart-val/internal/diff/adapters/pr_files"
+	rendercache "github.com/nathantilsley/chart-val/internal/diff/adapters/render_cache"
+	runstore "github.com/nathantilsley/chart-val/internal/diff/adapters/run_store"
+	runsapi "github.com/nathantilsley/chart-val/internal/diff/adapters/runs_api"
 	sourcectrl "github.com/nathantilsley/chart-val/internal/diff/adapters/source_ctrl"
+	webui "github.com/nathantilsley/chart-val/internal/diff/adapters/web_ui"
+	webhookout "github.com/nathantilsley/chart-val/internal/diff/adapters/webhook_out"
 	"github.com/nathantilsley/chart-val/internal/diff/app"
+	"github.com/nathantilsley/chart-val/internal/diff/domain"
 	"github.com/nathantilsley/chart-val/internal/diff/ports"
 	"github.com/nathantilsley/chart-val/internal/platform/config"
 	ghclient "github.com/nathantilsley/chart-val/internal/platform/github"
@@ -31,6 +49,14 @@ type Container struct {
 	GitHubClient   *gogithub.Client
 	DiffService    ports.DiffUseCase
 	WebhookHandler *githubin.WebhookHandler
+	RunsAPI        *runsapi.Handler  // nil when run history is disabled
+	WebUI          *webui.Handler    // nil when run history is disabled
+	Artifacts      *artifactfs.Store // Serves artifact downloads; nil unless ARTIFACT_STORE=filesystem
+	AdminAPI       *adminapi.Handler // nil unless ADMIN_TOKEN is set and a gitops repo is synced
+
+	runStore  *runstore.Store
+	retention *app.ArtifactRetention
+	stopSyncs []func() // Stop the gitops clones' background syncs
 }
 
 // NewContainer builds and wires all dependencies.
@@ -43,69 +69,352 @@ func NewContainer(cfg config.Config, log *slog.Logger, tel *telemetry.Telemetry)
 
 	// Adapters
 	sourceCtrl := sourcectrl.New(githubClient)
-	helmRenderer, err := helmcli.New()
+	helmRenderer, err := helmcli.New(helmcli.Options{DependencyCacheDir: cfg.HelmDependencyCacheDir})
 	if err != nil {
 		return nil, fmt.Errorf("creating helm adapter: %w", err)
 	}
-	reporter := githubout.New(githubClient, cfg.AppName, cfg.AppURL)
-	changedCharts := prfiles.New(githubClient, log, cfg.ChartDir)
+	var conclusionPolicy githubout.ConclusionPolicy
+	if cfg.ConclusionPolicyFile != "" {
+		conclusionPolicy, err = githubout.LoadConclusionPolicy(cfg.ConclusionPolicyFile)
+		if err != nil {
+			return nil, fmt.Errorf("loading conclusion policy: %w", err)
+		}
+		log.Info("conclusion policy loaded", "file", cfg.ConclusionPolicyFile, "rules", len(conclusionPolicy.Rules))
+	}
+	reportTemplates := githubout.DefaultTemplates()
+	if cfg.ReportTemplatesDir != "" {
+		reportTemplates, err = githubout.LoadTemplates(cfg.ReportTemplatesDir)
+		if err != nil {
+			return nil, fmt.Errorf("loading report templates: %w", err)
+		}
+		log.Info("report templates loaded", "dir", cfg.ReportTemplatesDir)
+	}
+	githubReporter := githubout.New(githubClient, cfg.AppName, cfg.AppURL, githubout.Options{
+		CommentMode:          githubout.CommentMode(cfg.CommentMode),
+		CheckRunNameTemplate: cfg.CheckRunNameTemplate,
+		RequiredCheckRuns:    cfg.RequiredCheckRuns,
+		ConclusionPolicy:     conclusionPolicy,
+		Templates:            reportTemplates,
+		RepoTemplatePath:     cfg.RepoTemplatesPath,
+		LinkRunHistory:       cfg.RunHistoryDB != "",
+	})
+
+	// Secondary sinks run alongside GitHub; their failures never block the check run
+	metricPrefix := strings.ReplaceAll(cfg.AppName, "-", "_")
+	sinkTimeout := func(name string, def time.Duration) time.Duration {
+		if d, ok := cfg.ReporterTimeouts[name]; ok {
+			return d
+		}
+		return def
+	}
+	var sinks []fanout.Sink
+	if cfg.NotifyRoutesFile != "" {
+		routes, err := chatnotify.LoadRoutes(cfg.NotifyRoutesFile)
+		if err != nil {
+			return nil, fmt.Errorf("loading notification routes: %w", err)
+		}
+		log.Info("chat notifications enabled", "file", cfg.NotifyRoutesFile, "routes", len(routes))
+		sinks = append(sinks, fanout.Sink{
+			Name:     "chat",
+			Reporter: chatnotify.New(&http.Client{}, cfg.AppName, routes),
+			Timeout:  sinkTimeout("chat", cfg.ReporterTimeout),
+		})
+	}
+	if cfg.ReportJSONDir != "" {
+		log.Info("json file reports enabled", "dir", cfg.ReportJSONDir)
+		sinks = append(sinks, fanout.Sink{
+			Name:     "json-file",
+			Reporter: jsonfile.New(cfg.ReportJSONDir),
+			Timeout:  sinkTimeout("json-file", cfg.ReporterTimeout),
+		})
+	}
+	if len(cfg.OutboundWebhookURLs) > 0 {
+		log.Info("outbound webhooks enabled",
+			"urls", len(cfg.OutboundWebhookURLs),
+			"signed", cfg.OutboundWebhookSecret != "",
+			"deadLetterFile", cfg.OutboundWebhookDeadLetterFile,
+		)
+		sinks = append(sinks, fanout.Sink{
+			Name: "webhook",
+			Reporter: webhookout.New(
+				&http.Client{Timeout: 10 * time.Second},
+				cfg.AppName,
+				cfg.OutboundWebhookURLs,
+				cfg.OutboundWebhookSecret,
+				cfg.OutboundWebhookMaxAttempts,
+				cfg.OutboundWebhookDeadLetterFile,
+			),
+			Timeout: sinkTimeout("webhook", cfg.ReporterTimeout),
+		})
+	}
+	var reporter ports.ReportingPort = githubReporter
+	if len(sinks) > 0 {
+		primary := fanout.Sink{Name: "github", Reporter: githubReporter, Timeout: sinkTimeout("github", 0)}
+		reporter = fanout.New(log, tel.Meter, metricPrefix, primary, sinks...)
+	}
 	semanticDiff := dyffdiff.New()
 	unifiedDiff := linediff.New()
 
 	// Environment config adapters (both discover where charts are deployed)
 	// Filesystem adapter - discovers from chart's env/ folder
-	filesystemEnvConfig := fsenv.New(sourceCtrl, cfg.ChartDir, cfg.EnvDir, cfg.ValuesFileSuffix)
+	filesystemEnvConfig := fsenv.New(sourceCtrl, cfg.EnvDir, cfg.ValuesFileSuffix)
 
-	// Optionally create Argo adapter (source of truth when available)
-	var argoEnvConfig ports.EnvironmentConfigPort
-	if cfg.ArgoAppsRepo != "" {
+	// Optionally create gitops adapters (source of truth when available)
+	var gitopsSources []combined.Source
+	var gitopsIndexes []ports.GitopsIndexPort // Synced clones, refreshed on push and on demand
+	var stopSyncs []func()
+	argoSources, err := loadArgoSources(cfg)
+	if err != nil {
+		return nil, err
+	}
+	for _, src := range argoSources {
 		log.Info("argo apps integration enabled",
-			"repo", cfg.ArgoAppsRepo,
-			"syncInterval", cfg.ArgoAppsSyncInterval,
-			"folderPattern", cfg.ArgoAppsFolderPattern,
+			"source", src.Name,
+			"repo", src.Repo,
+			"syncInterval", src.SyncInterval,
+			"folderPattern", src.FolderPattern,
+			"envKey", src.EnvKey,
+			"envFromDestination", src.EnvFromDestination,
+			"repos", src.Repos,
 		)
-		adapter, err := argoenv.New(
-			cfg.ArgoAppsRepo,
-			cfg.ArgoAppsLocalPath,
-			cfg.ArgoAppsSyncInterval,
-			cfg.ArgoAppsFolderPattern,
-			log,
-			cfg.ChartDir,
+		adapter, err := argoenv.New(src, log, tel.Meter, metricPrefix)
+		if err != nil {
+			return nil, fmt.Errorf("creating argo environment config adapter %q: %w", src.Name, err)
+		}
+		gitopsSources = append(gitopsSources, combined.Source{Name: src.Name, Config: adapter})
+		gitopsIndexes = append(gitopsIndexes, adapter)
+		stopSyncs = append(stopSyncs, adapter.Stop)
+	}
+	if cfg.FluxRepo != "" {
+		log.Info("flux integration enabled",
+			"repo", cfg.FluxRepo,
+			"syncInterval", cfg.FluxSyncInterval,
+			"envPattern", cfg.FluxEnvPattern,
 		)
+		adapter, err := fluxenv.New(fluxenv.Source{
+			Repo:         cfg.FluxRepo,
+			LocalPath:    cfg.FluxLocalPath,
+			EnvPattern:   cfg.FluxEnvPattern,
+			SyncInterval: cfg.FluxSyncInterval,
+			Token:        cfg.FluxToken,
+			SSHKeyFile:   cfg.FluxSSHKeyFile,
+		}, log)
 		if err != nil {
-			return nil, fmt.Errorf("creating argo environment config adapter: %w", err)
+			return nil, fmt.Errorf("creating flux environment config adapter: %w", err)
 		}
-		argoEnvConfig = adapter
-	} else {
-		log.Info("argo apps not configured, using filesystem discovery only")
+		gitopsSources = append(gitopsSources, combined.Source{Name: "flux", Config: adapter})
+		gitopsIndexes = append(gitopsIndexes, adapter)
+		stopSyncs = append(stopSyncs, adapter.Stop)
+	}
+	if len(cfg.HelmfilePaths) > 0 {
+		log.Info("helmfile integration enabled", "paths", cfg.HelmfilePaths)
+		adapter := helmfileenv.New(sourceCtrl, cfg.HelmfilePaths, log)
+		gitopsSources = append(gitopsSources, combined.Source{Name: "helmfile", Config: adapter})
 	}
 
-	// Domain service (handles composite strategy: Argo → Filesystem → Base chart)
-	metricPrefix := strings.ReplaceAll(cfg.AppName, "-", "_")
+	sourceNames := make(map[string]bool, len(gitopsSources))
+	for _, src := range gitopsSources {
+		if sourceNames[src.Name] {
+			return nil, fmt.Errorf("gitops source name %q is used twice", src.Name)
+		}
+		sourceNames[src.Name] = true
+	}
+
+	var gitopsEnvConfig ports.EnvironmentConfigPort
+	var valueIndex ports.ValueFileIndexPort // Charts using values outside their directory
+	switch len(gitopsSources) {
+	case 0:
+		log.Info("no gitops repo or helmfile configured, using filesystem discovery only")
+	case 1:
+		gitopsEnvConfig = gitopsSources[0].Config
+		valueIndex, _ = gitopsSources[0].Config.(ports.ValueFileIndexPort)
+	default:
+		merged := combined.New(log, gitopsSources...)
+		gitopsEnvConfig, valueIndex = merged, merged
+	}
+
+	chartLayout := domain.ChartLayout{Include: cfg.ChartInclude, Exclude: cfg.ChartExclude}
+	if err := chartLayout.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid chart layout: %w", err)
+	}
+	changedCharts := prfiles.New(githubClient, log, chartLayout, valueIndex)
+
+	// Rendered manifest artifacts (optional)
+	var artifactStore ports.ArtifactStorePort
+	var artifactDownloads *artifactfs.Store
+	switch cfg.ArtifactStore {
+	case "filesystem":
+		store, err := artifactfs.New(cfg.ArtifactDir, cfg.AppURL, []byte(cfg.ArtifactLinkSecret), cfg.ArtifactLinkTTL)
+		if err != nil {
+			return nil, fmt.Errorf("creating artifact store: %w", err)
+		}
+		artifactStore, artifactDownloads = store, store
+	case "s3":
+		store, err := artifacts3.New(&http.Client{Timeout: 30 * time.Second}, artifacts3.Config{
+			Endpoint:  cfg.ArtifactS3Endpoint,
+			Region:    cfg.ArtifactS3Region,
+			Bucket:    cfg.ArtifactS3Bucket,
+			Prefix:    cfg.ArtifactS3Prefix,
+			AccessKey: cfg.ArtifactS3AccessKeyID,
+			SecretKey: cfg.ArtifactS3SecretAccessKey,
+			LinkTTL:   cfg.ArtifactLinkTTL,
+		})
+		if err != nil {
+			return nil, fmt.Errorf("creating artifact store: %w", err)
+		}
+		artifactStore = store
+	}
+
+	// Run history (optional)
+	var history ports.RunHistoryPort
+	var runStore *runstore.Store
+	var runsAPI *runsapi.Handler
+	var webUI *webui.Handler
+	if cfg.RunHistoryDB != "" {
+		runStore, err = runstore.Open(cfg.RunHistoryDB)
+		if err != nil {
+			return nil, fmt.Errorf("opening run history: %w", err)
+		}
+		log.Info("run history enabled", "db", cfg.RunHistoryDB)
+		history = runStore
+		runQuery := app.NewRunQueryService(runStore, artifactStore)
+		runsAPI = runsapi.NewHandler(runQuery, cfg.AdminToken, log)
+		webUI = webui.NewHandler(runQuery, cfg.AppName, cfg.AdminToken, log)
+	}
+	if artifactStore != nil && webUI != nil && cfg.AppURL != "" {
+		// Reports outlive signed links; link them to the UI, which signs a
+		// fresh one on every visit
+		artifactStore = webui.NewArtifactLinks(artifactStore, cfg.AppURL)
+	}
+
+	var retention *app.ArtifactRetention
+	if artifactStore != nil {
+		log.Info("manifest artifacts enabled", "store", cfg.ArtifactStore, "retention", cfg.ArtifactRetention)
+		if cfg.ArtifactRetention > 0 {
+			retention = app.NewArtifactRetention(artifactStore, cfg.ArtifactRetention, cfg.ArtifactRetentionInterval, log)
+			retention.Start()
+		}
+	}
+
+	var renderer ports.RendererPort = helmRenderer
+	if cfg.RenderCacheMemoryMB > 0 || cfg.RenderCacheDir != "" {
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		salt, err := helmRenderer.CacheSalt(ctx)
+		cancel()
+		if err != nil {
+			return nil, fmt.Errorf("identifying helm for render cache: %w", err)
+		}
+		renderer, err = rendercache.New(log, tel.Meter, metricPrefix, helmRenderer, rendercache.Options{
+			MemoryBytes:        int64(cfg.RenderCacheMemoryMB) << 20,
+			DiskDir:            cfg.RenderCacheDir,
+			DiskTTL:            cfg.RenderCacheDiskTTL,
+			Salt:               salt,
+			ExcludeDirs:        []string{cfg.EnvDir},
+			DependencyCacheDir: cfg.HelmDependencyCacheDir,
+		})
+		if err != nil {
+			return nil, fmt.Errorf("creating render cache: %w", err)
+		}
+		log.Info("render cache enabled", "memoryMB", cfg.RenderCacheMemoryMB, "dir", cfg.RenderCacheDir, "helm", salt)
+	}
+
+	// Domain service (handles composite strategy: Argo/Flux → Filesystem → Base chart)
 	diffService := app.NewDiffService(
 		sourceCtrl,
 		changedCharts,
-		argoEnvConfig,       // nil if not configured
+		gitopsEnvConfig,     // nil if not configured
 		filesystemEnvConfig, // always present - discovers from chart's env/ folder
-		helmRenderer,
+		renderer,            // helm, behind the render cache when enabled
 		reporter,
+		history,       // nil if not configured
+		artifactStore, // nil if not configured
 		semanticDiff,
 		unifiedDiff,
 		log,
 		tel.Meter,
 		tel.Tracer,
-		cfg.ChartDir,
 		metricPrefix,
 	)
 
+	// PRs to the gitops repos diff the Applications they change (optional)
+	var useCase ports.DiffUseCase = diffService
+	if len(argoSources) > 0 && cfg.ArgoAppsPRDiff {
+		log.Info("argo apps PR diffs enabled", "sources", len(argoSources))
+		changes := argoenv.NewChangeDetector(sourceCtrl, argoSources, cfg.ArgoAppsPRDiffRepos, log)
+		useCase = app.NewGitopsDiffService(diffService, changes)
+	}
+
+	// Gitops index sync on push and on demand (optional)
+	var indexUseCase ports.GitopsIndexUseCase
+	var adminAPI *adminapi.Handler
+	if len(gitopsIndexes) > 0 {
+		indexes := app.NewGitopsIndexService(log, gitopsIndexes...)
+		indexUseCase = indexes
+		if cfg.AdminToken != "" {
+			log.Info("admin api enabled")
+			adminAPI = adminapi.NewHandler(indexes, cfg.AdminToken, log)
+		}
+	}
+
 	// Webhook handler
-	webhookHandler := githubin.NewWebhookHandler(diffService, cfg.WebhookSecret, log)
+	webhookHandler := githubin.NewWebhookHandler(useCase, indexUseCase, cfg.WebhookSecret, log)
 
 	return &Container{
 		Config:         cfg,
 		Logger:         log,
 		GitHubClient:   githubClient,
-		DiffService:    diffService,
+		DiffService:    useCase,
 		WebhookHandler: webhookHandler,
+		RunsAPI:        runsAPI,
+		WebUI:          webUI,
+		Artifacts:      artifactDownloads,
+		AdminAPI:       adminAPI,
+		runStore:       runStore,
+		retention:      retention,
+		stopSyncs:      stopSyncs,
 	}, nil
 }
+
+// loadArgoSources returns the Argo CD gitops sources: the ARGO_APPS_* repo,
+// named "argo", followed by those of ARGO_SOURCES_FILE.
+func loadArgoSources(cfg config.Config) ([]argoenv.Source, error) {
+	var sources []argoenv.Source
+	if cfg.ArgoAppsRepo != "" {
+		sources = append(sources, argoenv.Source{
+			Name:               "argo",
+			Repo:               cfg.ArgoAppsRepo,
+			LocalPath:          cfg.ArgoAppsLocalPath,
+			FolderPattern:      cfg.ArgoAppsFolderPattern,
+			EnvKey:             cfg.ArgoAppsEnvKey,
+			EnvFromDestination: cfg.ArgoAppsEnvFromDest,
+			SyncInterval:       cfg.ArgoAppsSyncInterval,
+		})
+	}
+	if cfg.ArgoSourcesFile != "" {
+		loaded, err := argoenv.LoadSources(cfg.ArgoSourcesFile)
+		if err != nil {
+			return nil, fmt.Errorf("loading argo sources: %w", err)
+		}
+		sources = append(sources, loaded...)
+	}
+	if err := argoenv.ValidateSources(sources); err != nil {
+		return nil, err
+	}
+	return sources, nil
+}
+
+// Close releases resources held by the container.
+func (c *Container) Close() error {
+	for _, stop := range c.stopSyncs {
+		stop()
+	}
+	if c.retention != nil {
+		c.retention.Stop()
+	}
+	if c.runStore != nil {
+		if err := c.runStore.Close(); err != nil {
+			return fmt.Errorf("closing run history: %w", err)
+		}
+	}
+	return nil
+}
@@ -50,6 +50,7 @@ func run() error {
 	if err != nil {
 		return fmt.Errorf("building container: %w", err)
 	}
+	defer func() { _ = container.Close() }()
 
 	// Create and run server
 	server := NewServer(container)
@@ -31,6 +31,18 @@ func NewServer(container *Container) *Server {
 		//nolint:errcheck // Health check response, error not actionable
 		_, _ = fmt.Fprintln(w, "ok")
 	})
+	if container.RunsAPI != nil {
+		container.RunsAPI.Register(mux)
+	}
+	if container.WebUI != nil {
+		container.WebUI.Register(mux)
+	}
+	if container.Artifacts != nil {
+		container.Artifacts.Register(mux)
+	}
+	if container.AdminAPI != nil {
+		container.AdminAPI.Register(mux)
+	}
 
 	srv := &http.Server{
 		Addr:         fmt.Sprintf(":%d", container.Config.Port),
@@ -0,0 +1,108 @@
+// Package adminapi serves operator endpoints behind a bearer token.
+package adminapi
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/nathantilsley/chart-val/internal/diff/domain"
+	"github.com/nathantilsley/chart-val/internal/diff/ports"
+	"github.com/nathantilsley/chart-val/internal/platform/httpauth"
+)
+
+// refreshTimeout bounds a refresh. The server's write timeout is too short
+// for pulling large repositories, so the route extends its own deadline.
+const refreshTimeout = 2 * time.Minute
+
+// Handler serves POST /admin/gitops/refresh.
+type Handler struct {
+	indexes ports.GitopsIndexUseCase
+	token   string
+	timeout time.Duration
+	logger  *slog.Logger
+}
+
+// NewHandler creates an admin API handler. Requests must carry token as a
+// bearer token.
+func NewHandler(indexes ports.GitopsIndexUseCase, token string, logger *slog.Logger) *Handler {
+	return &Handler{
+		indexes: indexes,
+		token:   token,
+		timeout: refreshTimeout,
+		logger:  logger,
+	}
+}
+
+// Register adds the admin routes to mux.
+func (h *Handler) Register(mux *http.ServeMux) {
+	mux.HandleFunc("POST /admin/gitops/refresh", httpauth.Require(h.token, h.Refresh))
+}
+
+// Refresh syncs every gitops index now and returns their status. It
+// responds 502 when a sync fails and 504 when the syncs outlast the
+// refresh timeout; the failed indexes keep serving their previous contents
+// and carry the error in their status.
+func (h *Handler) Refresh(w http.ResponseWriter, r *http.Request) {
+	h.logger.Info("gitops index refresh requested")
+
+	ctx, cancel := context.WithTimeout(r.Context(), h.timeout)
+	defer cancel()
+	//nolint:errcheck // Writers that can't extend the deadline keep the server's
+	_ = http.NewResponseController(w).SetWriteDeadline(time.Now().Add(h.timeout + 5*time.Second))
+
+	statuses, err := h.indexes.Sync(ctx)
+	resp := refreshResponse{Indexes: make([]indexJSON, 0, len(statuses))}
+	for _, s := range statuses {
+		resp.Indexes = append(resp.Indexes, toJSON(s))
+	}
+	if err != nil {
+		h.logger.Error("gitops index refresh failed", "error", err)
+		resp.Error = err.Error()
+		status := http.StatusBadGateway
+		if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+			status = http.StatusGatewayTimeout
+		}
+		writeJSON(w, status, resp)
+		return
+	}
+	writeJSON(w, http.StatusOK, resp)
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	//nolint:errcheck // Response write errors are not actionable
+	_ = json.NewEncoder(w).Encode(v)
+}
+
+type refreshResponse struct {
+	Indexes []indexJSON `json:"indexes"`
+	Error   string      `json:"error,omitempty"`
+}
+
+type indexJSON struct {
+	Source       string     `json:"source"`
+	Repo         string     `json:"repo"`
+	Applications int        `json:"applications"`
+	Charts       int        `json:"charts"`
+	LastSync     *time.Time `json:"lastSync,omitempty"` // Absent before the first successful sync
+	LastError    string     `json:"lastError,omitempty"`
+}
+
+func toJSON(s domain.IndexStatus) indexJSON {
+	out := indexJSON{
+		Source:       s.Source,
+		Repo:         s.Repo,
+		Applications: s.Applications,
+		Charts:       s.Charts,
+		LastError:    s.LastError,
+	}
+	if !s.LastSync.IsZero() {
+		out.LastSync = &s.LastSync
+	}
+	return out
+}
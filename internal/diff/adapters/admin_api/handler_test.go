@@ -0,0 +1,123 @@
+package adminapi
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/nathantilsley/chart-val/internal/diff/domain"
+)
+
+type mockIndexes struct {
+	statuses []domain.IndexStatus
+	err      error
+	block    bool // Sync waits for ctx to be done
+	syncs    int
+}
+
+func (m *mockIndexes) RequestSync(string, string) bool { return true }
+
+func (m *mockIndexes) Sync(ctx context.Context) ([]domain.IndexStatus, error) {
+	m.syncs++
+	if m.block {
+		<-ctx.Done()
+		return m.statuses, ctx.Err()
+	}
+	return m.statuses, m.err
+}
+
+func TestRefresh(t *testing.T) {
+	t.Parallel()
+
+	synced := time.Date(2026, 10, 1, 12, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name       string
+		auth       string
+		syncErr    error
+		wantStatus int
+		wantSyncs  int
+		wantError  string
+	}{
+		{name: "refreshed", auth: "Bearer s3cret", wantStatus: http.StatusOK, wantSyncs: 1},
+		{
+			name:       "sync failure",
+			auth:       "Bearer s3cret",
+			syncErr:    errors.New("syncing argo index: git pull failed"),
+			wantStatus: http.StatusBadGateway,
+			wantSyncs:  1,
+			wantError:  "syncing argo index: git pull failed",
+		},
+		{name: "missing token", wantStatus: http.StatusUnauthorized},
+		{name: "wrong token", auth: "Bearer guess", wantStatus: http.StatusUnauthorized},
+		{name: "not a bearer token", auth: "s3cret", wantStatus: http.StatusUnauthorized},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			indexes := &mockIndexes{
+				statuses: []domain.IndexStatus{{
+					Source: "argo", Repo: "https://github.com/example/gitops", Applications: 12, Charts: 4, LastSync: synced,
+				}},
+				err: tt.syncErr,
+			}
+			mux := http.NewServeMux()
+			NewHandler(indexes, "s3cret", slog.New(slog.NewTextHandler(io.Discard, nil))).Register(mux)
+
+			req := httptest.NewRequest(http.MethodPost, "/admin/gitops/refresh", nil)
+			if tt.auth != "" {
+				req.Header.Set("Authorization", tt.auth)
+			}
+			rec := httptest.NewRecorder()
+			mux.ServeHTTP(rec, req)
+
+			if rec.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d: %s", rec.Code, tt.wantStatus, rec.Body)
+			}
+			if indexes.syncs != tt.wantSyncs {
+				t.Errorf("syncs = %d, want %d", indexes.syncs, tt.wantSyncs)
+			}
+			if tt.wantSyncs == 0 {
+				return
+			}
+
+			var resp refreshResponse
+			if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+				t.Fatalf("decoding response: %v", err)
+			}
+			if resp.Error != tt.wantError {
+				t.Errorf("error = %q, want %q", resp.Error, tt.wantError)
+			}
+			if len(resp.Indexes) != 1 || resp.Indexes[0].Applications != 12 ||
+				resp.Indexes[0].LastSync == nil || !resp.Indexes[0].LastSync.Equal(synced) {
+				t.Errorf("indexes = %+v, want the argo index status", resp.Indexes)
+			}
+		})
+	}
+}
+
+func TestRefresh_Timeout(t *testing.T) {
+	t.Parallel()
+
+	indexes := &mockIndexes{block: true}
+	h := NewHandler(indexes, "s3cret", slog.New(slog.NewTextHandler(io.Discard, nil)))
+	h.timeout = 10 * time.Millisecond
+	mux := http.NewServeMux()
+	h.Register(mux)
+
+	req := httptest.NewRequest(http.MethodPost, "/admin/gitops/refresh", nil)
+	req.Header.Set("Authorization", "Bearer s3cret")
+	rec := httptest.NewRecorder()
+	mux.ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusGatewayTimeout {
+		t.Fatalf("status = %d, want %d: %s", rec.Code, http.StatusGatewayTimeout, rec.Body)
+	}
+}
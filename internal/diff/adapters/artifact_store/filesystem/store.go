@@ -0,0 +1,192 @@
+// Package filesystem stores rendered manifest artifacts in a local directory
+// and serves them over HTTP through signed, expiring download links.
+package filesystem
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io/fs"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Store implements ports.ArtifactStorePort on the local filesystem.
+type Store struct {
+	dir     string
+	baseURL string // Public server URL used in download links; empty disables links
+	secret  []byte // Signs download links
+	linkTTL time.Duration
+	now     func() time.Time
+}
+
+// New creates a filesystem artifact store rooted at dir, creating it if
+// needed. Download links point at baseURL (the server's public URL) and are
+// valid for linkTTL.
+func New(dir, baseURL string, secret []byte, linkTTL time.Duration) (*Store, error) {
+	if err := os.MkdirAll(dir, 0o750); err != nil {
+		return nil, fmt.Errorf("creating artifact dir: %w", err)
+	}
+	return &Store{
+		dir:     dir,
+		baseURL: strings.TrimSuffix(baseURL, "/"),
+		secret:  secret,
+		linkTTL: linkTTL,
+		now:     time.Now,
+	}, nil
+}
+
+// Put writes data to dir/key through a temp file so readers never see a
+// partial manifest.
+func (s *Store) Put(_ context.Context, key string, data []byte) error {
+	path, err := s.path(key)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o750); err != nil {
+		return fmt.Errorf("creating artifact dir: %w", err)
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".artifact-*")
+	if err != nil {
+		return fmt.Errorf("creating temp file: %w", err)
+	}
+	defer func() { _ = os.Remove(tmp.Name()) }()
+
+	if _, err := tmp.Write(data); err != nil {
+		_ = tmp.Close()
+		return fmt.Errorf("writing artifact: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("closing artifact: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return fmt.Errorf("renaming artifact: %w", err)
+	}
+	return nil
+}
+
+// URL returns a signed link to the artifact served by Register's handler,
+// or "" when no base URL is configured.
+func (s *Store) URL(_ context.Context, key string) (string, error) {
+	if s.baseURL == "" {
+		return "", nil
+	}
+	if _, err := s.path(key); err != nil {
+		return "", err
+	}
+	expires := strconv.FormatInt(s.now().Add(s.linkTTL).Unix(), 10)
+	q := url.Values{}
+	q.Set("expires", expires)
+	q.Set("signature", s.sign(key, expires))
+	// Keys are already path-escaped by domain.ArtifactKey; escape them again
+	// so the server's unescaping of the request path yields the key verbatim.
+	segments := strings.Split(key, "/")
+	for i, seg := range segments {
+		segments[i] = url.PathEscape(seg)
+	}
+	return s.baseURL + "/artifacts/" + strings.Join(segments, "/") + "?" + q.Encode(), nil
+}
+
+// Prune deletes artifacts last written before cutoff, then removes any
+// directories left empty.
+func (s *Store) Prune(_ context.Context, cutoff time.Time) (int, error) {
+	deleted := 0
+	var dirs []string
+	err := filepath.WalkDir(s.dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			if path != s.dir {
+				dirs = append(dirs, path)
+			}
+			return nil
+		}
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		if info.ModTime().Before(cutoff) {
+			if err := os.Remove(path); err != nil {
+				return err
+			}
+			deleted++
+		}
+		return nil
+	})
+	if err != nil {
+		return deleted, fmt.Errorf("pruning artifacts: %w", err)
+	}
+
+	// Deepest first, so parents empty out after their children
+	for i := len(dirs) - 1; i >= 0; i-- {
+		_ = os.Remove(dirs[i]) // Fails harmlessly when not empty
+	}
+	return deleted, nil
+}
+
+// Register adds the artifact download route to mux.
+func (s *Store) Register(mux *http.ServeMux) {
+	mux.HandleFunc("GET /artifacts/{key...}", s.serve)
+}
+
+func (s *Store) serve(w http.ResponseWriter, r *http.Request) {
+	key := r.PathValue("key")
+	expires := r.URL.Query().Get("expires")
+	signature := r.URL.Query().Get("signature")
+
+	exp, err := strconv.ParseInt(expires, 10, 64)
+	if err != nil || !hmac.Equal([]byte(signature), []byte(s.sign(key, expires))) {
+		http.Error(w, "invalid signature", http.StatusForbidden)
+		return
+	}
+	if s.now().Unix() > exp {
+		http.Error(w, "link expired", http.StatusForbidden)
+		return
+	}
+
+	path, err := s.path(key)
+	if err != nil {
+		http.Error(w, "invalid key", http.StatusBadRequest)
+		return
+	}
+	//nolint:gosec // G304: path is validated to stay inside the artifact dir
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			http.Error(w, "artifact not found", http.StatusNotFound)
+			return
+		}
+		http.Error(w, "failed to read artifact", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/yaml")
+	w.Header().Set("Content-Disposition", "attachment; filename="+strconv.Quote(filepath.Base(path)))
+	//nolint:errcheck // Response write errors are not actionable
+	_, _ = w.Write(data)
+}
+
+// path maps a key to a file inside the store, rejecting keys that would
+// escape it.
+func (s *Store) path(key string) (string, error) {
+	if !filepath.IsLocal(filepath.FromSlash(key)) {
+		return "", fmt.Errorf("invalid artifact key %q", key)
+	}
+	return filepath.Join(s.dir, filepath.FromSlash(key)), nil
+}
+
+func (s *Store) sign(key, expires string) string {
+	mac := hmac.New(sha256.New, s.secret)
+	mac.Write([]byte(key + "\n" + expires))
+	return hex.EncodeToString(mac.Sum(nil))
+}
@@ -0,0 +1,123 @@
+package filesystem
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestStore_PutAndServe(t *testing.T) {
+	ctx := context.Background()
+	mux := http.NewServeMux()
+	srv := httptest.NewServer(mux)
+	defer srv.Close()
+
+	s, err := New(t.TempDir(), srv.URL, []byte("secret"), time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.Register(mux)
+
+	const key = "org/charts/abc/app/team%2Fprod/head.yaml" // Escaped env name, as domain.ArtifactKey produces
+	if err := s.Put(ctx, key, []byte("kind: ConfigMap\n")); err != nil {
+		t.Fatalf("Put() error: %v", err)
+	}
+	link, err := s.URL(ctx, key)
+	if err != nil {
+		t.Fatalf("URL() error: %v", err)
+	}
+
+	tests := []struct {
+		name       string
+		url        string
+		wantStatus int
+		wantBody   string
+	}{
+		{name: "valid link", url: link, wantStatus: http.StatusOK, wantBody: "kind: ConfigMap\n"},
+		{name: "tampered key", url: strings.Replace(link, "head.yaml", "base.yaml", 1), wantStatus: http.StatusForbidden},
+		{name: "unsigned", url: srv.URL + "/artifacts/" + key, wantStatus: http.StatusForbidden},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp, err := http.Get(tt.url)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer func() { _ = resp.Body.Close() }()
+			body, _ := io.ReadAll(resp.Body)
+
+			if resp.StatusCode != tt.wantStatus {
+				t.Fatalf("status = %d, want %d (%s)", resp.StatusCode, tt.wantStatus, body)
+			}
+			if tt.wantBody != "" && string(body) != tt.wantBody {
+				t.Errorf("body = %q, want %q", body, tt.wantBody)
+			}
+		})
+	}
+
+	t.Run("expired link", func(t *testing.T) {
+		s.now = func() time.Time { return time.Now().Add(2 * time.Hour) }
+		defer func() { s.now = time.Now }()
+
+		resp, err := http.Get(link)
+		if err != nil {
+			t.Fatal(err)
+		}
+		_ = resp.Body.Close()
+		if resp.StatusCode != http.StatusForbidden {
+			t.Errorf("status = %d, want %d", resp.StatusCode, http.StatusForbidden)
+		}
+	})
+}
+
+func TestStore_RejectsEscapingKeys(t *testing.T) {
+	s, err := New(t.TempDir(), "", nil, time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, key := range []string{"../outside.yaml", "/abs.yaml", "a/../../b.yaml"} {
+		if err := s.Put(context.Background(), key, []byte("x")); err == nil {
+			t.Errorf("Put(%q) should fail", key)
+		}
+	}
+}
+
+func TestStore_Prune(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+	s, err := New(dir, "", nil, time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, key := range []string{"org/repo/old/app/prod/head.yaml", "org/repo/new/app/prod/head.yaml"} {
+		if err := s.Put(ctx, key, []byte("x")); err != nil {
+			t.Fatal(err)
+		}
+	}
+	old := time.Now().Add(-48 * time.Hour)
+	if err := os.Chtimes(filepath.Join(dir, "org/repo/old/app/prod/head.yaml"), old, old); err != nil {
+		t.Fatal(err)
+	}
+
+	n, err := s.Prune(ctx, time.Now().Add(-24*time.Hour))
+	if err != nil {
+		t.Fatalf("Prune() error: %v", err)
+	}
+	if n != 1 {
+		t.Errorf("Prune() deleted %d, want 1", n)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "org/repo/old")); !os.IsNotExist(err) {
+		t.Error("empty directories should be removed")
+	}
+	if _, err := os.Stat(filepath.Join(dir, "org/repo/new/app/prod/head.yaml")); err != nil {
+		t.Errorf("recent artifact should be kept: %v", err)
+	}
+}
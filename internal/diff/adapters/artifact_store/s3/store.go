@@ -0,0 +1,198 @@
+// Package s3 stores rendered manifest artifacts in an S3-compatible bucket
+// (AWS S3, MinIO, Ceph RGW, ...) using path-style requests signed with AWS
+// Signature Version 4. Download links are presigned GET URLs.
+package s3
+
+import (
+	"bytes"
+	"context"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// maxLinkTTL is the longest validity SigV4 allows for presigned URLs.
+const maxLinkTTL = 7 * 24 * time.Hour
+
+// Config identifies the bucket and credentials.
+type Config struct {
+	Endpoint  string        // Base URL, e.g. "https://s3.eu-west-1.amazonaws.com" or "http://minio:9000"
+	Region    string        // Signing region, e.g. "eu-west-1" ("us-east-1" for MinIO)
+	Bucket    string        // Bucket name
+	Prefix    string        // Optional key prefix, e.g. "chart-val/"
+	AccessKey string        // Access key ID
+	SecretKey string        // Secret access key
+	LinkTTL   time.Duration // Presigned link validity; capped at 7 days
+}
+
+// Store implements ports.ArtifactStorePort on an S3-compatible bucket.
+type Store struct {
+	client   *http.Client
+	endpoint *url.URL
+	bucket   string
+	prefix   string
+	linkTTL  time.Duration
+	signer   signer
+	now      func() time.Time
+}
+
+// New creates an S3 artifact store.
+func New(httpClient *http.Client, cfg Config) (*Store, error) {
+	endpoint, err := url.Parse(strings.TrimSuffix(cfg.Endpoint, "/"))
+	if err != nil || endpoint.Scheme == "" || endpoint.Host == "" {
+		return nil, fmt.Errorf("invalid S3 endpoint %q", cfg.Endpoint)
+	}
+	if cfg.Bucket == "" {
+		return nil, fmt.Errorf("S3 bucket is required")
+	}
+	ttl := cfg.LinkTTL
+	if ttl <= 0 || ttl > maxLinkTTL {
+		ttl = maxLinkTTL
+	}
+	return &Store{
+		client:   httpClient,
+		endpoint: endpoint,
+		bucket:   cfg.Bucket,
+		prefix:   cfg.Prefix,
+		linkTTL:  ttl,
+		signer:   signer{accessKey: cfg.AccessKey, secretKey: cfg.SecretKey, region: cfg.Region},
+		now:      time.Now,
+	}, nil
+}
+
+// Put uploads data under key.
+func (s *Store) Put(ctx context.Context, key string, data []byte) error {
+	resp, err := s.do(ctx, http.MethodPut, s.objectPath(key), nil, data)
+	if err != nil {
+		return fmt.Errorf("uploading %s: %w", key, err)
+	}
+	_ = resp.Body.Close()
+	return nil
+}
+
+// URL returns a presigned GET link valid for the configured link TTL.
+func (s *Store) URL(_ context.Context, key string) (string, error) {
+	uri := s.objectPath(key)
+	query := s.signer.presign(s.endpoint.Host, uri, s.now().UTC(), s.linkTTL)
+	return s.endpoint.Scheme + "://" + s.endpoint.Host + uri + "?" + query, nil
+}
+
+// Prune lists every object under the prefix and deletes those last modified
+// before cutoff. Listing completes before anything is deleted so paging
+// isn't disturbed by the deletions.
+func (s *Store) Prune(ctx context.Context, cutoff time.Time) (int, error) {
+	var expired []string
+	token := ""
+	for {
+		page, err := s.list(ctx, token)
+		if err != nil {
+			return 0, err
+		}
+		for _, obj := range page.Contents {
+			if obj.LastModified.Before(cutoff) {
+				expired = append(expired, obj.Key)
+			}
+		}
+		if !page.IsTruncated || page.NextContinuationToken == "" {
+			break
+		}
+		token = page.NextContinuationToken
+	}
+
+	for i, key := range expired {
+		resp, err := s.do(ctx, http.MethodDelete, s.bucketPath()+"/"+escapePath(key), nil, nil)
+		if err != nil {
+			return i, fmt.Errorf("deleting %s: %w", key, err)
+		}
+		_ = resp.Body.Close()
+	}
+	return len(expired), nil
+}
+
+type listBucketResult struct {
+	IsTruncated           bool   `xml:"IsTruncated"`
+	NextContinuationToken string `xml:"NextContinuationToken"`
+	Contents              []struct {
+		Key          string    `xml:"Key"`
+		LastModified time.Time `xml:"LastModified"`
+	} `xml:"Contents"`
+}
+
+// list fetches one ListObjectsV2 page.
+func (s *Store) list(ctx context.Context, token string) (listBucketResult, error) {
+	q := url.Values{}
+	q.Set("list-type", "2")
+	if s.prefix != "" {
+		q.Set("prefix", s.prefix)
+	}
+	if token != "" {
+		q.Set("continuation-token", token)
+	}
+
+	resp, err := s.do(ctx, http.MethodGet, s.bucketPath(), q, nil)
+	if err != nil {
+		return listBucketResult{}, fmt.Errorf("listing objects: %w", err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	var page listBucketResult
+	if err := xml.NewDecoder(resp.Body).Decode(&page); err != nil {
+		return listBucketResult{}, fmt.Errorf("decoding object list: %w", err)
+	}
+	return page, nil
+}
+
+// do sends a signed request and returns the response when its status is 2xx.
+func (s *Store) do(ctx context.Context, method, uri string, query url.Values, body []byte) (*http.Response, error) {
+	rawQuery := canonicalQuery(query)
+	target := s.endpoint.Scheme + "://" + s.endpoint.Host + uri
+	if rawQuery != "" {
+		target += "?" + rawQuery
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, target, bytes.NewReader(body))
+	if err != nil {
+		return nil, fmt.Errorf("creating request: %w", err)
+	}
+	// Send the path exactly as signed rather than Go's re-escaped form
+	req.URL.Opaque = "//" + s.endpoint.Host + uri
+
+	t := s.now().UTC()
+	payloadHash := hexSHA256(body)
+	headers := map[string]string{
+		"host":                 s.endpoint.Host,
+		"x-amz-content-sha256": payloadHash,
+		"x-amz-date":           t.Format(amzDateFormat),
+	}
+	signedHeaders, signature := s.signer.sign(method, uri, rawQuery, headers, payloadHash, t)
+
+	req.Header.Set("X-Amz-Content-Sha256", payloadHash)
+	req.Header.Set("X-Amz-Date", t.Format(amzDateFormat))
+	req.Header.Set("Authorization", s.signer.authorization(t, signedHeaders, signature))
+	if method == http.MethodPut {
+		req.Header.Set("Content-Type", "application/yaml")
+	}
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		_ = resp.Body.Close()
+		return nil, fmt.Errorf("status %d: %s", resp.StatusCode, strings.TrimSpace(string(msg)))
+	}
+	return resp, nil
+}
+
+func (s *Store) bucketPath() string {
+	return strings.TrimSuffix(s.endpoint.Path, "/") + "/" + awsEscape(s.bucket)
+}
+
+func (s *Store) objectPath(key string) string {
+	return s.bucketPath() + "/" + escapePath(s.prefix+key)
+}
@@ -0,0 +1,203 @@
+package s3
+
+import (
+	"context"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeS3 is a minimal path-style S3 stand-in for a single bucket. It checks
+// every request's signature and serves pages of two objects when listing.
+type fakeS3 struct {
+	bucket string
+	signer signer
+
+	mu       sync.Mutex
+	objects  map[string]fakeObject
+	badSigns int
+}
+
+type fakeObject struct {
+	data     []byte
+	modified time.Time
+}
+
+func (f *fakeS3) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if !f.validSignature(r) {
+		f.badSigns++
+		http.Error(w, "SignatureDoesNotMatch", http.StatusForbidden)
+		return
+	}
+
+	path := strings.TrimPrefix(r.URL.Path, "/"+f.bucket)
+	key := strings.TrimPrefix(path, "/")
+	switch {
+	case r.Method == http.MethodPut:
+		data, _ := io.ReadAll(r.Body)
+		f.objects[key] = fakeObject{data: data, modified: time.Now()}
+	case r.Method == http.MethodGet && key == "":
+		f.list(w, r)
+	case r.Method == http.MethodGet:
+		obj, ok := f.objects[key]
+		if !ok {
+			http.Error(w, "NoSuchKey", http.StatusNotFound)
+			return
+		}
+		_, _ = w.Write(obj.data)
+	case r.Method == http.MethodDelete:
+		delete(f.objects, key)
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+func (f *fakeS3) list(w http.ResponseWriter, r *http.Request) {
+	var keys []string
+	for k := range f.objects {
+		if strings.HasPrefix(k, r.URL.Query().Get("prefix")) {
+			keys = append(keys, k)
+		}
+	}
+	sort.Strings(keys)
+
+	start := 0
+	if token := r.URL.Query().Get("continuation-token"); token != "" {
+		_, _ = fmt.Sscanf(token, "%d", &start)
+	}
+	end := min(start+2, len(keys))
+
+	var b strings.Builder
+	b.WriteString("<ListBucketResult>")
+	if end < len(keys) {
+		fmt.Fprintf(&b, "<IsTruncated>true</IsTruncated><NextContinuationToken>%d</NextContinuationToken>", end)
+	}
+	for _, k := range keys[start:end] {
+		var escaped strings.Builder
+		_ = xml.EscapeText(&escaped, []byte(k))
+		fmt.Fprintf(&b, "<Contents><Key>%s</Key><LastModified>%s</LastModified></Contents>",
+			escaped.String(), f.objects[k].modified.UTC().Format(time.RFC3339))
+	}
+	b.WriteString("</ListBucketResult>")
+	_, _ = io.WriteString(w, b.String())
+}
+
+// validSignature recomputes the header signature from what arrived on the wire.
+func (f *fakeS3) validSignature(r *http.Request) bool {
+	t, err := time.Parse(amzDateFormat, r.Header.Get("X-Amz-Date"))
+	if err != nil {
+		return false
+	}
+	headers := map[string]string{
+		"host":                 r.Host,
+		"x-amz-content-sha256": r.Header.Get("X-Amz-Content-Sha256"),
+		"x-amz-date":           r.Header.Get("X-Amz-Date"),
+	}
+	signedHeaders, sig := f.signer.sign(r.Method, r.URL.EscapedPath(), r.URL.RawQuery, headers,
+		r.Header.Get("X-Amz-Content-Sha256"), t)
+	return r.Header.Get("Authorization") == f.signer.authorization(t, signedHeaders, sig)
+}
+
+func newTestStore(t *testing.T, prefix string) (*Store, *fakeS3) {
+	t.Helper()
+	fake := &fakeS3{
+		bucket:  "artifacts",
+		signer:  signer{accessKey: "minio", secretKey: "minio123", region: "us-east-1"},
+		objects: make(map[string]fakeObject),
+	}
+	srv := httptest.NewServer(fake)
+	t.Cleanup(srv.Close)
+
+	s, err := New(srv.Client(), Config{
+		Endpoint:  srv.URL,
+		Region:    "us-east-1",
+		Bucket:    "artifacts",
+		Prefix:    prefix,
+		AccessKey: "minio",
+		SecretKey: "minio123",
+		LinkTTL:   time.Hour,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return s, fake
+}
+
+func TestStore_PutAndURL(t *testing.T) {
+	ctx := context.Background()
+	s, fake := newTestStore(t, "chart-val/")
+
+	const key = "org/charts/abc/app/team%2Fprod/head.yaml"
+	if err := s.Put(ctx, key, []byte("kind: ConfigMap\n")); err != nil {
+		t.Fatalf("Put() error: %v", err)
+	}
+	if got := string(fake.objects["chart-val/"+key].data); got != "kind: ConfigMap\n" {
+		t.Errorf("stored object = %q", got)
+	}
+
+	link, err := s.URL(ctx, key)
+	if err != nil {
+		t.Fatalf("URL() error: %v", err)
+	}
+	if !strings.Contains(link, "/artifacts/chart-val/org/charts/abc/app/team%252Fprod/head.yaml?") ||
+		!strings.Contains(link, "X-Amz-Expires=3600") || !strings.Contains(link, "X-Amz-Signature=") {
+		t.Errorf("URL() = %s, want a presigned object link", link)
+	}
+	if fake.badSigns != 0 {
+		t.Errorf("%d requests had invalid signatures", fake.badSigns)
+	}
+}
+
+func TestStore_Prune(t *testing.T) {
+	ctx := context.Background()
+	s, fake := newTestStore(t, "")
+
+	for i := range 5 {
+		if err := s.Put(ctx, fmt.Sprintf("org/repo/sha%d/app/prod/head.yaml", i), []byte("x")); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// Age three objects past the cutoff; listing spans several pages
+	for _, i := range []int{0, 2, 4} {
+		k := fmt.Sprintf("org/repo/sha%d/app/prod/head.yaml", i)
+		obj := fake.objects[k]
+		obj.modified = time.Now().Add(-48 * time.Hour)
+		fake.objects[k] = obj
+	}
+
+	n, err := s.Prune(ctx, time.Now().Add(-24*time.Hour))
+	if err != nil {
+		t.Fatalf("Prune() error: %v", err)
+	}
+	if n != 3 || len(fake.objects) != 2 {
+		t.Errorf("Prune() deleted %d, %d objects left; want 3 deleted, 2 left", n, len(fake.objects))
+	}
+	if fake.badSigns != 0 {
+		t.Errorf("%d requests had invalid signatures", fake.badSigns)
+	}
+}
+
+func TestStore_ErrorStatus(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		http.Error(w, "<Error><Code>NoSuchBucket</Code></Error>", http.StatusNotFound)
+	}))
+	defer srv.Close()
+
+	s, err := New(srv.Client(), Config{Endpoint: srv.URL, Region: "us-east-1", Bucket: "missing"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = s.Put(context.Background(), "k.yaml", []byte("x"))
+	if err == nil || !strings.Contains(err.Error(), "status 404") || !strings.Contains(err.Error(), "NoSuchBucket") {
+		t.Errorf("Put() error = %v, want status 404 with body", err)
+	}
+}
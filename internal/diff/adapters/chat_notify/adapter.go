@@ -0,0 +1,167 @@
+// Package chatnotify posts compact diff summaries to Slack and Microsoft Teams.
+package chatnotify
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"os"
+	"strings"
+
+	"github.com/nathantilsley/chart-val/internal/diff/domain"
+)
+
+// Adapter implements ports.ReportingPort by notifying chat channels about
+// charts whose rendered manifests changed. It only acts on final results;
+// check run and comment calls are no-ops. Each webhook hears about a
+// chart's diff in a PR environment once; unchanged diffs on later runs
+// aren't repeated.
+type Adapter struct {
+	httpClient *http.Client
+	appName    string
+	routes     []Route
+	sent       *sentSet
+}
+
+// New creates a chat notification adapter that routes results using routes.
+func New(httpClient *http.Client, appName string, routes []Route) *Adapter {
+	return &Adapter{
+		httpClient: httpClient,
+		appName:    appName,
+		routes:     routes,
+		sent:       newSentSet(),
+	}
+}
+
+// destination is a single webhook and the changed results routed to it.
+type destination struct {
+	kind    string // "slack" or "teams"
+	url     string
+	results []domain.DiffResult
+	keys    []string // sentKey of each result
+}
+
+// CreateInProgressCheck is a no-op; chat channels only hear about final results.
+func (a *Adapter) CreateInProgressCheck(_ context.Context, _ domain.PRContext) (int64, error) {
+	return 0, nil
+}
+
+// UpdateCheckWithResults sends one notification per webhook whose routes
+// match at least one changed result it hasn't been notified about.
+func (a *Adapter) UpdateCheckWithResults(
+	ctx context.Context,
+	pr domain.PRContext,
+	_ int64,
+	results []domain.DiffResult,
+) error {
+	logger := slog.New(slog.NewTextHandler(os.Stderr, nil))
+
+	var errs []error
+	for _, dest := range a.route(pr, results) {
+		var err error
+		switch dest.kind {
+		case "slack":
+			err = a.post(ctx, dest.url, slackPayload(a.appName, pr, dest.results))
+		case "teams":
+			err = a.post(ctx, dest.url, teamsPayload(a.appName, pr, dest.results))
+		}
+		if err != nil {
+			errs = append(errs, fmt.Errorf("sending %s notification: %w", dest.kind, err))
+			continue
+		}
+		a.sent.add(dest.keys...)
+		logger.Info("chat notification sent", "kind", dest.kind, "pr", pr.PRNumber, "results", len(dest.results))
+	}
+	return errors.Join(errs...)
+}
+
+// PostComment is a no-op; notifications are sent once per run from UpdateCheckWithResults.
+func (a *Adapter) PostComment(_ context.Context, _ domain.PRContext, _ []domain.DiffResult) error {
+	return nil
+}
+
+// ResolveComment is a no-op; chat notifications are not retracted.
+func (a *Adapter) ResolveComment(_ context.Context, _ domain.PRContext, _ string) error {
+	return nil
+}
+
+// CommentedCharts returns nothing; chat notifications are not PR comments.
+func (a *Adapter) CommentedCharts(_ context.Context, _ domain.PRContext) ([]string, error) {
+	return nil, nil
+}
+
+// route groups changed results by webhook. A result matched by several routes
+// pointing at the same webhook is only included once, and results the
+// webhook was already notified about are left out.
+func (a *Adapter) route(pr domain.PRContext, results []domain.DiffResult) []*destination {
+	var dests []*destination
+	byURL := make(map[string]*destination)
+	seen := make(map[string]map[int]bool)
+
+	add := func(kind, url string, idx int, r domain.DiffResult) {
+		if url == "" {
+			return
+		}
+		if seen[url][idx] {
+			return
+		}
+		key := sentKey(url, pr, r)
+		if a.sent.contains(key) {
+			return
+		}
+		dest, ok := byURL[url]
+		if !ok {
+			dest = &destination{kind: kind, url: url}
+			byURL[url] = dest
+			seen[url] = make(map[int]bool)
+			dests = append(dests, dest)
+		}
+		seen[url][idx] = true
+		dest.results = append(dest.results, r)
+		dest.keys = append(dest.keys, key)
+	}
+
+	for i, r := range results {
+		if r.Status != domain.StatusChanges {
+			continue
+		}
+		for _, route := range a.routes {
+			if !route.matches(pr, r) {
+				continue
+			}
+			add("slack", route.Slack, i, r)
+			add("teams", route.Teams, i, r)
+		}
+	}
+	return dests
+}
+
+func (a *Adapter) post(ctx context.Context, url string, payload any) error {
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return fmt.Errorf("encoding payload: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("creating request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := a.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("posting webhook: %w", err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("webhook returned %d: %s", resp.StatusCode, strings.TrimSpace(string(msg)))
+	}
+	return nil
+}
@@ -0,0 +1,203 @@
+package chatnotify
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/nathantilsley/chart-val/internal/diff/domain"
+)
+
+// webhookRecorder captures JSON payloads posted to a test server.
+type webhookRecorder struct {
+	mu       sync.Mutex
+	payloads map[string][]map[string]any // path -> payloads
+}
+
+func newWebhookServer(t *testing.T) (*httptest.Server, *webhookRecorder) {
+	t.Helper()
+	rec := &webhookRecorder{payloads: make(map[string][]map[string]any)}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/fail" {
+			http.Error(w, "nope", http.StatusInternalServerError)
+			return
+		}
+		var payload map[string]any
+		if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+			t.Errorf("decoding payload: %v", err)
+		}
+		rec.mu.Lock()
+		rec.payloads[r.URL.Path] = append(rec.payloads[r.URL.Path], payload)
+		rec.mu.Unlock()
+	}))
+	t.Cleanup(srv.Close)
+	return srv, rec
+}
+
+func TestUpdateCheckWithResults_Routing(t *testing.T) {
+	srv, rec := newWebhookServer(t)
+
+	routes := []Route{
+		{Environment: "prod*", Slack: srv.URL + "/sre"},
+		{Chart: "payments", Slack: srv.URL + "/sre"}, // same webhook, must not duplicate
+		{Org: "other-org", Teams: srv.URL + "/other"},
+		{Chart: "payments", Environment: "prod", Teams: srv.URL + "/payments"},
+	}
+	a := New(srv.Client(), "chart-val", routes)
+	pr := domain.PRContext{Owner: "my-org", Repo: "charts", PRNumber: 42}
+
+	results := []domain.DiffResult{
+		{
+			ChartName: "payments", Environment: "prod", Status: domain.StatusChanges,
+			Resources: domain.ResourceChanges{Added: 1, Modified: 2},
+		},
+		{ChartName: "payments", Environment: "dev", Status: domain.StatusChanges},
+		{ChartName: "web", Environment: "prod-eu", Status: domain.StatusSuccess},
+		{ChartName: "web", Environment: "prod", Status: domain.StatusError},
+	}
+
+	if err := a.UpdateCheckWithResults(context.Background(), pr, 0, results); err != nil {
+		t.Fatalf("UpdateCheckWithResults() error: %v", err)
+	}
+
+	sre := rec.payloads["/sre"]
+	if len(sre) != 1 {
+		t.Fatalf("slack webhook received %d payloads, want 1", len(sre))
+	}
+	text, _ := sre[0]["text"].(string)
+	for _, want := range []string{
+		"<https://github.com/my-org/charts/pull/42|my-org/charts#42>",
+		"`payments`: `prod` (1 added, 2 modified), `dev` (changed)",
+	} {
+		if !strings.Contains(text, want) {
+			t.Errorf("slack text missing %q:\n%s", want, text)
+		}
+	}
+	if strings.Contains(text, "web") {
+		t.Errorf("slack text should only include changed results:\n%s", text)
+	}
+
+	if len(rec.payloads["/other"]) != 0 {
+		t.Error("route for another org should not be notified")
+	}
+
+	teams := rec.payloads["/payments"]
+	if len(teams) != 1 {
+		t.Fatalf("teams webhook received %d payloads, want 1", len(teams))
+	}
+	if teams[0]["@type"] != "MessageCard" {
+		t.Errorf("teams payload @type = %v, want MessageCard", teams[0]["@type"])
+	}
+	if text, _ := teams[0]["text"].(string); strings.Contains(text, "`dev`") {
+		t.Errorf("teams route is prod-only, got:\n%s", text)
+	}
+}
+
+func TestUpdateCheckWithResults_WebhookError(t *testing.T) {
+	srv, rec := newWebhookServer(t)
+
+	routes := []Route{{Slack: srv.URL + "/fail"}, {Slack: srv.URL + "/ok"}}
+	a := New(srv.Client(), "chart-val", routes)
+	results := []domain.DiffResult{{ChartName: "app", Environment: "prod", Status: domain.StatusChanges}}
+
+	err := a.UpdateCheckWithResults(context.Background(), domain.PRContext{}, 0, results)
+	if err == nil || !strings.Contains(err.Error(), "webhook returned 500") {
+		t.Fatalf("UpdateCheckWithResults() error = %v, want webhook status error", err)
+	}
+	if len(rec.payloads["/ok"]) != 1 {
+		t.Error("a failing webhook should not stop the others")
+	}
+}
+
+func TestUpdateCheckWithResults_Dedupe(t *testing.T) {
+	srv, rec := newWebhookServer(t)
+
+	a := New(srv.Client(), "chart-val", []Route{{Slack: srv.URL + "/ok"}, {Chart: "api", Teams: srv.URL + "/fail"}})
+	pr := domain.PRContext{Owner: "my-org", Repo: "charts", PRNumber: 42}
+	result := func(chart, diff string) domain.DiffResult {
+		return domain.DiffResult{ChartName: chart, Environment: "prod", Status: domain.StatusChanges, UnifiedDiff: diff}
+	}
+
+	runs := []struct {
+		results []domain.DiffResult
+		want    int // payloads received by /ok so far
+	}{
+		{results: []domain.DiffResult{result("api", "-a\n+b"), result("web", "-x\n+y")}, want: 1},
+		{results: []domain.DiffResult{result("api", "-a\n+b"), result("web", "-x\n+y")}, want: 1}, // rerun
+		{results: []domain.DiffResult{result("api", "-a\n+c"), result("web", "-x\n+y")}, want: 2}, // api diff changed
+	}
+	for i, run := range runs {
+		// The failing webhook is retried on every run
+		if err := a.UpdateCheckWithResults(context.Background(), pr, 0, run.results); err == nil {
+			t.Errorf("run %d: want error from failing webhook", i)
+		}
+		if got := len(rec.payloads["/ok"]); got != run.want {
+			t.Fatalf("run %d: webhook received %d payloads, want %d", i, got, run.want)
+		}
+	}
+	text, _ := rec.payloads["/ok"][1]["text"].(string)
+	if !strings.Contains(text, "`api`") || strings.Contains(text, "`web`") {
+		t.Errorf("repeat notification should only include the changed diff:\n%s", text)
+	}
+
+	other := pr
+	other.PRNumber = 43
+	if err := a.UpdateCheckWithResults(context.Background(), other, 0, runs[0].results[1:]); err != nil {
+		t.Fatalf("UpdateCheckWithResults() error: %v", err)
+	}
+	if got := len(rec.payloads["/ok"]); got != 3 {
+		t.Errorf("another PR with the same diff should be notified, got %d payloads", got)
+	}
+}
+
+func TestParseRoutes(t *testing.T) {
+	tests := []struct {
+		name    string
+		yaml    string
+		wantErr string
+	}{
+		{
+			name: "valid",
+			yaml: "routes:\n  - environment: prod\n    slack: https://hooks.slack.com/x\n",
+		},
+		{
+			name:    "no routes",
+			yaml:    "routes: []\n",
+			wantErr: "no routes defined",
+		},
+		{
+			name:    "missing webhook",
+			yaml:    "routes:\n  - environment: prod\n",
+			wantErr: "needs a slack or teams webhook URL",
+		},
+		{
+			name:    "renamed owner field",
+			yaml:    "routes:\n  - owner: my-org\n    slack: https://x\n",
+			wantErr: "owner is renamed to org",
+		},
+		{
+			name:    "bad pattern",
+			yaml:    "routes:\n  - chart: \"[\"\n    teams: https://x\n",
+			wantErr: "invalid pattern",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := ParseRoutes([]byte(tt.yaml))
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Fatalf("ParseRoutes() unexpected error: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Fatalf("ParseRoutes() error = %v, want containing %q", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,69 @@
+package chatnotify
+
+import (
+	"container/list"
+	"crypto/sha256"
+	"encoding/hex"
+	"strconv"
+	"sync"
+
+	"github.com/nathantilsley/chart-val/internal/diff/domain"
+)
+
+// maxSentEntries bounds how many notified results are remembered.
+const maxSentEntries = 10000
+
+// sentSet remembers which results each webhook was notified about, so a
+// rerun or a push that doesn't change a chart's diff doesn't repeat the
+// notification. It is an LRU bounded by entry count and is lost on
+// restart.
+type sentSet struct {
+	mu      sync.Mutex
+	order   *list.List // Front is most recently used
+	entries map[string]*list.Element
+}
+
+func newSentSet() *sentSet {
+	return &sentSet{
+		order:   list.New(),
+		entries: make(map[string]*list.Element),
+	}
+}
+
+// sentKey identifies a notification about a result: the webhook, the PR,
+// the chart and environment, and the diff itself.
+func sentKey(url string, pr domain.PRContext, r domain.DiffResult) string {
+	diff := sha256.Sum256([]byte(r.UnifiedDiff))
+	return url + "\x00" + pr.Owner + "/" + pr.Repo + "#" + strconv.Itoa(pr.PRNumber) +
+		"\x00" + r.ChartName + "\x00" + r.Environment + "\x00" + hex.EncodeToString(diff[:])
+}
+
+func (s *sentSet) contains(key string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	el, ok := s.entries[key]
+	if ok {
+		s.order.MoveToFront(el)
+	}
+	return ok
+}
+
+// add records keys, evicting the least recently used beyond maxSentEntries.
+func (s *sentSet) add(keys ...string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, key := range keys {
+		if el, ok := s.entries[key]; ok {
+			s.order.MoveToFront(el)
+			continue
+		}
+		s.entries[key] = s.order.PushFront(key)
+	}
+	for s.order.Len() > maxSentEntries {
+		oldest := s.order.Back()
+		s.order.Remove(oldest)
+		delete(s.entries, oldest.Value.(string))
+	}
+}
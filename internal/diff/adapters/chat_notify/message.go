@@ -0,0 +1,73 @@
+package chatnotify
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/nathantilsley/chart-val/internal/diff/domain"
+)
+
+// slackPayload builds an incoming webhook message using Slack mrkdwn.
+func slackPayload(appName string, pr domain.PRContext, results []domain.DiffResult) map[string]any {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "*%s*: <%s|%s> changes rendered manifests\n", appName, prURL(pr), prLabel(pr))
+	for _, line := range chartLines(results) {
+		fmt.Fprintf(&sb, "• %s\n", line)
+	}
+	return map[string]any{"text": strings.TrimSuffix(sb.String(), "\n")}
+}
+
+// teamsPayload builds a connector MessageCard using Teams markdown.
+func teamsPayload(appName string, pr domain.PRContext, results []domain.DiffResult) map[string]any {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "[%s](%s) changes rendered manifests\n\n", prLabel(pr), prURL(pr))
+	for _, line := range chartLines(results) {
+		fmt.Fprintf(&sb, "- %s\n", line)
+	}
+	return map[string]any{
+		"@type":    "MessageCard",
+		"@context": "https://schema.org/extensions",
+		"summary":  fmt.Sprintf("%s: %s changes rendered manifests", appName, prLabel(pr)),
+		"title":    appName,
+		"text":     strings.TrimSuffix(sb.String(), "\n"),
+	}
+}
+
+// chartLines renders one line per chart listing its changed environments
+// and resource counts, e.g. "`my-app`: `prod` (1 added, 2 modified)".
+func chartLines(results []domain.DiffResult) []string {
+	var lines []string
+	for _, group := range domain.GroupByChart(results) {
+		envs := make([]string, 0, len(group))
+		for _, r := range group {
+			envs = append(envs, fmt.Sprintf("`%s` (%s)", r.Environment, formatResourceChanges(r.Resources)))
+		}
+		lines = append(lines, fmt.Sprintf("`%s`: %s", group[0].ChartName, strings.Join(envs, ", ")))
+	}
+	return lines
+}
+
+func formatResourceChanges(c domain.ResourceChanges) string {
+	var parts []string
+	if c.Added > 0 {
+		parts = append(parts, fmt.Sprintf("%d added", c.Added))
+	}
+	if c.Modified > 0 {
+		parts = append(parts, fmt.Sprintf("%d modified", c.Modified))
+	}
+	if c.Removed > 0 {
+		parts = append(parts, fmt.Sprintf("%d removed", c.Removed))
+	}
+	if len(parts) == 0 {
+		return "changed"
+	}
+	return strings.Join(parts, ", ")
+}
+
+func prLabel(pr domain.PRContext) string {
+	return fmt.Sprintf("%s/%s#%d", pr.Owner, pr.Repo, pr.PRNumber)
+}
+
+func prURL(pr domain.PRContext) string {
+	return fmt.Sprintf("https://github.com/%s/%s/pull/%d", pr.Owner, pr.Repo, pr.PRNumber)
+}
@@ -0,0 +1,82 @@
+package chatnotify
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/nathantilsley/chart-val/internal/diff/domain"
+)
+
+// Route sends notifications for matching results to Slack and/or Teams.
+// Chart, Environment, and Org are path.Match globs; empty matches
+// everything. Every matching route is notified, not just the first.
+type Route struct {
+	Chart       string `yaml:"chart"`
+	Environment string `yaml:"environment"`
+	Org         string `yaml:"org"`   // GitHub org or user owning the PR's repository, not CODEOWNERS
+	Slack       string `yaml:"slack"` // Slack incoming webhook URL
+	Teams       string `yaml:"teams"` // Microsoft Teams connector webhook URL
+
+	// Owner is the former name of Org, rejected so old files don't
+	// silently match every repository.
+	Owner string `yaml:"owner"`
+}
+
+type routesFile struct {
+	Routes []Route `yaml:"routes"`
+}
+
+// LoadRoutes reads notification routes from a YAML file. ${VAR} references
+// are expanded from the environment so webhook URLs can be kept out of the file.
+func LoadRoutes(file string) ([]Route, error) {
+	//nolint:gosec // G304: path is from trusted config, not user input
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return nil, fmt.Errorf("reading notification routes: %w", err)
+	}
+	return ParseRoutes([]byte(os.ExpandEnv(string(data))))
+}
+
+// ParseRoutes parses and validates YAML notification routes.
+func ParseRoutes(data []byte) ([]Route, error) {
+	var f routesFile
+	if err := yaml.Unmarshal(data, &f); err != nil {
+		return nil, fmt.Errorf("parsing notification routes: %w", err)
+	}
+	if len(f.Routes) == 0 {
+		return nil, errors.New("notification routes: no routes defined")
+	}
+	for i, r := range f.Routes {
+		if r.Owner != "" {
+			return nil, fmt.Errorf("route %d: owner is renamed to org (the repository's GitHub org or user)", i)
+		}
+		if r.Slack == "" && r.Teams == "" {
+			return nil, fmt.Errorf("route %d: needs a slack or teams webhook URL", i)
+		}
+		for _, pattern := range []string{r.Chart, r.Environment, r.Org} {
+			if _, err := path.Match(pattern, ""); err != nil {
+				return nil, fmt.Errorf("route %d: invalid pattern %q: %w", i, pattern, err)
+			}
+		}
+	}
+	return f.Routes, nil
+}
+
+// matches reports whether the route applies to a result in the given PR.
+func (r Route) matches(pr domain.PRContext, result domain.DiffResult) bool {
+	return globMatch(r.Org, pr.Owner) &&
+		globMatch(r.Chart, result.ChartName) &&
+		globMatch(r.Environment, result.Environment)
+}
+
+func globMatch(pattern, value string) bool {
+	if pattern == "" {
+		return true
+	}
+	ok, err := path.Match(pattern, value)
+	return err == nil && ok
+}
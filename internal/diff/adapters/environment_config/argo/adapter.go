@@ -7,15 +7,20 @@ import (
 	"fmt"
 	"log/slog"
 	"os"
-	"os/exec"
+	"path"
 	"path/filepath"
+	"slices"
+	"sort"
 	"strings"
 	"sync"
 	"time"
 
+	"go.opentelemetry.io/otel/metric"
+	"go.opentelemetry.io/otel/metric/noop"
 	"gopkg.in/yaml.v3"
 
 	"github.com/nathantilsley/chart-val/internal/diff/domain"
+	"github.com/nathantilsley/chart-val/internal/platform/gitmirror"
 )
 
 // ErrNotAnApplication is returned when a manifest is not an Argo Application.
@@ -23,147 +28,187 @@ var ErrNotAnApplication = errors.New("not an Application")
 
 // Adapter implements ports.EnvironmentConfigPort by reading Argo CD Application
 // manifests from a locally cloned Git repository. It scans the entire repo
-// for Application files and extracts environment names from directory paths.
+// for Application files and matches them to charts by the repository and
+// path of their chart source.
 type Adapter struct {
-	repoURL       string        // Git repository URL
-	localPath     string        // Local filesystem path for clone
-	syncInterval  time.Duration // How often to sync the repo
-	folderPattern string        // Folder structure pattern (e.g., "{chartName}/{envName}")
-	chartDir      string        // Top-level chart directory (e.g., "charts")
-
-	mu     sync.RWMutex         // Protects index during updates
-	stopCh chan struct{}        // Signal to stop background sync
-	index  map[string][]AppData // Cache: chartName -> list of apps
+	name               string        // Source name
+	repoURL            string        // Git repository URL
+	repos              []string      // owner/repo globs of the chart repos indexed; empty indexes all
+	localPath          string        // Local filesystem path for clone
+	syncInterval       time.Duration // How often to sync the repo
+	folderPattern      string        // Folder structure pattern (e.g., "{chartName}/{envName}")
+	envKey             string        // Label or annotation naming an Application's environment
+	envFromDestination bool          // Use spec.destination.name as the environment
+
+	mu       sync.RWMutex           // Protects index and sync status during swaps
+	index    map[chartKey][]AppData // Cache: chart source -> list of apps
+	appCount int                    // Applications in index
+	lastSync time.Time              // Last successful sync
+	lastErr  error                  // Error of the last sync, nil when it succeeded
+
+	mirror *gitmirror.Mirror  // Clone of repoURL at localPath; lookups never wait for its syncs
+	stop   context.CancelFunc // Stops background sync and abandons one in progress
 	logger *slog.Logger
+
+	syncs        metric.Int64Counter
+	syncDuration metric.Float64Histogram
+}
+
+// chartKey identifies a chart by its repository ("owner/repo", lower-cased)
+// and its path within that repository.
+type chartKey struct {
+	Repo string
+	Path string
 }
 
 // AppData represents the minimal data we need from an Argo Application.
 type AppData struct {
-	ChartName   string   // Extracted from spec.source.path (e.g., "my-app")
-	ChartPath   string   // Full path from spec.source.path (e.g., "charts/my-app")
-	Environment string   // Extracted from file path (e.g., "dev", "staging", "prod")
-	ValueFiles  []string // From spec.source.helm.valueFiles
-	RepoURL     string   // From spec.source.repoURL
+	Name        string             // From metadata.name
+	ChartName   string             // Base name of spec.source.path (e.g., "my-app")
+	ChartPath   string             // Full path from spec.source.path (e.g., "charts/my-app")
+	Environment string             // From the environment label, destination or file path (e.g., "prod")
+	ValueFiles  []string           // From the chart source's helm.valueFiles, "$ref/..." entries qualified
+	Helm        domain.HelmOptions // Inline values, parameters, release name, namespace and CRD handling
+	RepoURL     string             // From spec.source.repoURL
+	Revision    string             // From spec.source.targetRevision ("HEAD" when unset)
+	File        string             // Manifest path relative to the repository root
 }
 
-// New creates a new Argo apps adapter. It performs an initial clone/sync
-// and starts a background goroutine to keep the repository updated.
-func New(
-	repoURL, localPath string,
-	syncInterval time.Duration,
-	folderPattern string,
-	logger *slog.Logger,
-	chartDir string,
-) (*Adapter, error) {
+// New creates a new Argo apps adapter for a gitops source. It performs an
+// initial clone/sync and starts a background goroutine to keep the
+// repository updated every SyncInterval and whenever a sync is requested.
+func New(src Source, logger *slog.Logger, meter metric.Meter, metricPrefix string) (*Adapter, error) {
 	if logger == nil {
 		logger = slog.New(slog.NewTextHandler(os.Stderr, nil))
 	}
+	if meter == nil {
+		meter = noop.NewMeterProvider().Meter("argo")
+	}
 
 	a := &Adapter{
-		repoURL:       repoURL,
-		localPath:     localPath,
-		syncInterval:  syncInterval,
-		folderPattern: folderPattern,
-		chartDir:      chartDir,
-		stopCh:        make(chan struct{}),
-		index:         make(map[string][]AppData),
-		logger:        logger,
+		name:               src.Name,
+		repoURL:            src.Repo,
+		repos:              src.Repos,
+		localPath:          src.LocalPath,
+		syncInterval:       src.SyncInterval,
+		folderPattern:      src.FolderPattern,
+		envKey:             src.EnvKey,
+		envFromDestination: src.EnvFromDestination,
+		mirror: gitmirror.New(gitmirror.Options{
+			URL:        src.Repo,
+			Dir:        src.LocalPath,
+			Token:      src.Token,
+			SSHKeyFile: src.SSHKeyFile,
+		}),
+		index:  make(map[chartKey][]AppData),
+		logger: logger,
 	}
+	a.registerMetrics(meter, metricPrefix)
+	ctx, stop := context.WithCancel(context.Background())
+	a.stop = stop
 
 	// Initial clone/sync
-	a.logger.Info("initializing argo apps repository", "repoURL", repoURL, "localPath", localPath)
-	if err := a.initRepo(); err != nil {
+	a.logger.Info("initializing argo apps repository", "source", src.Name, "repoURL", src.Repo, "localPath", src.LocalPath)
+	if err := a.mirror.Init(ctx); err != nil {
+		stop()
 		return nil, fmt.Errorf("initializing repo: %w", err)
 	}
 
 	// Build initial index
 	a.logger.Info("scanning repo for argo applications")
 	if err := a.rebuildIndex(); err != nil {
+		stop()
 		return nil, fmt.Errorf("building initial index: %w", err)
 	}
 
 	// Start background syncer
-	go a.syncLoop()
-	a.logger.Info("argo apps adapter started", "syncInterval", syncInterval)
+	go a.mirror.Run(ctx, a.syncInterval, func(ctx context.Context, trigger string) {
+		_ = a.sync(ctx, trigger) //nolint:errcheck // Logged and kept in the sync status
+	})
+	a.logger.Info("argo apps adapter started", "source", src.Name, "syncInterval", src.SyncInterval)
 
 	return a, nil
 }
 
-// initRepo clones the repository if it doesn't exist, or pulls latest if it does.
-func (a *Adapter) initRepo() error {
-	gitDir := filepath.Join(a.localPath, ".git")
-
-	// Check if repo already exists
-	if _, err := os.Stat(gitDir); err == nil {
-		a.logger.Info("repository already exists, pulling latest")
-		return a.pullRepo()
-	}
+// sync pulls the repository and rebuilds the index copy-on-write: the new
+// index is built from the pulled clone while lookups keep reading the old
+// one, and is swapped in under a brief write lock. Waiting for a running
+// sync and the git pull are both abandoned when ctx is done.
+func (a *Adapter) sync(ctx context.Context, trigger string) error {
+	a.logger.Info("syncing argo apps repository", "source", a.name, "trigger", trigger)
+	start := time.Now()
+
+	var index map[chartKey][]AppData
+	var appCount int
+	err := a.mirror.Sync(ctx, func() error {
+		var err error
+		index, appCount, err = a.buildIndex()
+		return err
+	})
+	a.recordSync(trigger, start, err)
 
-	// Clone fresh
-	a.logger.Info("cloning repository", "repoURL", a.repoURL)
-	//nolint:gosec // G204: repoURL is from trusted config, not user input
-	cmd := exec.CommandContext(context.Background(), "git", "clone", "--depth=1", a.repoURL, a.localPath)
-	output, err := cmd.CombinedOutput()
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.lastErr = err
 	if err != nil {
-		return fmt.Errorf("git clone failed: %w\noutput: %s", err, output)
+		a.logger.Error("failed to sync argo apps repository", "source", a.name, "trigger", trigger, "error", err)
+		return err
 	}
+	a.index, a.appCount, a.lastSync = index, appCount, time.Now()
 
+	a.logger.Info("argo apps repository synced successfully",
+		"source", a.name, "trigger", trigger, "uniqueCharts", len(index), "duration", time.Since(start))
 	return nil
 }
 
-// pullRepo pulls the latest changes from the remote repository.
-func (a *Adapter) pullRepo() error {
-	//nolint:gosec // G204: localPath is from trusted config, not user input
-	cmd := exec.CommandContext(context.Background(), "git", "-C", a.localPath, "pull", "--ff-only")
-	output, err := cmd.CombinedOutput()
+// rebuildIndex scans the entire repo for Application manifests and swaps
+// in the new index.
+func (a *Adapter) rebuildIndex() error {
+	index, appCount, err := a.buildIndex()
 	if err != nil {
-		return fmt.Errorf("git pull failed: %w\noutput: %s", err, output)
-	}
-	return nil
-}
-
-// syncLoop runs in the background and periodically syncs the repository.
-func (a *Adapter) syncLoop() {
-	ticker := time.NewTicker(a.syncInterval)
-	defer ticker.Stop()
-
-	for {
-		select {
-		case <-ticker.C:
-			a.sync()
-		case <-a.stopCh:
-			a.logger.Info("stopping argo apps sync loop")
-			return
-		}
+		return err
 	}
-}
-
-// sync performs a git pull and rebuilds the index.
-func (a *Adapter) sync() {
-	a.logger.Info("syncing argo apps repository")
 
 	a.mu.Lock()
 	defer a.mu.Unlock()
+	a.index, a.appCount, a.lastSync, a.lastErr = index, appCount, time.Now(), nil
+	return nil
+}
 
-	// Pull latest changes
-	if err := a.pullRepo(); err != nil {
-		a.logger.Error("failed to pull repository", "error", err)
-		return
+// buildIndex scans the entire repo for Application manifests and returns an
+// index of them by chart source, with the number of Applications indexed.
+func (a *Adapter) buildIndex() (map[chartKey][]AppData, int, error) {
+	apps, err := a.scan()
+	if err != nil {
+		return nil, 0, err
 	}
 
-	// Rebuild index
-	if err := a.rebuildIndex(); err != nil {
-		a.logger.Error("failed to rebuild index", "error", err)
-		return
+	index := make(map[chartKey][]AppData)
+	appCount := 0
+	for _, app := range apps {
+		key, err := sourceKey(app.RepoURL, app.ChartPath)
+		if err != nil {
+			// OCI and Helm repository charts cannot match a PR's charts
+			a.logger.Debug("skipping application without a git chart source", "path", app.File, "error", err)
+			continue
+		}
+		if !a.covers(key.Repo) {
+			a.logger.Debug("skipping application for a chart repository the source does not cover",
+				"path", app.File, "repo", key.Repo)
+			continue
+		}
+		index[key] = append(index[key], app)
+		appCount++
 	}
 
-	a.logger.Info("argo apps repository synced successfully", "uniqueCharts", len(a.index))
+	a.logger.Info("index rebuilt", "totalApps", appCount, "uniqueCharts", len(index))
+	return index, appCount, nil
 }
 
-// rebuildIndex scans the entire repo for Application manifests and builds an index.
-func (a *Adapter) rebuildIndex() error {
-	index := make(map[string][]AppData)
-	appCount := 0
+// scan walks the entire repo and returns every Application it declares,
+// hand-written or generated by an ApplicationSet, in file order.
+func (a *Adapter) scan() ([]AppData, error) {
+	var apps []AppData
 
 	// Walk the entire repository looking for YAML files
 	err := filepath.Walk(a.localPath, func(path string, info os.FileInfo, err error) error {
@@ -181,24 +226,24 @@ func (a *Adapter) rebuildIndex() error {
 			return nil
 		}
 
-		// Process the YAML file as a potential Argo Application
-		app, shouldIndex := a.processApplicationFile(path)
-		if shouldIndex {
-			index[app.ChartName] = append(index[app.ChartName], *app)
-			appCount++
+		// Process the YAML file as a potential Argo Application or ApplicationSet
+		found, shouldIndex := a.processApplicationFile(path)
+		if !shouldIndex {
+			return nil
+		}
+		rel, err := filepath.Rel(a.localPath, path)
+		if err != nil {
+			return err
+		}
+		for _, app := range found {
+			app.File = filepath.ToSlash(rel)
+			apps = append(apps, app)
 		}
 
 		return nil
 	})
 
-	if err != nil {
-		return err
-	}
-
-	a.index = index
-	a.logger.Info("index rebuilt", "totalApps", appCount, "uniqueCharts", len(index))
-
-	return nil
+	return apps, err
 }
 
 // shouldSkipPath determines if a path should be skipped during scanning.
@@ -220,108 +265,194 @@ func isYAMLFile(path string) bool {
 	return ext == ".yaml" || ext == ".yml"
 }
 
-// processApplicationFile attempts to parse and index an Argo Application manifest.
-// Returns the parsed app and whether it should be indexed.
-func (a *Adapter) processApplicationFile(path string) (*AppData, bool) {
-	// Try to parse as Argo Application
-	app, err := a.parseArgoApp(path)
-	if errors.Is(err, ErrNotAnApplication) {
+// processApplicationFile attempts to parse and index an Argo Application or
+// ApplicationSet manifest. Returns the parsed apps and whether they should be
+// indexed.
+func (a *Adapter) processApplicationFile(path string) ([]AppData, bool) {
+	//nolint:gosec // G304: path is from filepath.Walk, not user input
+	data, err := os.ReadFile(path)
+	if err != nil {
+		a.logger.Warn("failed to read file", "path", path, "error", err)
+		return nil, false
+	}
+
+	var header struct {
+		Kind string `yaml:"kind"`
+	}
+	if err := yaml.Unmarshal(data, &header); err != nil {
+		a.logger.Warn("failed to parse file as argo application", "path", path, "error", err)
+		return nil, false
+	}
+
+	switch header.Kind {
+	case "Application":
+		return a.processApplication(path, data)
+	case applicationSetKind:
+		return a.processApplicationSet(path, data)
+	default:
 		// Not an Application manifest - skip silently
 		return nil, false
 	}
+}
+
+// processApplication parses a hand-written Application. Its environment is
+// taken from the folder structure unless a label, annotation or destination
+// names it.
+func (a *Adapter) processApplication(path string, data []byte) ([]AppData, bool) {
+	manifest, err := decodeApplication(data)
 	if err != nil {
 		// Invalid YAML or other error - log and skip
 		a.logger.Warn("failed to parse file as argo application", "path", path, "error", err)
 		return nil, false
 	}
-
-	// Extract chart name and environment from folder structure
-	chartName, env, err := a.extractFromFolderStructure(path)
+	app, err := appFromManifest(manifest)
 	if err != nil {
-		a.logger.Warn("failed to extract chart/env from path", "path", path, "error", err)
+		a.logger.Warn("failed to parse file as argo application", "path", path, "error", err)
 		return nil, false
 	}
 
-	app.ChartName = chartName
-	app.Environment = env
+	app.Environment = a.environmentName(manifest)
+	if app.Environment == "" {
+		// Fall back to the folder structure
+		if app.Environment, err = a.envFromFolderStructure(path); err != nil {
+			a.logger.Warn("failed to extract environment from path", "path", path, "error", err)
+			return nil, false
+		}
+	}
 
-	return app, true
+	return []AppData{*app}, true
 }
 
-// parseArgoApp parses an Argo CD Application manifest from a file.
-// Returns minimal data needed for chart validation.
-// Supports both OCI charts (spec.source.chart) and Git-based charts (spec.source.path).
-func (a *Adapter) parseArgoApp(path string) (*AppData, error) {
-	//nolint:gosec // G304: path is from filepath.Walk, not user input
-	data, err := os.ReadFile(path)
+// processApplicationSet expands an ApplicationSet into the Applications it
+// generates. All of them live in one file, so the folder structure cannot
+// tell them apart: unless a label or annotation names it, the environment is
+// the destination cluster name, or the Application name.
+func (a *Adapter) processApplicationSet(path string, data []byte) ([]AppData, bool) {
+	manifests, err := a.expandApplicationSet(data)
 	if err != nil {
-		return nil, err
+		a.logger.Warn("failed to expand argo applicationset", "path", path, "error", err)
+		return nil, false
 	}
 
-	var manifest struct {
-		APIVersion string `yaml:"apiVersion"`
-		Kind       string `yaml:"kind"`
-		Spec       struct {
-			Source struct {
-				RepoURL string `yaml:"repoURL"`
-				Path    string `yaml:"path"`  // For Git-based charts
-				Chart   string `yaml:"chart"` // For OCI charts
-				Helm    struct {
-					ValueFiles []string `yaml:"valueFiles"`
-				} `yaml:"helm"`
-			} `yaml:"source"`
-		} `yaml:"spec"`
+	apps := make([]AppData, 0, len(manifests))
+	for _, manifest := range manifests {
+		app, err := appFromManifest(manifest)
+		if err != nil {
+			a.logger.Warn("skipping generated application",
+				"path", path, "application", manifest.Metadata.Name, "error", err)
+			continue
+		}
+		app.Environment = a.environmentName(manifest)
+		if app.Environment == "" {
+			app.Environment = manifest.Spec.Destination.Name
+		}
+		if app.Environment == "" {
+			app.Environment = manifest.Metadata.Name
+		}
+		if app.Environment == "" {
+			a.logger.Warn("skipping generated application without a name", "path", path)
+			continue
+		}
+		apps = append(apps, *app)
 	}
 
+	return apps, len(apps) > 0
+}
+
+// decodeApplication decodes an Argo CD Application manifest.
+func decodeApplication(data []byte) (applicationManifest, error) {
+	var manifest applicationManifest
 	if err := yaml.Unmarshal(data, &manifest); err != nil {
-		return nil, err
+		return applicationManifest{}, err
 	}
 
 	// Only process Argo Application resources
 	if manifest.Kind != "Application" {
-		return nil, ErrNotAnApplication
+		return applicationManifest{}, ErrNotAnApplication
 	}
 
-	if manifest.Spec.Source.RepoURL == "" {
-		return nil, errors.New("missing required field: repoURL")
+	return manifest, nil
+}
+
+// environmentName returns the environment an Application declares through the
+// configured label or annotation (labels win), or its destination cluster
+// name when enabled. It returns "" when neither applies.
+func (a *Adapter) environmentName(manifest applicationManifest) string {
+	if a.envKey != "" {
+		if env := manifest.Metadata.Labels[a.envKey]; env != "" {
+			return env
+		}
+		if env := manifest.Metadata.Annotations[a.envKey]; env != "" {
+			return env
+		}
+	}
+	if a.envFromDestination {
+		return manifest.Spec.Destination.Name
 	}
+	return ""
+}
 
-	// Determine chart name/path: prefer OCI chart, fall back to path
-	chartIdentifier := manifest.Spec.Source.Chart
-	if chartIdentifier == "" {
-		chartIdentifier = manifest.Spec.Source.Path
+// appFromManifest extracts the data chart-val needs from an Application,
+// whether hand-written or generated by an ApplicationSet. Returns minimal
+// data needed for chart validation.
+// Supports both OCI charts (spec.source.chart) and Git-based charts (spec.source.path),
+// and multi-source Applications (spec.sources) whose valueFiles reference
+// other sources as "$ref/path".
+func appFromManifest(manifest applicationManifest) (*AppData, error) {
+	source, err := manifest.chartSource()
+	if err != nil {
+		return nil, err
+	}
+
+	valueFiles, err := manifest.valueFiles(source)
+	if err != nil {
+		return nil, err
+	}
+
+	helm, err := manifest.helmOptions(source)
+	if err != nil {
+		return nil, err
 	}
 
+	// Determine chart name/path: prefer OCI chart, fall back to path
+	chartIdentifier := source.Chart
 	if chartIdentifier == "" {
-		return nil, errors.New("missing both spec.source.chart and spec.source.path")
+		chartIdentifier = source.Path
 	}
 
 	return &AppData{
-		ChartPath:  chartIdentifier, // Will be parsed as ChartName during indexing
-		ValueFiles: manifest.Spec.Source.Helm.ValueFiles,
-		RepoURL:    manifest.Spec.Source.RepoURL,
+		Name:       manifest.Metadata.Name,
+		ChartName:  path.Base(chartIdentifier),
+		ChartPath:  chartIdentifier,
+		ValueFiles: valueFiles,
+		Helm:       helm,
+		RepoURL:    source.RepoURL,
+		Revision:   revision(source.TargetRevision),
 	}, nil
 }
 
 // GetEnvironmentConfig implements ports.EnvironmentConfigPort.
-// It looks up environments for the given chart name from Argo Application manifests.
+// It looks up environments for the Applications whose chart source is the
+// given chart in the PR's repository.
 // If the chart is not found, returns empty environments (fallback will be used).
 func (a *Adapter) GetEnvironmentConfig(
 	_ context.Context,
-	_ domain.PRContext,
-	chartName string,
+	pr domain.PRContext,
+	chart domain.ChangedChart,
 ) (domain.ChartConfig, error) {
 	a.mu.RLock()
 	defer a.mu.RUnlock()
 
-	a.logger.Info("looking up chart in argo apps", "chartName", chartName)
+	chartName := chart.Name
 
-	// Look up in index by chart name
-	apps, exists := a.index[chartName]
+	a.logger.Info("looking up chart in argo apps", "chartName", chartName, "chartPath", chart.Path)
+
+	// Look up in index by repository and chart path
+	apps, exists := a.index[prChartKey(pr, chart.Path)]
 	if !exists || len(apps) == 0 {
 		a.logger.Info("chart not found in argo apps", "chartName", chartName)
 		return domain.ChartConfig{
-			Path:         a.chartDir + "/" + chartName,
+			Path:         chart.Path,
 			Environments: []domain.EnvironmentConfig{}, // Empty - will fall back to discovery
 		}, nil
 	}
@@ -329,7 +460,7 @@ func (a *Adapter) GetEnvironmentConfig(
 	a.logger.Info("found argo apps for chart", "chartName", chartName, "count", len(apps))
 
 	config := domain.ChartConfig{
-		Path:         apps[0].ChartPath,
+		Path:         chart.Path,
 		Environments: []domain.EnvironmentConfig{},
 	}
 
@@ -337,6 +468,7 @@ func (a *Adapter) GetEnvironmentConfig(
 		config.Environments = append(config.Environments, domain.EnvironmentConfig{
 			Name:       app.Environment,
 			ValueFiles: app.ValueFiles,
+			Helm:       app.Helm,
 		})
 	}
 
@@ -345,21 +477,98 @@ func (a *Adapter) GetEnvironmentConfig(
 	return config, nil
 }
 
-// Stop signals the background sync loop to stop.
+// ChartsUsingFiles implements ports.ValueFileIndexPort. It returns the charts
+// in the PR's repository of every Application whose helm valueFiles, resolved
+// against the Application's source path, include one of the given repository
+// files.
+func (a *Adapter) ChartsUsingFiles(
+	_ context.Context,
+	pr domain.PRContext,
+	files []string,
+) ([]domain.ChangedChart, error) {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+
+	changed := make(map[string]bool, len(files))
+	for _, f := range files {
+		changed[f] = true
+	}
+
+	repo := prChartKey(pr, "").Repo
+	byPath := make(map[string]*domain.ChangedChart)
+	for key, apps := range a.index {
+		if key.Repo != repo {
+			continue
+		}
+		for _, app := range apps {
+			for _, vf := range app.ValueFiles {
+				ref, err := domain.ParseValueFileRef(vf)
+				if err != nil {
+					continue
+				}
+				repoPath, ok := ref.RepoPath(pr, app.ChartPath)
+				if !ok || !changed[repoPath] {
+					continue
+				}
+				chart, ok := byPath[app.ChartPath]
+				if !ok {
+					chart = &domain.ChangedChart{Name: app.ChartName, Path: app.ChartPath}
+					byPath[app.ChartPath] = chart
+				}
+				if !slices.Contains(chart.AffectedVia, repoPath) {
+					chart.AffectedVia = append(chart.AffectedVia, repoPath)
+				}
+			}
+		}
+	}
+
+	charts := make([]domain.ChangedChart, 0, len(byPath))
+	for _, chart := range byPath {
+		sort.Strings(chart.AffectedVia)
+		charts = append(charts, *chart)
+	}
+	sort.Slice(charts, func(i, j int) bool { return charts[i].Path < charts[j].Path })
+	return charts, nil
+}
+
+// sourceKey returns the index key of a chart source: its normalized
+// repository and its cleaned path.
+func sourceKey(repoURL, chartPath string) (chartKey, error) {
+	owner, repo, err := domain.RepoFromURL(repoURL)
+	if err != nil {
+		return chartKey{}, err
+	}
+	return chartKey{
+		Repo: strings.ToLower(owner + "/" + repo),
+		Path: path.Clean(strings.Trim(chartPath, "/")),
+	}, nil
+}
+
+// prChartKey returns the index key of a chart in the PR's repository.
+func prChartKey(pr domain.PRContext, chartPath string) chartKey {
+	return chartKey{
+		Repo: strings.ToLower(pr.Owner + "/" + pr.Repo),
+		Path: path.Clean(strings.Trim(chartPath, "/")),
+	}
+}
+
+// Stop stops the background sync loop, abandoning a sync in progress.
 func (a *Adapter) Stop() {
-	close(a.stopCh)
+	a.logger.Info("stopping argo apps sync loop", "source", a.name)
+	a.stop()
 }
 
-// extractFromFolderStructure extracts chart name and environment from file path
-// using the configured folder pattern.
+// envFromFolderStructure extracts the environment from file path using the
+// configured folder pattern. Pattern parts other than {envName} (such as
+// {chartName}) only position it.
 // Example: pattern="{chartName}/{envName}", path="/tmp/repo/my-app/prod/app.yaml"
 //
-//	→ chartName="my-app", env="prod"
-func (a *Adapter) extractFromFolderStructure(filePath string) (chartName, env string, err error) {
+//	→ env="prod"
+func (a *Adapter) envFromFolderStructure(filePath string) (string, error) {
 	// Get relative path from repo root
 	relPath, err := filepath.Rel(a.localPath, filePath)
 	if err != nil {
-		return "", "", fmt.Errorf("getting relative path: %w", err)
+		return "", fmt.Errorf("getting relative path: %w", err)
 	}
 
 	// Remove filename to get directory path
@@ -371,7 +580,7 @@ func (a *Adapter) extractFromFolderStructure(filePath string) (chartName, env st
 	patternParts := strings.Split(a.folderPattern, "/")
 
 	if len(parts) < len(patternParts) {
-		return "", "", errors.New("path has fewer components than pattern")
+		return "", errors.New("path has fewer components than pattern")
 	}
 
 	// Extract values based on pattern
@@ -380,20 +589,10 @@ func (a *Adapter) extractFromFolderStructure(filePath string) (chartName, env st
 	relevantParts := parts[offset:]
 
 	for i, patternPart := range patternParts {
-		switch patternPart {
-		case "{chartName}":
-			chartName = relevantParts[i]
-		case "{envName}":
-			env = relevantParts[i]
+		if patternPart == "{envName}" {
+			return relevantParts[i], nil
 		}
 	}
 
-	if chartName == "" {
-		return "", "", errors.New("could not extract chartName from path")
-	}
-	if env == "" {
-		return "", "", errors.New("could not extract envName from path")
-	}
-
-	return chartName, env, nil
+	return "", errors.New("could not extract envName from path")
 }
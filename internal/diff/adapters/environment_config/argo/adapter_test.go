@@ -1,30 +1,39 @@
 package argo
 
 import (
+	"bytes"
 	"context"
 	"log/slog"
 	"os"
 	"os/exec"
 	"path/filepath"
+	"reflect"
 	"testing"
 	"time"
 
 	"github.com/nathantilsley/chart-val/internal/diff/domain"
 )
 
-func TestParseArgoApp(t *testing.T) {
-	t.Parallel()
+// fixtureAdapter returns an adapter indexing the testdata fixtures, naming
+// each Application's environment after its fixture directory, and the
+// buffer its logs are written to.
+func fixtureAdapter() (*Adapter, *bytes.Buffer) {
+	logs := &bytes.Buffer{}
+	return &Adapter{
+		logger:        slog.New(slog.NewTextHandler(logs, nil)),
+		localPath:     "testdata",
+		folderPattern: "{envName}",
+	}, logs
+}
 
-	logger := slog.New(slog.NewTextHandler(os.Stderr, nil))
-	adapter := &Adapter{logger: logger}
+func TestProcessApplicationFile(t *testing.T) {
+	t.Parallel()
 
 	tests := []struct {
-		name        string
-		fixture     string // Path relative to testdata/
-		wantApp     *AppData
-		wantErr     bool
-		wantNil     bool
-		errContains string
+		name    string
+		fixture string // Path relative to testdata/
+		wantApp *AppData
+		wantLog string // Reason logged for a file that isn't indexed; "" when skipped silently
 	}{
 		{
 			name:    "valid application with path",
@@ -34,7 +43,6 @@ func TestParseArgoApp(t *testing.T) {
 				ValueFiles: []string{"values-prod.yaml"},
 				RepoURL:    "https://github.com/example/charts",
 			},
-			wantErr: false,
 		},
 		{
 			name:    "valid application with OCI chart",
@@ -44,39 +52,56 @@ func TestParseArgoApp(t *testing.T) {
 				ValueFiles: []string{"values.yaml"},
 				RepoURL:    "oci://registry.example.com",
 			},
-			wantErr: false,
 		},
 		{
-			name:        "non-application manifest",
-			fixture:     "non-applications/configmap.yaml",
-			wantNil:     true,
-			wantErr:     true,
-			errContains: "not an Application",
+			name:    "non-application manifest",
+			fixture: "non-applications/configmap.yaml",
 		},
 		{
-			name:        "deployment manifest",
-			fixture:     "non-applications/deployment.yaml",
-			wantNil:     true,
-			wantErr:     true,
-			errContains: "not an Application",
+			name:    "deployment manifest",
+			fixture: "non-applications/deployment.yaml",
 		},
 		{
-			name:        "invalid yaml",
-			fixture:     "invalid/invalid.yaml",
-			wantErr:     true,
-			errContains: "yaml",
+			name:    "invalid yaml",
+			fixture: "invalid/invalid.yaml",
+			wantLog: "yaml",
 		},
 		{
-			name:        "missing repoURL",
-			fixture:     "invalid/missing-repo-url.yaml",
-			wantErr:     true,
-			errContains: "repoURL",
+			name:    "missing repoURL",
+			fixture: "invalid/missing-repo-url.yaml",
+			wantLog: "repoURL",
 		},
 		{
-			name:        "missing both chart and path",
-			fixture:     "invalid/missing-chart-and-path.yaml",
-			wantErr:     true,
-			errContains: "chart and",
+			name:    "missing both chart and path",
+			fixture: "invalid/missing-chart-and-path.yaml",
+			wantLog: "chart and",
+		},
+		{
+			name:    "multi-source application with $values references",
+			fixture: "applications/multi-source.yaml",
+			wantApp: &AppData{
+				ChartPath: "charts/my-app",
+				ValueFiles: []string{
+					"values.yaml",
+					"example/config@v1.4.0:envs/prod/common.yaml",
+					"example/config@v1.4.0:envs/prod/my-app.yaml",
+				},
+				RepoURL: "https://github.com/example/charts",
+			},
+		},
+		{
+			name:    "multi-source application with ref source first and default revision",
+			fixture: "applications/multi-source-default-revision.yaml",
+			wantApp: &AppData{
+				ChartPath:  "charts/my-app",
+				ValueFiles: []string{"example/config@HEAD:my-app/prod.yaml"},
+				RepoURL:    "https://github.com/example/charts.git",
+			},
+		},
+		{
+			name:    "multi-source application with unknown ref",
+			fixture: "invalid/multi-source-unknown-ref.yaml",
+			wantLog: "unknown source ref",
 		},
 		{
 			name:    "application without valueFiles",
@@ -86,7 +111,6 @@ func TestParseArgoApp(t *testing.T) {
 				ValueFiles: nil,
 				RepoURL:    "https://github.com/example/charts",
 			},
-			wantErr: false,
 		},
 	}
 
@@ -94,35 +118,26 @@ func TestParseArgoApp(t *testing.T) {
 		t.Run(tt.name, func(t *testing.T) {
 			t.Parallel()
 
-			fixturePath := filepath.Join("testdata", tt.fixture)
-			app, err := adapter.parseArgoApp(fixturePath)
+			adapter, logs := fixtureAdapter()
+			apps, ok := adapter.processApplicationFile(filepath.Join("testdata", tt.fixture))
 
-			if tt.wantErr {
-				if err == nil {
-					t.Errorf("expected error, got nil")
-				} else if tt.errContains != "" && !contains(err.Error(), tt.errContains) {
-					t.Errorf("error %q does not contain %q", err.Error(), tt.errContains)
+			if tt.wantApp == nil {
+				if ok || len(apps) != 0 {
+					t.Errorf("expected the file not to be indexed, got %+v", apps)
 				}
-				return
-			}
-
-			if err != nil {
-				t.Errorf("unexpected error: %v", err)
-				return
-			}
-
-			if tt.wantNil {
-				if app != nil {
-					t.Errorf("expected nil app, got %+v", app)
+				if tt.wantLog == "" && logs.Len() != 0 {
+					t.Errorf("expected the file to be skipped silently, logged:\n%s", logs)
+				}
+				if tt.wantLog != "" && !contains(logs.String(), tt.wantLog) {
+					t.Errorf("log %q does not contain %q", logs.String(), tt.wantLog)
 				}
 				return
 			}
 
-			if app == nil {
-				t.Errorf("expected app, got nil")
-				return
+			if !ok || len(apps) != 1 {
+				t.Fatalf("expected 1 indexed app, got %+v (logs: %s)", apps, logs)
 			}
-
+			app := apps[0]
 			if app.ChartPath != tt.wantApp.ChartPath {
 				t.Errorf("ChartPath = %q, want %q", app.ChartPath, tt.wantApp.ChartPath)
 			}
@@ -132,11 +147,60 @@ func TestParseArgoApp(t *testing.T) {
 			if !equalStringSlices(app.ValueFiles, tt.wantApp.ValueFiles) {
 				t.Errorf("ValueFiles = %v, want %v", app.ValueFiles, tt.wantApp.ValueFiles)
 			}
+			if env := filepath.Base(filepath.Dir(tt.fixture)); app.Environment != env {
+				t.Errorf("Environment = %q, want %q", app.Environment, env)
+			}
+		})
+	}
+}
+
+func TestProcessApplicationFile_HelmOptions(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		fixture string
+		want    domain.HelmOptions
+	}{
+		{
+			fixture: "applications/helm-options.yaml",
+			want: domain.HelmOptions{
+				Values: "replicas: 3\n",
+				Parameters: []domain.HelmParameter{
+					{Name: "image.tag", Value: "v1.2.3"},
+					{Name: "podAnnotations.commit", Value: "0123", ForceString: true},
+				},
+				ReleaseName: "my-app-prod", // Defaults to the Application name
+				Namespace:   "my-app",
+				IncludeCRDs: true,
+			},
+		},
+		{
+			fixture: "applications/helm-values-object.yaml",
+			want: domain.HelmOptions{
+				Values:      "replicas: 2\n", // valuesObject wins over values
+				ReleaseName: "my-app",
+				Namespace:   "my-app-dev",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.fixture, func(t *testing.T) {
+			t.Parallel()
+
+			adapter, logs := fixtureAdapter()
+			apps, ok := adapter.processApplicationFile(filepath.Join("testdata", tt.fixture))
+			if !ok || len(apps) != 1 {
+				t.Fatalf("expected 1 indexed app, got %+v (logs: %s)", apps, logs)
+			}
+			if !reflect.DeepEqual(apps[0].Helm, tt.want) {
+				t.Errorf("Helm = %+v, want %+v", apps[0].Helm, tt.want)
+			}
 		})
 	}
 }
 
-func TestExtractFromFolderStructure(t *testing.T) {
+func TestEnvFromFolderStructure(t *testing.T) {
 	t.Parallel()
 
 	tmpDir := t.TempDir()
@@ -145,26 +209,29 @@ func TestExtractFromFolderStructure(t *testing.T) {
 		name        string
 		pattern     string
 		filePath    string
-		wantChart   string
 		wantEnv     string
 		wantErr     bool
 		errContains string
 	}{
 		{
-			name:      "simple pattern matching",
-			pattern:   "{chartName}/{envName}",
-			filePath:  filepath.Join(tmpDir, "my-app", "prod", "app.yaml"),
-			wantChart: "my-app",
-			wantEnv:   "prod",
-			wantErr:   false,
+			name:     "simple pattern matching",
+			pattern:  "{chartName}/{envName}",
+			filePath: filepath.Join(tmpDir, "my-app", "prod", "app.yaml"),
+			wantEnv:  "prod",
+			wantErr:  false,
+		},
+		{
+			name:     "nested path with pattern at end",
+			pattern:  "{chartName}/{envName}",
+			filePath: filepath.Join(tmpDir, "nested", "path", "my-app", "staging", "app.yaml"),
+			wantEnv:  "staging",
+			wantErr:  false,
 		},
 		{
-			name:      "nested path with pattern at end",
-			pattern:   "{chartName}/{envName}",
-			filePath:  filepath.Join(tmpDir, "nested", "path", "my-app", "staging", "app.yaml"),
-			wantChart: "my-app",
-			wantEnv:   "staging",
-			wantErr:   false,
+			name:     "environment only pattern",
+			pattern:  "{envName}",
+			filePath: filepath.Join(tmpDir, "clusters", "prod", "app.yaml"),
+			wantEnv:  "prod",
 		},
 		{
 			name:        "path too short for pattern",
@@ -174,11 +241,11 @@ func TestExtractFromFolderStructure(t *testing.T) {
 			errContains: "fewer components",
 		},
 		{
-			name:        "cannot extract chart name",
-			pattern:     "{envName}",
-			filePath:    filepath.Join(tmpDir, "prod", "app.yaml"),
+			name:        "cannot extract environment",
+			pattern:     "{chartName}",
+			filePath:    filepath.Join(tmpDir, "my-app", "app.yaml"),
 			wantErr:     true,
-			errContains: "chartName",
+			errContains: "envName",
 		},
 	}
 
@@ -192,7 +259,7 @@ func TestExtractFromFolderStructure(t *testing.T) {
 				logger:        slog.New(slog.NewTextHandler(os.Stderr, nil)),
 			}
 
-			chartName, env, err := adapter.extractFromFolderStructure(tt.filePath)
+			env, err := adapter.envFromFolderStructure(tt.filePath)
 
 			if tt.wantErr {
 				if err == nil {
@@ -208,9 +275,6 @@ func TestExtractFromFolderStructure(t *testing.T) {
 				return
 			}
 
-			if chartName != tt.wantChart {
-				t.Errorf("chartName = %q, want %q", chartName, tt.wantChart)
-			}
 			if env != tt.wantEnv {
 				t.Errorf("env = %q, want %q", env, tt.wantEnv)
 			}
@@ -244,7 +308,7 @@ func TestRebuildIndex_ContinuesOnErrors(t *testing.T) {
 	adapter := &Adapter{
 		localPath:     tmpDir,
 		folderPattern: "{chartName}/{envName}",
-		index:         make(map[string][]AppData),
+		index:         make(map[chartKey][]AppData),
 		logger:        slog.New(slog.NewTextHandler(os.Stderr, nil)),
 	}
 
@@ -258,10 +322,10 @@ func TestRebuildIndex_ContinuesOnErrors(t *testing.T) {
 		t.Errorf("expected 2 charts in index, got %d", len(adapter.index))
 	}
 
-	if _, exists := adapter.index["my-app"]; !exists {
+	if _, exists := adapter.index[exampleChart("my-app")]; !exists {
 		t.Errorf("my-app should be indexed")
 	}
-	if _, exists := adapter.index["other-app"]; !exists {
+	if _, exists := adapter.index[exampleChart("other-app")]; !exists {
 		t.Errorf("other-app should be indexed")
 	}
 }
@@ -289,7 +353,7 @@ func TestRebuildIndex(t *testing.T) {
 	adapter := &Adapter{
 		localPath:     tmpDir,
 		folderPattern: "{chartName}/{envName}",
-		index:         make(map[string][]AppData),
+		index:         make(map[chartKey][]AppData),
 		logger:        slog.New(slog.NewTextHandler(os.Stderr, nil)),
 	}
 
@@ -303,7 +367,7 @@ func TestRebuildIndex(t *testing.T) {
 	}
 
 	// Check my-app has 2 environments
-	myAppEnvs, exists := adapter.index["my-app"]
+	myAppEnvs, exists := adapter.index[exampleChart("my-app")]
 	if !exists {
 		t.Errorf("my-app not found in index")
 	} else {
@@ -326,7 +390,7 @@ func TestRebuildIndex(t *testing.T) {
 	}
 
 	// Check other-app has 1 environment
-	otherAppEnvs, exists := adapter.index["other-app"]
+	otherAppEnvs, exists := adapter.index[exampleChart("other-app")]
 	if !exists {
 		t.Errorf("other-app not found in index")
 	} else {
@@ -343,9 +407,8 @@ func TestGetEnvironmentConfig(t *testing.T) {
 	t.Parallel()
 
 	adapter := &Adapter{
-		chartDir: "charts",
-		index: map[string][]AppData{
-			"my-app": {
+		index: map[chartKey][]AppData{
+			exampleChart("my-app"): {
 				{
 					ChartName:   "my-app",
 					ChartPath:   "charts/my-app",
@@ -361,29 +424,57 @@ func TestGetEnvironmentConfig(t *testing.T) {
 					RepoURL:     "https://github.com/example/charts",
 				},
 			},
+			{Repo: "other/charts", Path: "charts/my-app"}: {
+				{
+					ChartName:   "my-app",
+					ChartPath:   "charts/my-app",
+					Environment: "qa",
+					RepoURL:     "https://github.com/other/charts",
+				},
+			},
 		},
 		logger: slog.New(slog.NewTextHandler(os.Stderr, nil)),
 	}
 
 	tests := []struct {
-		name        string
-		chartName   string
-		wantPath    string
-		wantEnvs    int
-		wantDefault bool
+		name      string
+		pr        domain.PRContext
+		chartName string
+		wantPath  string
+		wantEnvs  []string
 	}{
 		{
 			name:      "chart found in index",
+			pr:        domain.PRContext{Owner: "example", Repo: "charts"},
 			chartName: "my-app",
 			wantPath:  "charts/my-app",
-			wantEnvs:  2,
+			wantEnvs:  []string{"prod", "dev"},
 		},
 		{
-			name:        "chart not found - returns default",
-			chartName:   "unknown-app",
-			wantPath:    "charts/unknown-app",
-			wantEnvs:    0,
-			wantDefault: true,
+			name:      "repository matched case-insensitively",
+			pr:        domain.PRContext{Owner: "Example", Repo: "Charts"},
+			chartName: "my-app",
+			wantPath:  "charts/my-app",
+			wantEnvs:  []string{"prod", "dev"},
+		},
+		{
+			name:      "same chart path in another repository",
+			pr:        domain.PRContext{Owner: "other", Repo: "charts"},
+			chartName: "my-app",
+			wantPath:  "charts/my-app",
+			wantEnvs:  []string{"qa"},
+		},
+		{
+			name:      "chart not found - returns default",
+			pr:        domain.PRContext{Owner: "example", Repo: "charts"},
+			chartName: "unknown-app",
+			wantPath:  "charts/unknown-app",
+		},
+		{
+			name:      "repository not found - returns default",
+			pr:        domain.PRContext{Owner: "example", Repo: "services"},
+			chartName: "my-app",
+			wantPath:  "charts/my-app",
 		},
 	}
 
@@ -391,7 +482,10 @@ func TestGetEnvironmentConfig(t *testing.T) {
 		t.Run(tt.name, func(t *testing.T) {
 			t.Parallel()
 
-			config, err := adapter.GetEnvironmentConfig(context.Background(), domain.PRContext{}, tt.chartName)
+			config, err := adapter.GetEnvironmentConfig(
+				context.Background(), tt.pr,
+				domain.ChangedChart{Name: tt.chartName, Path: "charts/" + tt.chartName},
+			)
 			if err != nil {
 				t.Fatalf("GetEnvironmentConfig failed: %v", err)
 			}
@@ -400,23 +494,145 @@ func TestGetEnvironmentConfig(t *testing.T) {
 				t.Errorf("Path = %q, want %q", config.Path, tt.wantPath)
 			}
 
-			if len(config.Environments) != tt.wantEnvs {
-				t.Errorf("got %d environments, want %d", len(config.Environments), tt.wantEnvs)
+			var envs []string
+			for _, env := range config.Environments {
+				envs = append(envs, env.Name)
+			}
+			if !equalStringSlices(envs, tt.wantEnvs) {
+				t.Errorf("environments = %v, want %v", envs, tt.wantEnvs)
 			}
+		})
+	}
+}
 
-			if !tt.wantDefault && len(config.Environments) > 0 {
-				// Verify we got the expected environments
-				envMap := make(map[string]domain.EnvironmentConfig)
-				for _, env := range config.Environments {
-					envMap[env.Name] = env
-				}
+func TestProcessApplication_EnvironmentName(t *testing.T) {
+	t.Parallel()
 
-				if _, hasProd := envMap["prod"]; !hasProd {
-					t.Errorf("missing prod environment")
-				}
-				if _, hasDev := envMap["dev"]; !hasDev {
-					t.Errorf("missing dev environment")
-				}
+	tmpDir := t.TempDir()
+	file := filepath.Join(tmpDir, "my-app", "prod", "app.yaml")
+	if err := os.MkdirAll(filepath.Dir(file), 0o755); err != nil {
+		t.Fatalf("failed to create dir: %v", err)
+	}
+	manifest := `apiVersion: argoproj.io/v1alpha1
+kind: Application
+metadata:
+  name: my-app-eu
+  labels:
+    example.com/env: from-label
+  annotations:
+    example.com/env: from-annotation
+    example.com/stage: from-stage-annotation
+spec:
+  source:
+    repoURL: https://github.com/example/charts
+    path: charts/my-app
+  destination:
+    name: eu-prod-cluster
+`
+	if err := os.WriteFile(file, []byte(manifest), 0o600); err != nil {
+		t.Fatalf("failed to write manifest: %v", err)
+	}
+
+	tests := []struct {
+		name            string
+		envKey          string
+		fromDestination bool
+		want            string
+	}{
+		{name: "folder pattern by default", want: "prod"},
+		{name: "label wins over annotation", envKey: "example.com/env", fromDestination: true, want: "from-label"},
+		{name: "annotation", envKey: "example.com/stage", want: "from-stage-annotation"},
+		{name: "destination cluster", envKey: "example.com/missing", fromDestination: true, want: "eu-prod-cluster"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			adapter := &Adapter{
+				localPath:          tmpDir,
+				folderPattern:      "{chartName}/{envName}",
+				envKey:             tt.envKey,
+				envFromDestination: tt.fromDestination,
+				logger:             slog.New(slog.NewTextHandler(os.Stderr, nil)),
+			}
+
+			apps, ok := adapter.processApplicationFile(file)
+			if !ok || len(apps) != 1 {
+				t.Fatalf("processApplicationFile() = %+v, %v, want one app", apps, ok)
+			}
+			if apps[0].Environment != tt.want {
+				t.Errorf("Environment = %q, want %q", apps[0].Environment, tt.want)
+			}
+		})
+	}
+}
+
+func TestChartsUsingFiles(t *testing.T) {
+	t.Parallel()
+
+	adapter := &Adapter{
+		index: map[chartKey][]AppData{
+			{Repo: "org/repo", Path: "charts/api"}: {
+				{ChartName: "api", ChartPath: "charts/api", Environment: "prod", ValueFiles: []string{"env/prod.yaml", "/deploy/values/prod.yaml"}},
+				{ChartName: "api", ChartPath: "charts/api", Environment: "dev", ValueFiles: []string{"../../deploy/values/dev.yaml", "/deploy/values/prod.yaml"}},
+			},
+			{Repo: "org/repo", Path: "charts/web"}: {
+				{ChartName: "web", ChartPath: "charts/web", Environment: "prod", ValueFiles: []string{"org/repo@main:deploy/values/prod.yaml"}},
+				{ChartName: "web", ChartPath: "charts/web", Environment: "qa", ValueFiles: []string{"org/config@main:deploy/values/dev.yaml"}},
+			},
+			// Charts of other repositories are never affected by the PR's files
+			{Repo: "org/other", Path: "charts/worker"}: {
+				{ChartName: "worker", ChartPath: "charts/worker", Environment: "prod", ValueFiles: []string{"/deploy/values/prod.yaml"}},
+			},
+		},
+		logger: slog.New(slog.NewTextHandler(os.Stderr, nil)),
+	}
+	pr := domain.PRContext{Owner: "org", Repo: "repo", BaseRef: "main", HeadRef: "feat"}
+
+	tests := []struct {
+		name  string
+		files []string
+		want  []domain.ChangedChart
+	}{
+		{
+			name:  "repo root and qualified references",
+			files: []string{"deploy/values/prod.yaml"},
+			want: []domain.ChangedChart{
+				{Name: "api", Path: "charts/api", AffectedVia: []string{"deploy/values/prod.yaml"}},
+				{Name: "web", Path: "charts/web", AffectedVia: []string{"deploy/values/prod.yaml"}},
+			},
+		},
+		{
+			name:  "chart relative path outside the chart",
+			files: []string{"deploy/values/dev.yaml", "deploy/values/prod.yaml", "README.md"},
+			want: []domain.ChangedChart{
+				{Name: "api", Path: "charts/api", AffectedVia: []string{"deploy/values/dev.yaml", "deploy/values/prod.yaml"}},
+				{Name: "web", Path: "charts/web", AffectedVia: []string{"deploy/values/prod.yaml"}},
+			},
+		},
+		{
+			name:  "chart relative path inside the chart",
+			files: []string{"charts/api/env/prod.yaml"},
+			want:  []domain.ChangedChart{{Name: "api", Path: "charts/api", AffectedVia: []string{"charts/api/env/prod.yaml"}}},
+		},
+		{
+			name:  "unused file",
+			files: []string{"deploy/values/staging.yaml"},
+			want:  []domain.ChangedChart{},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			got, err := adapter.ChartsUsingFiles(context.Background(), pr, tt.files)
+			if err != nil {
+				t.Fatalf("ChartsUsingFiles failed: %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ChartsUsingFiles() = %+v, want %+v", got, tt.want)
 			}
 		})
 	}
@@ -455,12 +671,16 @@ func TestNew(t *testing.T) {
 	// Test New with local "clone"
 	cloneDir := filepath.Join(tmpDir, "clone")
 	adapter, err := New(
-		repoDir, // Use file:// URL for local git repo
-		cloneDir,
-		1*time.Hour,
-		"{chartName}/{envName}",
+		Source{
+			Name:          "argo",
+			Repo:          repoDir, // Local git repo as the remote
+			LocalPath:     cloneDir,
+			SyncInterval:  1 * time.Hour,
+			FolderPattern: "{chartName}/{envName}",
+		},
 		slog.New(slog.NewTextHandler(os.Stderr, nil)),
-		"charts",
+		nil,
+		"chart_val",
 	)
 	if err != nil {
 		t.Fatalf("New failed: %v", err)
@@ -472,7 +692,7 @@ func TestNew(t *testing.T) {
 		t.Errorf("expected 1 chart in index, got %d", len(adapter.index))
 	}
 
-	if apps, exists := adapter.index["my-app"]; !exists {
+	if apps, exists := adapter.index[exampleChart("my-app")]; !exists {
 		t.Errorf("my-app not found in index")
 	} else if len(apps) != 1 {
 		t.Errorf("expected 1 environment for my-app, got %d", len(apps))
@@ -481,6 +701,12 @@ func TestNew(t *testing.T) {
 
 // Helper functions
 
+// exampleChart returns the index key of a chart in the testdata repos'
+// example/charts repository.
+func exampleChart(name string) chartKey {
+	return chartKey{Repo: "example/charts", Path: "charts/" + name}
+}
+
 func contains(s, substr string) bool {
 	return len(s) >= len(substr) && (s == substr || len(s) > len(substr) && containsSubstring(s, substr))
 }
@@ -0,0 +1,156 @@
+package argo
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/nathantilsley/chart-val/internal/diff/domain"
+)
+
+// applicationManifest is the subset of an Argo CD Application that chart-val
+// reads. Single-source Applications set spec.source; multi-source ones set
+// spec.sources.
+type applicationManifest struct {
+	APIVersion string `yaml:"apiVersion"`
+	Kind       string `yaml:"kind"`
+	Metadata   struct {
+		Name        string            `yaml:"name"`
+		Labels      map[string]string `yaml:"labels"`
+		Annotations map[string]string `yaml:"annotations"`
+	} `yaml:"metadata"`
+	Spec struct {
+		Source      *applicationSource  `yaml:"source"`
+		Sources     []applicationSource `yaml:"sources"`
+		Destination struct {
+			Name      string `yaml:"name"`
+			Namespace string `yaml:"namespace"`
+		} `yaml:"destination"`
+	} `yaml:"spec"`
+}
+
+// applicationSource is one entry of spec.source or spec.sources.
+type applicationSource struct {
+	RepoURL        string `yaml:"repoURL"`
+	Path           string `yaml:"path"`  // For Git-based charts
+	Chart          string `yaml:"chart"` // For OCI charts
+	TargetRevision string `yaml:"targetRevision"`
+	Ref            string `yaml:"ref"` // Name other sources use as "$name/..." in valueFiles
+	Helm           struct {
+		ValueFiles   []string        `yaml:"valueFiles"`
+		Values       string          `yaml:"values"`
+		ValuesObject map[string]any  `yaml:"valuesObject"`
+		Parameters   []helmParameter `yaml:"parameters"`
+		ReleaseName  string          `yaml:"releaseName"`
+		SkipCrds     bool            `yaml:"skipCrds"`
+	} `yaml:"helm"`
+}
+
+// helmParameter is one entry of helm.parameters.
+type helmParameter struct {
+	Name        string `yaml:"name"`
+	Value       string `yaml:"value"`
+	ForceString bool   `yaml:"forceString"`
+}
+
+// sources returns the Application's sources, single-source or not.
+func (m applicationManifest) sources() []applicationSource {
+	if len(m.Spec.Sources) > 0 {
+		return m.Spec.Sources
+	}
+	if m.Spec.Source != nil {
+		return []applicationSource{*m.Spec.Source}
+	}
+	return nil
+}
+
+// chartSource returns the source that renders the chart: the first one with a
+// chart or path. Sources that only carry a ref (e.g. a values repository)
+// are skipped.
+func (m applicationManifest) chartSource() (applicationSource, error) {
+	sources := m.sources()
+	for _, src := range sources {
+		if src.Chart != "" || src.Path != "" {
+			if src.RepoURL == "" {
+				return applicationSource{}, errors.New("missing required field: repoURL")
+			}
+			return src, nil
+		}
+	}
+	if len(sources) == 1 && sources[0].RepoURL == "" {
+		return applicationSource{}, errors.New("missing required field: repoURL")
+	}
+	return applicationSource{}, errors.New("missing both spec.source.chart and spec.source.path")
+}
+
+// valueFiles returns the chart source's valueFiles in order, with
+// "$ref/path" entries rewritten to fully qualified value file references
+// ("owner/repo@revision:path") for the source named ref. Other entries are
+// relative to the chart source, as in Argo CD.
+func (m applicationManifest) valueFiles(chart applicationSource) ([]string, error) {
+	refs := make(map[string]applicationSource)
+	for _, src := range m.sources() {
+		if src.Ref != "" {
+			refs[src.Ref] = src
+		}
+	}
+
+	files := make([]string, 0, len(chart.Helm.ValueFiles))
+	for _, vf := range chart.Helm.ValueFiles {
+		if !strings.HasPrefix(vf, "$") {
+			files = append(files, vf)
+			continue
+		}
+		name, rel, _ := strings.Cut(vf[1:], "/")
+		src, ok := refs[name]
+		if !ok {
+			return nil, fmt.Errorf("value file %q references unknown source ref %q", vf, name)
+		}
+		owner, repo, err := domain.RepoFromURL(src.RepoURL)
+		if err != nil {
+			return nil, fmt.Errorf("value file %q: %w", vf, err)
+		}
+		files = append(files, fmt.Sprintf("%s/%s@%s:%s", owner, repo, revision(src.TargetRevision), rel))
+	}
+	return files, nil
+}
+
+// helmOptions returns the render settings Argo CD applies to the chart
+// source beyond its value files. valuesObject wins over values, the release
+// defaults to the Application's name, and CRDs are rendered unless skipped.
+func (m applicationManifest) helmOptions(chart applicationSource) (domain.HelmOptions, error) {
+	opts := domain.HelmOptions{
+		Values:      chart.Helm.Values,
+		ReleaseName: chart.Helm.ReleaseName,
+		Namespace:   m.Spec.Destination.Namespace,
+		IncludeCRDs: !chart.Helm.SkipCrds,
+	}
+	if len(chart.Helm.ValuesObject) > 0 {
+		data, err := yaml.Marshal(chart.Helm.ValuesObject)
+		if err != nil {
+			return domain.HelmOptions{}, fmt.Errorf("encoding helm.valuesObject: %w", err)
+		}
+		opts.Values = string(data)
+	}
+	if opts.ReleaseName == "" {
+		opts.ReleaseName = m.Metadata.Name
+	}
+	for _, p := range chart.Helm.Parameters {
+		opts.Parameters = append(opts.Parameters, domain.HelmParameter{
+			Name:        p.Name,
+			Value:       p.Value,
+			ForceString: p.ForceString,
+		})
+	}
+	return opts, nil
+}
+
+// revision returns the Git revision a source tracks; Argo CD defaults to HEAD.
+func revision(targetRevision string) string {
+	if targetRevision == "" {
+		return "HEAD"
+	}
+	return targetRevision
+}
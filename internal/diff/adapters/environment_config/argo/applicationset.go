@@ -0,0 +1,566 @@
+package argo
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"text/template"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/nathantilsley/chart-val/internal/diff/domain"
+)
+
+// applicationSetKind is the kind of Argo CD ApplicationSet manifests.
+const applicationSetKind = "ApplicationSet"
+
+// applicationSetManifest is the subset of an Argo CD ApplicationSet that
+// chart-val expands offline.
+type applicationSetManifest struct {
+	Kind     string `yaml:"kind"`
+	Metadata struct {
+		Name string `yaml:"name"`
+	} `yaml:"metadata"`
+	Spec struct {
+		GoTemplate        bool           `yaml:"goTemplate"`
+		GoTemplateOptions []string       `yaml:"goTemplateOptions"`
+		Generators        []generator    `yaml:"generators"`
+		Template          map[string]any `yaml:"template"`
+	} `yaml:"spec"`
+}
+
+// generator is one entry of spec.generators. Exactly one field is set.
+type generator struct {
+	List *struct {
+		Elements []map[string]any `yaml:"elements"`
+	} `yaml:"list"`
+	Git    *gitGenerator `yaml:"git"`
+	Matrix *struct {
+		Generators []generator `yaml:"generators"`
+	} `yaml:"matrix"`
+	Merge *struct {
+		MergeKeys  []string    `yaml:"mergeKeys"`
+		Generators []generator `yaml:"generators"`
+	} `yaml:"merge"`
+}
+
+// gitGenerator is the git generator, in either its directories or files form.
+type gitGenerator struct {
+	RepoURL     string `yaml:"repoURL"`
+	Revision    string `yaml:"revision"`
+	Directories []struct {
+		Path    string `yaml:"path"`
+		Exclude bool   `yaml:"exclude"`
+	} `yaml:"directories"`
+	Files []struct {
+		Path string `yaml:"path"`
+	} `yaml:"files"`
+}
+
+// nonDNSChars matches characters Argo CD replaces when normalizing path names.
+var nonDNSChars = regexp.MustCompile(`[^a-z0-9.-]`)
+
+// fastTemplateParam matches a {{ param }} placeholder in non-Go templates.
+var fastTemplateParam = regexp.MustCompile(`{{\s*([^{}\s]+)\s*}}`)
+
+// expandApplicationSet expands an ApplicationSet manifest into the
+// Applications Argo CD would generate from it. Git generators are evaluated
+// against the local clone, so only generators for the indexed repository are
+// supported.
+func (a *Adapter) expandApplicationSet(data []byte) ([]applicationManifest, error) {
+	var set applicationSetManifest
+	if err := yaml.Unmarshal(data, &set); err != nil {
+		return nil, err
+	}
+	if set.Kind != applicationSetKind {
+		return nil, ErrNotAnApplication
+	}
+	if len(set.Spec.Generators) == 0 {
+		return nil, errors.New("missing spec.generators")
+	}
+
+	var params []map[string]any
+	for i, g := range set.Spec.Generators {
+		p, err := a.generate(g, set.Spec.GoTemplate)
+		if err != nil {
+			return nil, fmt.Errorf("generator %d: %w", i, err)
+		}
+		params = append(params, p...)
+	}
+
+	apps := make([]applicationManifest, 0, len(params))
+	for _, p := range params {
+		app, err := renderApplication(set.Spec.Template, p, set.Spec.GoTemplate, set.Spec.GoTemplateOptions)
+		if err != nil {
+			return nil, fmt.Errorf("rendering template: %w", err)
+		}
+		apps = append(apps, app)
+	}
+	return apps, nil
+}
+
+// generate returns the parameter sets produced by a generator. With Go
+// templates parameters keep their structure; otherwise they are flattened
+// into dotted keys as Argo CD does.
+func (a *Adapter) generate(g generator, goTemplate bool) ([]map[string]any, error) {
+	switch {
+	case g.List != nil:
+		params := make([]map[string]any, 0, len(g.List.Elements))
+		for _, el := range g.List.Elements {
+			params = append(params, shapeParams(el, goTemplate))
+		}
+		return params, nil
+	case g.Git != nil:
+		return a.generateGit(*g.Git, goTemplate)
+	case g.Matrix != nil:
+		return a.generateMatrix(g.Matrix.Generators, goTemplate)
+	case g.Merge != nil:
+		return a.generateMerge(g.Merge.MergeKeys, g.Merge.Generators, goTemplate)
+	default:
+		return nil, errors.New("unsupported generator (supported: list, git, matrix, merge)")
+	}
+}
+
+// generateMatrix combines every parameter set of the first generator with
+// every parameter set of the second.
+func (a *Adapter) generateMatrix(generators []generator, goTemplate bool) ([]map[string]any, error) {
+	if len(generators) != 2 {
+		return nil, fmt.Errorf("matrix generator needs exactly 2 child generators, got %d", len(generators))
+	}
+	left, err := a.generate(generators[0], goTemplate)
+	if err != nil {
+		return nil, fmt.Errorf("matrix: %w", err)
+	}
+	right, err := a.generate(generators[1], goTemplate)
+	if err != nil {
+		return nil, fmt.Errorf("matrix: %w", err)
+	}
+
+	params := make([]map[string]any, 0, len(left)*len(right))
+	for _, l := range left {
+		for _, r := range right {
+			params = append(params, mergeParams(l, r))
+		}
+	}
+	return params, nil
+}
+
+// generateMerge returns the parameter sets of the first generator, each
+// overridden by the parameter sets of later generators that agree with it on
+// every merge key.
+func (a *Adapter) generateMerge(mergeKeys []string, generators []generator, goTemplate bool) ([]map[string]any, error) {
+	if len(generators) < 2 {
+		return nil, fmt.Errorf("merge generator needs at least 2 child generators, got %d", len(generators))
+	}
+	if len(mergeKeys) == 0 {
+		return nil, errors.New("merge generator needs mergeKeys")
+	}
+
+	base, err := a.generate(generators[0], goTemplate)
+	if err != nil {
+		return nil, fmt.Errorf("merge: %w", err)
+	}
+	for _, g := range generators[1:] {
+		overrides, err := a.generate(g, goTemplate)
+		if err != nil {
+			return nil, fmt.Errorf("merge: %w", err)
+		}
+		byKey := make(map[string]map[string]any, len(overrides))
+		for _, o := range overrides {
+			byKey[mergeKey(o, mergeKeys)] = o
+		}
+		for i, b := range base {
+			if o, ok := byKey[mergeKey(b, mergeKeys)]; ok {
+				base[i] = mergeParams(b, o)
+			}
+		}
+	}
+	return base, nil
+}
+
+// generateGit lists directories or files of the local clone matching the
+// generator's globs.
+func (a *Adapter) generateGit(g gitGenerator, goTemplate bool) ([]map[string]any, error) {
+	if err := a.checkGeneratorRepo(g.RepoURL); err != nil {
+		return nil, err
+	}
+
+	switch {
+	case len(g.Directories) > 0:
+		var include, exclude []string
+		for _, d := range g.Directories {
+			if d.Exclude {
+				exclude = append(exclude, d.Path)
+			} else {
+				include = append(include, d.Path)
+			}
+		}
+		dirs, err := a.walkRepo(func(rel string, d fs.DirEntry) bool {
+			return d.IsDir() && matchAnyGlob(include, rel) && !matchAnyGlob(exclude, rel)
+		})
+		if err != nil {
+			return nil, err
+		}
+		params := make([]map[string]any, 0, len(dirs))
+		for _, dir := range dirs {
+			params = append(params, pathParams(dir, "", goTemplate))
+		}
+		return params, nil
+	case len(g.Files) > 0:
+		patterns := make([]string, 0, len(g.Files))
+		for _, f := range g.Files {
+			patterns = append(patterns, f.Path)
+		}
+		files, err := a.walkRepo(func(rel string, d fs.DirEntry) bool {
+			return !d.IsDir() && matchAnyGlob(patterns, rel)
+		})
+		if err != nil {
+			return nil, err
+		}
+		var params []map[string]any
+		for _, file := range files {
+			p, err := a.fileParams(file, goTemplate)
+			if err != nil {
+				return nil, err
+			}
+			params = append(params, p...)
+		}
+		return params, nil
+	default:
+		return nil, errors.New("git generator needs directories or files")
+	}
+}
+
+// checkGeneratorRepo rejects git generators for repositories other than the
+// indexed one, since they cannot be evaluated against the local clone.
+func (a *Adapter) checkGeneratorRepo(repoURL string) error {
+	if a.repoURL == "" || repoURL == "" {
+		return nil
+	}
+	owner, repo, err := domain.RepoFromURL(repoURL)
+	if err != nil {
+		return err
+	}
+	wantOwner, wantRepo, err := domain.RepoFromURL(a.repoURL)
+	if err != nil {
+		return err
+	}
+	if !strings.EqualFold(owner, wantOwner) || !strings.EqualFold(repo, wantRepo) {
+		return fmt.Errorf("git generator repository %q is not the indexed repository %q", repoURL, a.repoURL)
+	}
+	return nil
+}
+
+// matchAnyGlob reports whether rel matches one of the generator's globs.
+func matchAnyGlob(patterns []string, rel string) bool {
+	for _, p := range patterns {
+		if domain.MatchGlob(p, rel) {
+			return true
+		}
+	}
+	return false
+}
+
+// walkRepo returns the sorted slash-separated paths, relative to the local
+// clone, of the entries accepted by match. The .git directory is skipped.
+func (a *Adapter) walkRepo(match func(rel string, d fs.DirEntry) bool) ([]string, error) {
+	var paths []string
+	err := filepath.WalkDir(a.localPath, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() && d.Name() == ".git" {
+			return filepath.SkipDir
+		}
+		rel, err := filepath.Rel(a.localPath, p)
+		if err != nil || rel == "." {
+			return err
+		}
+		rel = filepath.ToSlash(rel)
+		if match(rel, d) {
+			paths = append(paths, rel)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("walking repository: %w", err)
+	}
+	sort.Strings(paths)
+	return paths, nil
+}
+
+// fileParams returns the parameter sets of a git files generator match: the
+// file's contents (one set per element if it holds a list) plus path
+// parameters for the file.
+func (a *Adapter) fileParams(rel string, goTemplate bool) ([]map[string]any, error) {
+	//nolint:gosec // G304: rel is from walking the local clone, not user input
+	data, err := os.ReadFile(filepath.Join(a.localPath, filepath.FromSlash(rel)))
+	if err != nil {
+		return nil, fmt.Errorf("reading %s: %w", rel, err)
+	}
+
+	var content any
+	if err := yaml.Unmarshal(data, &content); err != nil {
+		return nil, fmt.Errorf("parsing %s: %w", rel, err)
+	}
+
+	var elements []map[string]any
+	switch c := content.(type) {
+	case map[string]any:
+		elements = []map[string]any{c}
+	case []any:
+		for _, item := range c {
+			m, ok := item.(map[string]any)
+			if !ok {
+				return nil, fmt.Errorf("parsing %s: list entries must be objects", rel)
+			}
+			elements = append(elements, m)
+		}
+	case nil:
+		elements = []map[string]any{{}}
+	default:
+		return nil, fmt.Errorf("parsing %s: expected an object or a list of objects", rel)
+	}
+
+	params := make([]map[string]any, 0, len(elements))
+	for _, el := range elements {
+		params = append(params, mergeParams(shapeParams(el, goTemplate), pathParams(path.Dir(rel), path.Base(rel), goTemplate)))
+	}
+	return params, nil
+}
+
+// pathParams returns the path parameters Argo CD's git generator sets for a
+// directory, and for the file in it when filename is not empty.
+func pathParams(dir, filename string, goTemplate bool) map[string]any {
+	segments := strings.Split(dir, "/")
+	base := path.Base(dir)
+
+	if goTemplate {
+		p := map[string]any{
+			"path":               dir,
+			"basename":           base,
+			"basenameNormalized": normalizeName(base),
+			"segments":           segments,
+		}
+		if filename != "" {
+			p["filename"] = filename
+			p["filenameNormalized"] = normalizeName(filename)
+		}
+		return map[string]any{"path": p}
+	}
+
+	p := map[string]any{
+		"path":                    dir,
+		"path.basename":           base,
+		"path.basenameNormalized": normalizeName(base),
+	}
+	for i, s := range segments {
+		p["path["+strconv.Itoa(i)+"]"] = s
+	}
+	if filename != "" {
+		p["path.filename"] = filename
+		p["path.filenameNormalized"] = normalizeName(filename)
+	}
+	return p
+}
+
+// normalizeName lower-cases name and replaces characters not allowed in
+// Kubernetes resource names with '-'.
+func normalizeName(name string) string {
+	return nonDNSChars.ReplaceAllString(strings.ToLower(name), "-")
+}
+
+// shapeParams returns params as-is for Go templates, or flattened into dotted
+// string keys for non-Go templates.
+func shapeParams(params map[string]any, goTemplate bool) map[string]any {
+	if goTemplate {
+		return params
+	}
+	flat := make(map[string]any)
+	flattenParams("", params, flat)
+	return flat
+}
+
+func flattenParams(prefix string, value any, out map[string]any) {
+	switch v := value.(type) {
+	case map[string]any:
+		for k, child := range v {
+			key := k
+			if prefix != "" {
+				key = prefix + "." + k
+			}
+			flattenParams(key, child, out)
+		}
+	case []any:
+		for i, child := range v {
+			flattenParams(prefix+"."+strconv.Itoa(i), child, out)
+		}
+	case nil:
+		out[prefix] = ""
+	default:
+		out[prefix] = fmt.Sprint(v)
+	}
+}
+
+// mergeParams returns base with override's keys applied on top. Nested maps
+// are merged recursively.
+func mergeParams(base, override map[string]any) map[string]any {
+	merged := make(map[string]any, len(base)+len(override))
+	for k, v := range base {
+		merged[k] = v
+	}
+	for k, v := range override {
+		if bm, ok := merged[k].(map[string]any); ok {
+			if om, ok := v.(map[string]any); ok {
+				merged[k] = mergeParams(bm, om)
+				continue
+			}
+		}
+		merged[k] = v
+	}
+	return merged
+}
+
+// mergeKey identifies a parameter set by the values of the merge keys.
+func mergeKey(params map[string]any, keys []string) string {
+	values := make([]string, 0, len(keys))
+	for _, k := range keys {
+		values = append(values, fmt.Sprint(lookupParam(params, k)))
+	}
+	return strings.Join(values, "\x00")
+}
+
+// lookupParam returns the value of a flattened or dotted nested key.
+func lookupParam(params map[string]any, key string) any {
+	if v, ok := params[key]; ok {
+		return v
+	}
+	var cur any = params
+	for _, part := range strings.Split(key, ".") {
+		m, ok := cur.(map[string]any)
+		if !ok {
+			return nil
+		}
+		cur = m[part]
+	}
+	return cur
+}
+
+// renderApplication substitutes params into the ApplicationSet template and
+// decodes the result as an Application.
+func renderApplication(tmpl map[string]any, params map[string]any, goTemplate bool, options []string) (applicationManifest, error) {
+	render := func(s string) (string, error) {
+		return renderFastTemplate(s, params), nil
+	}
+	if goTemplate {
+		render = func(s string) (string, error) {
+			return renderGoTemplate(s, params, options)
+		}
+	}
+
+	rendered, err := renderValue(tmpl, render)
+	if err != nil {
+		return applicationManifest{}, err
+	}
+
+	data, err := yaml.Marshal(rendered)
+	if err != nil {
+		return applicationManifest{}, fmt.Errorf("encoding application: %w", err)
+	}
+	var app applicationManifest
+	if err := yaml.Unmarshal(data, &app); err != nil {
+		return applicationManifest{}, fmt.Errorf("decoding application: %w", err)
+	}
+	app.Kind = "Application"
+	return app, nil
+}
+
+// renderValue applies render to every string key and value in v.
+func renderValue(v any, render func(string) (string, error)) (any, error) {
+	switch t := v.(type) {
+	case string:
+		return render(t)
+	case map[string]any:
+		out := make(map[string]any, len(t))
+		for k, child := range t {
+			key, err := render(k)
+			if err != nil {
+				return nil, err
+			}
+			if out[key], err = renderValue(child, render); err != nil {
+				return nil, err
+			}
+		}
+		return out, nil
+	case []any:
+		out := make([]any, len(t))
+		for i, child := range t {
+			var err error
+			if out[i], err = renderValue(child, render); err != nil {
+				return nil, err
+			}
+		}
+		return out, nil
+	default:
+		return v, nil
+	}
+}
+
+// renderFastTemplate replaces {{ key }} placeholders with flattened params.
+// Unknown placeholders are left untouched, as Argo CD does.
+func renderFastTemplate(s string, params map[string]any) string {
+	return fastTemplateParam.ReplaceAllStringFunc(s, func(m string) string {
+		key := fastTemplateParam.FindStringSubmatch(m)[1]
+		if v, ok := params[key]; ok {
+			return fmt.Sprint(v)
+		}
+		return m
+	})
+}
+
+// templateFuncs is the subset of Sprig functions available to Go templates.
+var templateFuncs = template.FuncMap{
+	"lower":      strings.ToLower,
+	"upper":      strings.ToUpper,
+	"trim":       strings.TrimSpace,
+	"trimPrefix": func(prefix, s string) string { return strings.TrimPrefix(s, prefix) },
+	"trimSuffix": func(suffix, s string) string { return strings.TrimSuffix(s, suffix) },
+	"replace":    func(old, replacement, s string) string { return strings.ReplaceAll(s, old, replacement) },
+	"quote":      strconv.Quote,
+	"default": func(def, v any) any {
+		if v == nil || v == "" {
+			return def
+		}
+		return v
+	},
+}
+
+// renderGoTemplate executes s as a Go template with params as its data.
+func renderGoTemplate(s string, params map[string]any, options []string) (string, error) {
+	if !strings.Contains(s, "{{") {
+		return s, nil
+	}
+	t := template.New("").Funcs(templateFuncs)
+	for _, opt := range options {
+		if !strings.HasPrefix(opt, "missingkey=") {
+			return "", fmt.Errorf("unsupported goTemplateOptions entry %q", opt)
+		}
+		t = t.Option(opt)
+	}
+	t, err := t.Parse(s)
+	if err != nil {
+		return "", fmt.Errorf("parsing template %q: %w", s, err)
+	}
+	var b strings.Builder
+	if err := t.Execute(&b, params); err != nil {
+		return "", fmt.Errorf("executing template %q: %w", s, err)
+	}
+	return b.String(), nil
+}
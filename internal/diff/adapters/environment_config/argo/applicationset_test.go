@@ -0,0 +1,202 @@
+package argo
+
+import (
+	"log/slog"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// generatedApp is the part of an indexed ApplicationSet app the tests check.
+type generatedApp struct {
+	Chart, Env, Path string
+	ValueFiles       []string
+	Release          string
+	Namespace        string
+}
+
+func TestProcessApplicationSet(t *testing.T) {
+	t.Parallel()
+
+	repo := filepath.Join("testdata", "repos", "applicationsets")
+	adapter := &Adapter{
+		repoURL:   "https://github.com/example/gitops",
+		localPath: repo,
+		logger:    slog.New(slog.NewTextHandler(os.Stderr, nil)),
+	}
+
+	tests := []struct {
+		fixture string
+		want    []generatedApp
+	}{
+		{
+			fixture: "appsets/list.yaml",
+			want: []generatedApp{
+				{"api", "staging", "charts/api", []string{"values-staging.yaml"}, "api-staging", "api"},
+				{"api", "prod", "charts/api", []string{"values-prod.yaml"}, "api-prod", "api"},
+			},
+		},
+		{
+			fixture: "appsets/git-directories.yaml",
+			want: []generatedApp{
+				{"api", "dev", "charts/api", []string{}, "API", "api"},
+				{"web", "dev", "charts/web", []string{}, "WEB", "web"},
+			},
+		},
+		{
+			fixture: "appsets/git-files.yaml",
+			want: []generatedApp{
+				{"web", "dev-cluster", "charts/web", []string{"values-dev.yaml"}, "web-dev", ""},
+				{"web", "prod-cluster", "charts/web", []string{"values-prod.yaml"}, "web-prod", ""},
+			},
+		},
+		{
+			// Without a destination name the Application name is the environment
+			fixture: "appsets/matrix.yaml",
+			want: []generatedApp{
+				{"api", "api-qa", "charts/api", []string{"values-qa.yaml"}, "api-qa", "qa"},
+				{"api", "api-perf", "charts/api", []string{"values-perf.yaml"}, "api-perf", "perf"},
+				{"web", "web-qa", "charts/web", []string{"values-qa.yaml"}, "web-qa", "qa"},
+				{"web", "web-perf", "charts/web", []string{"values-perf.yaml"}, "web-perf", "perf"},
+			},
+		},
+		{
+			fixture: "appsets/merge.yaml",
+			want: []generatedApp{
+				{"legacy-worker", "eu", "charts/legacy-worker", []string{"values.yaml"}, "legacy-worker-eu", ""},
+				{"legacy-worker", "us", "charts/legacy-worker", []string{"values-us.yaml"}, "legacy-worker-us", ""},
+			},
+		},
+		{
+			fixture: "invalid/unsupported-generator.yaml",
+		},
+		{
+			fixture: "invalid/other-repo.yaml",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.fixture, func(t *testing.T) {
+			t.Parallel()
+
+			apps, ok := adapter.processApplicationFile(filepath.Join(repo, tt.fixture))
+			if ok != (len(tt.want) > 0) {
+				t.Fatalf("shouldIndex = %v, want %v", ok, len(tt.want) > 0)
+			}
+
+			var got []generatedApp
+			for _, app := range apps {
+				got = append(got, generatedApp{
+					Chart:      app.ChartName,
+					Env:        app.Environment,
+					Path:       app.ChartPath,
+					ValueFiles: app.ValueFiles,
+					Release:    app.Helm.ReleaseName,
+					Namespace:  app.Helm.Namespace,
+				})
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("apps = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestExpandApplicationSet_Errors(t *testing.T) {
+	t.Parallel()
+
+	repo := filepath.Join("testdata", "repos", "applicationsets")
+	adapter := &Adapter{
+		repoURL:   "https://github.com/example/gitops",
+		localPath: repo,
+		logger:    slog.New(slog.NewTextHandler(os.Stderr, nil)),
+	}
+
+	tests := []struct {
+		fixture     string
+		errContains string
+	}{
+		{fixture: "invalid/unsupported-generator.yaml", errContains: "unsupported generator"},
+		{fixture: "invalid/other-repo.yaml", errContains: "not the indexed repository"},
+		{fixture: "../multi-env/my-app/prod/app.yaml", errContains: "not an Application"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.fixture, func(t *testing.T) {
+			t.Parallel()
+
+			data, err := os.ReadFile(filepath.Join(repo, tt.fixture))
+			if err != nil {
+				t.Fatalf("reading fixture: %v", err)
+			}
+			_, err = adapter.expandApplicationSet(data)
+			if err == nil || !strings.Contains(err.Error(), tt.errContains) {
+				t.Errorf("error = %v, want it to contain %q", err, tt.errContains)
+			}
+		})
+	}
+}
+
+func TestRenderTemplates(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name       string
+		in         string
+		params     map[string]any
+		goTemplate bool
+		options    []string
+		want       string
+		wantErr    bool
+	}{
+		{
+			name:   "fasttemplate replaces known keys",
+			in:     "{{ cluster }}-{{path.basename}}",
+			params: map[string]any{"cluster": "prod", "path.basename": "api"},
+			want:   "prod-api",
+		},
+		{
+			name:   "fasttemplate leaves unknown keys",
+			in:     "{{cluster}}-{{region}}",
+			params: map[string]any{"cluster": "prod"},
+			want:   "prod-{{region}}",
+		},
+		{
+			name:       "go template with functions",
+			in:         `{{ .env | upper }}-{{ default "x" .missing }}-{{ trimPrefix "charts/" .path }}`,
+			params:     map[string]any{"env": "qa", "path": "charts/api"},
+			goTemplate: true,
+			want:       "QA-x-api",
+		},
+		{
+			name:       "go template missingkey=error",
+			in:         "{{ .missing }}",
+			params:     map[string]any{},
+			goTemplate: true,
+			options:    []string{"missingkey=error"},
+			wantErr:    true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			var got string
+			var err error
+			if tt.goTemplate {
+				got, err = renderGoTemplate(tt.in, tt.params, tt.options)
+			} else {
+				got = renderFastTemplate(tt.in, tt.params)
+			}
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("err = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && got != tt.want {
+				t.Errorf("got %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
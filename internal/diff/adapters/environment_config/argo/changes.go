@@ -0,0 +1,372 @@
+package argo
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"log/slog"
+	"maps"
+	"os"
+	"path"
+	"path/filepath"
+	"reflect"
+	"strings"
+
+	"github.com/nathantilsley/chart-val/internal/diff/domain"
+	"github.com/nathantilsley/chart-val/internal/diff/ports"
+)
+
+// ChangeDetector implements ports.DeploymentChangesPort for pull requests to
+// the Argo CD gitops repositories. It reads the PR's repository at its base
+// and head refs, reading Applications and ApplicationSets the way Adapter
+// does, and returns the Applications whose settings differ.
+//
+// The head side is unreviewed, so its Applications are only rendered from
+// repositories the base side already reads or that allowedRepos lists;
+// otherwise a PR could have any repository the installation can read
+// rendered into its comments.
+type ChangeDetector struct {
+	sourceControl ports.SourceControlPort
+	sources       []Source // Gitops repositories
+	allowedRepos  []string // "owner/repo" path.Match globs
+	logger        *slog.Logger
+}
+
+// NewChangeDetector creates a change detector for the gitops repositories
+// of sources. Environments are named as by New. allowedRepos are
+// "owner/repo" globs (e.g., "my-org/*") head-side Applications may read
+// besides the repositories the base side already does.
+func NewChangeDetector(
+	sourceControl ports.SourceControlPort,
+	sources []Source,
+	allowedRepos []string,
+	logger *slog.Logger,
+) *ChangeDetector {
+	return &ChangeDetector{
+		sourceControl: sourceControl,
+		sources:       sources,
+		allowedRepos:  allowedRepos,
+		logger:        logger,
+	}
+}
+
+// IsGitopsRepo implements ports.DeploymentChangesPort.
+func (d *ChangeDetector) IsGitopsRepo(pr domain.PRContext) bool {
+	_, ok := d.source(pr)
+	return ok
+}
+
+// source returns the source whose gitops repository the PR is to.
+func (d *ChangeDetector) source(pr domain.PRContext) (Source, bool) {
+	for _, src := range d.sources {
+		owner, repo, err := domain.RepoFromURL(src.Repo)
+		if err == nil && strings.EqualFold(owner, pr.Owner) && strings.EqualFold(repo, pr.Repo) {
+			return src, true
+		}
+	}
+	return Source{}, false
+}
+
+// appKey identifies an Application across the two sides of a PR.
+type appKey struct {
+	name string
+	env  string
+}
+
+// ChangedDeployments implements ports.DeploymentChangesPort. Applications
+// are matched by name and environment. One whose settings are the same on
+// both sides is left out, even if its manifest was reformatted or moved,
+// unless it reads a chart or value file from the gitops repository that
+// the PR changes. Applications whose chart isn't in git are returned with
+// the reason in Unsupported.
+func (d *ChangeDetector) ChangedDeployments(ctx context.Context, pr domain.PRContext) ([]domain.DeploymentChange, error) {
+	src, ok := d.source(pr)
+	if !ok {
+		return nil, fmt.Errorf("%s/%s is not a gitops repository", pr.Owner, pr.Repo)
+	}
+
+	baseRoot, baseCleanup, err := d.sourceControl.FetchChartFiles(ctx, pr.Owner, pr.Repo, pr.BaseRef, ".")
+	if err != nil {
+		return nil, fmt.Errorf("fetching %s: %w", pr.BaseRef, err)
+	}
+	defer baseCleanup()
+	headRoot, headCleanup, err := d.sourceControl.FetchChartFiles(ctx, pr.Owner, pr.Repo, pr.HeadRef, ".")
+	if err != nil {
+		return nil, fmt.Errorf("fetching %s: %w", pr.HeadRef, err)
+	}
+	defer headCleanup()
+
+	base, err := d.apps(src, baseRoot)
+	if err != nil {
+		return nil, fmt.Errorf("reading applications at %s: %w", pr.BaseRef, err)
+	}
+	head, err := d.apps(src, headRoot)
+	if err != nil {
+		return nil, fmt.Errorf("reading applications at %s: %w", pr.HeadRef, err)
+	}
+
+	trusted := trustedRepos(pr, base)
+	var changes []domain.DeploymentChange
+	for _, h := range head.order {
+		headApp := head.apps[h]
+		change := domain.DeploymentChange{Name: displayName(h), File: headApp.File}
+		change.Head = d.deploymentSource(pr, pr.HeadRef, headApp)
+		change.Unsupported = d.untrusted(headApp, trusted)
+		if baseApp, inBase := base.apps[h]; inBase {
+			change.Base = d.deploymentSource(pr, pr.BaseRef, baseApp)
+			if sameSettings(baseApp, headApp) && !d.prFilesChanged(pr, baseRoot, headRoot, headApp) {
+				continue
+			}
+			if change.Unsupported == "" {
+				change.Unsupported = unsupported(baseApp)
+			}
+		}
+		changes = append(changes, change)
+	}
+	for _, b := range base.order {
+		if _, inHead := head.apps[b]; inHead {
+			continue
+		}
+		baseApp := base.apps[b]
+		changes = append(changes, domain.DeploymentChange{
+			Name:        displayName(b),
+			File:        baseApp.File,
+			Base:        d.deploymentSource(pr, pr.BaseRef, baseApp),
+			Unsupported: unsupported(baseApp),
+		})
+	}
+
+	for _, c := range changes {
+		if c.Unsupported != "" {
+			d.logger.Warn("changed application can't be rendered", "application", c.Name, "file", c.File, "reason", c.Unsupported)
+		}
+	}
+	return changes, nil
+}
+
+// prFilesChanged reports whether an Application reads its chart or a value
+// file from the gitops repository at the PR's refs, and the PR changes it.
+func (d *ChangeDetector) prFilesChanged(pr domain.PRContext, baseRoot, headRoot string, app AppData) bool {
+	var paths []string
+	if owner, repo, err := domain.RepoFromURL(app.RepoURL); err == nil && followsPR(pr, owner, repo, app.Revision) {
+		chartPath := strings.Trim(app.ChartPath, "/")
+		paths = append(paths, chartPath)
+		for _, vf := range app.ValueFiles {
+			if ref, err := domain.ParseValueFileRef(vf); err == nil && !ref.External() {
+				paths = append(paths, path.Join(chartPath, ref.Path))
+			}
+		}
+	}
+	for _, vf := range app.ValueFiles {
+		if ref, err := domain.ParseValueFileRef(vf); err == nil && ref.Repo != "" && followsPR(pr, ref.Owner, ref.Repo, ref.Ref) {
+			paths = append(paths, ref.Path)
+		}
+	}
+
+	for _, p := range paths {
+		same, err := sameTree(filepath.Join(baseRoot, filepath.FromSlash(p)), filepath.Join(headRoot, filepath.FromSlash(p)))
+		if err != nil {
+			d.logger.Warn("comparing application files failed", "application", app.Name, "path", p, "error", err)
+			return true
+		}
+		if !same {
+			return true
+		}
+	}
+	return false
+}
+
+// sideApps is the Applications of one side of a PR, keyed and in file order.
+type sideApps struct {
+	apps  map[appKey]AppData
+	order []appKey
+}
+
+// apps reads the Applications of a checkout of a source's gitops repository.
+func (d *ChangeDetector) apps(src Source, root string) (sideApps, error) {
+	side := sideApps{apps: make(map[appKey]AppData)}
+
+	scanner := &Adapter{
+		name:               src.Name,
+		repoURL:            src.Repo,
+		localPath:          root,
+		folderPattern:      src.FolderPattern,
+		envKey:             src.EnvKey,
+		envFromDestination: src.EnvFromDestination,
+		logger:             d.logger,
+	}
+	apps, err := scanner.scan()
+	if err != nil {
+		return side, err
+	}
+	for _, app := range apps {
+		key := appKey{name: app.Name, env: app.Environment}
+		if key.name == "" {
+			key.name = app.File
+		}
+		if _, dup := side.apps[key]; dup {
+			d.logger.Warn("duplicate application, using the first", "application", key.name, "env", key.env, "file", app.File)
+			continue
+		}
+		side.apps[key] = app
+		side.order = append(side.order, key)
+	}
+	return side, nil
+}
+
+// deploymentSource maps an Application onto the chart and settings it
+// renders. Charts and "$ref" value files in the gitops repository itself
+// that follow its default branch are read at the PR's ref for the side,
+// so changes to them in the PR are rendered too. Charts outside git get
+// an empty Repo.
+func (d *ChangeDetector) deploymentSource(pr domain.PRContext, sideRef string, app AppData) *domain.DeploymentSource {
+	src := &domain.DeploymentSource{
+		Path: app.ChartPath,
+		Ref:  app.Revision,
+		Environment: domain.EnvironmentConfig{
+			Name:       app.Environment,
+			ValueFiles: make([]string, 0, len(app.ValueFiles)),
+			Helm:       app.Helm,
+		},
+	}
+	owner, repo, err := domain.RepoFromURL(app.RepoURL)
+	if err != nil {
+		return src
+	}
+	src.Owner, src.Repo = owner, repo
+	src.Path = strings.Trim(app.ChartPath, "/")
+
+	if followsPR(pr, owner, repo, app.Revision) {
+		src.Ref = sideRef
+	}
+	for _, vf := range app.ValueFiles {
+		ref, err := domain.ParseValueFileRef(vf)
+		if err == nil && ref.Repo != "" && followsPR(pr, ref.Owner, ref.Repo, ref.Ref) {
+			ref.Ref = sideRef
+			vf = ref.String()
+		}
+		src.Environment.ValueFiles = append(src.Environment.ValueFiles, vf)
+	}
+	return src
+}
+
+// unsupported returns why an Application can't be rendered, or "" if it
+// can. Only charts in git repositories are fetched; Helm repository and OCI
+// charts (spec.source.chart) are not.
+func unsupported(app AppData) string {
+	if _, _, err := domain.RepoFromURL(app.RepoURL); err == nil {
+		return ""
+	}
+	return fmt.Sprintf("chart %q %s from %s is not in a git repository, so it is not rendered",
+		app.ChartPath, app.Revision, app.RepoURL)
+}
+
+// untrusted is unsupported for a head-side Application, which additionally
+// can't read repositories outside trusted and the allowed ones.
+func (d *ChangeDetector) untrusted(app AppData, trusted map[string]bool) string {
+	if reason := unsupported(app); reason != "" {
+		return reason
+	}
+	owner, repo, _ := domain.RepoFromURL(app.RepoURL)
+	repos := []string{owner + "/" + repo}
+	for _, vf := range app.ValueFiles {
+		if ref, err := domain.ParseValueFileRef(vf); err == nil && ref.Repo != "" {
+			repos = append(repos, ref.Owner+"/"+ref.Repo)
+		}
+	}
+	for _, r := range repos {
+		if !trusted[strings.ToLower(r)] && !d.allowed(r) {
+			return fmt.Sprintf("repository %s is not read by the base branch's applications or allowed, so it is not rendered", r)
+		}
+	}
+	return ""
+}
+
+// allowed reports whether an "owner/repo" matches allowedRepos.
+func (d *ChangeDetector) allowed(repo string) bool {
+	for _, pattern := range d.allowedRepos {
+		if ok, err := path.Match(strings.ToLower(pattern), strings.ToLower(repo)); err == nil && ok {
+			return true
+		}
+	}
+	return false
+}
+
+// trustedRepos returns the lowercased "owner/repo" of the PR's repository
+// and of every repository the base side's Applications read.
+func trustedRepos(pr domain.PRContext, base sideApps) map[string]bool {
+	trusted := map[string]bool{strings.ToLower(pr.Owner + "/" + pr.Repo): true}
+	for _, app := range base.apps {
+		if owner, repo, err := domain.RepoFromURL(app.RepoURL); err == nil {
+			trusted[strings.ToLower(owner+"/"+repo)] = true
+		}
+		for _, vf := range app.ValueFiles {
+			if ref, err := domain.ParseValueFileRef(vf); err == nil && ref.Repo != "" {
+				trusted[strings.ToLower(ref.Owner+"/"+ref.Repo)] = true
+			}
+		}
+	}
+	return trusted
+}
+
+// followsPR reports whether owner/repo at ref is the PR's repository on its
+// base branch, which the PR's head ref replaces.
+func followsPR(pr domain.PRContext, owner, repo, ref string) bool {
+	return domain.ValueFileRef{Owner: owner, Repo: repo, Ref: ref}.InPRRepo(pr)
+}
+
+// sameSettings reports whether two Applications render the same way.
+func sameSettings(a, b AppData) bool {
+	a.File, b.File = "", ""
+	return reflect.DeepEqual(a, b)
+}
+
+// displayName names an Application in reports.
+func displayName(key appKey) string {
+	if key.env == "" || key.env == key.name {
+		return key.name
+	}
+	return key.name + " (" + key.env + ")"
+}
+
+// sameTree reports whether two files, or two directories and everything in
+// them, have the same content. A path missing on both sides is the same.
+func sameTree(a, b string) (bool, error) {
+	aFiles, err := treeFiles(a)
+	if err != nil {
+		return false, err
+	}
+	bFiles, err := treeFiles(b)
+	if err != nil {
+		return false, err
+	}
+	return maps.EqualFunc(aFiles, bFiles, bytes.Equal), nil
+}
+
+// treeFiles returns the contents of a file, or of every file below a
+// directory, keyed by path relative to root.
+func treeFiles(root string) (map[string][]byte, error) {
+	files := make(map[string][]byte)
+	err := filepath.WalkDir(root, func(p string, entry fs.DirEntry, err error) error {
+		if errors.Is(err, fs.ErrNotExist) && p == root {
+			return filepath.SkipAll
+		}
+		if err != nil || entry.IsDir() {
+			return err
+		}
+		rel, err := filepath.Rel(root, p)
+		if err != nil {
+			return err
+		}
+		//nolint:gosec // G304: p is from filepath.WalkDir over a fetched checkout
+		data, err := os.ReadFile(p)
+		if err != nil {
+			return err
+		}
+		files[rel] = data
+		return nil
+	})
+	return files, err
+}
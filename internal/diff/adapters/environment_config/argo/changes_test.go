@@ -0,0 +1,181 @@
+package argo
+
+import (
+	"context"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/nathantilsley/chart-val/internal/diff/domain"
+)
+
+// refSourceControl serves testdata/repos/gitops-pr/<side> for the PR's refs.
+type refSourceControl map[string]string // ref -> side
+
+func (s refSourceControl) FetchChartFiles(_ context.Context, _, _, ref, chartPath string) (string, func(), error) {
+	side, ok := s[ref]
+	if !ok {
+		return "", nil, domain.NewNotFoundError(chartPath, ref)
+	}
+	return filepath.Join("testdata", "repos", "gitops-pr", side, chartPath), func() {}, nil
+}
+
+// sourceSummary is the part of a DeploymentSource the tests compare.
+type sourceSummary struct {
+	Repo       string
+	Ref        string
+	Path       string
+	Env        string
+	ValueFiles []string
+}
+
+func summarize(src *domain.DeploymentSource) *sourceSummary {
+	if src == nil {
+		return nil
+	}
+	return &sourceSummary{
+		Repo:       src.Owner + "/" + src.Repo,
+		Ref:        src.Ref,
+		Path:       src.Path,
+		Env:        src.Environment.Name,
+		ValueFiles: src.Environment.ValueFiles,
+	}
+}
+
+func TestChangedDeployments(t *testing.T) {
+	t.Parallel()
+
+	detector := NewChangeDetector(
+		refSourceControl{"main": "base", "bump": "head"},
+		[]Source{{Name: "argo", Repo: "https://github.com/example/gitops", FolderPattern: "{envName}"}},
+		nil,
+		slog.New(slog.NewTextHandler(os.Stderr, nil)),
+	)
+	pr := domain.PRContext{Owner: "example", Repo: "gitops", BaseRef: "main", HeadRef: "bump"}
+
+	changes, err := detector.ChangedDeployments(context.Background(), pr)
+	if err != nil {
+		t.Fatalf("ChangedDeployments failed: %v", err)
+	}
+
+	type change struct {
+		Name        string
+		File        string
+		Base        *sourceSummary
+		Head        *sourceSummary
+		Unsupported string
+	}
+	var got []change
+	for _, c := range changes {
+		got = append(got, change{
+			Name: c.Name, File: c.File, Base: summarize(c.Base), Head: summarize(c.Head), Unsupported: c.Unsupported,
+		})
+	}
+
+	// api-dev is only reformatted
+	want := []change{
+		{
+			Name: "api-prod (prod)",
+			File: "apps/prod/api.yaml",
+			Base: &sourceSummary{"example/charts", "v1", "charts/api", "prod", []string{"values-prod.yaml"}},
+			Head: &sourceSummary{"example/charts", "v2", "charts/api", "prod", []string{"values-prod.yaml"}},
+		},
+		{
+			// Chart in the gitops repository on its default branch follows the PR
+			Name: "platform-prod (prod)",
+			File: "apps/prod/platform.yaml",
+			Base: &sourceSummary{"example/gitops", "main", "charts/platform", "prod", []string{}},
+			Head: &sourceSummary{"example/gitops", "bump", "charts/platform", "prod", []string{}},
+		},
+		{
+			// Helm repository charts are reported, not rendered
+			Name: "redis-prod (prod)",
+			File: "apps/prod/redis.yaml",
+			Base: &sourceSummary{"/", "18.0.0", "redis", "prod", []string{}},
+			Head: &sourceSummary{"/", "18.1.0", "redis", "prod", []string{}},
+			Unsupported: `chart "redis" 18.1.0 from https://charts.bitnami.com/bitnami ` +
+				"is not in a git repository, so it is not rendered",
+		},
+		{
+			// The base branch reads nothing from this repository
+			Name:        "secrets-qa (qa)",
+			File:        "apps/qa/secrets.yaml",
+			Head:        &sourceSummary{"example/infra-secrets", "main", "charts/secrets", "qa", []string{}},
+			Unsupported: "repository example/infra-secrets is not read by the base branch's applications or allowed, so it is not rendered",
+		},
+		{
+			Name: "web-qa (qa)",
+			File: "apps/qa/web.yaml",
+			Head: &sourceSummary{"example/charts", "v1", "charts/web", "qa", []string{"values-qa.yaml"}},
+		},
+		{
+			// Only its $values file in the gitops repository changed
+			Name: "api-staging (staging)",
+			File: "apps/staging/api.yaml",
+			Base: &sourceSummary{"example/charts", "v1", "charts/api", "staging",
+				[]string{"example/gitops@main:envs/staging/values.yaml"}},
+			Head: &sourceSummary{"example/charts", "v1", "charts/api", "staging",
+				[]string{"example/gitops@bump:envs/staging/values.yaml"}},
+		},
+		{
+			Name: "worker-old (prod)",
+			File: "apps/prod/worker.yaml",
+			Base: &sourceSummary{"example/charts", "v1", "charts/worker", "prod", []string{"values-prod.yaml"}},
+		},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("changes =\n%+v\nwant\n%+v", got, want)
+	}
+}
+
+func TestChangedDeployments_AllowedRepos(t *testing.T) {
+	t.Parallel()
+
+	detector := NewChangeDetector(
+		refSourceControl{"main": "base", "bump": "head"},
+		[]Source{{Name: "argo", Repo: "https://github.com/example/gitops", FolderPattern: "{envName}"}},
+		[]string{"Example/infra-*"},
+		slog.New(slog.NewTextHandler(os.Stderr, nil)),
+	)
+	pr := domain.PRContext{Owner: "example", Repo: "gitops", BaseRef: "main", HeadRef: "bump"}
+
+	changes, err := detector.ChangedDeployments(context.Background(), pr)
+	if err != nil {
+		t.Fatalf("ChangedDeployments failed: %v", err)
+	}
+	for _, c := range changes {
+		if c.Name == "secrets-qa (qa)" {
+			if c.Unsupported != "" {
+				t.Errorf("allowed repository is not rendered: %s", c.Unsupported)
+			}
+			return
+		}
+	}
+	t.Error("secrets-qa is missing from the changes")
+}
+
+func TestIsGitopsRepo(t *testing.T) {
+	t.Parallel()
+
+	detector := NewChangeDetector(nil, []Source{
+		{Name: "payments", Repo: "git@github.com:Example/GitOps.git"},
+		{Name: "platform", Repo: "https://github.com/platform/deploy"},
+	}, nil, nil)
+
+	tests := []struct {
+		pr   domain.PRContext
+		want bool
+	}{
+		{pr: domain.PRContext{Owner: "example", Repo: "gitops"}, want: true},
+		{pr: domain.PRContext{Owner: "example", Repo: "charts"}, want: false},
+		{pr: domain.PRContext{Owner: "other", Repo: "gitops"}, want: false},
+		{pr: domain.PRContext{Owner: "platform", Repo: "deploy"}, want: true},
+	}
+	for _, tt := range tests {
+		if got := detector.IsGitopsRepo(tt.pr); got != tt.want {
+			t.Errorf("IsGitopsRepo(%s/%s) = %v, want %v", tt.pr.Owner, tt.pr.Repo, got, tt.want)
+		}
+	}
+}
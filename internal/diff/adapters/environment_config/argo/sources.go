@@ -0,0 +1,122 @@
+package argo
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Source defaults.
+const (
+	defaultFolderPattern = "{chartName}/{envName}"
+	defaultSyncInterval  = time.Hour
+)
+
+// Source is a gitops repository of Argo CD Applications.
+type Source struct {
+	Name               string        `yaml:"name"`               // Labels logs, metrics and the environments it supplies
+	Repo               string        `yaml:"repo"`               // Git URL to clone
+	LocalPath          string        `yaml:"localPath"`          // Clone directory (default: "/tmp/chart-val-argocd-{name}")
+	FolderPattern      string        `yaml:"folderPattern"`      // Environment fallback (default: "{chartName}/{envName}")
+	EnvKey             string        `yaml:"envKey"`             // Application label/annotation naming the environment
+	EnvFromDestination bool          `yaml:"envFromDestination"` // Name environments after spec.destination.name
+	SyncInterval       time.Duration `yaml:"syncInterval"`       // Periodic sync (default: 1h)
+	Token              string        `yaml:"token"`              // HTTPS access token for Repo
+	SSHKeyFile         string        `yaml:"sshKeyFile"`         // SSH private key for Repo
+	Repos              []string      `yaml:"repos"`              // owner/repo globs of the chart repos it covers; empty covers all
+}
+
+type sourcesFile struct {
+	Sources []Source `yaml:"sources"`
+}
+
+// LoadSources reads gitops sources from a YAML file. ${VAR} references are
+// expanded from the environment so tokens can be kept out of the file.
+func LoadSources(file string) ([]Source, error) {
+	//nolint:gosec // G304: path is from trusted config, not user input
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return nil, fmt.Errorf("reading argo sources: %w", err)
+	}
+	return ParseSources([]byte(os.ExpandEnv(string(data))))
+}
+
+// ParseSources parses YAML gitops sources, applies defaults and validates
+// them.
+func ParseSources(data []byte) ([]Source, error) {
+	var f sourcesFile
+	if err := yaml.Unmarshal(data, &f); err != nil {
+		return nil, fmt.Errorf("parsing argo sources: %w", err)
+	}
+	if len(f.Sources) == 0 {
+		return nil, errors.New("argo sources: no sources defined")
+	}
+	for i := range f.Sources {
+		src := &f.Sources[i]
+		if src.LocalPath == "" && src.Name != "" {
+			src.LocalPath = "/tmp/chart-val-argocd-" + src.Name
+		}
+		if src.FolderPattern == "" {
+			src.FolderPattern = defaultFolderPattern
+		}
+		if src.SyncInterval == 0 {
+			src.SyncInterval = defaultSyncInterval
+		}
+	}
+	if err := ValidateSources(f.Sources); err != nil {
+		return nil, err
+	}
+	return f.Sources, nil
+}
+
+// ValidateSources checks that every source is complete and that names and
+// clone directories are not shared.
+func ValidateSources(sources []Source) error {
+	names := make(map[string]bool, len(sources))
+	paths := make(map[string]bool, len(sources))
+	for i, src := range sources {
+		switch {
+		case src.Name == "":
+			return fmt.Errorf("argo source %d: name is required", i)
+		case names[src.Name]:
+			return fmt.Errorf("argo source %q: duplicate name", src.Name)
+		case src.Repo == "":
+			return fmt.Errorf("argo source %q: repo is required", src.Name)
+		case src.LocalPath == "":
+			return fmt.Errorf("argo source %q: localPath is required", src.Name)
+		case paths[src.LocalPath]:
+			return fmt.Errorf("argo source %q: localPath %s is used by another source", src.Name, src.LocalPath)
+		case src.SyncInterval <= 0:
+			return fmt.Errorf("argo source %q: syncInterval must be positive", src.Name)
+		case src.Token != "" && src.SSHKeyFile != "":
+			return fmt.Errorf("argo source %q: set token or sshKeyFile, not both", src.Name)
+		}
+		for _, pattern := range src.Repos {
+			if _, err := path.Match(pattern, ""); err != nil {
+				return fmt.Errorf("argo source %q: invalid repos pattern %q: %w", src.Name, pattern, err)
+			}
+		}
+		names[src.Name] = true
+		paths[src.LocalPath] = true
+	}
+	return nil
+}
+
+// covers reports whether the source indexes charts of repo ("owner/repo",
+// lower-cased).
+func (a *Adapter) covers(repo string) bool {
+	if len(a.repos) == 0 {
+		return true
+	}
+	for _, pattern := range a.repos {
+		if ok, _ := path.Match(strings.ToLower(pattern), repo); ok {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,135 @@
+package argo
+
+import (
+	"log/slog"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseSources(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name    string
+		yaml    string
+		want    []Source
+		wantErr string
+	}{
+		{
+			name: "defaults",
+			yaml: `
+sources:
+  - name: payments
+    repo: https://github.com/example/payments-gitops
+    token: t0ken
+    repos: ["example/payments-*"]
+  - name: platform
+    repo: git@github.com:example/platform-gitops.git
+    localPath: /var/lib/chart-val/platform
+    folderPattern: "clusters/{envName}"
+    envKey: example.com/environment
+    envFromDestination: true
+    syncInterval: 15m
+    sshKeyFile: /etc/chart-val/platform-key
+`,
+			want: []Source{
+				{
+					Name:          "payments",
+					Repo:          "https://github.com/example/payments-gitops",
+					LocalPath:     "/tmp/chart-val-argocd-payments",
+					FolderPattern: "{chartName}/{envName}",
+					SyncInterval:  time.Hour,
+					Token:         "t0ken",
+					Repos:         []string{"example/payments-*"},
+				},
+				{
+					Name:               "platform",
+					Repo:               "git@github.com:example/platform-gitops.git",
+					LocalPath:          "/var/lib/chart-val/platform",
+					FolderPattern:      "clusters/{envName}",
+					EnvKey:             "example.com/environment",
+					EnvFromDestination: true,
+					SyncInterval:       15 * time.Minute,
+					SSHKeyFile:         "/etc/chart-val/platform-key",
+				},
+			},
+		},
+		{name: "no sources", yaml: "sources: []\n", wantErr: "no sources defined"},
+		{name: "missing name", yaml: "sources:\n  - repo: https://github.com/example/gitops\n", wantErr: "name is required"},
+		{name: "missing repo", yaml: "sources:\n  - name: payments\n", wantErr: "repo is required"},
+		{
+			name:    "duplicate name",
+			yaml:    "sources:\n  - {name: a, repo: r1, localPath: /tmp/a}\n  - {name: a, repo: r2, localPath: /tmp/b}\n",
+			wantErr: "duplicate name",
+		},
+		{
+			name:    "shared clone directory",
+			yaml:    "sources:\n  - {name: a, repo: r1, localPath: /tmp/x}\n  - {name: b, repo: r2, localPath: /tmp/x}\n",
+			wantErr: "used by another source",
+		},
+		{
+			name:    "token and ssh key",
+			yaml:    "sources:\n  - {name: a, repo: r1, token: t, sshKeyFile: /k}\n",
+			wantErr: "not both",
+		},
+		{name: "invalid repos pattern", yaml: "sources:\n  - {name: a, repo: r1, repos: ['[']}\n", wantErr: "invalid repos pattern"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			got, err := ParseSources([]byte(tt.yaml))
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("ParseSources error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ParseSources failed: %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ParseSources() =\n%+v\nwant\n%+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestBuildIndex_Repos(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name  string
+		repos []string
+		want  int // Charts indexed
+	}{
+		{name: "every repository", want: 2},
+		{name: "matching glob", repos: []string{"Example/*"}, want: 2},
+		{name: "other repositories", repos: []string{"platform/*", "example/web"}, want: 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			adapter := &Adapter{
+				localPath:     filepath.Join("testdata", "repos", "multi-env"),
+				folderPattern: "{chartName}/{envName}",
+				repos:         tt.repos,
+				logger:        slog.New(slog.NewTextHandler(os.Stderr, nil)),
+			}
+			index, _, err := adapter.buildIndex()
+			if err != nil {
+				t.Fatalf("buildIndex failed: %v", err)
+			}
+			if len(index) != tt.want {
+				t.Errorf("indexed %d charts, want %d", len(index), tt.want)
+			}
+		})
+	}
+}
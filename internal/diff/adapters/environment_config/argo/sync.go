@@ -0,0 +1,105 @@
+package argo
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+
+	"github.com/nathantilsley/chart-val/internal/diff/domain"
+	"github.com/nathantilsley/chart-val/internal/platform/gitmirror"
+)
+
+// registerMetrics creates the sync instruments and the index staleness
+// gauges, which are observed from the adapter's sync status.
+func (a *Adapter) registerMetrics(meter metric.Meter, metricPrefix string) {
+	a.syncs, _ = meter.Int64Counter(metricPrefix+".argo.syncs",
+		metric.WithUnit("{sync}"),
+		metric.WithDescription("Argo apps repository syncs by source, trigger (interval, webhook, admin) and result"),
+	)
+	a.syncDuration, _ = meter.Float64Histogram(metricPrefix+".argo.sync.duration",
+		metric.WithUnit("s"),
+		metric.WithDescription("Time to pull the Argo apps repository and rebuild the index"),
+	)
+
+	indexAge, _ := meter.Float64ObservableGauge(metricPrefix+".argo.index.age",
+		metric.WithUnit("s"),
+		metric.WithDescription("Time since the Argo apps index was last synced successfully"),
+	)
+	indexApps, _ := meter.Int64ObservableGauge(metricPrefix+".argo.index.applications",
+		metric.WithUnit("{application}"),
+		metric.WithDescription("Applications in the Argo apps index"),
+	)
+	//nolint:errcheck // Without the callback the gauges are just not reported
+	_, _ = meter.RegisterCallback(func(_ context.Context, o metric.Observer) error {
+		status := a.status()
+		source := metric.WithAttributes(attribute.String("source", a.name))
+		if !status.LastSync.IsZero() {
+			o.ObserveFloat64(indexAge, time.Since(status.LastSync).Seconds(), source)
+		}
+		o.ObserveInt64(indexApps, int64(status.Applications), source)
+		return nil
+	}, indexAge, indexApps)
+}
+
+// recordSync records a finished sync on the sync metrics.
+func (a *Adapter) recordSync(trigger string, start time.Time, err error) {
+	result := "success"
+	if err != nil {
+		result = "error"
+	}
+	ctx := context.Background()
+	attrs := metric.WithAttributes(
+		attribute.String("source", a.name),
+		attribute.String("trigger", trigger),
+		attribute.String("result", result),
+	)
+	a.syncs.Add(ctx, 1, attrs)
+	a.syncDuration.Record(ctx, time.Since(start).Seconds(), attrs)
+}
+
+// RequestSync implements ports.GitopsIndexPort. A sync is queued when
+// owner/repo is the Argo apps repository; requests arriving while one is
+// already queued are coalesced into it.
+func (a *Adapter) RequestSync(owner, repo string) bool {
+	appsOwner, appsRepo, err := domain.RepoFromURL(a.repoURL)
+	if err != nil || !strings.EqualFold(owner, appsOwner) || !strings.EqualFold(repo, appsRepo) {
+		return false
+	}
+
+	if a.mirror.Request(gitmirror.TriggerWebhook) {
+		a.logger.Info("argo apps repository sync requested", "source", a.name, "repo", owner+"/"+repo)
+	} else {
+		a.logger.Debug("argo apps repository sync already pending", "repo", owner+"/"+repo)
+	}
+	return true
+}
+
+// Sync implements ports.GitopsIndexPort. It syncs the repository now,
+// waiting for a sync already running to finish first, and returns the
+// index status. Lookups keep being served from the previous index meanwhile.
+// When ctx is done the sync is abandoned and the previous index kept.
+func (a *Adapter) Sync(ctx context.Context) (domain.IndexStatus, error) {
+	err := a.sync(ctx, gitmirror.TriggerAdmin)
+	return a.status(), err
+}
+
+// status returns the index's sync status.
+func (a *Adapter) status() domain.IndexStatus {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+
+	status := domain.IndexStatus{
+		Source:       a.name,
+		Repo:         a.repoURL,
+		Applications: a.appCount,
+		Charts:       len(a.index),
+		LastSync:     a.lastSync,
+	}
+	if a.lastErr != nil {
+		status.LastError = a.lastErr.Error()
+	}
+	return status
+}
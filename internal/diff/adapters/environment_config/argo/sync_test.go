@@ -0,0 +1,118 @@
+package argo
+
+import (
+	"context"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/nathantilsley/chart-val/internal/platform/gitmirror"
+)
+
+// runGit runs git in dir, failing the test on error.
+func runGit(t *testing.T, dir string, args ...string) {
+	t.Helper()
+	cmd := execCommand("git", args...)
+	cmd.Dir = dir
+	if output, err := cmd.CombinedOutput(); err != nil {
+		t.Fatalf("git %v failed: %v\noutput: %s", args, err, output)
+	}
+}
+
+func TestSync(t *testing.T) {
+	t.Parallel()
+
+	// Remote repository with one Application, cloned by New
+	tmpDir := t.TempDir()
+	repoDir := filepath.Join(tmpDir, "test-repo")
+	if err := copyDir(filepath.Join("testdata", "repos", "single-env"), repoDir); err != nil {
+		t.Fatalf("failed to copy testdata: %v", err)
+	}
+	runGit(t, repoDir, "init")
+	runGit(t, repoDir, "config", "user.email", "test@example.com")
+	runGit(t, repoDir, "config", "user.name", "Test User")
+	runGit(t, repoDir, "add", ".")
+	runGit(t, repoDir, "commit", "-m", "Initial commit")
+
+	src := Source{
+		Name:          "payments",
+		Repo:          repoDir,
+		LocalPath:     filepath.Join(tmpDir, "clone"),
+		SyncInterval:  time.Hour,
+		FolderPattern: "{chartName}/{envName}",
+	}
+	adapter, err := New(src, slog.New(slog.NewTextHandler(os.Stderr, nil)), nil, "chart_val")
+	if err != nil {
+		t.Fatalf("New failed: %v", err)
+	}
+	defer adapter.Stop()
+
+	// An Application merged after the initial sync
+	appFile := filepath.Join(repoDir, "other-app", "dev", "app.yaml")
+	if err := os.MkdirAll(filepath.Dir(appFile), 0o755); err != nil {
+		t.Fatalf("failed to create application directory: %v", err)
+	}
+	manifest := "apiVersion: argoproj.io/v1alpha1\nkind: Application\nspec:\n  source:\n" +
+		"    repoURL: https://github.com/example/charts\n    path: charts/other-app\n"
+	if err := os.WriteFile(appFile, []byte(manifest), 0o600); err != nil {
+		t.Fatalf("failed to write application: %v", err)
+	}
+	runGit(t, repoDir, "add", ".")
+	runGit(t, repoDir, "commit", "-m", "Add other-app")
+
+	status, err := adapter.Sync(context.Background())
+	if err != nil {
+		t.Fatalf("Sync failed: %v", err)
+	}
+	if status.Source != "payments" || status.Applications != 2 || status.Charts != 2 ||
+		status.LastError != "" || status.LastSync.IsZero() {
+		t.Errorf("status after sync = %+v, want 2 applications, 2 charts and no error", status)
+	}
+	if _, ok := adapter.index[exampleChart("other-app")]; !ok {
+		t.Errorf("other-app not in the index after sync")
+	}
+
+	// A failed sync keeps serving the last index
+	if err := os.RemoveAll(filepath.Join(repoDir, ".git")); err != nil {
+		t.Fatalf("failed to remove remote: %v", err)
+	}
+	failed, err := adapter.Sync(context.Background())
+	if err == nil {
+		t.Fatal("expected Sync to fail without a remote")
+	}
+	if failed.Applications != 2 || failed.LastError == "" || !failed.LastSync.Equal(status.LastSync) {
+		t.Errorf("status after failed sync = %+v, want the previous index and the error", failed)
+	}
+}
+
+func TestRequestSync(t *testing.T) {
+	t.Parallel()
+
+	newAdapter := func() *Adapter {
+		return &Adapter{
+			repoURL: "https://github.com/example/gitops.git",
+			mirror:  gitmirror.New(gitmirror.Options{}),
+			logger:  slog.New(slog.NewTextHandler(os.Stderr, nil)),
+		}
+	}
+
+	other := newAdapter()
+	if other.RequestSync("example", "charts") {
+		t.Error("RequestSync matched another repository")
+	}
+	if !other.mirror.Request(gitmirror.TriggerAdmin) {
+		t.Fatalf("sync queued for another repository")
+	}
+
+	adapter := newAdapter()
+	for range 3 {
+		if !adapter.RequestSync("Example", "GitOps") {
+			t.Fatal("RequestSync did not match the apps repository")
+		}
+	}
+	if adapter.mirror.Request(gitmirror.TriggerAdmin) {
+		t.Error("RequestSync did not queue a sync")
+	}
+}
@@ -0,0 +1,125 @@
+// Package combined merges several gitops environment config sources (e.g.
+// Argo CD and Flux) into one.
+package combined
+
+import (
+	"context"
+	"log/slog"
+	"reflect"
+	"slices"
+	"sort"
+
+	"github.com/nathantilsley/chart-val/internal/diff/domain"
+	"github.com/nathantilsley/chart-val/internal/diff/ports"
+)
+
+// Source is a named environment config source. The name labels logs and
+// the environments it supplies, and disambiguates environments that several
+// sources define.
+type Source struct {
+	Name   string
+	Config ports.EnvironmentConfigPort
+}
+
+// Adapter implements ports.EnvironmentConfigPort and ports.ValueFileIndexPort
+// by asking every source and merging their answers. A failing source is
+// logged and skipped so the others still apply.
+type Adapter struct {
+	sources []Source
+	logger  *slog.Logger
+}
+
+// New creates an adapter merging the given sources, in order.
+func New(logger *slog.Logger, sources ...Source) *Adapter {
+	return &Adapter{sources: sources, logger: logger}
+}
+
+// GetEnvironmentConfig implements ports.EnvironmentConfigPort. It returns
+// the environments of every source, each labelled with the source that
+// supplied it. An environment name already used by an earlier source is a
+// conflict: with the same settings (e.g. an Application present in two
+// gitops repositories during a migration) the later one is dropped,
+// otherwise it gets the source's name appended, e.g. "prod (flux)".
+func (a *Adapter) GetEnvironmentConfig(
+	ctx context.Context,
+	pr domain.PRContext,
+	chart domain.ChangedChart,
+) (domain.ChartConfig, error) {
+	config := domain.ChartConfig{Path: chart.Path, Environments: []domain.EnvironmentConfig{}}
+	seen := make(map[string]domain.EnvironmentConfig)
+
+	for _, src := range a.sources {
+		cfg, err := src.Config.GetEnvironmentConfig(ctx, pr, chart)
+		if err != nil {
+			a.logger.Warn("environment config source failed, skipping",
+				"source", src.Name, "chart", chart.Path, "error", err)
+			continue
+		}
+		for _, env := range cfg.Environments {
+			if env.Source == "" {
+				env.Source = src.Name
+			}
+			if first, dup := seen[env.Name]; dup {
+				if sameSettings(first, env) {
+					a.logger.Info("environment supplied by several sources, using the first",
+						"chart", chart.Path, "env", env.Name, "source", first.Source, "duplicate", env.Source)
+					continue
+				}
+				a.logger.Warn("conflicting environment from several sources",
+					"chart", chart.Path, "env", env.Name, "source", first.Source, "conflict", env.Source)
+				env.Name += " (" + env.Source + ")"
+			}
+			seen[env.Name] = env
+			config.Environments = append(config.Environments, env)
+		}
+	}
+
+	return config, nil
+}
+
+// sameSettings reports whether two environments render the same way.
+func sameSettings(a, b domain.EnvironmentConfig) bool {
+	a.Source, b.Source = "", ""
+	return reflect.DeepEqual(a, b)
+}
+
+// ChartsUsingFiles implements ports.ValueFileIndexPort by merging the
+// answers of the sources that index value files.
+func (a *Adapter) ChartsUsingFiles(
+	ctx context.Context,
+	pr domain.PRContext,
+	files []string,
+) ([]domain.ChangedChart, error) {
+	byPath := make(map[string]*domain.ChangedChart)
+	for _, src := range a.sources {
+		index, ok := src.Config.(ports.ValueFileIndexPort)
+		if !ok {
+			continue
+		}
+		charts, err := index.ChartsUsingFiles(ctx, pr, files)
+		if err != nil {
+			a.logger.Warn("value file index source failed, skipping", "source", src.Name, "error", err)
+			continue
+		}
+		for _, c := range charts {
+			merged, ok := byPath[c.Path]
+			if !ok {
+				merged = &domain.ChangedChart{Name: c.Name, Path: c.Path}
+				byPath[c.Path] = merged
+			}
+			for _, f := range c.AffectedVia {
+				if !slices.Contains(merged.AffectedVia, f) {
+					merged.AffectedVia = append(merged.AffectedVia, f)
+				}
+			}
+		}
+	}
+
+	charts := make([]domain.ChangedChart, 0, len(byPath))
+	for _, chart := range byPath {
+		sort.Strings(chart.AffectedVia)
+		charts = append(charts, *chart)
+	}
+	sort.Slice(charts, func(i, j int) bool { return charts[i].Path < charts[j].Path })
+	return charts, nil
+}
@@ -0,0 +1,148 @@
+package combined
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"os"
+	"reflect"
+	"testing"
+
+	"github.com/nathantilsley/chart-val/internal/diff/domain"
+)
+
+// staticSource returns fixed environments and charts.
+type staticSource struct {
+	envs   []string
+	values string // Inline values of every environment
+	charts []domain.ChangedChart
+	err    error
+}
+
+func (s staticSource) GetEnvironmentConfig(
+	_ context.Context,
+	_ domain.PRContext,
+	chart domain.ChangedChart,
+) (domain.ChartConfig, error) {
+	config := domain.ChartConfig{Path: chart.Path}
+	for _, name := range s.envs {
+		config.Environments = append(config.Environments, domain.EnvironmentConfig{
+			Name: name,
+			Helm: domain.HelmOptions{Values: s.values},
+		})
+	}
+	return config, s.err
+}
+
+func (s staticSource) ChartsUsingFiles(context.Context, domain.PRContext, []string) ([]domain.ChangedChart, error) {
+	return s.charts, s.err
+}
+
+// envOnlySource does not index value files.
+type envOnlySource struct{}
+
+func (envOnlySource) GetEnvironmentConfig(
+	_ context.Context,
+	_ domain.PRContext,
+	chart domain.ChangedChart,
+) (domain.ChartConfig, error) {
+	return domain.ChartConfig{Path: chart.Path}, nil
+}
+
+func TestGetEnvironmentConfig(t *testing.T) {
+	t.Parallel()
+
+	logger := slog.New(slog.NewTextHandler(os.Stderr, nil))
+	tests := []struct {
+		name    string
+		sources []Source
+		want    []string // name|source
+	}{
+		{
+			name: "environments of every source in order",
+			sources: []Source{
+				{Name: "argo", Config: staticSource{envs: []string{"dev", "prod"}}},
+				{Name: "flux", Config: staticSource{envs: []string{"edge"}}},
+			},
+			want: []string{"dev|argo", "prod|argo", "edge|flux"},
+		},
+		{
+			name: "conflicting environments are qualified with the source",
+			sources: []Source{
+				{Name: "argo", Config: staticSource{envs: []string{"prod"}, values: "replicas: 3"}},
+				{Name: "flux", Config: staticSource{envs: []string{"prod"}, values: "replicas: 2"}},
+			},
+			want: []string{"prod|argo", "prod (flux)|flux"},
+		},
+		{
+			name: "environments with the same settings are merged",
+			sources: []Source{
+				{Name: "payments", Config: staticSource{envs: []string{"prod"}, values: "replicas: 3"}},
+				{Name: "platform", Config: staticSource{envs: []string{"prod", "dev"}, values: "replicas: 3"}},
+			},
+			want: []string{"prod|payments", "dev|platform"},
+		},
+		{
+			name: "failing source is skipped",
+			sources: []Source{
+				{Name: "argo", Config: staticSource{envs: []string{"prod"}, err: errors.New("boom")}},
+				{Name: "flux", Config: staticSource{envs: []string{"edge"}}},
+			},
+			want: []string{"edge|flux"},
+		},
+		{
+			name: "no environments",
+			want: nil,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			config, err := New(logger, tt.sources...).GetEnvironmentConfig(
+				context.Background(), domain.PRContext{}, domain.ChangedChart{Name: "api", Path: "charts/api"},
+			)
+			if err != nil {
+				t.Fatalf("GetEnvironmentConfig failed: %v", err)
+			}
+			if config.Path != "charts/api" {
+				t.Errorf("Path = %q, want charts/api", config.Path)
+			}
+			var got []string
+			for _, env := range config.Environments {
+				got = append(got, env.Name+"|"+env.Source)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("environments = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestChartsUsingFiles(t *testing.T) {
+	t.Parallel()
+
+	adapter := New(slog.New(slog.NewTextHandler(os.Stderr, nil)),
+		Source{Name: "argo", Config: staticSource{charts: []domain.ChangedChart{
+			{Name: "web", Path: "charts/web", AffectedVia: []string{"values/prod.yaml"}},
+			{Name: "api", Path: "charts/api", AffectedVia: []string{"values/prod.yaml"}},
+		}}},
+		Source{Name: "flux", Config: staticSource{charts: []domain.ChangedChart{
+			{Name: "api", Path: "charts/api", AffectedVia: []string{"values/common.yaml", "values/prod.yaml"}},
+		}}},
+		Source{Name: "helmfile", Config: envOnlySource{}},
+	)
+
+	got, err := adapter.ChartsUsingFiles(context.Background(), domain.PRContext{}, []string{"values/prod.yaml"})
+	if err != nil {
+		t.Fatalf("ChartsUsingFiles failed: %v", err)
+	}
+	want := []domain.ChangedChart{
+		{Name: "api", Path: "charts/api", AffectedVia: []string{"values/common.yaml", "values/prod.yaml"}},
+		{Name: "web", Path: "charts/web", AffectedVia: []string{"values/prod.yaml"}},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("ChartsUsingFiles() = %+v, want %+v", got, want)
+	}
+}
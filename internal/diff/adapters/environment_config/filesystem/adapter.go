@@ -17,16 +17,14 @@ import (
 // env/ subdirectory for *-values.yaml files.
 type Adapter struct {
 	sourceControl    ports.SourceControlPort
-	chartDir         string
 	envDir           string
 	valuesFileSuffix string
 }
 
 // New creates a new filesystem environment config adapter.
-func New(sourceControl ports.SourceControlPort, chartDir, envDir, valuesFileSuffix string) *Adapter {
+func New(sourceControl ports.SourceControlPort, envDir, valuesFileSuffix string) *Adapter {
 	return &Adapter{
 		sourceControl:    sourceControl,
-		chartDir:         chartDir,
 		envDir:           envDir,
 		valuesFileSuffix: valuesFileSuffix,
 	}
@@ -37,12 +35,10 @@ func New(sourceControl ports.SourceControlPort, chartDir, envDir, valuesFileSuff
 func (a *Adapter) GetEnvironmentConfig(
 	ctx context.Context,
 	pr domain.PRContext,
-	chartName string,
+	chart domain.ChangedChart,
 ) (domain.ChartConfig, error) {
-	chartPath := a.chartDir + "/" + chartName
-
 	// Fetch chart directory to discover environments
-	chartDir, cleanup, err := a.sourceControl.FetchChartFiles(ctx, pr.Owner, pr.Repo, pr.HeadRef, chartPath)
+	chartDir, cleanup, err := a.sourceControl.FetchChartFiles(ctx, pr.Owner, pr.Repo, pr.HeadRef, chart.Path)
 	if err != nil {
 		return domain.ChartConfig{}, fmt.Errorf("fetching chart files: %w", err)
 	}
@@ -52,7 +48,7 @@ func (a *Adapter) GetEnvironmentConfig(
 	envs := a.discoverEnvironments(chartDir)
 
 	return domain.ChartConfig{
-		Path:         chartPath,
+		Path:         chart.Path,
 		Environments: envs,
 	}, nil
 }
@@ -0,0 +1,564 @@
+// Package flux discovers environment configuration by reading Flux HelmRelease
+// manifests and the sources and ConfigMaps they reference.
+package flux
+
+import (
+	"context"
+	"fmt"
+	"io/fs"
+	"log/slog"
+	"os"
+	"path"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/nathantilsley/chart-val/internal/diff/domain"
+	"github.com/nathantilsley/chart-val/internal/platform/gitmirror"
+)
+
+// Adapter implements ports.EnvironmentConfigPort by reading Flux HelmRelease
+// manifests from a locally cloned gitops repository. Releases are matched to
+// charts by the repository of their GitRepository source and their chart
+// path; the environment is the cluster directory they live in.
+type Adapter struct {
+	repoURL      string        // Git repository URL
+	localPath    string        // Local filesystem path for clone
+	syncInterval time.Duration // How often to sync the repo
+	envPattern   string        // Directory pattern naming the environment (e.g., "clusters/{envName}")
+
+	mu           sync.RWMutex               // Protects index and sync status during swaps
+	index        map[chartKey][]ReleaseData // Cache: chart source -> list of releases
+	releaseCount int                        // HelmReleases in index
+	lastSync     time.Time                  // Last successful sync
+	lastErr      error                      // Error of the last sync, nil when it succeeded
+
+	mirror *gitmirror.Mirror  // Clone of repoURL at localPath; lookups never wait for its syncs
+	stop   context.CancelFunc // Stops background sync and abandons one in progress
+	logger *slog.Logger
+}
+
+// Source is a gitops repository of Flux HelmReleases.
+type Source struct {
+	Repo         string        // Git URL to clone
+	LocalPath    string        // Clone directory
+	EnvPattern   string        // Directory pattern naming the environment (e.g., "clusters/{envName}")
+	SyncInterval time.Duration // Periodic sync
+	Token        string        // HTTPS access token for Repo
+	SSHKeyFile   string        // SSH private key for Repo
+}
+
+// chartKey identifies a chart by its repository ("owner/repo", lower-cased)
+// and its path within that repository.
+type chartKey struct {
+	Repo string
+	Path string
+}
+
+// ReleaseData represents the data we need from a Flux HelmRelease.
+type ReleaseData struct {
+	ChartName   string             // Base name of the chart path (e.g., "my-app")
+	ChartPath   string             // spec.chart.spec.chart (e.g., "charts/my-app")
+	Environment string             // From the directory, e.g. "prod" for clusters/prod/...
+	ValueFiles  []string           // spec.chart.spec.valuesFiles, as repository-root references
+	Helm        domain.HelmOptions // valuesFrom and inline values, release name, namespace and CRD handling
+	RepoURL     string             // The GitRepository's spec.url
+	File        string             // Manifest path within the gitops repo
+}
+
+// New creates a new Flux adapter for a gitops source. It performs an
+// initial clone/sync and starts a background goroutine to keep the
+// repository updated every SyncInterval and whenever a sync is requested.
+func New(src Source, logger *slog.Logger) (*Adapter, error) {
+	if logger == nil {
+		logger = slog.New(slog.NewTextHandler(os.Stderr, nil))
+	}
+
+	a := &Adapter{
+		repoURL:      src.Repo,
+		localPath:    src.LocalPath,
+		syncInterval: src.SyncInterval,
+		envPattern:   src.EnvPattern,
+		index:        make(map[chartKey][]ReleaseData),
+		mirror: gitmirror.New(gitmirror.Options{
+			URL:        src.Repo,
+			Dir:        src.LocalPath,
+			Token:      src.Token,
+			SSHKeyFile: src.SSHKeyFile,
+		}),
+		logger: logger,
+	}
+	ctx, stop := context.WithCancel(context.Background())
+	a.stop = stop
+
+	a.logger.Info("initializing flux repository", "repoURL", src.Repo, "localPath", src.LocalPath)
+	if err := a.mirror.Init(ctx); err != nil {
+		stop()
+		return nil, fmt.Errorf("initializing repo: %w", err)
+	}
+
+	a.logger.Info("scanning repo for flux helm releases")
+	if err := a.rebuildIndex(); err != nil {
+		stop()
+		return nil, fmt.Errorf("building initial index: %w", err)
+	}
+
+	go a.mirror.Run(ctx, a.syncInterval, func(ctx context.Context, trigger string) {
+		_ = a.sync(ctx, trigger) //nolint:errcheck // Logged
+	})
+	a.logger.Info("flux adapter started", "syncInterval", src.SyncInterval)
+
+	return a, nil
+}
+
+// sync pulls the repository and rebuilds the index copy-on-write: the new
+// index is built from the pulled clone while lookups keep reading the old
+// one, and is swapped in under a brief write lock. Waiting for a running
+// sync and the git pull are both abandoned when ctx is done.
+func (a *Adapter) sync(ctx context.Context, trigger string) error {
+	a.logger.Info("syncing flux repository", "trigger", trigger)
+	start := time.Now()
+
+	var index map[chartKey][]ReleaseData
+	var releaseCount int
+	err := a.mirror.Sync(ctx, func() error {
+		var err error
+		index, releaseCount, err = a.buildIndex()
+		return err
+	})
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.lastErr = err
+	if err != nil {
+		a.logger.Error("failed to sync flux repository", "trigger", trigger, "error", err)
+		return err
+	}
+	a.index, a.releaseCount, a.lastSync = index, releaseCount, time.Now()
+
+	a.logger.Info("flux repository synced successfully",
+		"trigger", trigger, "uniqueCharts", len(index), "duration", time.Since(start))
+	return nil
+}
+
+// Stop stops the background sync loop, abandoning a sync in progress.
+func (a *Adapter) Stop() {
+	a.logger.Info("stopping flux sync loop")
+	a.stop()
+}
+
+// scan collects the objects of one pass over the repository. Namespaces and
+// references are resolved once every file has been read.
+type scan struct {
+	releases      []scanned[helmRelease]
+	gitRepos      []scanned[gitRepository]
+	configMaps    []scanned[configMap]
+	dirNamespaces map[string]string // Directory -> namespace set by its kustomization
+}
+
+// scanned is an object with the repository directory of its file.
+type scanned[T any] struct {
+	dir    string
+	file   string
+	object T
+}
+
+// objectKey identifies a namespaced object within an environment. Objects
+// outside every environment directory have an empty env and are shared.
+type objectKey struct {
+	env       string
+	namespace string
+	name      string
+}
+
+// rebuildIndex scans the entire repo for HelmReleases and swaps in the new
+// index.
+func (a *Adapter) rebuildIndex() error {
+	index, releaseCount, err := a.buildIndex()
+	if err != nil {
+		return err
+	}
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.index, a.releaseCount, a.lastSync, a.lastErr = index, releaseCount, time.Now(), nil
+	return nil
+}
+
+// buildIndex scans the entire repo for HelmReleases and returns an index of
+// them by chart source, with the number of releases indexed.
+func (a *Adapter) buildIndex() (map[chartKey][]ReleaseData, int, error) {
+	s := &scan{dirNamespaces: make(map[string]string)}
+
+	err := filepath.WalkDir(a.localPath, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			// Log errors accessing individual files but continue scanning
+			a.logger.Warn("error accessing path, skipping", "path", p, "error", err)
+			return nil
+		}
+		if d.IsDir() {
+			if d.Name() == ".git" {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		ext := filepath.Ext(p)
+		if ext != ".yaml" && ext != ".yml" && !isKustomizeFile(d.Name()) {
+			return nil
+		}
+		if err := a.scanFile(s, p); err != nil {
+			a.logger.Warn("failed to parse file as flux manifests", "path", p, "error", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, 0, err
+	}
+
+	index := make(map[chartKey][]ReleaseData)
+	count := 0
+	for _, r := range a.resolve(s) {
+		owner, repo, err := domain.RepoFromURL(r.RepoURL)
+		if err != nil {
+			a.logger.Warn("skipping helm release with unrecognized source URL", "file", r.File, "error", err)
+			continue
+		}
+		key := chartKey{Repo: strings.ToLower(owner + "/" + repo), Path: r.ChartPath}
+		index[key] = append(index[key], r)
+		count++
+	}
+	disambiguateEnvironments(index)
+
+	a.logger.Info("index rebuilt", "totalReleases", count, "uniqueCharts", len(index))
+	return index, count, nil
+}
+
+// scanFile records the Flux objects, ConfigMaps and kustomize settings of a file.
+func (a *Adapter) scanFile(s *scan, absPath string) error {
+	//nolint:gosec // G304: absPath is from filepath.WalkDir, not user input
+	data, err := os.ReadFile(absPath)
+	if err != nil {
+		return err
+	}
+	rel, err := filepath.Rel(a.localPath, absPath)
+	if err != nil {
+		return err
+	}
+	rel = filepath.ToSlash(rel)
+	dir := path.Dir(rel)
+
+	return decodeDocuments(data, func(h header, node *yaml.Node) error {
+		switch {
+		case isKustomizeFile(path.Base(rel)) && (h.Kind == "" || strings.HasPrefix(h.APIVersion, "kustomize.config.k8s.io/")):
+			var k kustomization
+			if err := node.Decode(&k); err != nil {
+				return err
+			}
+			if k.Namespace != "" {
+				s.dirNamespaces[dir] = k.Namespace
+			}
+			maps, err := k.generatedConfigMaps(filepath.Dir(absPath))
+			if err != nil {
+				return err
+			}
+			for _, cm := range maps {
+				s.configMaps = append(s.configMaps, scanned[configMap]{dir: dir, file: rel, object: cm})
+			}
+		case h.Kind == "HelmRelease" && strings.HasPrefix(h.APIVersion, "helm.toolkit.fluxcd.io/"):
+			var hr helmRelease
+			if err := node.Decode(&hr); err != nil {
+				return err
+			}
+			s.releases = append(s.releases, scanned[helmRelease]{dir: dir, file: rel, object: hr})
+		case h.Kind == "GitRepository" && strings.HasPrefix(h.APIVersion, "source.toolkit.fluxcd.io/"):
+			var repo gitRepository
+			if err := node.Decode(&repo); err != nil {
+				return err
+			}
+			s.gitRepos = append(s.gitRepos, scanned[gitRepository]{dir: dir, file: rel, object: repo})
+		case h.Kind == "ConfigMap" && h.APIVersion == "v1":
+			var cm configMap
+			if err := node.Decode(&cm); err != nil {
+				return err
+			}
+			s.configMaps = append(s.configMaps, scanned[configMap]{dir: dir, file: rel, object: cm})
+		}
+		return nil
+	})
+}
+
+// resolve turns the scanned HelmReleases into releases, resolving their
+// GitRepository sources and valuesFrom ConfigMaps within their environment.
+func (a *Adapter) resolve(s *scan) []ReleaseData {
+	gitRepos := make(map[objectKey]gitRepository)
+	for _, r := range s.gitRepos {
+		gitRepos[a.keyOf(s, r.dir, r.object.Metadata)] = r.object
+	}
+	configMaps := make(map[objectKey]configMap)
+	for _, cm := range s.configMaps {
+		configMaps[a.keyOf(s, cm.dir, cm.object.Metadata)] = cm.object
+	}
+
+	var releases []ReleaseData
+	for _, sr := range s.releases {
+		hr := sr.object
+		env := a.envFromDir(sr.dir)
+		if env == "" {
+			a.logger.Debug("skipping helm release outside environment directories", "file", sr.file)
+			continue
+		}
+		namespace := a.keyOf(s, sr.dir, hr.Metadata).namespace
+
+		ref := hr.Spec.Chart.Spec.SourceRef
+		if ref.Kind != "GitRepository" {
+			// HelmRepository, OCIRepository and Bucket charts cannot match a PR's charts
+			a.logger.Debug("skipping helm release without a git chart source", "file", sr.file, "sourceKind", ref.Kind)
+			continue
+		}
+		refNamespace := ref.Namespace
+		if refNamespace == "" {
+			refNamespace = namespace
+		}
+		repo, ok := lookup(gitRepos, env, refNamespace, ref.Name)
+		if !ok {
+			a.logger.Warn("skipping helm release with unknown GitRepository",
+				"file", sr.file, "gitRepository", refNamespace+"/"+ref.Name)
+			continue
+		}
+
+		values, err := a.releaseValues(hr, configMaps, env, namespace)
+		if err != nil {
+			a.logger.Warn("skipping helm release", "file", sr.file, "error", err)
+			continue
+		}
+
+		chartPath := cleanPath(hr.Spec.Chart.Spec.Chart)
+		targetNamespace := hr.Spec.TargetNamespace
+		if targetNamespace == "" {
+			targetNamespace = namespace
+		}
+		releases = append(releases, ReleaseData{
+			ChartName:   path.Base(chartPath),
+			ChartPath:   chartPath,
+			Environment: env,
+			ValueFiles:  hr.valuesFiles(),
+			Helm: domain.HelmOptions{
+				Values:      values,
+				ReleaseName: hr.releaseName(),
+				Namespace:   targetNamespace,
+				IncludeCRDs: hr.Spec.Install.CRDs != "Skip",
+			},
+			RepoURL: repo.Spec.URL,
+			File:    sr.file,
+		})
+	}
+	return releases
+}
+
+// releaseValues layers the HelmRelease's valuesFrom ConfigMaps and inline
+// values, in Flux's order, into one values document. It returns "" when the
+// release sets no values this way.
+func (a *Adapter) releaseValues(
+	hr helmRelease,
+	configMaps map[objectKey]configMap,
+	env, namespace string,
+) (string, error) {
+	values := make(map[string]any)
+	for _, ref := range hr.Spec.ValuesFrom {
+		if ref.Kind != "ConfigMap" {
+			// Secrets are not committed in plain text, so their values are unknown
+			a.logger.Warn("ignoring valuesFrom that is not a ConfigMap",
+				"helmRelease", hr.Metadata.Name, "kind", ref.Kind, "name", ref.Name)
+			continue
+		}
+		cm, ok := lookup(configMaps, env, namespace, ref.Name)
+		key := ref.ValuesKey
+		if key == "" {
+			key = "values.yaml"
+		}
+		content, hasKey := cm.Data[key]
+		if !ok || !hasKey {
+			if ref.Optional {
+				continue
+			}
+			return "", fmt.Errorf("valuesFrom ConfigMap %s/%s key %q not found in the repository", namespace, ref.Name, key)
+		}
+
+		if ref.TargetPath != "" {
+			setValue(values, ref.TargetPath, content)
+			continue
+		}
+		var doc map[string]any
+		if err := yaml.Unmarshal([]byte(content), &doc); err != nil {
+			return "", fmt.Errorf("parsing valuesFrom ConfigMap %s key %q: %w", ref.Name, key, err)
+		}
+		values = mergeValues(values, doc)
+	}
+	values = mergeValues(values, hr.Spec.Values)
+
+	if len(values) == 0 {
+		return "", nil
+	}
+	data, err := yaml.Marshal(values)
+	if err != nil {
+		return "", fmt.Errorf("encoding values: %w", err)
+	}
+	return string(data), nil
+}
+
+// keyOf returns the key of an object found in dir. Objects without a
+// namespace take the one of the nearest kustomization above them.
+func (a *Adapter) keyOf(s *scan, dir string, meta objectMeta) objectKey {
+	namespace := meta.Namespace
+	for d := dir; namespace == ""; d = path.Dir(d) {
+		namespace = s.dirNamespaces[d]
+		if d == "." || d == "/" {
+			break
+		}
+	}
+	return objectKey{env: a.envFromDir(dir), namespace: namespace, name: meta.Name}
+}
+
+// lookup finds a referenced object, preferring one in the same environment
+// and namespace over unnamespaced and shared ones.
+func lookup[T any](objects map[objectKey]T, env, namespace, name string) (T, bool) {
+	for _, key := range []objectKey{
+		{env, namespace, name},
+		{env, "", name},
+		{"", namespace, name},
+		{"", "", name},
+	} {
+		if obj, ok := objects[key]; ok {
+			return obj, true
+		}
+	}
+	var zero T
+	return zero, false
+}
+
+// envFromDir extracts the environment from a repository directory using the
+// configured pattern, matched from the repository root. Pattern segments are
+// literal directory names, "*", or "{envName}". Returns "" when dir is not
+// inside an environment directory.
+// Example: pattern="clusters/{envName}", dir="clusters/prod/apps/my-app" → "prod"
+func (a *Adapter) envFromDir(dir string) string {
+	parts := strings.Split(dir, "/")
+	patternParts := strings.Split(a.envPattern, "/")
+	if len(parts) < len(patternParts) {
+		return ""
+	}
+
+	env := ""
+	for i, p := range patternParts {
+		switch p {
+		case "{envName}":
+			env = parts[i]
+		case "*":
+		default:
+			if p != parts[i] {
+				return ""
+			}
+		}
+	}
+	return env
+}
+
+// disambiguateEnvironments qualifies the environment of releases that share
+// a chart and environment with their release name, e.g. "prod/api-canary".
+func disambiguateEnvironments(index map[chartKey][]ReleaseData) {
+	for _, releases := range index {
+		count := make(map[string]int)
+		for _, r := range releases {
+			count[r.Environment]++
+		}
+		for i, r := range releases {
+			if count[r.Environment] > 1 {
+				releases[i].Environment = r.Environment + "/" + r.Helm.ReleaseName
+			}
+		}
+	}
+}
+
+// GetEnvironmentConfig implements ports.EnvironmentConfigPort.
+// It returns one environment per HelmRelease whose chart source is the given
+// chart in the PR's repository. If there is none, returns empty environments
+// (fallback will be used).
+func (a *Adapter) GetEnvironmentConfig(
+	_ context.Context,
+	pr domain.PRContext,
+	chart domain.ChangedChart,
+) (domain.ChartConfig, error) {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+
+	config := domain.ChartConfig{
+		Path:         chart.Path,
+		Environments: []domain.EnvironmentConfig{},
+	}
+
+	releases := a.index[chartKey{Repo: strings.ToLower(pr.Owner + "/" + pr.Repo), Path: cleanPath(chart.Path)}]
+	for _, r := range releases {
+		config.Environments = append(config.Environments, domain.EnvironmentConfig{
+			Name:       r.Environment,
+			ValueFiles: r.ValueFiles,
+			Helm:       r.Helm,
+		})
+	}
+
+	a.logger.Info("flux helm releases for chart", "chartPath", chart.Path, "envCount", len(config.Environments))
+	return config, nil
+}
+
+// ChartsUsingFiles implements ports.ValueFileIndexPort. It returns the charts
+// of the PR's repository whose HelmReleases list one of the given files in
+// valuesFiles.
+func (a *Adapter) ChartsUsingFiles(
+	_ context.Context,
+	pr domain.PRContext,
+	files []string,
+) ([]domain.ChangedChart, error) {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+
+	changed := make(map[string]bool, len(files))
+	for _, f := range files {
+		changed[f] = true
+	}
+
+	repo := strings.ToLower(pr.Owner + "/" + pr.Repo)
+	byPath := make(map[string]*domain.ChangedChart)
+	for key, releases := range a.index {
+		if key.Repo != repo {
+			continue
+		}
+		for _, r := range releases {
+			for _, vf := range r.ValueFiles {
+				file := strings.TrimPrefix(vf, "/")
+				if !changed[file] {
+					continue
+				}
+				chart, ok := byPath[r.ChartPath]
+				if !ok {
+					chart = &domain.ChangedChart{Name: r.ChartName, Path: r.ChartPath}
+					byPath[r.ChartPath] = chart
+				}
+				if !slices.Contains(chart.AffectedVia, file) {
+					chart.AffectedVia = append(chart.AffectedVia, file)
+				}
+			}
+		}
+	}
+
+	charts := make([]domain.ChangedChart, 0, len(byPath))
+	for _, chart := range byPath {
+		sort.Strings(chart.AffectedVia)
+		charts = append(charts, *chart)
+	}
+	sort.Slice(charts, func(i, j int) bool { return charts[i].Path < charts[j].Path })
+	return charts, nil
+}
@@ -0,0 +1,301 @@
+package flux
+
+import (
+	"context"
+	"log/slog"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/nathantilsley/chart-val/internal/diff/domain"
+	"github.com/nathantilsley/chart-val/internal/platform/gitmirror"
+)
+
+// testAdapter returns an adapter indexed from testdata/repo.
+func testAdapter(t *testing.T) *Adapter {
+	t.Helper()
+	a := &Adapter{
+		localPath:  filepath.Join("testdata", "repo"),
+		envPattern: "clusters/{envName}",
+		index:      make(map[chartKey][]ReleaseData),
+		logger:     slog.New(slog.NewTextHandler(os.Stderr, nil)),
+	}
+	if err := a.rebuildIndex(); err != nil {
+		t.Fatalf("rebuildIndex failed: %v", err)
+	}
+	return a
+}
+
+func TestGetEnvironmentConfig(t *testing.T) {
+	t.Parallel()
+
+	adapter := testAdapter(t)
+
+	tests := []struct {
+		name  string
+		pr    domain.PRContext
+		chart string
+		want  []domain.EnvironmentConfig
+	}{
+		{
+			name:  "releases per cluster directory",
+			pr:    domain.PRContext{Owner: "example", Repo: "charts"},
+			chart: "charts/api",
+			want: []domain.EnvironmentConfig{
+				{
+					Name:       "dev",
+					ValueFiles: []string{"/charts/api/values.yaml", "/charts/api/values-dev.yaml"},
+					Helm: domain.HelmOptions{
+						// Generated ConfigMap, then inline values
+						Values:      "image:\n    tag: dev\nreplicas: 1\n",
+						ReleaseName: "api-api",
+						Namespace:   "api",
+						IncludeCRDs: true,
+					},
+				},
+				{
+					Name:       "prod",
+					ValueFiles: []string{},
+					Helm: domain.HelmOptions{
+						Values:      "image:\n    tag: v1.4.2\nreplicas: 5\nresources:\n    limits:\n        cpu: \"2\"\n",
+						ReleaseName: "api",
+						Namespace:   "apps",
+					},
+				},
+			},
+		},
+		{
+			name:  "same chart path in another repository",
+			pr:    domain.PRContext{Owner: "example", Repo: "services"},
+			chart: "charts/api",
+			want: []domain.EnvironmentConfig{
+				{
+					Name:       "prod",
+					ValueFiles: []string{},
+					Helm:       domain.HelmOptions{ReleaseName: "services-api", Namespace: "apps", IncludeCRDs: true},
+				},
+			},
+		},
+		{
+			name:  "release with a missing ConfigMap is skipped",
+			pr:    domain.PRContext{Owner: "example", Repo: "charts"},
+			chart: "charts/web",
+			want:  []domain.EnvironmentConfig{},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			config, err := adapter.GetEnvironmentConfig(context.Background(), tt.pr,
+				domain.ChangedChart{Name: filepath.Base(tt.chart), Path: tt.chart})
+			if err != nil {
+				t.Fatalf("GetEnvironmentConfig failed: %v", err)
+			}
+			if config.Path != tt.chart {
+				t.Errorf("Path = %q, want %q", config.Path, tt.chart)
+			}
+			if !reflect.DeepEqual(config.Environments, tt.want) {
+				t.Errorf("Environments = %+v\nwant %+v", config.Environments, tt.want)
+			}
+		})
+	}
+}
+
+func TestRebuildIndex(t *testing.T) {
+	t.Parallel()
+
+	adapter := testAdapter(t)
+
+	// base/ is outside the cluster directories and redis comes from a Helm repository
+	want := map[chartKey]int{
+		{Repo: "example/charts", Path: "charts/api"}:   2,
+		{Repo: "example/services", Path: "charts/api"}: 1,
+	}
+	got := make(map[chartKey]int)
+	for key, releases := range adapter.index {
+		got[key] = len(releases)
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("index = %v, want %v", got, want)
+	}
+}
+
+func TestChartsUsingFiles(t *testing.T) {
+	t.Parallel()
+
+	adapter := testAdapter(t)
+	pr := domain.PRContext{Owner: "example", Repo: "charts"}
+
+	got, err := adapter.ChartsUsingFiles(context.Background(), pr, []string{"charts/api/values-dev.yaml", "README.md"})
+	if err != nil {
+		t.Fatalf("ChartsUsingFiles failed: %v", err)
+	}
+	want := []domain.ChangedChart{{Name: "api", Path: "charts/api", AffectedVia: []string{"charts/api/values-dev.yaml"}}}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("ChartsUsingFiles() = %+v, want %+v", got, want)
+	}
+
+	got, err = adapter.ChartsUsingFiles(context.Background(), domain.PRContext{Owner: "example", Repo: "services"},
+		[]string{"charts/api/values-dev.yaml"})
+	if err != nil {
+		t.Fatalf("ChartsUsingFiles failed: %v", err)
+	}
+	if len(got) != 0 {
+		t.Errorf("ChartsUsingFiles() for another repository = %+v, want none", got)
+	}
+}
+
+func TestEnvFromDir(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		pattern string
+		dir     string
+		want    string
+	}{
+		{pattern: "clusters/{envName}", dir: "clusters/prod/apps/api", want: "prod"},
+		{pattern: "clusters/{envName}", dir: "clusters/prod", want: "prod"},
+		{pattern: "clusters/{envName}", dir: "clusters", want: ""},
+		{pattern: "clusters/{envName}", dir: "base/api", want: ""},
+		{pattern: "*/{envName}/apps", dir: "eu/prod/apps/api", want: "prod"},
+		{pattern: "*/{envName}/apps", dir: "eu/prod/infra", want: ""},
+		{pattern: "{envName}", dir: "staging/api", want: "staging"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.pattern+" "+tt.dir, func(t *testing.T) {
+			t.Parallel()
+
+			a := &Adapter{envPattern: tt.pattern}
+			if got := a.envFromDir(tt.dir); got != tt.want {
+				t.Errorf("envFromDir(%q) = %q, want %q", tt.dir, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDisambiguateEnvironments(t *testing.T) {
+	t.Parallel()
+
+	key := chartKey{Repo: "example/charts", Path: "charts/api"}
+	index := map[chartKey][]ReleaseData{
+		key: {
+			{Environment: "prod", Helm: domain.HelmOptions{ReleaseName: "api"}},
+			{Environment: "prod", Helm: domain.HelmOptions{ReleaseName: "api-canary"}},
+			{Environment: "dev", Helm: domain.HelmOptions{ReleaseName: "api"}},
+		},
+	}
+	disambiguateEnvironments(index)
+
+	var got []string
+	for _, r := range index[key] {
+		got = append(got, r.Environment)
+	}
+	if want := []string{"prod/api", "prod/api-canary", "dev"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("environments = %v, want %v", got, want)
+	}
+}
+
+// runGit runs git in dir, failing the test on error.
+func runGit(t *testing.T, dir string, args ...string) {
+	t.Helper()
+	cmd := exec.Command("git", args...)
+	cmd.Dir = dir
+	if output, err := cmd.CombinedOutput(); err != nil {
+		t.Fatalf("git %v failed: %v\noutput: %s", args, err, output)
+	}
+}
+
+func TestSync(t *testing.T) {
+	t.Parallel()
+
+	// Remote repository cloned by New
+	tmpDir := t.TempDir()
+	repoDir := filepath.Join(tmpDir, "fleet")
+	if err := os.CopyFS(repoDir, os.DirFS(filepath.Join("testdata", "repo"))); err != nil {
+		t.Fatalf("failed to copy testdata: %v", err)
+	}
+	runGit(t, repoDir, "init")
+	runGit(t, repoDir, "config", "user.email", "test@example.com")
+	runGit(t, repoDir, "config", "user.name", "Test User")
+	runGit(t, repoDir, "add", ".")
+	runGit(t, repoDir, "commit", "-m", "Initial commit")
+
+	adapter, err := New(Source{
+		Repo:         repoDir,
+		LocalPath:    filepath.Join(tmpDir, "clone"),
+		EnvPattern:   "clusters/{envName}",
+		SyncInterval: time.Hour,
+	}, slog.New(slog.NewTextHandler(os.Stderr, nil)))
+	if err != nil {
+		t.Fatalf("New failed: %v", err)
+	}
+	defer adapter.Stop()
+
+	services := chartKey{Repo: "example/services", Path: "charts/api"}
+	if _, ok := adapter.index[services]; !ok {
+		t.Fatal("services release not indexed after the initial clone")
+	}
+
+	// A release removed after the initial sync
+	runGit(t, repoDir, "rm", "-q", "clusters/prod/apps/others.yaml")
+	runGit(t, repoDir, "commit", "-m", "Remove services-api")
+
+	status, err := adapter.Sync(context.Background())
+	if err != nil {
+		t.Fatalf("Sync failed: %v", err)
+	}
+	if status.Source != "flux" || status.Repo != repoDir || status.Applications != 2 || status.Charts != 1 ||
+		status.LastError != "" || status.LastSync.IsZero() {
+		t.Errorf("status after sync = %+v, want 2 releases, 1 chart and no error", status)
+	}
+	if _, ok := adapter.index[services]; ok {
+		t.Error("removed release still indexed after sync")
+	}
+
+	// A cancelled sync keeps serving the last index
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	failed, err := adapter.Sync(ctx)
+	if err == nil {
+		t.Fatal("expected a cancelled sync to fail")
+	}
+	if failed.Charts != 1 || failed.LastError == "" || !failed.LastSync.Equal(status.LastSync) {
+		t.Errorf("status after cancelled sync = %+v, want the previous index and the error", failed)
+	}
+}
+
+func TestRequestSync(t *testing.T) {
+	t.Parallel()
+
+	newAdapter := func() *Adapter {
+		return &Adapter{
+			repoURL: "git@github.com:example/fleet.git",
+			mirror:  gitmirror.New(gitmirror.Options{}),
+			logger:  slog.New(slog.NewTextHandler(os.Stderr, nil)),
+		}
+	}
+
+	other := newAdapter()
+	if other.RequestSync("example", "gitops") {
+		t.Error("RequestSync matched another repository")
+	}
+	if !other.mirror.Request(gitmirror.TriggerAdmin) {
+		t.Fatal("sync queued for another repository")
+	}
+
+	adapter := newAdapter()
+	for range 3 {
+		if !adapter.RequestSync("Example", "Fleet") {
+			t.Fatal("RequestSync did not match the flux repository")
+		}
+	}
+	if adapter.mirror.Request(gitmirror.TriggerAdmin) {
+		t.Error("RequestSync did not queue a sync")
+	}
+}
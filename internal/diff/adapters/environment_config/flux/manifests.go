@@ -0,0 +1,210 @@
+package flux
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// objectMeta is the metadata chart-val reads from every object.
+type objectMeta struct {
+	Name      string `yaml:"name"`
+	Namespace string `yaml:"namespace"`
+}
+
+// header identifies a YAML document.
+type header struct {
+	APIVersion string     `yaml:"apiVersion"`
+	Kind       string     `yaml:"kind"`
+	Metadata   objectMeta `yaml:"metadata"`
+}
+
+// helmRelease is the subset of a Flux HelmRelease that chart-val reads.
+type helmRelease struct {
+	Metadata objectMeta `yaml:"metadata"`
+	Spec     struct {
+		ReleaseName     string `yaml:"releaseName"`
+		TargetNamespace string `yaml:"targetNamespace"`
+		Chart           struct {
+			Spec struct {
+				Chart     string `yaml:"chart"`
+				SourceRef struct {
+					Kind      string `yaml:"kind"`
+					Name      string `yaml:"name"`
+					Namespace string `yaml:"namespace"`
+				} `yaml:"sourceRef"`
+				ValuesFiles []string `yaml:"valuesFiles"`
+				ValuesFile  string   `yaml:"valuesFile"` // Deprecated single-file form
+			} `yaml:"spec"`
+		} `yaml:"chart"`
+		Values     map[string]any `yaml:"values"`
+		ValuesFrom []valuesRef    `yaml:"valuesFrom"`
+		Install    struct {
+			CRDs string `yaml:"crds"`
+		} `yaml:"install"`
+	} `yaml:"spec"`
+}
+
+// valuesRef is one entry of a HelmRelease's spec.valuesFrom.
+type valuesRef struct {
+	Kind       string `yaml:"kind"`
+	Name       string `yaml:"name"`
+	ValuesKey  string `yaml:"valuesKey"`
+	TargetPath string `yaml:"targetPath"`
+	Optional   bool   `yaml:"optional"`
+}
+
+// gitRepository is the subset of a Flux GitRepository that chart-val reads.
+type gitRepository struct {
+	Metadata objectMeta `yaml:"metadata"`
+	Spec     struct {
+		URL string `yaml:"url"`
+	} `yaml:"spec"`
+}
+
+// configMap is a ConfigMap manifest.
+type configMap struct {
+	Metadata objectMeta        `yaml:"metadata"`
+	Data     map[string]string `yaml:"data"`
+}
+
+// kustomization is the subset of a kustomize kustomization.yaml that
+// chart-val reads: the namespace it applies and its ConfigMap generators.
+type kustomization struct {
+	Namespace          string `yaml:"namespace"`
+	ConfigMapGenerator []struct {
+		Name      string   `yaml:"name"`
+		Namespace string   `yaml:"namespace"`
+		Files     []string `yaml:"files"`
+		Literals  []string `yaml:"literals"`
+	} `yaml:"configMapGenerator"`
+}
+
+// isKustomizeFile reports whether a file is a kustomize kustomization.
+func isKustomizeFile(name string) bool {
+	return name == "kustomization.yaml" || name == "kustomization.yml" || name == "Kustomization"
+}
+
+// decodeDocuments calls fn for every document of a multi-document YAML file.
+func decodeDocuments(data []byte, fn func(h header, node *yaml.Node) error) error {
+	dec := yaml.NewDecoder(bytes.NewReader(data))
+	for {
+		var node yaml.Node
+		err := dec.Decode(&node)
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		var h header
+		if err := node.Decode(&h); err != nil {
+			return err
+		}
+		if err := fn(h, &node); err != nil {
+			return err
+		}
+	}
+}
+
+// generatedConfigMaps returns the ConfigMaps a kustomization generates.
+// File sources are read relative to the kustomization's directory, absDir.
+func (k kustomization) generatedConfigMaps(absDir string) ([]configMap, error) {
+	var maps []configMap
+	for _, gen := range k.ConfigMapGenerator {
+		cm := configMap{Data: make(map[string]string)}
+		cm.Metadata.Name = gen.Name
+		cm.Metadata.Namespace = gen.Namespace
+		if cm.Metadata.Namespace == "" {
+			cm.Metadata.Namespace = k.Namespace
+		}
+		for _, file := range gen.Files {
+			key, rel, ok := strings.Cut(file, "=")
+			if !ok {
+				key, rel = path.Base(file), file
+			}
+			if !filepath.IsLocal(filepath.FromSlash(rel)) {
+				return nil, fmt.Errorf("configMapGenerator %s: file %q is outside the kustomization", gen.Name, rel)
+			}
+			//nolint:gosec // G304: rel is checked to stay inside the cloned repo
+			data, err := os.ReadFile(filepath.Join(absDir, filepath.FromSlash(rel)))
+			if err != nil {
+				return nil, fmt.Errorf("configMapGenerator %s: %w", gen.Name, err)
+			}
+			cm.Data[key] = string(data)
+		}
+		for _, literal := range gen.Literals {
+			key, value, _ := strings.Cut(literal, "=")
+			cm.Data[key] = value
+		}
+		maps = append(maps, cm)
+	}
+	return maps, nil
+}
+
+// releaseName returns the Helm release name Flux uses for a HelmRelease:
+// spec.releaseName, or "[targetNamespace-]name".
+func (hr helmRelease) releaseName() string {
+	if hr.Spec.ReleaseName != "" {
+		return hr.Spec.ReleaseName
+	}
+	if hr.Spec.TargetNamespace != "" {
+		return hr.Spec.TargetNamespace + "-" + hr.Metadata.Name
+	}
+	return hr.Metadata.Name
+}
+
+// valuesFiles returns the HelmRelease's valuesFiles as repository-root
+// value file references; Flux resolves them against the source's root.
+func (hr helmRelease) valuesFiles() []string {
+	files := hr.Spec.Chart.Spec.ValuesFiles
+	if len(files) == 0 && hr.Spec.Chart.Spec.ValuesFile != "" {
+		files = []string{hr.Spec.Chart.Spec.ValuesFile}
+	}
+	refs := make([]string, 0, len(files))
+	for _, f := range files {
+		refs = append(refs, "/"+cleanPath(f))
+	}
+	return refs
+}
+
+// cleanPath normalizes a source-relative path such as "./charts/api".
+func cleanPath(p string) string {
+	return path.Clean(strings.Trim(p, "/"))
+}
+
+// mergeValues deep-merges src into dst, as Helm merges values.
+func mergeValues(dst, src map[string]any) map[string]any {
+	for k, v := range src {
+		if sm, ok := v.(map[string]any); ok {
+			if dm, ok := dst[k].(map[string]any); ok {
+				dst[k] = mergeValues(dm, sm)
+				continue
+			}
+		}
+		dst[k] = v
+	}
+	return dst
+}
+
+// setValue sets a dotted targetPath in values, as valuesFrom.targetPath does.
+func setValue(values map[string]any, targetPath string, value any) {
+	parts := strings.Split(targetPath, ".")
+	cur := values
+	for _, part := range parts[:len(parts)-1] {
+		next, ok := cur[part].(map[string]any)
+		if !ok {
+			next = make(map[string]any)
+			cur[part] = next
+		}
+		cur = next
+	}
+	cur[parts[len(parts)-1]] = value
+}
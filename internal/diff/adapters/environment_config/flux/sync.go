@@ -0,0 +1,56 @@
+package flux
+
+import (
+	"context"
+	"strings"
+
+	"github.com/nathantilsley/chart-val/internal/diff/domain"
+	"github.com/nathantilsley/chart-val/internal/platform/gitmirror"
+)
+
+// sourceName names the Flux index in sync status and among gitops sources.
+const sourceName = "flux"
+
+// RequestSync implements ports.GitopsIndexPort. A sync is queued when
+// owner/repo is the Flux repository; requests arriving while one is
+// already queued are coalesced into it.
+func (a *Adapter) RequestSync(owner, repo string) bool {
+	fluxOwner, fluxRepo, err := domain.RepoFromURL(a.repoURL)
+	if err != nil || !strings.EqualFold(owner, fluxOwner) || !strings.EqualFold(repo, fluxRepo) {
+		return false
+	}
+
+	if a.mirror.Request(gitmirror.TriggerWebhook) {
+		a.logger.Info("flux repository sync requested", "repo", owner+"/"+repo)
+	} else {
+		a.logger.Debug("flux repository sync already pending", "repo", owner+"/"+repo)
+	}
+	return true
+}
+
+// Sync implements ports.GitopsIndexPort. It syncs the repository now,
+// waiting for a sync already running to finish first, and returns the
+// index status. Lookups keep being served from the previous index meanwhile.
+// When ctx is done the sync is abandoned and the previous index kept.
+func (a *Adapter) Sync(ctx context.Context) (domain.IndexStatus, error) {
+	err := a.sync(ctx, gitmirror.TriggerAdmin)
+	return a.status(), err
+}
+
+// status returns the index's sync status.
+func (a *Adapter) status() domain.IndexStatus {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+
+	status := domain.IndexStatus{
+		Source:       sourceName,
+		Repo:         a.repoURL,
+		Applications: a.releaseCount,
+		Charts:       len(a.index),
+		LastSync:     a.lastSync,
+	}
+	if a.lastErr != nil {
+		status.LastError = a.lastErr.Error()
+	}
+	return status
+}
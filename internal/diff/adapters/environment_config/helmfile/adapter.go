@@ -0,0 +1,283 @@
+// Package helmfile discovers environment configuration from helmfile state
+// files in the PR's repository, mapping each release and environment onto
+// an environment to diff.
+package helmfile
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"log/slog"
+	"path"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/nathantilsley/chart-val/internal/diff/domain"
+	"github.com/nathantilsley/chart-val/internal/diff/ports"
+)
+
+// ReleaseData is one release of a local chart in one helmfile environment.
+type ReleaseData struct {
+	ChartName   string
+	ChartPath   string // Repository path of the chart
+	Environment string
+	ValueFiles  []string // Repository-root value file references
+	Helm        domain.HelmOptions
+	Inputs      []string // Repository files the release's settings were read from
+}
+
+// headState is the releases read from one PR head.
+type headState struct {
+	sha      string
+	releases []ReleaseData
+}
+
+// Adapter implements ports.EnvironmentConfigPort and ports.ValueFileIndexPort
+// by reading helmfile state files from the PR head. Results are cached per
+// pull request until its head commit changes.
+type Adapter struct {
+	sourceControl ports.SourceControlPort
+	paths         []string // Repository paths of the state files
+	logger        *slog.Logger
+
+	mu    sync.Mutex
+	heads map[string]headState // PR URL -> releases at its head
+}
+
+// New creates a helmfile environment config adapter reading the state files
+// at paths (e.g. "helmfile.yaml", "deploy/helmfile.yaml.gotmpl").
+func New(sourceControl ports.SourceControlPort, paths []string, logger *slog.Logger) *Adapter {
+	return &Adapter{
+		sourceControl: sourceControl,
+		paths:         paths,
+		logger:        logger,
+		heads:         make(map[string]headState),
+	}
+}
+
+// GetEnvironmentConfig implements ports.EnvironmentConfigPort.
+// It returns one environment per release of the chart in each helmfile
+// environment.
+func (a *Adapter) GetEnvironmentConfig(
+	ctx context.Context,
+	pr domain.PRContext,
+	chart domain.ChangedChart,
+) (domain.ChartConfig, error) {
+	releases, err := a.releases(ctx, pr)
+	if err != nil {
+		return domain.ChartConfig{}, err
+	}
+
+	envs := make([]domain.EnvironmentConfig, 0)
+	for _, r := range releases {
+		if r.ChartPath != chart.Path {
+			continue
+		}
+		envs = append(envs, domain.EnvironmentConfig{
+			Name:       r.Environment,
+			ValueFiles: r.ValueFiles,
+			Helm:       r.Helm,
+		})
+	}
+	return domain.ChartConfig{Path: chart.Path, Environments: envs}, nil
+}
+
+// ChartsUsingFiles implements ports.ValueFileIndexPort. A chart is affected
+// by its releases' value files and by the state files and environment values
+// the releases were rendered from.
+func (a *Adapter) ChartsUsingFiles(
+	ctx context.Context,
+	pr domain.PRContext,
+	files []string,
+) ([]domain.ChangedChart, error) {
+	releases, err := a.releases(ctx, pr)
+	if err != nil {
+		return nil, err
+	}
+
+	changed := make(map[string]bool, len(files))
+	for _, f := range files {
+		changed[f] = true
+	}
+
+	byPath := make(map[string]*domain.ChangedChart)
+	for _, r := range releases {
+		for _, file := range r.Inputs {
+			if !changed[file] {
+				continue
+			}
+			chart, ok := byPath[r.ChartPath]
+			if !ok {
+				chart = &domain.ChangedChart{Name: r.ChartName, Path: r.ChartPath}
+				byPath[r.ChartPath] = chart
+			}
+			if !slices.Contains(chart.AffectedVia, file) {
+				chart.AffectedVia = append(chart.AffectedVia, file)
+			}
+		}
+	}
+
+	charts := make([]domain.ChangedChart, 0, len(byPath))
+	for _, chart := range byPath {
+		sort.Strings(chart.AffectedVia)
+		charts = append(charts, *chart)
+	}
+	sort.Slice(charts, func(i, j int) bool { return charts[i].Path < charts[j].Path })
+	return charts, nil
+}
+
+// releases returns the releases of every state file at the PR head.
+func (a *Adapter) releases(ctx context.Context, pr domain.PRContext) ([]ReleaseData, error) {
+	key := pr.URL()
+	a.mu.Lock()
+	head, ok := a.heads[key]
+	a.mu.Unlock()
+	if ok && pr.HeadSHA != "" && head.sha == pr.HeadSHA {
+		return head.releases, nil
+	}
+
+	var releases []ReleaseData
+	for _, p := range a.paths {
+		found, err := a.loadStateFile(ctx, pr, p)
+		if err != nil {
+			return nil, fmt.Errorf("reading helmfile %s: %w", p, err)
+		}
+		releases = append(releases, found...)
+	}
+	disambiguateEnvironments(releases)
+
+	a.mu.Lock()
+	a.heads[key] = headState{sha: pr.HeadSHA, releases: releases}
+	a.mu.Unlock()
+	return releases, nil
+}
+
+// loadStateFile fetches a state file's directory at the PR head and returns
+// its releases of local charts in every environment. A state file missing
+// from the repository has no releases.
+func (a *Adapter) loadStateFile(ctx context.Context, pr domain.PRContext, repoPath string) ([]ReleaseData, error) {
+	dir, cleanup, err := a.sourceControl.FetchChartFiles(ctx, pr.Owner, pr.Repo, pr.HeadRef, path.Dir(repoPath))
+	if domain.IsNotFound(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("fetching files: %w", err)
+	}
+	defer cleanup()
+
+	f, err := newStateFile(repoPath, dir)
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			a.logger.Debug("helmfile not found", "path", repoPath, "ref", pr.HeadRef)
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	var releases []ReleaseData
+	for _, env := range f.environments() {
+		found, err := f.releaseData(env)
+		if err != nil {
+			return nil, fmt.Errorf("environment %s: %w", env, err)
+		}
+		releases = append(releases, found...)
+	}
+	return releases, nil
+}
+
+// releaseData renders the state file for env and maps its enabled releases
+// of local charts onto ReleaseData.
+func (f *stateFile) releaseData(env string) ([]ReleaseData, error) {
+	values, releases, err := f.render(env)
+	if err != nil {
+		return nil, err
+	}
+
+	var data []ReleaseData
+	for _, r := range releases {
+		chartPath, ok := r.localChart(f.dir)
+		if !ok || !r.enabled(values) {
+			continue
+		}
+
+		rd := ReleaseData{
+			ChartName:   path.Base(chartPath),
+			ChartPath:   chartPath,
+			Environment: env,
+			ValueFiles:  []string{},
+			Helm: domain.HelmOptions{
+				ReleaseName: r.Name,
+				Namespace:   r.Namespace,
+				IncludeCRDs: true, // helmfile installs CRDs unless --skip-crds
+			},
+			Inputs: slices.Clone(f.inputs),
+		}
+
+		// Plain files are passed to Helm as-is; templates and inline maps are
+		// merged into inline values, which Helm layers after the files.
+		inline := make(map[string]any)
+		for _, entry := range r.Values {
+			switch e := entry.(type) {
+			case map[string]any:
+				mergeValues(inline, e)
+			case string:
+				repoPath := path.Join(f.dir, e)
+				if repoPath == ".." || strings.HasPrefix(repoPath, "../") {
+					return nil, fmt.Errorf("release %s: values file %s is outside the repository", r.Name, e)
+				}
+				rd.Inputs = append(rd.Inputs, repoPath)
+				if !strings.HasSuffix(e, ".gotmpl") {
+					rd.ValueFiles = append(rd.ValueFiles, "/"+repoPath)
+					continue
+				}
+				content, err := f.readValuesFile(e, templateData(env, values, &r))
+				if err != nil {
+					return nil, fmt.Errorf("release %s: %w", r.Name, err)
+				}
+				var doc map[string]any
+				if err := yaml.Unmarshal([]byte(content), &doc); err != nil {
+					return nil, fmt.Errorf("release %s: parsing values file %s: %w", r.Name, e, err)
+				}
+				mergeValues(inline, doc)
+			default:
+				return nil, fmt.Errorf("release %s: unsupported values entry %v", r.Name, entry)
+			}
+		}
+		if len(inline) > 0 {
+			out, err := yaml.Marshal(inline)
+			if err != nil {
+				return nil, fmt.Errorf("release %s: encoding values: %w", r.Name, err)
+			}
+			rd.Helm.Values = string(out)
+		}
+
+		for _, s := range r.Set {
+			rd.Helm.Parameters = append(rd.Helm.Parameters, domain.HelmParameter{
+				Name:  s.Name,
+				Value: fmt.Sprint(s.Value),
+			})
+		}
+		data = append(data, rd)
+	}
+	return data, nil
+}
+
+// disambiguateEnvironments renames environments deploying the same chart
+// more than once to "env/release", so each release gets its own diff.
+func disambiguateEnvironments(releases []ReleaseData) {
+	type key struct{ chart, env string }
+	counts := make(map[key]int)
+	for _, r := range releases {
+		counts[key{r.ChartPath, r.Environment}]++
+	}
+	for i, r := range releases {
+		if counts[key{r.ChartPath, r.Environment}] > 1 {
+			releases[i].Environment = r.Environment + "/" + r.Helm.ReleaseName
+		}
+	}
+}
@@ -0,0 +1,200 @@
+package helmfile
+
+import (
+	"context"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/nathantilsley/chart-val/internal/diff/domain"
+)
+
+// repoSourceControl serves directories of testdata/repo at any ref.
+type repoSourceControl struct{}
+
+func (repoSourceControl) FetchChartFiles(_ context.Context, _, _, ref, chartPath string) (string, func(), error) {
+	dir := filepath.Join("testdata", "repo", filepath.FromSlash(chartPath))
+	if _, err := os.Stat(dir); err != nil {
+		return "", nil, domain.NewNotFoundError(chartPath, ref)
+	}
+	return dir, func() {}, nil
+}
+
+func testAdapter(paths ...string) *Adapter {
+	return New(repoSourceControl{}, paths, slog.New(slog.NewTextHandler(os.Stderr, nil)))
+}
+
+var testPR = domain.PRContext{Owner: "example", Repo: "platform", PRNumber: 7, HeadRef: "feature", HeadSHA: "abc123"}
+
+func TestGetEnvironmentConfig(t *testing.T) {
+	t.Parallel()
+
+	adapter := testAdapter("helmfile.yaml", "missing/helmfile.yaml")
+
+	tests := []struct {
+		name  string
+		chart string
+		want  []domain.EnvironmentConfig
+	}{
+		{
+			name:  "releases per environment",
+			chart: "charts/api",
+			want: []domain.EnvironmentConfig{
+				{
+					Name:       "dev",
+					ValueFiles: []string{"/charts/api/values-dev.yaml"},
+					Helm: domain.HelmOptions{
+						Values:      "replicas: 1\n",
+						Parameters:  []domain.HelmParameter{{Name: "image.tag", Value: "dev"}},
+						ReleaseName: "api",
+						Namespace:   "dev",
+						IncludeCRDs: true,
+					},
+				},
+				{
+					Name:       "prod/api",
+					ValueFiles: []string{"/charts/api/values-prod.yaml"},
+					Helm: domain.HelmOptions{
+						Values:      "replicas: 3\n",
+						Parameters:  []domain.HelmParameter{{Name: "image.tag", Value: "v1.2.0"}},
+						ReleaseName: "api",
+						Namespace:   "prod-apps",
+						IncludeCRDs: true,
+					},
+				},
+				{
+					// Installed in prod only; its values are a release template
+					Name:       "prod/api-canary",
+					ValueFiles: []string{},
+					Helm: domain.HelmOptions{
+						Values:      "fullnameOverride: api-canary\n",
+						ReleaseName: "api-canary",
+						Namespace:   "prod-apps",
+						IncludeCRDs: true,
+					},
+				},
+			},
+		},
+		{
+			name:  "condition disables the release in prod",
+			chart: "charts/worker",
+			want: []domain.EnvironmentConfig{
+				{
+					Name:       "dev",
+					ValueFiles: []string{},
+					Helm:       domain.HelmOptions{ReleaseName: "worker", IncludeCRDs: true},
+				},
+			},
+		},
+		{
+			name:  "chart without releases",
+			chart: "charts/web",
+			want:  []domain.EnvironmentConfig{},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			config, err := adapter.GetEnvironmentConfig(context.Background(), testPR,
+				domain.ChangedChart{Name: filepath.Base(tt.chart), Path: tt.chart})
+			if err != nil {
+				t.Fatalf("GetEnvironmentConfig failed: %v", err)
+			}
+			if config.Path != tt.chart {
+				t.Errorf("Path = %q, want %q", config.Path, tt.chart)
+			}
+			if !reflect.DeepEqual(config.Environments, tt.want) {
+				t.Errorf("Environments = %+v\nwant %+v", config.Environments, tt.want)
+			}
+		})
+	}
+}
+
+func TestGetEnvironmentConfig_RenderError(t *testing.T) {
+	t.Parallel()
+
+	_, err := testAdapter("broken/helmfile.yaml").GetEnvironmentConfig(context.Background(), testPR,
+		domain.ChangedChart{Name: "api", Path: "charts/api"})
+	if err == nil {
+		t.Fatal("expected an error for a value missing from the environment")
+	}
+}
+
+func TestChartsUsingFiles(t *testing.T) {
+	t.Parallel()
+
+	adapter := testAdapter("helmfile.yaml")
+
+	tests := []struct {
+		name  string
+		files []string
+		want  []domain.ChangedChart
+	}{
+		{
+			name:  "release value file",
+			files: []string{"charts/api/values-dev.yaml", "README.md"},
+			want:  []domain.ChangedChart{{Name: "api", Path: "charts/api", AffectedVia: []string{"charts/api/values-dev.yaml"}}},
+		},
+		{
+			name:  "environment values affect the releases installed there",
+			files: []string{"env/prod.yaml.gotmpl"},
+			want:  []domain.ChangedChart{{Name: "api", Path: "charts/api", AffectedVia: []string{"env/prod.yaml.gotmpl"}}},
+		},
+		{
+			name:  "state file affects every release",
+			files: []string{"helmfile.yaml"},
+			want: []domain.ChangedChart{
+				{Name: "api", Path: "charts/api", AffectedVia: []string{"helmfile.yaml"}},
+				{Name: "worker", Path: "charts/worker", AffectedVia: []string{"helmfile.yaml"}},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			got, err := adapter.ChartsUsingFiles(context.Background(), testPR, tt.files)
+			if err != nil {
+				t.Fatalf("ChartsUsingFiles failed: %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ChartsUsingFiles() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestEnvironments(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name string
+		text string
+		want []string
+	}{
+		{name: "declared environments", text: "environments:\n  prod: {}\n  dev: {}\n", want: []string{"dev", "prod"}},
+		{name: "default left out", text: "environments:\n  default: {}\n  prod: {}\n", want: []string{"prod"}},
+		{name: "no environments", text: "releases: []\n", want: []string{"default"}},
+		{
+			name: "later parts needing values are skipped",
+			text: "environments:\n  dev: {}\n---\nreleases:\n  - name: {{ .Values.app.name }}\n",
+			want: []string{"dev"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			f := &stateFile{path: "helmfile.yaml", dir: ".", parts: partSeparator.Split(tt.text, -1)}
+			if got := f.environments(); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("environments() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,303 @@
+package helmfile
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"text/template"
+
+	"gopkg.in/yaml.v3"
+)
+
+// defaultEnvironment is the environment helmfile uses when none is selected.
+const defaultEnvironment = "default"
+
+// state is the subset of a helmfile state file that chart-val reads.
+type state struct {
+	Environments map[string]struct {
+		Values []any `yaml:"values"`
+	} `yaml:"environments"`
+	Values   []any     `yaml:"values"` // Base state values, below every environment's
+	Releases []release `yaml:"releases"`
+}
+
+// release is one entry of a state file's releases.
+type release struct {
+	Name      string    `yaml:"name"`
+	Namespace string    `yaml:"namespace"`
+	Chart     string    `yaml:"chart"`
+	Values    []any     `yaml:"values"`
+	Set       []setItem `yaml:"set"`
+	Installed *bool     `yaml:"installed"`
+	Condition string    `yaml:"condition"`
+}
+
+// setItem is one entry of a release's set.
+type setItem struct {
+	Name  string `yaml:"name"`
+	Value any    `yaml:"value"`
+}
+
+// stateFile is a helmfile state file read from the PR head. Paths in it are
+// relative to its directory, dir, whose files were fetched to root.
+type stateFile struct {
+	path  string   // Repository path of the state file
+	dir   string   // Repository directory of the state file
+	root  string   // Local directory holding dir's files
+	parts []string // Raw template parts, separated by "---" lines
+
+	inputs []string // Repository files the last render read
+}
+
+// partSeparator splits a state file into the parts helmfile renders in turn.
+var partSeparator = regexp.MustCompile(`(?m)^---\s*$`)
+
+// newStateFile reads a state file fetched to root.
+func newStateFile(repoPath, root string) (*stateFile, error) {
+	//nolint:gosec // G304: root is a fetched checkout, not user input
+	data, err := os.ReadFile(filepath.Join(root, path.Base(repoPath)))
+	if err != nil {
+		return nil, err
+	}
+	return &stateFile{
+		path:  repoPath,
+		dir:   path.Dir(repoPath),
+		root:  root,
+		parts: partSeparator.Split(string(data), -1),
+	}, nil
+}
+
+// environments returns the environment names the state file declares,
+// sorted. "default" is left out when other environments exist, since it is
+// only used when none is selected.
+func (f *stateFile) environments() []string {
+	names := make(map[string]bool)
+	for i, part := range f.parts {
+		// Environment values are not known yet, so parts that need them (the
+		// release parts, usually) are skipped; render reports their errors.
+		rendered, err := renderTemplate(fmt.Sprintf("%s part %d", f.path, i), part,
+			templateData(defaultEnvironment, map[string]any{}, nil), "missingkey=zero")
+		if err != nil {
+			continue
+		}
+		var s state
+		if err := yaml.Unmarshal([]byte(rendered), &s); err != nil {
+			continue
+		}
+		for name := range s.Environments {
+			names[name] = true
+		}
+	}
+
+	if len(names) > 1 {
+		delete(names, defaultEnvironment)
+	}
+	if len(names) == 0 {
+		names[defaultEnvironment] = true
+	}
+	envs := make([]string, 0, len(names))
+	for name := range names {
+		envs = append(envs, name)
+	}
+	sort.Strings(envs)
+	return envs
+}
+
+// render renders the state file for an environment: parts in order, each
+// with the values collected from the parts before it, as helmfile does.
+// Returns the environment's values and its releases.
+func (f *stateFile) render(env string) (map[string]any, []release, error) {
+	values := make(map[string]any)
+	var releases []release
+	f.inputs = []string{f.path}
+
+	for i, part := range f.parts {
+		name := fmt.Sprintf("%s part %d", f.path, i)
+		rendered, err := renderTemplate(name, part, templateData(env, values, nil), "missingkey=error")
+		if err != nil {
+			return nil, nil, err
+		}
+		var s state
+		if err := yaml.Unmarshal([]byte(rendered), &s); err != nil {
+			return nil, nil, fmt.Errorf("parsing %s: %w", name, err)
+		}
+		if err := f.loadValues(values, s.Values, env); err != nil {
+			return nil, nil, err
+		}
+		if e, ok := s.Environments[env]; ok {
+			if err := f.loadValues(values, e.Values, env); err != nil {
+				return nil, nil, err
+			}
+		}
+		releases = append(releases, s.Releases...)
+	}
+	return values, releases, nil
+}
+
+// loadValues merges state or environment values entries into values.
+// Entries are inline maps or files relative to the state file; ".gotmpl"
+// files are rendered first.
+func (f *stateFile) loadValues(values map[string]any, entries []any, env string) error {
+	for _, entry := range entries {
+		switch e := entry.(type) {
+		case map[string]any:
+			mergeValues(values, e)
+		case string:
+			f.inputs = append(f.inputs, path.Join(f.dir, e))
+			content, err := f.readValuesFile(e, templateData(env, values, nil))
+			if err != nil {
+				return err
+			}
+			var doc map[string]any
+			if err := yaml.Unmarshal([]byte(content), &doc); err != nil {
+				return fmt.Errorf("parsing values file %s: %w", e, err)
+			}
+			mergeValues(values, doc)
+		default:
+			return fmt.Errorf("unsupported values entry %v", entry)
+		}
+	}
+	return nil
+}
+
+// readValuesFile reads a values file relative to the state file, rendering
+// it when it is a ".gotmpl" template.
+func (f *stateFile) readValuesFile(rel string, data map[string]any) (string, error) {
+	local := filepath.FromSlash(rel)
+	if !filepath.IsLocal(local) {
+		return "", fmt.Errorf("values file %s is outside the state file's directory", rel)
+	}
+	//nolint:gosec // G304: rel is checked to stay inside the fetched directory
+	content, err := os.ReadFile(filepath.Join(f.root, local))
+	if err != nil {
+		return "", fmt.Errorf("reading values file %s: %w", rel, err)
+	}
+	if !strings.HasSuffix(rel, ".gotmpl") {
+		return string(content), nil
+	}
+	return renderTemplate(rel, string(content), data, "missingkey=error")
+}
+
+// enabled reports whether a release is installed in the environment: not
+// "installed: false", and its condition (e.g. "api.enabled") not false.
+func (r release) enabled(values map[string]any) bool {
+	if r.Installed != nil && !*r.Installed {
+		return false
+	}
+	if r.Condition == "" {
+		return true
+	}
+	var cur any = values
+	for _, part := range strings.Split(r.Condition, ".") {
+		m, ok := cur.(map[string]any)
+		if !ok {
+			return false
+		}
+		cur = m[part]
+	}
+	enabled, _ := cur.(bool)
+	return enabled
+}
+
+// localChart returns the repository path of a release's chart when it is a
+// local directory ("./charts/api", "../charts/api"), relative to dir.
+func (r release) localChart(dir string) (string, bool) {
+	if !strings.HasPrefix(r.Chart, "./") && !strings.HasPrefix(r.Chart, "../") {
+		return "", false // Repository chart such as "bitnami/redis"
+	}
+	p := path.Join(dir, r.Chart)
+	if p == ".." || strings.HasPrefix(p, "../") {
+		return "", false
+	}
+	return p, true
+}
+
+// templateFuncs is the subset of helmfile's template functions chart-val
+// supports. Functions reading the environment or running commands are not
+// available.
+var templateFuncs = template.FuncMap{
+	"default": func(def, v any) any {
+		if v == nil || v == "" {
+			return def
+		}
+		return v
+	},
+	"quote": func(v any) string { return strconv.Quote(fmt.Sprint(v)) },
+	"lower": strings.ToLower,
+	"upper": strings.ToUpper,
+	"trim":  strings.TrimSpace,
+	"replace": func(old, replacement, s string) string {
+		return strings.ReplaceAll(s, old, replacement)
+	},
+	"hasKey": func(m map[string]any, key string) bool {
+		_, ok := m[key]
+		return ok
+	},
+	"get": func(m map[string]any, key string) any { return m[key] },
+	"required": func(msg string, v any) (any, error) {
+		if v == nil || v == "" {
+			return nil, errors.New(msg)
+		}
+		return v, nil
+	},
+	"toYaml": func(v any) (string, error) {
+		data, err := yaml.Marshal(v)
+		return strings.TrimSuffix(string(data), "\n"), err
+	},
+	"indent": func(n int, s string) string {
+		pad := strings.Repeat(" ", n)
+		return pad + strings.ReplaceAll(s, "\n", "\n"+pad)
+	},
+	"nindent": func(n int, s string) string {
+		pad := strings.Repeat(" ", n)
+		return "\n" + pad + strings.ReplaceAll(s, "\n", "\n"+pad)
+	},
+	"env":         func(string) string { return "" },
+	"requiredEnv": func(name string) (string, error) { return "", fmt.Errorf("requiredEnv %s is not available", name) },
+	"exec":        func(string, ...any) (string, error) { return "", errors.New("exec is not available") },
+}
+
+// templateData is the data helmfile templates see.
+func templateData(env string, values map[string]any, rel *release) map[string]any {
+	data := map[string]any{
+		"Environment": map[string]any{"Name": env, "Values": values},
+		"Values":      values,
+		"StateValues": values,
+	}
+	if rel != nil {
+		data["Release"] = map[string]any{"Name": rel.Name, "Namespace": rel.Namespace, "Chart": rel.Chart}
+	}
+	return data
+}
+
+// renderTemplate executes text as a Go template with helmfile's data.
+func renderTemplate(name, text string, data map[string]any, missingKey string) (string, error) {
+	t, err := template.New(name).Funcs(templateFuncs).Option(missingKey).Parse(text)
+	if err != nil {
+		return "", fmt.Errorf("parsing template %s: %w", name, err)
+	}
+	var b strings.Builder
+	if err := t.Execute(&b, data); err != nil {
+		return "", fmt.Errorf("rendering template %s: %w", name, err)
+	}
+	return b.String(), nil
+}
+
+// mergeValues deep-merges src into dst, as helmfile merges values.
+func mergeValues(dst, src map[string]any) {
+	for k, v := range src {
+		if sm, ok := v.(map[string]any); ok {
+			if dm, ok := dst[k].(map[string]any); ok {
+				mergeValues(dm, sm)
+				continue
+			}
+		}
+		dst[k] = v
+	}
+}
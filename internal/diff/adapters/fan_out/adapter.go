@@ -0,0 +1,272 @@
+// Package fanout dispatches reporting calls to several ReportingPorts.
+package fanout
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"slices"
+	"sync"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+
+	"github.com/nathantilsley/chart-val/internal/diff/domain"
+	"github.com/nathantilsley/chart-val/internal/diff/ports"
+)
+
+// Sink is a named reporter. The name labels logs and metrics.
+type Sink struct {
+	Name     string
+	Reporter ports.ReportingPort
+	Timeout  time.Duration // Per-call timeout; zero means only the caller's deadline applies
+}
+
+// errSkipped is returned by dispatch callbacks that leave a sink out of a call.
+var errSkipped = errors.New("sink skipped")
+
+// Reporter implements ports.ReportingPort by dispatching every call to a
+// primary sink and any number of secondary sinks concurrently. Each sink runs
+// under its own timeout and a failing, slow, or panicking sink never affects
+// the others. The primary's check run IDs and errors are returned to the
+// caller; secondary failures are logged and counted. When only the primary
+// fails to create its check, the run carries on with a synthetic check run
+// ID so the secondaries still complete theirs.
+type Reporter struct {
+	primary     Sink
+	secondaries []Sink
+	logger      *slog.Logger
+
+	mu        sync.Mutex
+	checkRuns map[int64]checkRun // check run ID returned to the caller -> per-sink check runs
+	synthetic int64              // Last synthetic check run ID; negative so it never clashes with real IDs
+
+	// Pre-created metric instruments (created once, reused per call)
+	calls    metric.Int64Counter
+	duration metric.Float64Histogram
+}
+
+// New creates a fan-out reporter.
+func New(
+	logger *slog.Logger,
+	meter metric.Meter,
+	metricPrefix string,
+	primary Sink,
+	secondaries ...Sink,
+) *Reporter {
+	calls, _ := meter.Int64Counter(metricPrefix+".reporter.calls",
+		metric.WithUnit("{call}"),
+		metric.WithDescription("Reporting calls per sink by method and result (success, failure, timeout)"),
+	)
+	duration, _ := meter.Float64Histogram(metricPrefix+".reporter.duration",
+		metric.WithUnit("s"),
+		metric.WithDescription("Duration of reporting calls per sink"),
+	)
+
+	return &Reporter{
+		primary:     primary,
+		secondaries: secondaries,
+		logger:      logger,
+		checkRuns:   make(map[int64]checkRun),
+		calls:       calls,
+		duration:    duration,
+	}
+}
+
+// checkRun is the check run each sink created for one run.
+type checkRun struct {
+	ids     []int64 // Per sink, indexed like the sinks
+	primary bool    // Whether the primary created its check
+}
+
+// CreateInProgressCheck creates the check on every sink and returns the
+// primary's ID. If only the primary fails, the failure is logged and a
+// synthetic ID is returned instead; the primary's error is returned only
+// when every sink failed.
+func (r *Reporter) CreateInProgressCheck(ctx context.Context, pr domain.PRContext) (int64, error) {
+	// A sink abandoned at its timeout may still write its ID later, so
+	// results are collected under a lock and copied once dispatch returns.
+	var idsMu sync.Mutex
+	ids := make([]int64, len(r.secondaries)+1)
+	errs := r.dispatch(ctx, "CreateInProgressCheck", func(ctx context.Context, i int, s Sink) error {
+		id, err := s.Reporter.CreateInProgressCheck(ctx, pr)
+		idsMu.Lock()
+		ids[i] = id
+		idsMu.Unlock()
+		return err
+	})
+	if errs[0] != nil && !slices.ContainsFunc(errs[1:], func(err error) bool { return err == nil }) {
+		return 0, errs[0]
+	}
+
+	idsMu.Lock()
+	run := checkRun{ids: append([]int64(nil), ids...), primary: errs[0] == nil}
+	idsMu.Unlock()
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	id := run.ids[0]
+	if !run.primary {
+		r.synthetic--
+		id = r.synthetic
+		r.logger.Error("primary reporter failed to create its check, continuing with the secondary reporters",
+			"sink", r.primary.Name, "error", errs[0])
+	}
+	r.checkRuns[id] = run
+	return id, nil
+}
+
+// UpdateCheckWithResults updates the check on every sink, mapping the
+// returned check run ID to the ID each sink created earlier. A primary
+// that failed to create its check is skipped.
+func (r *Reporter) UpdateCheckWithResults(
+	ctx context.Context,
+	pr domain.PRContext,
+	checkRunID int64,
+	results []domain.DiffResult,
+) error {
+	r.mu.Lock()
+	run, ok := r.checkRuns[checkRunID]
+	delete(r.checkRuns, checkRunID)
+	r.mu.Unlock()
+	if !ok {
+		run = checkRun{ids: []int64{checkRunID}, primary: true}
+	}
+
+	errs := r.dispatch(ctx, "UpdateCheckWithResults", func(ctx context.Context, i int, s Sink) error {
+		if i == 0 && !run.primary {
+			return errSkipped
+		}
+		var id int64
+		if i < len(run.ids) {
+			id = run.ids[i]
+		}
+		return s.Reporter.UpdateCheckWithResults(ctx, pr, id, results)
+	})
+	return errs[0]
+}
+
+// PostComment posts the chart's comment on every sink.
+func (r *Reporter) PostComment(ctx context.Context, pr domain.PRContext, results []domain.DiffResult) error {
+	errs := r.dispatch(ctx, "PostComment", func(ctx context.Context, _ int, s Sink) error {
+		return s.Reporter.PostComment(ctx, pr, results)
+	})
+	return errs[0]
+}
+
+// ResolveComment resolves the chart's comment on every sink.
+func (r *Reporter) ResolveComment(ctx context.Context, pr domain.PRContext, chartName string) error {
+	errs := r.dispatch(ctx, "ResolveComment", func(ctx context.Context, _ int, s Sink) error {
+		return s.Reporter.ResolveComment(ctx, pr, chartName)
+	})
+	return errs[0]
+}
+
+// CommentedCharts returns the charts with an unresolved comment on any sink.
+func (r *Reporter) CommentedCharts(ctx context.Context, pr domain.PRContext) ([]string, error) {
+	var mu sync.Mutex
+	seen := make(map[string]bool)
+	var charts []string
+	errs := r.dispatch(ctx, "CommentedCharts", func(ctx context.Context, _ int, s Sink) error {
+		names, err := s.Reporter.CommentedCharts(ctx, pr)
+		mu.Lock()
+		defer mu.Unlock()
+		for _, name := range names {
+			if !seen[name] {
+				seen[name] = true
+				charts = append(charts, name)
+			}
+		}
+		return err
+	})
+
+	mu.Lock()
+	defer mu.Unlock()
+	return append([]string(nil), charts...), errs[0]
+}
+
+// dispatch calls fn for every sink concurrently (index 0 is the primary) and
+// waits for all of them. It returns each sink's error, indexed like the sinks.
+func (r *Reporter) dispatch(
+	ctx context.Context,
+	method string,
+	fn func(ctx context.Context, i int, s Sink) error,
+) []error {
+	sinks := append([]Sink{r.primary}, r.secondaries...)
+	errs := make([]error, len(sinks))
+
+	var wg sync.WaitGroup
+	for i, s := range sinks {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			errs[i] = r.call(ctx, method, i, s, fn)
+		}()
+	}
+	wg.Wait()
+
+	for i, err := range errs[1:] {
+		if err != nil {
+			r.logger.Warn("secondary reporter failed", "sink", sinks[i+1].Name, "method", method, "error", err)
+		}
+	}
+	return errs
+}
+
+// call runs fn for a single sink under its timeout and records metrics.
+// The call runs in its own goroutine so a sink that ignores cancellation is
+// abandoned at the deadline instead of holding up the others, and a panic is
+// reported as that sink's error.
+func (r *Reporter) call(
+	ctx context.Context,
+	method string,
+	i int,
+	s Sink,
+	fn func(ctx context.Context, i int, s Sink) error,
+) error {
+	if s.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, s.Timeout)
+		defer cancel()
+	}
+
+	start := time.Now()
+	done := make(chan error, 1)
+	go func() {
+		defer func() {
+			if p := recover(); p != nil {
+				done <- fmt.Errorf("reporter %s panicked: %v", s.Name, p)
+			}
+		}()
+		done <- fn(ctx, i, s)
+	}()
+
+	var err error
+	select {
+	case err = <-done:
+	case <-ctx.Done():
+		err = fmt.Errorf("reporter %s: %w", s.Name, ctx.Err())
+	}
+
+	result := "success"
+	switch {
+	case errors.Is(err, errSkipped):
+		return nil
+	case err != nil && errors.Is(ctx.Err(), context.DeadlineExceeded):
+		result = "timeout"
+	case err != nil:
+		result = "failure"
+	}
+
+	attrs := metric.WithAttributes(
+		attribute.String("sink", s.Name),
+		attribute.String("method", method),
+		attribute.String("result", result),
+	)
+	r.calls.Add(context.WithoutCancel(ctx), 1, attrs)
+	r.duration.Record(context.WithoutCancel(ctx), time.Since(start).Seconds(), attrs)
+
+	return err
+}
@@ -0,0 +1,245 @@
+package fanout
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	noopmetric "go.opentelemetry.io/otel/metric/noop"
+	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
+	"go.opentelemetry.io/otel/sdk/metric/metricdata"
+
+	"github.com/nathantilsley/chart-val/internal/diff/domain"
+)
+
+// fakeReporter records calls and optionally fails, blocks, or panics on every one of them.
+type fakeReporter struct {
+	id    int64
+	err   error
+	block chan struct{} // if set, calls wait on it, ignoring ctx
+	panic bool
+
+	commented []string // Returned by CommentedCharts
+
+	mu        sync.Mutex
+	updatedID int64
+	comments  int
+	resolved  int
+}
+
+func (f *fakeReporter) do() error {
+	if f.block != nil {
+		<-f.block
+	}
+	if f.panic {
+		panic("sink exploded")
+	}
+	return f.err
+}
+
+func (f *fakeReporter) CreateInProgressCheck(_ context.Context, _ domain.PRContext) (int64, error) {
+	return f.id, f.do()
+}
+
+func (f *fakeReporter) UpdateCheckWithResults(_ context.Context, _ domain.PRContext, id int64, _ []domain.DiffResult) error {
+	f.mu.Lock()
+	f.updatedID = id
+	f.mu.Unlock()
+	return f.do()
+}
+
+func (f *fakeReporter) PostComment(_ context.Context, _ domain.PRContext, _ []domain.DiffResult) error {
+	f.mu.Lock()
+	f.comments++
+	f.mu.Unlock()
+	return f.do()
+}
+
+func (f *fakeReporter) ResolveComment(_ context.Context, _ domain.PRContext, _ string) error {
+	f.mu.Lock()
+	f.resolved++
+	f.mu.Unlock()
+	return f.do()
+}
+
+func (f *fakeReporter) CommentedCharts(_ context.Context, _ domain.PRContext) ([]string, error) {
+	return f.commented, f.do()
+}
+
+func newTestReporter(primary Sink, secondaries ...Sink) *Reporter {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	return New(logger, noopmetric.NewMeterProvider().Meter("test"), "test", primary, secondaries...)
+}
+
+func TestReporter_SecondaryFailuresAreIsolated(t *testing.T) {
+	ctx := context.Background()
+
+	primary := &fakeReporter{id: 100}
+	failing := &fakeReporter{err: errors.New("boom")}
+	panicking := &fakeReporter{panic: true}
+	secondary := &fakeReporter{id: 7}
+	r := newTestReporter(
+		Sink{Name: "github", Reporter: primary},
+		Sink{Name: "failing", Reporter: failing},
+		Sink{Name: "panicking", Reporter: panicking},
+		Sink{Name: "chat", Reporter: secondary},
+	)
+
+	id, err := r.CreateInProgressCheck(ctx, domain.PRContext{})
+	if err != nil || id != 100 {
+		t.Fatalf("CreateInProgressCheck() = %d, %v; want primary ID 100", id, err)
+	}
+	if err := r.UpdateCheckWithResults(ctx, domain.PRContext{}, id, nil); err != nil {
+		t.Fatalf("UpdateCheckWithResults() error: %v", err)
+	}
+	if err := r.PostComment(ctx, domain.PRContext{}, nil); err != nil {
+		t.Fatalf("PostComment() error: %v", err)
+	}
+	if err := r.ResolveComment(ctx, domain.PRContext{}, "app"); err != nil {
+		t.Fatalf("ResolveComment() error: %v", err)
+	}
+
+	if primary.updatedID != 100 {
+		t.Errorf("primary updated check %d, want 100", primary.updatedID)
+	}
+	if secondary.updatedID != 7 {
+		t.Errorf("secondary updated check %d, want its own ID 7", secondary.updatedID)
+	}
+	if secondary.comments != 1 || secondary.resolved != 1 {
+		t.Errorf("secondary comments=%d resolved=%d, want 1 each", secondary.comments, secondary.resolved)
+	}
+}
+
+func TestReporter_CommentedCharts(t *testing.T) {
+	r := newTestReporter(
+		Sink{Name: "github", Reporter: &fakeReporter{commented: []string{"app", "web"}}},
+		Sink{Name: "failing", Reporter: &fakeReporter{err: errors.New("boom")}},
+		Sink{Name: "other", Reporter: &fakeReporter{commented: []string{"web", "worker"}}},
+	)
+
+	charts, err := r.CommentedCharts(context.Background(), domain.PRContext{})
+	if err != nil {
+		t.Fatalf("CommentedCharts() error: %v", err)
+	}
+	slices.Sort(charts)
+	if want := []string{"app", "web", "worker"}; !slices.Equal(charts, want) {
+		t.Errorf("CommentedCharts() = %v, want %v", charts, want)
+	}
+}
+
+func TestReporter_PrimaryErrorsPropagate(t *testing.T) {
+	primary := &fakeReporter{err: errors.New("github down")}
+	secondary := &fakeReporter{}
+	r := newTestReporter(Sink{Name: "github", Reporter: primary}, Sink{Name: "chat", Reporter: secondary})
+
+	if err := r.PostComment(context.Background(), domain.PRContext{}, nil); err == nil {
+		t.Fatal("PostComment() should return the primary's error")
+	}
+	if secondary.comments != 1 {
+		t.Error("secondary should still be called when the primary fails")
+	}
+}
+
+func TestReporter_PrimaryCheckFailureContinues(t *testing.T) {
+	ctx := context.Background()
+
+	primary := &fakeReporter{id: 100, err: errors.New("github down")}
+	secondary := &fakeReporter{id: 7}
+	r := newTestReporter(Sink{Name: "github", Reporter: primary}, Sink{Name: "webhook", Reporter: secondary})
+
+	id, err := r.CreateInProgressCheck(ctx, domain.PRContext{})
+	if err != nil {
+		t.Fatalf("CreateInProgressCheck() error: %v; want a synthetic ID while a secondary succeeded", err)
+	}
+	if id >= 0 {
+		t.Errorf("CreateInProgressCheck() = %d, want a negative synthetic ID", id)
+	}
+
+	// The primary has no check to update, so it is skipped and its error
+	// isn't reported again
+	primary.err = nil
+	if err := r.UpdateCheckWithResults(ctx, domain.PRContext{}, id, nil); err != nil {
+		t.Fatalf("UpdateCheckWithResults() error: %v", err)
+	}
+	if secondary.updatedID != 7 {
+		t.Errorf("secondary updated check %d, want its own ID 7", secondary.updatedID)
+	}
+	if primary.updatedID != 0 {
+		t.Errorf("primary updated check %d, want no update", primary.updatedID)
+	}
+	if len(r.checkRuns) != 0 {
+		t.Errorf("check runs still tracked after the update: %v", r.checkRuns)
+	}
+
+	// With every sink failing the primary's error is returned
+	secondary.err = errors.New("webhook down")
+	primary.err = errors.New("github down")
+	if _, err := r.CreateInProgressCheck(ctx, domain.PRContext{}); err == nil {
+		t.Error("CreateInProgressCheck() should fail when every sink fails")
+	}
+}
+
+func TestReporter_SlowSinkTimesOut(t *testing.T) {
+	block := make(chan struct{})
+	defer close(block)
+
+	reader := sdkmetric.NewManualReader()
+	meter := sdkmetric.NewMeterProvider(sdkmetric.WithReader(reader)).Meter("test")
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+
+	primary := &fakeReporter{}
+	slow := &fakeReporter{block: block}
+	r := New(logger, meter, "test",
+		Sink{Name: "github", Reporter: primary},
+		Sink{Name: "slow", Reporter: slow, Timeout: 20 * time.Millisecond},
+	)
+
+	start := time.Now()
+	if err := r.PostComment(context.Background(), domain.PRContext{}, nil); err != nil {
+		t.Fatalf("PostComment() error: %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Fatalf("PostComment() took %s; a sink ignoring cancellation should be abandoned at its timeout", elapsed)
+	}
+
+	var rm metricdata.ResourceMetrics
+	if err := reader.Collect(context.Background(), &rm); err != nil {
+		t.Fatalf("collecting metrics: %v", err)
+	}
+	got := callCounts(t, rm)
+	if got["github/success"] != 1 {
+		t.Errorf("github success calls = %d, want 1", got["github/success"])
+	}
+	if got["slow/timeout"] != 1 {
+		t.Errorf("slow timeout calls = %d, want 1 (counts: %v)", got["slow/timeout"], got)
+	}
+}
+
+// callCounts flattens the reporter.calls counter into "sink/result" -> count.
+func callCounts(t *testing.T, rm metricdata.ResourceMetrics) map[string]int64 {
+	t.Helper()
+	counts := make(map[string]int64)
+	for _, sm := range rm.ScopeMetrics {
+		for _, m := range sm.Metrics {
+			if m.Name != "test.reporter.calls" {
+				continue
+			}
+			sum, ok := m.Data.(metricdata.Sum[int64])
+			if !ok {
+				t.Fatalf("reporter.calls has unexpected type %T", m.Data)
+			}
+			for _, dp := range sum.DataPoints {
+				sink, _ := dp.Attributes.Value(attribute.Key("sink"))
+				result, _ := dp.Attributes.Value(attribute.Key("result"))
+				counts[sink.AsString()+"/"+result.AsString()] += dp.Value
+			}
+		}
+	}
+	return counts
+}
@@ -5,6 +5,7 @@ import (
 	"context"
 	"log/slog"
 	"net/http"
+	"strings"
 
 	gogithub "github.com/google/go-github/v68/github"
 	"go.opentelemetry.io/otel/trace"
@@ -16,14 +17,22 @@ import (
 // WebhookHandler handles incoming GitHub webhook events.
 type WebhookHandler struct {
 	useCase       ports.DiffUseCase
+	indexes       ports.GitopsIndexUseCase // nil when no gitops repository is synced
 	webhookSecret []byte
 	logger        *slog.Logger
 }
 
-// NewWebhookHandler creates a new webhook handler.
-func NewWebhookHandler(uc ports.DiffUseCase, secret string, logger *slog.Logger) *WebhookHandler {
+// NewWebhookHandler creates a new webhook handler. indexes may be nil; when
+// set, pushes to the default branch of a gitops repository sync its index.
+func NewWebhookHandler(
+	uc ports.DiffUseCase,
+	indexes ports.GitopsIndexUseCase,
+	secret string,
+	logger *slog.Logger,
+) *WebhookHandler {
 	return &WebhookHandler{
 		useCase:       uc,
+		indexes:       indexes,
 		webhookSecret: []byte(secret),
 		logger:        logger,
 	}
@@ -31,6 +40,7 @@ func NewWebhookHandler(uc ports.DiffUseCase, secret string, logger *slog.Logger)
 
 // ServeHTTP validates the webhook signature, parses the event, and
 // dispatches the diff use case in a goroutine (responds 202 immediately).
+// Push events request a gitops index sync, which also runs in the background.
 func (h *WebhookHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 	payload, err := gogithub.ValidatePayload(r, h.webhookSecret)
 	if err != nil {
@@ -46,12 +56,18 @@ func (h *WebhookHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	prEvent, ok := event.(*gogithub.PullRequestEvent)
-	if !ok {
+	switch event := event.(type) {
+	case *gogithub.PullRequestEvent:
+		h.handlePullRequest(w, r, event)
+	case *gogithub.PushEvent:
+		h.handlePush(w, event)
+	default:
 		w.WriteHeader(http.StatusOK)
-		return
 	}
+}
 
+// handlePullRequest dispatches the diff use case for opened and updated PRs.
+func (h *WebhookHandler) handlePullRequest(w http.ResponseWriter, r *http.Request, prEvent *gogithub.PullRequestEvent) {
 	action := prEvent.GetAction()
 	if action != "opened" && action != "synchronize" && action != "reopened" {
 		w.WriteHeader(http.StatusOK)
@@ -94,3 +110,27 @@ func (h *WebhookHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 
 	w.WriteHeader(http.StatusAccepted)
 }
+
+// handlePush requests a sync of the gitops indexes built from the pushed
+// repository when its default branch moves, so Applications merged there
+// are picked up without waiting for the next periodic sync.
+func (h *WebhookHandler) handlePush(w http.ResponseWriter, push *gogithub.PushEvent) {
+	repo := push.GetRepo()
+	if h.indexes == nil || push.GetRef() != "refs/heads/"+repo.GetDefaultBranch() {
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+
+	owner, name, ok := strings.Cut(repo.GetFullName(), "/")
+	if !ok || !h.indexes.RequestSync(owner, name) {
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+
+	h.logger.Info("gitops repository pushed, index sync requested",
+		"owner", owner,
+		"repo", name,
+		"after", push.GetAfter(),
+	)
+	w.WriteHeader(http.StatusAccepted)
+}
@@ -6,8 +6,11 @@ import (
 	"errors"
 	"fmt"
 	"log/slog"
+	"net/http"
 	"os"
 	"strings"
+	"sync"
+	"time"
 
 	gogithub "github.com/google/go-github/v68/github"
 
@@ -16,17 +19,106 @@ import (
 
 const maxCheckRunTextLen = 65535
 
+// CommentMode controls how PR comments are laid out across charts.
+type CommentMode string
+
+const (
+	// CommentModePerChart posts one comment per chart (default).
+	CommentModePerChart CommentMode = "per-chart"
+	// CommentModeConsolidated keeps a single comment for the PR with one
+	// section per chart that has changes.
+	CommentModeConsolidated CommentMode = "consolidated"
+)
+
+// Options configures optional adapter behaviour. The zero value matches the
+// original behaviour (one comment per chart).
+type Options struct {
+	CommentMode CommentMode
+
+	// CheckRunNameTemplate, when set, adds extra completed check runs named
+	// by the template, one per distinct rendered name. Supported placeholders
+	// are {app}, {chart} and {env}; e.g. "{app} / {chart} / {env}" yields one
+	// check run per chart and environment that branch protection can require.
+	CheckRunNameTemplate string
+
+	// RequiredCheckRuns are check run names rendered from
+	// CheckRunNameTemplate that branch protection requires. Every run
+	// reports all of them: names the run doesn't render, because the PR
+	// doesn't touch that chart or environment, complete as skipped, which
+	// satisfies branch protection instead of leaving the PR waiting.
+	RequiredCheckRuns []string
+
+	// ConclusionPolicy maps outcomes to check run conclusions. The zero value
+	// fails on errors and succeeds otherwise.
+	ConclusionPolicy ConclusionPolicy
+
+	// Templates renders check run and PR comment markdown. Nil uses the
+	// embedded defaults.
+	Templates *Templates
+
+	// RepoTemplatePath is a directory in the PR's repository whose template
+	// files (read at the base ref) override Templates. Empty disables lookup.
+	RepoTemplatePath string
+
+	// LinkRunHistory adds a link to the run history web UI (served at
+	// appURL) to check run summaries. Ignored when appURL is empty.
+	LinkRunHistory bool
+}
+
 // Adapter implements ports.ReportingPort by posting results via the
 // GitHub Checks API.
 type Adapter struct {
-	client  *gogithub.Client
-	appName string
-	appURL  string
+	client               *gogithub.Client
+	appName              string
+	appURL               string
+	commentMode          CommentMode
+	checkRunNameTemplate string
+	requiredCheckRuns    []string
+	policy               ConclusionPolicy
+	templates            *Templates
+	repoTemplatePath     string
+	linkRunHistory       bool
+
+	templateCacheMu sync.Mutex
+	templateCache   map[string]cachedTemplates // "owner/repo@baseRef" -> repo overrides
+}
+
+// cachedTemplates is a repo's resolved template set and when it was fetched.
+type cachedTemplates struct {
+	templates *Templates
+	fetchedAt time.Time
 }
 
+// repoTemplateCacheTTL bounds how long repo template overrides are reused.
+const repoTemplateCacheTTL = 5 * time.Minute
+
+// defaultTemplates is the embedded template set, also used as the fallback
+// when a custom template fails to render.
+var defaultTemplates = DefaultTemplates()
+
 // New creates a new GitHub reporting adapter.
-func New(client *gogithub.Client, appName, appURL string) *Adapter {
-	return &Adapter{client: client, appName: appName, appURL: appURL}
+func New(client *gogithub.Client, appName, appURL string, opts Options) *Adapter {
+	mode := opts.CommentMode
+	if mode == "" {
+		mode = CommentModePerChart
+	}
+	templates := opts.Templates
+	if templates == nil {
+		templates = defaultTemplates
+	}
+	return &Adapter{
+		client:               client,
+		appName:              appName,
+		appURL:               appURL,
+		commentMode:          mode,
+		checkRunNameTemplate: opts.CheckRunNameTemplate,
+		requiredCheckRuns:    opts.RequiredCheckRuns,
+		policy:               opts.ConclusionPolicy,
+		templates:            templates,
+		repoTemplatePath:     opts.RepoTemplatePath,
+		linkRunHistory:       opts.LinkRunHistory && appURL != "",
+		templateCache:        make(map[string]cachedTemplates),
+	}
 }
 
 // CreateInProgressCheck creates a single check run in "in_progress" status for the PR.
@@ -68,12 +160,14 @@ func (a *Adapter) UpdateCheckWithResults(
 	}
 
 	client := a.client
-	conclusion, summary, text := formatCheckRun(results)
+	tpl := a.templatesFor(ctx, pr)
+	conclusion, summary, text := a.formatCheckRun(tpl, pr, results)
 
 	_, _, err := client.Checks.UpdateCheckRun(ctx, pr.Owner, pr.Repo, checkRunID, gogithub.UpdateCheckRunOptions{
 		Name:       a.appName,
 		Status:     gogithub.Ptr("completed"),
 		Conclusion: gogithub.Ptr(conclusion),
+		DetailsURL: a.detailsURL(pr, conclusion),
 		Output: &gogithub.CheckRunOutput{
 			Title:   gogithub.Ptr("Helm Diff"),
 			Summary: gogithub.Ptr(summary),
@@ -81,14 +175,107 @@ func (a *Adapter) UpdateCheckWithResults(
 		},
 	})
 	if err != nil {
-		return fmt.Errorf("updating check run: %w", err)
+		err = fmt.Errorf("updating check run: %w", err)
+	} else {
+		logger.Info("check run updated successfully", "checkRunID", checkRunID)
 	}
 
-	logger.Info("check run updated successfully", "checkRunID", checkRunID)
-	return nil
+	// Split check runs are reported even if the overall one failed to
+	// update, so required ones never leave the PR waiting.
+	if a.checkRunNameTemplate != "" {
+		err = errors.Join(err, a.createSplitCheckRuns(ctx, tpl, pr, results))
+	}
+	return err
 }
 
-// PostComment posts a PR comment with the diff summary for a single chart.
+// createSplitCheckRuns creates one completed check run per distinct name
+// rendered from the check run name template, so that individual charts or
+// environments can be wired into branch protection independently.
+func (a *Adapter) createSplitCheckRuns(
+	ctx context.Context,
+	tpl *Templates,
+	pr domain.PRContext,
+	results []domain.DiffResult,
+) error {
+	logger := slog.New(slog.NewTextHandler(os.Stderr, nil))
+
+	groups := make(map[string][]domain.DiffResult)
+	var order []string
+	for _, r := range results {
+		name := a.checkRunName(r)
+		if _, exists := groups[name]; !exists {
+			order = append(order, name)
+		}
+		groups[name] = append(groups[name], r)
+	}
+
+	var checkRuns []gogithub.CreateCheckRunOptions
+	for _, name := range order {
+		conclusion, summary, text := a.formatCheckRun(tpl, pr, groups[name])
+		checkRuns = append(checkRuns, gogithub.CreateCheckRunOptions{
+			Name:       name,
+			HeadSHA:    pr.HeadSHA,
+			Status:     gogithub.Ptr("completed"),
+			Conclusion: gogithub.Ptr(conclusion),
+			DetailsURL: a.detailsURL(pr, conclusion),
+			Output: &gogithub.CheckRunOutput{
+				Title:   gogithub.Ptr("Helm Diff"),
+				Summary: gogithub.Ptr(summary),
+				Text:    gogithub.Ptr(text),
+			},
+		})
+	}
+	checkRuns = append(checkRuns, a.skippedCheckRuns(pr, groups)...)
+
+	// Check runs are created completed, so a failure leaves none of them in
+	// progress; every one is attempted regardless of earlier failures.
+	var errs []error
+	for _, opts := range checkRuns {
+		if _, _, err := a.client.Checks.CreateCheckRun(ctx, pr.Owner, pr.Repo, opts); err != nil {
+			errs = append(errs, fmt.Errorf("creating check run %q: %w", opts.Name, err))
+			continue
+		}
+		logger.Info("split check run created", "name", opts.Name, "conclusion", opts.GetConclusion())
+	}
+
+	return errors.Join(errs...)
+}
+
+// skippedCheckRuns returns a skipped check run for every required check run
+// name the run's results didn't render.
+func (a *Adapter) skippedCheckRuns(pr domain.PRContext, rendered map[string][]domain.DiffResult) []gogithub.CreateCheckRunOptions {
+	var checkRuns []gogithub.CreateCheckRunOptions
+	for _, name := range a.requiredCheckRuns {
+		if _, ok := rendered[name]; ok {
+			continue
+		}
+		checkRuns = append(checkRuns, gogithub.CreateCheckRunOptions{
+			Name:       name,
+			HeadSHA:    pr.HeadSHA,
+			Status:     gogithub.Ptr("completed"),
+			Conclusion: gogithub.Ptr("skipped"),
+			Output: &gogithub.CheckRunOutput{
+				Title:   gogithub.Ptr("Helm Diff"),
+				Summary: gogithub.Ptr("Not affected by this pull request."),
+			},
+		})
+	}
+	return checkRuns
+}
+
+// checkRunName renders the check run name template for a single result.
+func (a *Adapter) checkRunName(r domain.DiffResult) string {
+	return strings.NewReplacer(
+		"{app}", a.appName,
+		"{chart}", r.ChartName,
+		"{env}", r.Environment,
+	).Replace(a.checkRunNameTemplate)
+}
+
+// PostComment creates or updates the PR comment for a single chart. An existing
+// comment carrying the chart's marker is edited in place so the conversation
+// order and notifications are preserved. In consolidated mode the chart's
+// section inside the shared PR comment is replaced instead.
 func (a *Adapter) PostComment(ctx context.Context, pr domain.PRContext, results []domain.DiffResult) error {
 	logger := slog.New(slog.NewTextHandler(os.Stderr, nil))
 
@@ -96,53 +283,291 @@ func (a *Adapter) PostComment(ctx context.Context, pr domain.PRContext, results
 		return errors.New("no results to post comment")
 	}
 
-	chartName := results[0].ChartName
-	logger.Info("posting PR comment", "chart", chartName, "pr", pr.PRNumber)
+	chart := results[0].ChartKey()
+	logger.Info("posting PR comment", "chart", chart, "pr", pr.PRNumber, "mode", a.commentMode)
+
+	tpl := a.templatesFor(ctx, pr)
+	if a.commentMode == CommentModeConsolidated {
+		return a.upsertConsolidatedSection(ctx, tpl, pr, chart, a.formatChartSection(tpl, results))
+	}
+
+	existing, err := a.findComment(ctx, pr, a.chartMarker(chart))
+	if err != nil {
+		return err
+	}
+
+	if err := a.upsertComment(ctx, pr, existing, a.formatPRComment(tpl, results)); err != nil {
+		return err
+	}
+
+	logger.Info("PR comment posted successfully", "chart", chart)
+	return nil
+}
+
+// ResolveComment marks the chart's earlier comment as resolved once a later
+// push leaves the chart without changes. The comment body is replaced with a
+// short note and the comment is minimized. Does nothing if no comment exists.
+func (a *Adapter) ResolveComment(ctx context.Context, pr domain.PRContext, chart string) error {
+	logger := slog.New(slog.NewTextHandler(os.Stderr, nil))
+
+	tpl := a.templatesFor(ctx, pr)
+	if a.commentMode == CommentModeConsolidated {
+		return a.removeConsolidatedSection(ctx, tpl, pr, chart)
+	}
+
+	existing, err := a.findComment(ctx, pr, a.chartMarker(chart))
+	if err != nil {
+		return err
+	}
+	if existing == nil || a.isResolved(existing.GetBody()) {
+		return nil
+	}
+
+	logger.Info("resolving PR comment", "chart", chart, "commentID", existing.GetID())
+	return a.resolveComment(ctx, pr, existing, a.formatResolvedComment(tpl, a.chartMarker(chart), chart, pr))
+}
+
+// CommentedCharts returns the keys of the charts with an unresolved comment
+// on the PR: the charts named by per-chart comment markers, or the sections
+// of the consolidated comment in consolidated mode.
+func (a *Adapter) CommentedCharts(ctx context.Context, pr domain.PRContext) ([]string, error) {
+	comments, err := a.listComments(ctx, pr)
+	if err != nil {
+		return nil, err
+	}
+	return a.commentedCharts(comments), nil
+}
+
+// commentedCharts returns the charts with an unresolved comment among comments.
+func (a *Adapter) commentedCharts(comments []*gogithub.IssueComment) []string {
+	var charts []string
+	seen := make(map[string]bool)
+	chartPrefix := fmt.Sprintf("<!-- %s: ", a.appName)
+	for _, comment := range comments {
+		body := comment.GetBody()
+		if a.isResolved(body) {
+			continue
+		}
+
+		var names []string
+		if a.commentMode == CommentModeConsolidated {
+			if strings.HasPrefix(body, a.consolidatedMarker()+"\n") {
+				for _, sec := range a.parseSections(body) {
+					names = append(names, sec.chart)
+				}
+			}
+		} else if rest, ok := strings.CutPrefix(body, chartPrefix); ok {
+			if name, _, ok := strings.Cut(rest, " -->\n"); ok {
+				names = append(names, name)
+			}
+		}
+
+		for _, name := range names {
+			if !seen[name] {
+				seen[name] = true
+				charts = append(charts, name)
+			}
+		}
+	}
+	return charts
+}
+
+// upsertConsolidatedSection replaces (or appends) a chart's section in the
+// single consolidated PR comment, creating the comment if needed.
+func (a *Adapter) upsertConsolidatedSection(
+	ctx context.Context,
+	tpl *Templates,
+	pr domain.PRContext,
+	chart, section string,
+) error {
+	existing, err := a.findComment(ctx, pr, a.consolidatedMarker())
+	if err != nil {
+		return err
+	}
+
+	var sections []chartSection
+	if existing != nil && !a.isResolved(existing.GetBody()) {
+		sections = a.parseSections(existing.GetBody())
+	}
+	sections = upsertSection(sections, chartSection{chart: chart, body: section})
+
+	return a.upsertComment(ctx, pr, existing, a.formatConsolidatedComment(tpl, sections))
+}
 
+// removeConsolidatedSection drops a chart's section from the consolidated
+// comment. When no sections remain the comment is marked resolved.
+func (a *Adapter) removeConsolidatedSection(
+	ctx context.Context,
+	tpl *Templates,
+	pr domain.PRContext,
+	chart string,
+) error {
+	existing, err := a.findComment(ctx, pr, a.consolidatedMarker())
+	if err != nil {
+		return err
+	}
+	if existing == nil || a.isResolved(existing.GetBody()) {
+		return nil
+	}
+
+	sections := a.parseSections(existing.GetBody())
+	remaining := removeSection(sections, chart)
+	if len(remaining) == len(sections) {
+		return nil
+	}
+
+	if len(remaining) == 0 {
+		return a.resolveComment(ctx, pr, existing, a.formatResolvedComment(tpl, a.consolidatedMarker(), "", pr))
+	}
+	return a.upsertComment(ctx, pr, existing, a.formatConsolidatedComment(tpl, remaining))
+}
+
+// upsertComment edits the existing comment in place, or creates a new one
+// when existing is nil. Comments that were previously minimized as resolved
+// are restored first so the fresh diff is visible.
+func (a *Adapter) upsertComment(
+	ctx context.Context,
+	pr domain.PRContext,
+	existing *gogithub.IssueComment,
+	body string,
+) error {
+	logger := slog.New(slog.NewTextHandler(os.Stderr, nil))
 	client := a.client
-	commentMarker := fmt.Sprintf("<!-- %s: %s -->", a.appName, chartName)
 
-	// Delete old comments for this chart to avoid bloat
-	a.deleteMatchingComments(ctx, pr, commentMarker)
+	if existing == nil {
+		_, _, err := client.Issues.CreateComment(ctx, pr.Owner, pr.Repo, pr.PRNumber, &gogithub.IssueComment{
+			Body: gogithub.Ptr(body),
+		})
+		if err != nil {
+			return fmt.Errorf("creating PR comment: %w", err)
+		}
+		return nil
+	}
+
+	if a.isResolved(existing.GetBody()) {
+		if err := a.setMinimized(ctx, existing.GetNodeID(), false); err != nil {
+			logger.Warn("failed to unminimize comment", "commentID", existing.GetID(), "error", err)
+		}
+	}
+
+	logger.Info("editing existing comment", "commentID", existing.GetID())
+	_, _, err := client.Issues.EditComment(ctx, pr.Owner, pr.Repo, existing.GetID(), &gogithub.IssueComment{
+		Body: gogithub.Ptr(body),
+	})
+	if err != nil {
+		return fmt.Errorf("editing PR comment: %w", err)
+	}
+	return nil
+}
 
-	commentBody := a.FormatPRComment(results)
+// resolveComment rewrites a comment with the resolved body and minimizes it.
+// Minimizing is best-effort: the edited body already tells readers the diff is gone.
+func (a *Adapter) resolveComment(
+	ctx context.Context,
+	pr domain.PRContext,
+	existing *gogithub.IssueComment,
+	body string,
+) error {
+	logger := slog.New(slog.NewTextHandler(os.Stderr, nil))
 
-	_, _, err := client.Issues.CreateComment(ctx, pr.Owner, pr.Repo, pr.PRNumber, &gogithub.IssueComment{
-		Body: gogithub.Ptr(commentBody),
+	_, _, err := a.client.Issues.EditComment(ctx, pr.Owner, pr.Repo, existing.GetID(), &gogithub.IssueComment{
+		Body: gogithub.Ptr(body),
 	})
 	if err != nil {
-		return fmt.Errorf("creating PR comment: %w", err)
+		return fmt.Errorf("editing PR comment: %w", err)
 	}
 
-	logger.Info("PR comment posted successfully", "chart", chartName)
+	if err := a.setMinimized(ctx, existing.GetNodeID(), true); err != nil {
+		logger.Warn("failed to minimize resolved comment", "commentID", existing.GetID(), "error", err)
+	}
 	return nil
 }
 
-// deleteMatchingComments deletes comments containing the given marker.
-func (a *Adapter) deleteMatchingComments(ctx context.Context, pr domain.PRContext, marker string) {
+// findComment returns the first comment whose body starts with marker,
+// paging through every comment on the PR. Any later duplicates (left over
+// from the old delete-and-recreate behaviour) are deleted.
+func (a *Adapter) findComment(
+	ctx context.Context,
+	pr domain.PRContext,
+	marker string,
+) (*gogithub.IssueComment, error) {
 	logger := slog.New(slog.NewTextHandler(os.Stderr, nil))
-	client := a.client
 
-	comments, _, err := client.Issues.ListComments(
-		ctx,
-		pr.Owner,
-		pr.Repo,
-		pr.PRNumber,
-		&gogithub.IssueListCommentsOptions{},
-	)
+	comments, err := a.listComments(ctx, pr)
 	if err != nil {
-		logger.Warn("failed to list comments, continuing anyway", "error", err)
-		return
+		return nil, err
 	}
+
+	var found *gogithub.IssueComment
 	for _, comment := range comments {
-		if strings.Contains(comment.GetBody(), marker) {
-			logger.Info("deleting old comment", "commentID", comment.GetID())
-			_, err := client.Issues.DeleteComment(ctx, pr.Owner, pr.Repo, comment.GetID())
-			if err != nil {
-				logger.Warn("failed to delete old comment", "commentID", comment.GetID(), "error", err)
-			}
+		if !strings.HasPrefix(comment.GetBody(), marker+"\n") {
+			continue
+		}
+		if found == nil {
+			found = comment
+			continue
+		}
+		logger.Info("deleting duplicate comment", "commentID", comment.GetID())
+		if _, err := a.client.Issues.DeleteComment(ctx, pr.Owner, pr.Repo, comment.GetID()); err != nil {
+			logger.Warn("failed to delete duplicate comment", "commentID", comment.GetID(), "error", err)
+		}
+	}
+	return found, nil
+}
+
+// listComments returns every comment on the PR, following pagination.
+func (a *Adapter) listComments(ctx context.Context, pr domain.PRContext) ([]*gogithub.IssueComment, error) {
+	var all []*gogithub.IssueComment
+	opts := &gogithub.IssueListCommentsOptions{ListOptions: gogithub.ListOptions{PerPage: 100}}
+
+	for {
+		comments, resp, err := a.client.Issues.ListComments(ctx, pr.Owner, pr.Repo, pr.PRNumber, opts)
+		if err != nil {
+			return nil, fmt.Errorf("listing PR comments: %w", err)
 		}
+		all = append(all, comments...)
+
+		if resp.NextPage == 0 {
+			break
+		}
+		opts.Page = resp.NextPage
 	}
+
+	return all, nil
+}
+
+// setMinimized minimizes (as RESOLVED) or restores a comment. The REST API has
+// no equivalent, so this goes through the GraphQL endpoint.
+func (a *Adapter) setMinimized(ctx context.Context, nodeID string, minimized bool) error {
+	if nodeID == "" {
+		return errors.New("comment has no node ID")
+	}
+
+	query := `mutation($id: ID!) { unminimizeComment(input: {subjectId: $id}) { clientMutationId } }`
+	if minimized {
+		query = `mutation($id: ID!) { minimizeComment(input: {subjectId: $id, classifier: RESOLVED}) { clientMutationId } }`
+	}
+
+	req, err := a.client.NewRequest(http.MethodPost, "graphql", map[string]any{
+		"query":     query,
+		"variables": map[string]string{"id": nodeID},
+	})
+	if err != nil {
+		return fmt.Errorf("creating graphql request: %w", err)
+	}
+
+	var out struct {
+		Errors []struct {
+			Message string `json:"message"`
+		} `json:"errors"`
+	}
+	if _, err := a.client.Do(ctx, req, &out); err != nil {
+		return fmt.Errorf("calling graphql: %w", err)
+	}
+	if len(out.Errors) > 0 {
+		return fmt.Errorf("graphql error: %s", out.Errors[0].Message)
+	}
+	return nil
 }
 
 // FormatCheckRunMarkdown formats a complete check run markdown document for testing.
@@ -152,7 +577,7 @@ func (a *Adapter) FormatCheckRunMarkdown(results []domain.DiffResult) string {
 		return ""
 	}
 
-	conclusion, summary, text := formatCheckRun(results)
+	conclusion, summary, text := a.formatCheckRun(a.templates, domain.PRContext{}, results)
 
 	var sb strings.Builder
 	fmt.Fprintf(&sb, "# %s\n\n", a.appName)
@@ -166,122 +591,123 @@ func (a *Adapter) FormatCheckRunMarkdown(results []domain.DiffResult) string {
 }
 
 // formatCheckRun builds the conclusion, summary, and collapsible text for the check run.
-// Groups results by chart, showing diffs for changed charts and listing unchanged charts.
-func formatCheckRun(results []domain.DiffResult) (conclusion, summary, text string) {
-	_, _, errorCount := domain.CountByStatus(results)
-	conclusion = determineConclusion(errorCount)
-
-	grouped, chartOrder := groupResultsByChart(results)
-	changedCharts, unchangedCharts := separateChangedCharts(grouped, chartOrder)
+// The conclusion comes from the configured conclusion policy; summary and text are
+// rendered from the check run templates.
+func (a *Adapter) formatCheckRun(
+	tpl *Templates,
+	pr domain.PRContext,
+	results []domain.DiffResult,
+) (conclusion, summary, text string) {
+	conclusion, notes := a.policy.Evaluate(pr, results)
+
+	data := newReportData(a.appName, a.appURL, pr, results)
+	data.Conclusion = conclusion
+	data.Notes = notes
+	if a.linkRunHistory {
+		data.RunURL = runHistoryURL(a.appURL, pr)
+	}
 
-	summary = buildSummary(chartOrder, changedCharts, unchangedCharts)
-	text = buildCheckRunText(grouped, changedCharts, unchangedCharts)
+	summary = a.renderTemplate(tpl, TemplateCheckRunSummary, data)
+	text = truncateIfNeeded(a.renderTemplate(tpl, TemplateCheckRunText, data))
 
 	return conclusion, summary, text
 }
 
-func determineConclusion(errorCount int) string {
-	if errorCount > 0 {
-		return "failure"
+// renderTemplate renders a report template, falling back to the embedded
+// default when a custom template fails so a bad override never blocks reporting.
+func (a *Adapter) renderTemplate(tpl *Templates, name string, data any) string {
+	out, err := tpl.render(name, data)
+	if err == nil {
+		return out
 	}
-	return "success"
-}
 
-func groupResultsByChart(results []domain.DiffResult) (map[string][]domain.DiffResult, []string) {
-	grouped := make(map[string][]domain.DiffResult)
-	var chartOrder []string
-	for _, r := range results {
-		if _, exists := grouped[r.ChartName]; !exists {
-			chartOrder = append(chartOrder, r.ChartName)
-		}
-		grouped[r.ChartName] = append(grouped[r.ChartName], r)
-	}
-	return grouped, chartOrder
-}
+	logger := slog.New(slog.NewTextHandler(os.Stderr, nil))
+	logger.Warn("report template failed, using default", "template", name, "error", err)
 
-func separateChangedCharts(grouped map[string][]domain.DiffResult, chartOrder []string) (changed, unchanged []string) {
-	for _, name := range chartOrder {
-		if chartHasChanges(grouped[name]) {
-			changed = append(changed, name)
-		} else {
-			unchanged = append(unchanged, name)
-		}
+	out, err = defaultTemplates.render(name, data)
+	if err != nil {
+		return fmt.Sprintf("failed to render report: %s", err)
 	}
-	return changed, unchanged
+	return out
 }
 
-func buildSummary(chartOrder, changedCharts, unchangedCharts []string) string {
-	return fmt.Sprintf("Analyzed %d chart(s): %d with changes, %d unchanged",
-		len(chartOrder), len(changedCharts), len(unchangedCharts))
-}
+// templatesFor returns the templates to use for a PR: overrides committed to
+// the repository (read at the base ref, so a PR can't restyle its own report)
+// layered over the service templates. Lookups are cached briefly because a
+// single run renders several reports.
+func (a *Adapter) templatesFor(ctx context.Context, pr domain.PRContext) *Templates {
+	if a.repoTemplatePath == "" || a.client == nil {
+		return a.templates
+	}
 
-func buildCheckRunText(grouped map[string][]domain.DiffResult, changedCharts, unchangedCharts []string) string {
-	var sb strings.Builder
-	formatChangedCharts(&sb, grouped, changedCharts)
-	formatUnchangedCharts(&sb, unchangedCharts)
-	return truncateIfNeeded(sb.String())
-}
+	logger := slog.New(slog.NewTextHandler(os.Stderr, nil))
+	key := pr.Owner + "/" + pr.Repo + "@" + pr.BaseRef
 
-func formatChangedCharts(sb *strings.Builder, grouped map[string][]domain.DiffResult, changedCharts []string) {
-	for _, chartName := range changedCharts {
-		fmt.Fprintf(sb, "## %s\n\n", chartName)
-		for _, r := range grouped[chartName] {
-			formatEnvironmentResult(sb, r)
-		}
+	a.templateCacheMu.Lock()
+	cached, ok := a.templateCache[key]
+	a.templateCacheMu.Unlock()
+	if ok && time.Since(cached.fetchedAt) < repoTemplateCacheTTL {
+		return cached.templates
 	}
-}
-
-func formatEnvironmentResult(sb *strings.Builder, r domain.DiffResult) {
-	statusLabel := getStatusLabel(r.Status)
-	fmt.Fprintf(sb, "<details><summary>%s — %s</summary>\n\n", r.Environment, statusLabel)
 
-	switch {
-	case r.Status == domain.StatusError:
-		fmt.Fprintf(sb, "%s\n", r.Summary)
-	case r.UnifiedDiff == "" && r.SemanticDiff == "":
-		sb.WriteString("No changes detected.\n")
-	default:
-		formatDiffs(sb, r)
+	tpl := a.templates
+	overrides, err := a.fetchRepoTemplates(ctx, pr)
+	if err != nil {
+		logger.Warn("failed to fetch repo report templates, using service templates", "error", err)
+	} else if withRepo, err := a.templates.WithOverrides(overrides); err != nil {
+		logger.Warn("invalid repo report templates, using service templates", "error", err)
+	} else {
+		tpl = withRepo
 	}
 
-	sb.WriteString("\n</details>\n\n")
-}
+	a.templateCacheMu.Lock()
+	a.templateCache[key] = cachedTemplates{templates: tpl, fetchedAt: time.Now()}
+	a.templateCacheMu.Unlock()
 
-func getStatusLabel(status domain.Status) string {
-	switch status {
-	case domain.StatusError:
-		return "Error"
-	case domain.StatusChanges:
-		return "Changed"
-	case domain.StatusSuccess:
-		return "No Changes"
-	default:
-		return "Unknown"
-	}
+	return tpl
 }
 
-func formatDiffs(sb *strings.Builder, r domain.DiffResult) {
-	if r.SemanticDiff != "" {
-		sb.WriteString("**Semantic Diff (dyff):**\n")
-		fmt.Fprintf(sb, "```diff\n%s\n```\n\n", r.SemanticDiff)
+// fetchRepoTemplates reads known template files from the repository's
+// template directory at the PR's base ref. A missing directory is not an error.
+func (a *Adapter) fetchRepoTemplates(ctx context.Context, pr domain.PRContext) (map[string]string, error) {
+	opts := &gogithub.RepositoryContentGetOptions{Ref: pr.BaseRef}
+	_, entries, _, err := a.client.Repositories.GetContents(ctx, pr.Owner, pr.Repo, a.repoTemplatePath, opts)
+	if err != nil {
+		var ghErr *gogithub.ErrorResponse
+		if errors.As(err, &ghErr) && ghErr.Response != nil && ghErr.Response.StatusCode == http.StatusNotFound {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("listing %s: %w", a.repoTemplatePath, err)
 	}
-	if r.UnifiedDiff != "" {
-		sb.WriteString("**Unified Diff (line-based):**\n")
-		fmt.Fprintf(sb, "```diff\n%s\n```\n", r.UnifiedDiff)
+
+	overrides := make(map[string]string)
+	for _, entry := range entries {
+		if entry.GetType() != "file" || !isTemplateName(entry.GetName()) {
+			continue
+		}
+		file, _, _, err := a.client.Repositories.GetContents(ctx, pr.Owner, pr.Repo, entry.GetPath(), opts)
+		if err != nil {
+			return nil, fmt.Errorf("fetching %s: %w", entry.GetPath(), err)
+		}
+		content, err := file.GetContent()
+		if err != nil {
+			return nil, fmt.Errorf("decoding %s: %w", entry.GetPath(), err)
+		}
+		overrides[entry.GetName()] = content
 	}
+	return overrides, nil
 }
 
-func formatUnchangedCharts(sb *strings.Builder, unchangedCharts []string) {
-	if len(unchangedCharts) == 0 {
-		return
+// detailsURL returns the check run details URL. GitHub requires one for
+// action_required; the app URL is preferred, falling back to the PR itself.
+func (a *Adapter) detailsURL(pr domain.PRContext, conclusion string) *string {
+	if conclusion != ConclusionActionRequired {
+		return nil
 	}
-
-	sb.WriteString("## Unchanged charts\n\n")
-	sb.WriteString("The following charts were analyzed and had no changes across all environments:\n\n")
-	for _, name := range unchangedCharts {
-		fmt.Fprintf(sb, "- `%s`\n", name)
+	if a.appURL != "" {
+		return gogithub.Ptr(a.appURL)
 	}
-	sb.WriteString("\n")
+	return gogithub.Ptr(fmt.Sprintf("https://github.com/%s/%s/pull/%d", pr.Owner, pr.Repo, pr.PRNumber))
 }
 
 func truncateIfNeeded(text string) string {
@@ -302,73 +728,155 @@ func chartHasChanges(results []domain.DiffResult) bool {
 	return false
 }
 
-// FormatPRComment formats a PR comment body for a single chart's diff results.
-// Exported for use in integration tests.
+// FormatPRComment formats a PR comment body for a single chart's diff results
+// using the service templates. Exported for use in integration tests.
 func (a *Adapter) FormatPRComment(results []domain.DiffResult) string {
+	return a.formatPRComment(a.templates, results)
+}
+
+func (a *Adapter) formatPRComment(tpl *Templates, results []domain.DiffResult) string {
 	if len(results) == 0 {
 		return ""
 	}
 
-	chartName := results[0].ChartName
 	var sb strings.Builder
 
-	// Hidden marker for identifying this comment (for deletion on updates)
-	fmt.Fprintf(&sb, "<!-- %s: %s -->\n", a.appName, chartName)
+	// Hidden marker for identifying this comment (for in-place updates)
+	fmt.Fprintf(&sb, "%s\n", a.chartMarker(results[0].ChartKey()))
+	sb.WriteString(a.formatChartSection(tpl, results))
+	sb.WriteString(a.formatFooter(tpl))
 
-	// Header
-	fmt.Fprintf(&sb, "## 📊 Helm Diff Report: `%s`\n\n", chartName)
+	return sb.String()
+}
 
-	// Summary counts
-	_, changes, errorCount := domain.CountByStatus(results)
+// formatChartSection renders the report for one chart: header, status line,
+// environment table, and collapsible diffs. Shared by per-chart and
+// consolidated comments.
+func (a *Adapter) formatChartSection(tpl *Templates, results []domain.DiffResult) string {
+	return a.renderTemplate(tpl, TemplatePRComment, newChartReport(results))
+}
 
-	switch {
-	case errorCount > 0:
-		sb.WriteString("❌ **Status:** Failed to analyze chart\n\n")
-	case changes > 0:
-		fmt.Fprintf(&sb, "✅ **Status:** Analysis complete — %d environment(s) with changes\n\n", changes)
-	default:
-		sb.WriteString("✅ **Status:** Analysis complete — No changes detected\n\n")
+// formatConsolidatedComment renders the single PR comment used in consolidated
+// mode. Each chart section is wrapped in start/end markers so later runs can
+// replace or remove it without re-rendering the other charts.
+func (a *Adapter) formatConsolidatedComment(tpl *Templates, sections []chartSection) string {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "%s\n", a.consolidatedMarker())
+	for _, sec := range sections {
+		fmt.Fprintf(&sb, "%s\n", a.sectionStartMarker(sec.chart))
+		sb.WriteString(sec.body)
+		fmt.Fprintf(&sb, "%s\n\n", a.sectionEndMarker(sec.chart))
 	}
+	sb.WriteString(a.formatFooter(tpl))
+	return sb.String()
+}
 
-	// Environment details table
-	sb.WriteString("| Environment | Status |\n")
-	sb.WriteString("|-------------|--------|\n")
-	for _, r := range results {
-		var statusLabel string
-		switch r.Status {
-		case domain.StatusError:
-			statusLabel = "❌ Error"
-		case domain.StatusChanges:
-			statusLabel = "📝 Changed"
-		case domain.StatusSuccess:
-			statusLabel = "✅ No changes"
+// formatResolvedComment renders the body that replaces a comment once its
+// chart (or, for the consolidated comment, every chart) no longer has changes.
+func (a *Adapter) formatResolvedComment(tpl *Templates, marker, chart string, pr domain.PRContext) string {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "%s\n%s\n", marker, a.resolvedMarker())
+	sb.WriteString(a.renderTemplate(tpl, TemplateResolvedComment, ResolvedCommentData{
+		AppName: a.appName,
+		PR:      pr,
+		Chart:   chart,
+		HeadSHA: shortSHA(pr.HeadSHA),
+	}))
+	sb.WriteString("\n")
+	sb.WriteString(a.formatFooter(tpl))
+	return sb.String()
+}
+
+func (a *Adapter) formatFooter(tpl *Templates) string {
+	return a.renderTemplate(tpl, TemplateFooter, FooterData{AppName: a.appName, AppURL: a.appURL})
+}
+
+// chartSection is one chart's block within the consolidated comment.
+type chartSection struct {
+	chart string
+	body  string
+}
+
+// parseSections extracts chart sections from a consolidated comment body,
+// preserving their order.
+func (a *Adapter) parseSections(body string) []chartSection {
+	var sections []chartSection
+	startPrefix := fmt.Sprintf("<!-- %s:section ", a.appName)
+
+	rest := body
+	for {
+		i := strings.Index(rest, startPrefix)
+		if i < 0 {
+			break
 		}
-		fmt.Fprintf(&sb, "| `%s` | %s |\n", r.Environment, statusLabel)
+		rest = rest[i+len(startPrefix):]
+		nameEnd := strings.Index(rest, " -->\n")
+		if nameEnd < 0 {
+			break
+		}
+		chart := rest[:nameEnd]
+		rest = rest[nameEnd+len(" -->\n"):]
+
+		end := strings.Index(rest, a.sectionEndMarker(chart))
+		if end < 0 {
+			break
+		}
+		sections = append(sections, chartSection{chart: chart, body: rest[:end]})
+		rest = rest[end:]
 	}
-	sb.WriteString("\n")
+	return sections
+}
 
-	// Detailed diffs per environment - prefer semantic diff in PR comments
-	for _, r := range results {
-		switch r.Status {
-		case domain.StatusError:
-			fmt.Fprintf(&sb, "<details>\n<summary><b>%s</b> — Error details</summary>\n\n", r.Environment)
-			fmt.Fprintf(&sb, "%s\n\n", r.Summary)
-			sb.WriteString("</details>\n\n")
-		case domain.StatusChanges:
-			fmt.Fprintf(&sb, "<details>\n<summary><b>%s</b> — View diff</summary>\n\n", r.Environment)
-			fmt.Fprintf(&sb, "```diff\n%s\n```\n\n", r.PreferredDiff())
-			sb.WriteString("</details>\n\n")
-		case domain.StatusSuccess:
-			// Skip environments with no changes (already shown in table)
+// upsertSection replaces the section for sec.chart in place, or appends it.
+func upsertSection(sections []chartSection, sec chartSection) []chartSection {
+	for i := range sections {
+		if sections[i].chart == sec.chart {
+			sections[i] = sec
+			return sections
 		}
 	}
+	return append(sections, sec)
+}
 
-	sb.WriteString("---\n")
-	if a.appURL != "" {
-		fmt.Fprintf(&sb, "_Posted by [%s](%s)_\n", a.appName, a.appURL)
-	} else {
-		fmt.Fprintf(&sb, "_Posted by %s_\n", a.appName)
+// removeSection returns sections without the given chart.
+func removeSection(sections []chartSection, chart string) []chartSection {
+	out := make([]chartSection, 0, len(sections))
+	for _, sec := range sections {
+		if sec.chart != chart {
+			out = append(out, sec)
+		}
 	}
+	return out
+}
 
-	return sb.String()
+func (a *Adapter) chartMarker(chart string) string {
+	return fmt.Sprintf("<!-- %s: %s -->", a.appName, chart)
+}
+
+func (a *Adapter) consolidatedMarker() string {
+	return fmt.Sprintf("<!-- %s -->", a.appName)
+}
+
+func (a *Adapter) sectionStartMarker(chart string) string {
+	return fmt.Sprintf("<!-- %s:section %s -->", a.appName, chart)
+}
+
+func (a *Adapter) sectionEndMarker(chart string) string {
+	return fmt.Sprintf("<!-- %s:end %s -->", a.appName, chart)
+}
+
+func (a *Adapter) resolvedMarker() string {
+	return fmt.Sprintf("<!-- %s:resolved -->", a.appName)
+}
+
+// isResolved reports whether a comment body was written by resolveComment.
+func (a *Adapter) isResolved(body string) bool {
+	return strings.Contains(body, a.resolvedMarker())
+}
+
+func shortSHA(sha string) string {
+	if len(sha) > 7 {
+		return sha[:7]
+	}
+	return sha
 }
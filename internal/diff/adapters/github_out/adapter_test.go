@@ -0,0 +1,149 @@
+package githubout
+
+import (
+	"slices"
+	"strings"
+	"testing"
+
+	gogithub "github.com/google/go-github/v68/github"
+
+	"github.com/nathantilsley/chart-val/internal/diff/domain"
+)
+
+func TestConsolidatedComment_RoundTrip(t *testing.T) {
+	a := New(nil, "chart-val", "", Options{CommentMode: CommentModeConsolidated})
+
+	appA := a.formatChartSection(a.templates, []domain.DiffResult{
+		{ChartName: "app-a", Environment: "prod", Status: domain.StatusChanges, UnifiedDiff: "-a\n+b"},
+	})
+	appB := a.formatChartSection(a.templates, []domain.DiffResult{
+		{ChartName: "app-b", Environment: "dev", Status: domain.StatusError, Summary: "boom"},
+	})
+
+	body := a.formatConsolidatedComment(a.templates, []chartSection{{chart: "app-a", body: appA}, {chart: "app-b", body: appB}})
+
+	if !strings.HasPrefix(body, a.consolidatedMarker()+"\n") {
+		t.Fatalf("consolidated comment should start with marker, got:\n%s", body)
+	}
+
+	sections := a.parseSections(body)
+	if len(sections) != 2 {
+		t.Fatalf("parseSections() returned %d sections, want 2", len(sections))
+	}
+	if sections[0].chart != "app-a" || sections[0].body != appA {
+		t.Errorf("section 0 = %q, want app-a with original body", sections[0].chart)
+	}
+	if sections[1].chart != "app-b" || sections[1].body != appB {
+		t.Errorf("section 1 = %q, want app-b with original body", sections[1].chart)
+	}
+
+	// Re-rendering the parsed sections must be stable
+	if got := a.formatConsolidatedComment(a.templates, sections); got != body {
+		t.Errorf("re-rendered body differs:\n--- want ---\n%s\n--- got ---\n%s", body, got)
+	}
+}
+
+func TestUpsertAndRemoveSection(t *testing.T) {
+	sections := []chartSection{{chart: "app-a", body: "old"}, {chart: "app-b", body: "b"}}
+
+	sections = upsertSection(sections, chartSection{chart: "app-a", body: "new"})
+	if len(sections) != 2 || sections[0].body != "new" {
+		t.Errorf("upsertSection() should replace in place, got %+v", sections)
+	}
+
+	sections = upsertSection(sections, chartSection{chart: "app-c", body: "c"})
+	if len(sections) != 3 || sections[2].chart != "app-c" {
+		t.Errorf("upsertSection() should append new chart, got %+v", sections)
+	}
+
+	sections = removeSection(sections, "app-b")
+	if len(sections) != 2 || sections[0].chart != "app-a" || sections[1].chart != "app-c" {
+		t.Errorf("removeSection() = %+v, want app-a, app-c", sections)
+	}
+}
+
+func TestFormatResolvedComment(t *testing.T) {
+	a := New(nil, "chart-val", "", Options{})
+	pr := domain.PRContext{HeadSHA: "0123456789abcdef"}
+
+	body := a.formatResolvedComment(a.templates, a.chartMarker("my-app"), "my-app", pr)
+
+	if !strings.HasPrefix(body, "<!-- chart-val: my-app -->\n") {
+		t.Errorf("resolved comment must keep the chart marker first, got:\n%s", body)
+	}
+	if !a.isResolved(body) {
+		t.Error("isResolved() = false for resolved body")
+	}
+	if !strings.Contains(body, "`0123456`") {
+		t.Errorf("resolved comment should reference short head SHA, got:\n%s", body)
+	}
+	if a.isResolved(a.FormatPRComment([]domain.DiffResult{{ChartName: "my-app", Status: domain.StatusChanges}})) {
+		t.Error("isResolved() = true for a regular comment")
+	}
+}
+
+func TestCommentedCharts(t *testing.T) {
+	comment := func(body string) *gogithub.IssueComment { return &gogithub.IssueComment{Body: gogithub.Ptr(body)} }
+
+	perChart := New(nil, "chart-val", "", Options{})
+	pr := domain.PRContext{HeadSHA: "0123456789abcdef"}
+	comments := []*gogithub.IssueComment{
+		comment("Looks good to me"),
+		comment(perChart.FormatPRComment([]domain.DiffResult{{ChartName: "app-a", Status: domain.StatusChanges}})),
+		comment(perChart.formatResolvedComment(perChart.templates, perChart.chartMarker("app-b"), "app-b", pr)),
+		comment(perChart.FormatPRComment([]domain.DiffResult{{ChartName: "app-c", Status: domain.StatusChanges}})),
+	}
+	if got, want := perChart.commentedCharts(comments), []string{"app-a", "app-c"}; !slices.Equal(got, want) {
+		t.Errorf("commentedCharts() = %v, want %v (resolved comments skipped)", got, want)
+	}
+
+	consolidated := New(nil, "chart-val", "", Options{CommentMode: CommentModeConsolidated})
+	body := consolidated.formatConsolidatedComment(consolidated.templates, []chartSection{
+		{chart: "app-a", body: "a"}, {chart: "app-b", body: "b"},
+	})
+	if got, want := consolidated.commentedCharts(append(comments, comment(body))), []string{"app-a", "app-b"}; !slices.Equal(got, want) {
+		t.Errorf("consolidated commentedCharts() = %v, want %v", got, want)
+	}
+}
+
+func TestCheckRunName(t *testing.T) {
+	tests := []struct {
+		template string
+		want     string
+	}{
+		{"{app} / {chart} / {env}", "chart-val / my-app / prod"},
+		{"{app} / {env}", "chart-val / prod"},
+		{"helm-diff {chart}", "helm-diff my-app"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.template, func(t *testing.T) {
+			a := New(nil, "chart-val", "", Options{CheckRunNameTemplate: tt.template})
+			got := a.checkRunName(domain.DiffResult{ChartName: "my-app", Environment: "prod"})
+			if got != tt.want {
+				t.Errorf("checkRunName() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSkippedCheckRuns(t *testing.T) {
+	a := New(nil, "chart-val", "", Options{
+		CheckRunNameTemplate: "{app} / {chart} / {env}",
+		RequiredCheckRuns:    []string{"chart-val / api / prod", "chart-val / web / prod"},
+	})
+	pr := domain.PRContext{HeadSHA: "abc123"}
+	rendered := map[string][]domain.DiffResult{
+		"chart-val / web / prod": {{ChartName: "web", Environment: "prod", Status: domain.StatusChanges}},
+		"chart-val / web / dev":  {{ChartName: "web", Environment: "dev", Status: domain.StatusSuccess}},
+	}
+
+	got := a.skippedCheckRuns(pr, rendered)
+	if len(got) != 1 {
+		t.Fatalf("skippedCheckRuns() returned %d check runs, want 1: %+v", len(got), got)
+	}
+	if got[0].Name != "chart-val / api / prod" || got[0].GetConclusion() != "skipped" ||
+		got[0].GetStatus() != "completed" || got[0].HeadSHA != "abc123" {
+		t.Errorf("skipped check run = %+v, want a completed, skipped chart-val / api / prod", got[0])
+	}
+}
@@ -0,0 +1,169 @@
+package githubout
+
+import (
+	"fmt"
+	"os"
+	"path"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/nathantilsley/chart-val/internal/diff/domain"
+)
+
+// Check run conclusions supported by conclusion policies, in increasing
+// order of severity. The overall conclusion is the most severe one produced
+// by any result.
+const (
+	ConclusionSuccess        = "success"
+	ConclusionNeutral        = "neutral"
+	ConclusionActionRequired = "action_required"
+	ConclusionFailure        = "failure"
+)
+
+var conclusionSeverity = map[string]int{
+	ConclusionSuccess:        0,
+	ConclusionNeutral:        1,
+	ConclusionActionRequired: 2,
+	ConclusionFailure:        3,
+}
+
+// ConclusionPolicy maps diff outcomes to check run conclusions. A result's
+// primary outcome and, when it has validation warnings, the warning outcome
+// are each concluded separately: the first rule for that outcome wins, then
+// Defaults, then the built-in defaults (errors fail, everything else
+// succeeds). The result's conclusion is the more severe of the two, and the
+// overall conclusion the most severe across results, so a lenient rule never
+// hides a more severe outcome regardless of rule order.
+type ConclusionPolicy struct {
+	Defaults map[domain.Outcome]string `yaml:"defaults"`
+	Rules    []ConclusionRule          `yaml:"rules"`
+}
+
+// ConclusionRule maps one outcome to a conclusion, optionally restricted to
+// repositories and environments. Repo and Environment are path.Match globs
+// (e.g. "my-org/*", "prod-*"); empty matches everything.
+type ConclusionRule struct {
+	Repo        string         `yaml:"repo"`
+	Environment string         `yaml:"environment"`
+	Outcome     domain.Outcome `yaml:"outcome"`
+	Conclusion  string         `yaml:"conclusion"`
+	Note        string         `yaml:"note"` // Shown in the check run summary when the rule applies
+}
+
+// LoadConclusionPolicy reads a conclusion policy from a YAML file.
+func LoadConclusionPolicy(file string) (ConclusionPolicy, error) {
+	//nolint:gosec // G304: path is from trusted config, not user input
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return ConclusionPolicy{}, fmt.Errorf("reading conclusion policy: %w", err)
+	}
+	return ParseConclusionPolicy(data)
+}
+
+// ParseConclusionPolicy parses and validates a YAML conclusion policy.
+func ParseConclusionPolicy(data []byte) (ConclusionPolicy, error) {
+	var p ConclusionPolicy
+	if err := yaml.Unmarshal(data, &p); err != nil {
+		return ConclusionPolicy{}, fmt.Errorf("parsing conclusion policy: %w", err)
+	}
+	if err := p.validate(); err != nil {
+		return ConclusionPolicy{}, err
+	}
+	return p, nil
+}
+
+func (p ConclusionPolicy) validate() error {
+	for outcome, conclusion := range p.Defaults {
+		if err := validateOutcome(outcome); err != nil {
+			return fmt.Errorf("defaults: %w", err)
+		}
+		if _, ok := conclusionSeverity[conclusion]; !ok {
+			return fmt.Errorf("defaults: unknown conclusion %q", conclusion)
+		}
+	}
+	for i, r := range p.Rules {
+		if err := validateOutcome(r.Outcome); err != nil {
+			return fmt.Errorf("rule %d: %w", i, err)
+		}
+		if _, ok := conclusionSeverity[r.Conclusion]; !ok {
+			return fmt.Errorf("rule %d: unknown conclusion %q", i, r.Conclusion)
+		}
+		for _, pattern := range []string{r.Repo, r.Environment} {
+			if _, err := path.Match(pattern, ""); err != nil {
+				return fmt.Errorf("rule %d: invalid pattern %q: %w", i, pattern, err)
+			}
+		}
+	}
+	return nil
+}
+
+func validateOutcome(o domain.Outcome) error {
+	switch o {
+	case domain.OutcomeNoChanges, domain.OutcomeChanges, domain.OutcomeError,
+		domain.OutcomeBaseOnly, domain.OutcomeWarning:
+		return nil
+	default:
+		return fmt.Errorf("unknown outcome %q", o)
+	}
+}
+
+// Evaluate returns the overall conclusion for a set of results along with
+// the notes of every rule that applied (deduplicated, in rule order).
+func (p ConclusionPolicy) Evaluate(pr domain.PRContext, results []domain.DiffResult) (string, []string) {
+	conclusion := ConclusionSuccess
+	applied := make(map[int]bool)
+
+	for _, r := range results {
+		outcomes := []domain.Outcome{r.Outcome()}
+		if len(r.Warnings) > 0 {
+			outcomes = append(outcomes, domain.OutcomeWarning)
+		}
+		for _, outcome := range outcomes {
+			c, ruleIdx := p.conclusionFor(pr, r.Environment, outcome)
+			if conclusionSeverity[c] > conclusionSeverity[conclusion] {
+				conclusion = c
+			}
+			if ruleIdx >= 0 {
+				applied[ruleIdx] = true
+			}
+		}
+	}
+
+	var notes []string
+	seen := make(map[string]bool)
+	for i, rule := range p.Rules {
+		if applied[i] && rule.Note != "" && !seen[rule.Note] {
+			seen[rule.Note] = true
+			notes = append(notes, rule.Note)
+		}
+	}
+	return conclusion, notes
+}
+
+// conclusionFor returns the conclusion for one outcome of a result in env
+// and the index of the rule that produced it (-1 when a default was used).
+func (p ConclusionPolicy) conclusionFor(pr domain.PRContext, env string, outcome domain.Outcome) (string, int) {
+	repo := pr.Owner + "/" + pr.Repo
+
+	for i, rule := range p.Rules {
+		if rule.Outcome == outcome && globMatch(rule.Repo, repo) && globMatch(rule.Environment, env) {
+			return rule.Conclusion, i
+		}
+	}
+
+	if c, ok := p.Defaults[outcome]; ok {
+		return c, -1
+	}
+	if outcome == domain.OutcomeError {
+		return ConclusionFailure, -1
+	}
+	return ConclusionSuccess, -1
+}
+
+func globMatch(pattern, value string) bool {
+	if pattern == "" {
+		return true
+	}
+	ok, err := path.Match(pattern, value)
+	return err == nil && ok
+}
@@ -0,0 +1,177 @@
+package githubout
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/nathantilsley/chart-val/internal/diff/domain"
+)
+
+const testPolicy = `
+defaults:
+  base-only: neutral
+rules:
+  - outcome: warning
+    conclusion: success
+    note: Rendered with warnings.
+  - environment: "prod*"
+    outcome: changes
+    conclusion: action_required
+    note: Production manifests change.
+  - repo: "my-org/*"
+    environment: dev
+    outcome: error
+    conclusion: neutral
+`
+
+func TestConclusionPolicy_Evaluate(t *testing.T) {
+	policy, err := ParseConclusionPolicy([]byte(testPolicy))
+	if err != nil {
+		t.Fatalf("ParseConclusionPolicy() error = %v", err)
+	}
+
+	myOrg := domain.PRContext{Owner: "my-org", Repo: "charts"}
+	other := domain.PRContext{Owner: "other-org", Repo: "charts"}
+
+	tests := []struct {
+		name      string
+		pr        domain.PRContext
+		results   []domain.DiffResult
+		want      string
+		wantNotes []string
+	}{
+		{
+			name:    "no changes succeeds",
+			pr:      myOrg,
+			results: []domain.DiffResult{{Environment: "prod", Status: domain.StatusSuccess}},
+			want:    ConclusionSuccess,
+		},
+		{
+			name:      "prod changes require action",
+			pr:        myOrg,
+			results:   []domain.DiffResult{{Environment: "prod-eu", Status: domain.StatusChanges}},
+			want:      ConclusionActionRequired,
+			wantNotes: []string{"Production manifests change."},
+		},
+		{
+			name:    "dev changes succeed",
+			pr:      myOrg,
+			results: []domain.DiffResult{{Environment: "dev", Status: domain.StatusChanges}},
+			want:    ConclusionSuccess,
+		},
+		{
+			name:    "dev error is neutral for matching repo",
+			pr:      myOrg,
+			results: []domain.DiffResult{{Environment: "dev", Status: domain.StatusError}},
+			want:    ConclusionNeutral,
+		},
+		{
+			name:    "dev error fails for other repos",
+			pr:      other,
+			results: []domain.DiffResult{{Environment: "dev", Status: domain.StatusError}},
+			want:    ConclusionFailure,
+		},
+		{
+			name:    "base-only uses defaults",
+			pr:      myOrg,
+			results: []domain.DiffResult{{Environment: "base", Status: domain.StatusSuccess, BaseOnly: true}},
+			want:    ConclusionNeutral,
+		},
+		{
+			name: "warnings succeed with note",
+			pr:   myOrg,
+			results: []domain.DiffResult{
+				{Environment: "staging", Status: domain.StatusSuccess, Warnings: []string{"empty"}},
+			},
+			want:      ConclusionSuccess,
+			wantNotes: []string{"Rendered with warnings."},
+		},
+		{
+			name: "warning rule does not hide prod changes",
+			pr:   myOrg,
+			results: []domain.DiffResult{
+				{Environment: "prod", Status: domain.StatusChanges, Warnings: []string{"deprecated API"}},
+			},
+			want:      ConclusionActionRequired,
+			wantNotes: []string{"Rendered with warnings.", "Production manifests change."},
+		},
+		{
+			name: "warning rule does not hide errors",
+			pr:   other,
+			results: []domain.DiffResult{
+				{Environment: "staging", Status: domain.StatusError, Warnings: []string{"empty"}},
+			},
+			want:      ConclusionFailure,
+			wantNotes: []string{"Rendered with warnings."},
+		},
+		{
+			name: "lenient dev error does not hide prod changes",
+			pr:   myOrg,
+			results: []domain.DiffResult{
+				{Environment: "dev", Status: domain.StatusError},
+				{Environment: "prod", Status: domain.StatusChanges, Warnings: []string{"empty"}},
+			},
+			want:      ConclusionActionRequired,
+			wantNotes: []string{"Rendered with warnings.", "Production manifests change."},
+		},
+		{
+			name: "most severe conclusion wins",
+			pr:   myOrg,
+			results: []domain.DiffResult{
+				{Environment: "dev", Status: domain.StatusError},
+				{Environment: "prod", Status: domain.StatusChanges},
+				{Environment: "staging", Status: domain.StatusError},
+			},
+			want:      ConclusionFailure,
+			wantNotes: []string{"Production manifests change."},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, notes := policy.Evaluate(tt.pr, tt.results)
+			if got != tt.want {
+				t.Errorf("Evaluate() conclusion = %q, want %q", got, tt.want)
+			}
+			if strings.Join(notes, "|") != strings.Join(tt.wantNotes, "|") {
+				t.Errorf("Evaluate() notes = %v, want %v", notes, tt.wantNotes)
+			}
+		})
+	}
+}
+
+func TestConclusionPolicy_ZeroValue(t *testing.T) {
+	var policy ConclusionPolicy
+
+	got, _ := policy.Evaluate(domain.PRContext{}, []domain.DiffResult{{Status: domain.StatusChanges}})
+	if got != ConclusionSuccess {
+		t.Errorf("zero policy with changes = %q, want success", got)
+	}
+
+	got, _ = policy.Evaluate(domain.PRContext{}, []domain.DiffResult{{Status: domain.StatusError}})
+	if got != ConclusionFailure {
+		t.Errorf("zero policy with error = %q, want failure", got)
+	}
+}
+
+func TestParseConclusionPolicy_Invalid(t *testing.T) {
+	tests := []struct {
+		name        string
+		yaml        string
+		errContains string
+	}{
+		{"unknown outcome", "rules:\n  - outcome: exploded\n    conclusion: failure\n", "unknown outcome"},
+		{"unknown conclusion", "rules:\n  - outcome: error\n    conclusion: maybe\n", "unknown conclusion"},
+		{"bad default", "defaults:\n  error: nope\n", "unknown conclusion"},
+		{"bad glob", "rules:\n  - outcome: error\n    conclusion: failure\n    environment: \"[\"\n", "invalid pattern"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := ParseConclusionPolicy([]byte(tt.yaml))
+			if err == nil || !strings.Contains(err.Error(), tt.errContains) {
+				t.Errorf("ParseConclusionPolicy() error = %v, want containing %q", err, tt.errContains)
+			}
+		})
+	}
+}
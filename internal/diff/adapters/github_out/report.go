@@ -0,0 +1,244 @@
+package githubout
+
+import (
+	"bytes"
+	"embed"
+	"errors"
+	"fmt"
+	"io/fs"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"text/template"
+
+	"github.com/nathantilsley/chart-val/internal/diff/domain"
+)
+
+// Template file names. Overrides (from REPORT_TEMPLATES_DIR or the repo's
+// template directory) must use the same names; missing files keep the default.
+const (
+	TemplateCheckRunSummary = "check-run-summary.md.tmpl"
+	TemplateCheckRunText    = "check-run-text.md.tmpl"
+	TemplatePRComment       = "pr-comment.md.tmpl"
+	TemplateFooter          = "footer.md.tmpl"
+	TemplateResolvedComment = "resolved-comment.md.tmpl"
+)
+
+// templateNames lists every template an override set may replace.
+var templateNames = []string{
+	TemplateCheckRunSummary, TemplateCheckRunText, TemplatePRComment, TemplateFooter, TemplateResolvedComment,
+}
+
+//go:embed templates/*.md.tmpl
+var defaultTemplateFS embed.FS
+
+// ReportData is the data model passed to the check run templates
+// (check-run-summary.md.tmpl and check-run-text.md.tmpl).
+type ReportData struct {
+	AppName         string
+	AppURL          string
+	RunURL          string // Run history web UI page for this commit; empty when not linked
+	PR              domain.PRContext
+	Conclusion      string          // Check run conclusion chosen by the conclusion policy
+	Notes           []string        // Notes from conclusion policy rules that applied
+	Charts          []ChartReport   // Every chart, in processing order
+	ChangedCharts   []ChartReport   // Charts with at least one changed or failed environment
+	UnchangedCharts []ChartReport   // Charts with no changes in any environment
+	Warnings        []WarningReport // Validation warnings across all results
+}
+
+// ChartReport is one chart's results. It is the data passed to
+// pr-comment.md.tmpl and appears in ReportData's chart lists.
+type ChartReport struct {
+	Name        string
+	Results     []domain.DiffResult // One per environment
+	Changes     int                 // Environments with StatusChanges
+	Errors      int                 // Environments with StatusError
+	AffectedVia []string            // Changed dependency charts the chart was included for; empty if it changed itself
+}
+
+// WarningReport is a single validation warning attached to a result.
+type WarningReport struct {
+	Chart       string
+	Environment string
+	Message     string
+}
+
+// FooterData is the data passed to footer.md.tmpl.
+type FooterData struct {
+	AppName string
+	AppURL  string
+}
+
+// ResolvedCommentData is the data passed to resolved-comment.md.tmpl, which
+// replaces a comment once its chart no longer has changes.
+type ResolvedCommentData struct {
+	AppName string
+	PR      domain.PRContext
+	Chart   string // Resolved chart's path (name for gitops deployments); empty for the consolidated comment
+	HeadSHA string // Short SHA of the commit the changes are gone as of
+}
+
+// templateFuncs are available to every template.
+var templateFuncs = template.FuncMap{
+	"statusLabel":        statusLabel,
+	"commentStatusLabel": commentStatusLabel,
+	"isError":            func(r domain.DiffResult) bool { return r.Status == domain.StatusError },
+	"isChanged":          func(r domain.DiffResult) bool { return r.Status == domain.StatusChanges },
+	"hasDiff":            func(r domain.DiffResult) bool { return r.UnifiedDiff != "" || r.SemanticDiff != "" },
+	"codeList":           codeList,
+}
+
+// Templates is a parsed set of report templates.
+type Templates struct {
+	set *template.Template
+}
+
+// DefaultTemplates returns the embedded default templates, which reproduce
+// the built-in report layout.
+func DefaultTemplates() *Templates {
+	set := template.Must(template.New("reports").Funcs(templateFuncs).ParseFS(defaultTemplateFS, "templates/*.md.tmpl"))
+	return &Templates{set: set}
+}
+
+// LoadTemplates returns the default templates overlaid with any template
+// files found in dir.
+func LoadTemplates(dir string) (*Templates, error) {
+	overrides := make(map[string]string)
+	for _, name := range templateNames {
+		//nolint:gosec // G304: dir is from trusted config, not user input
+		data, err := os.ReadFile(filepath.Join(dir, name))
+		if err != nil {
+			if errors.Is(err, fs.ErrNotExist) {
+				continue
+			}
+			return nil, fmt.Errorf("reading template %s: %w", name, err)
+		}
+		overrides[name] = string(data)
+	}
+	return DefaultTemplates().WithOverrides(overrides)
+}
+
+// WithOverrides returns a copy of t with the named templates replaced.
+// Unknown names are rejected so typos don't silently fall back to defaults.
+func (t *Templates) WithOverrides(overrides map[string]string) (*Templates, error) {
+	if len(overrides) == 0 {
+		return t, nil
+	}
+
+	set, err := t.set.Clone()
+	if err != nil {
+		return nil, fmt.Errorf("cloning templates: %w", err)
+	}
+	for name, content := range overrides {
+		if !isTemplateName(name) {
+			return nil, fmt.Errorf("unknown template %q", name)
+		}
+		if _, err := set.New(name).Parse(content); err != nil {
+			return nil, fmt.Errorf("parsing template %s: %w", name, err)
+		}
+	}
+	return &Templates{set: set}, nil
+}
+
+func (t *Templates) render(name string, data any) (string, error) {
+	var buf bytes.Buffer
+	if err := t.set.ExecuteTemplate(&buf, name, data); err != nil {
+		return "", fmt.Errorf("rendering template %s: %w", name, err)
+	}
+	return buf.String(), nil
+}
+
+func isTemplateName(name string) bool {
+	for _, n := range templateNames {
+		if n == name {
+			return true
+		}
+	}
+	return false
+}
+
+// runHistoryURL links to the run history web UI's latest run for the PR's
+// head commit.
+func runHistoryURL(appURL string, pr domain.PRContext) string {
+	q := url.Values{}
+	q.Set("repo", pr.Owner+"/"+pr.Repo)
+	q.Set("sha", pr.HeadSHA)
+	return strings.TrimSuffix(appURL, "/") + "/ui/runs/latest?" + q.Encode()
+}
+
+// newReportData builds the check run data model from a set of results.
+func newReportData(appName, appURL string, pr domain.PRContext, results []domain.DiffResult) ReportData {
+	data := ReportData{AppName: appName, AppURL: appURL, PR: pr}
+
+	for _, group := range domain.GroupByChart(results) {
+		chart := newChartReport(group)
+		data.Charts = append(data.Charts, chart)
+		if chartHasChanges(group) {
+			data.ChangedCharts = append(data.ChangedCharts, chart)
+		} else {
+			data.UnchangedCharts = append(data.UnchangedCharts, chart)
+		}
+	}
+
+	for _, r := range results {
+		for _, w := range r.Warnings {
+			data.Warnings = append(data.Warnings, WarningReport{Chart: r.ChartName, Environment: r.Environment, Message: w})
+		}
+	}
+	return data
+}
+
+// newChartReport builds the data model for a single chart's results.
+func newChartReport(results []domain.DiffResult) ChartReport {
+	_, changes, errorCount := domain.CountByStatus(results)
+	return ChartReport{
+		Name:        results[0].ChartName,
+		Results:     results,
+		Changes:     changes,
+		Errors:      errorCount,
+		AffectedVia: results[0].AffectedVia,
+	}
+}
+
+// codeList formats names as inline code, e.g. "`common`, `base`".
+func codeList(names []string) string {
+	quoted := make([]string, len(names))
+	for i, n := range names {
+		quoted[i] = "`" + n + "`"
+	}
+	return strings.Join(quoted, ", ")
+}
+
+func statusLabel(r domain.DiffResult) string {
+	switch r.Status {
+	case domain.StatusError:
+		return "Error"
+	case domain.StatusChanges:
+		return "Changed"
+	case domain.StatusSuccess:
+		if r.InputsUnchanged {
+			return "Unchanged (inputs identical)"
+		}
+		return "No Changes"
+	default:
+		return "Unknown"
+	}
+}
+
+func commentStatusLabel(r domain.DiffResult) string {
+	switch r.Status {
+	case domain.StatusError:
+		return "❌ Error"
+	case domain.StatusChanges:
+		return "📝 Changed"
+	case domain.StatusSuccess:
+		if r.InputsUnchanged {
+			return "✅ Unchanged (inputs identical)"
+		}
+		return "✅ No changes"
+	default:
+		return ""
+	}
+}
@@ -0,0 +1,217 @@
+package githubout
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/nathantilsley/chart-val/internal/diff/domain"
+)
+
+func TestTemplates_WithOverrides(t *testing.T) {
+	tests := []struct {
+		name      string
+		overrides map[string]string
+		wantErr   string
+	}{
+		{
+			name:      "no overrides",
+			overrides: nil,
+		},
+		{
+			name:      "valid override",
+			overrides: map[string]string{TemplateFooter: "custom footer"},
+		},
+		{
+			name:      "unknown template",
+			overrides: map[string]string{"footer.tmpl": "x"},
+			wantErr:   `unknown template "footer.tmpl"`,
+		},
+		{
+			name:      "parse error",
+			overrides: map[string]string{TemplatePRComment: "{{ .Name "},
+			wantErr:   "parsing template pr-comment.md.tmpl",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := DefaultTemplates().WithOverrides(tt.overrides)
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Fatalf("WithOverrides() unexpected error: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Fatalf("WithOverrides() error = %v, want containing %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestTemplates_OverrideDoesNotAffectDefaults(t *testing.T) {
+	custom, err := DefaultTemplates().WithOverrides(map[string]string{TemplateFooter: "custom footer"})
+	if err != nil {
+		t.Fatalf("WithOverrides() error: %v", err)
+	}
+
+	a := New(nil, "chart-val", "", Options{Templates: custom})
+	if got := a.formatFooter(a.templates); got != "custom footer" {
+		t.Errorf("custom footer = %q, want %q", got, "custom footer")
+	}
+
+	d := New(nil, "chart-val", "", Options{})
+	if got := d.formatFooter(d.templates); !strings.Contains(got, "_Posted by chart-val_") {
+		t.Errorf("default footer changed after override: %q", got)
+	}
+}
+
+func TestTemplates_ResolvedCommentOverride(t *testing.T) {
+	custom, err := DefaultTemplates().WithOverrides(map[string]string{
+		TemplateResolvedComment: "{{ .Chart }} is clean as of {{ .HeadSHA }} in {{ .PR.Repo }}\n",
+	})
+	if err != nil {
+		t.Fatalf("WithOverrides() error: %v", err)
+	}
+
+	a := New(nil, "chart-val", "", Options{Templates: custom})
+	pr := domain.PRContext{Repo: "charts", HeadSHA: "0123456789abcdef"}
+	body := a.formatResolvedComment(a.templates, a.chartMarker("my-app"), "my-app", pr)
+
+	if !strings.HasPrefix(body, "<!-- chart-val: my-app -->\n") || !a.isResolved(body) {
+		t.Errorf("resolved comment must keep its markers, got:\n%s", body)
+	}
+	if !strings.Contains(body, "my-app is clean as of 0123456 in charts\n") {
+		t.Errorf("custom resolved comment template not used, got:\n%s", body)
+	}
+	if strings.Contains(body, "Helm Diff Report") {
+		t.Errorf("default resolved comment still rendered, got:\n%s", body)
+	}
+}
+
+func TestLoadTemplates(t *testing.T) {
+	dir := t.TempDir()
+	tpl := "{{ .Name }}: {{ range .Results }}{{ .Environment }}={{ statusLabel . }} {{ end }}"
+	if err := os.WriteFile(filepath.Join(dir, TemplatePRComment), []byte(tpl), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	templates, err := LoadTemplates(dir)
+	if err != nil {
+		t.Fatalf("LoadTemplates() error: %v", err)
+	}
+
+	a := New(nil, "chart-val", "", Options{Templates: templates})
+	body := a.FormatPRComment([]domain.DiffResult{
+		{ChartName: "my-app", Environment: "prod", Status: domain.StatusChanges},
+		{ChartName: "my-app", Environment: "dev", Status: domain.StatusSuccess},
+		{ChartName: "my-app", Environment: "qa", Status: domain.StatusSuccess, InputsUnchanged: true},
+	})
+
+	if !strings.HasPrefix(body, "<!-- chart-val: my-app -->\n") {
+		t.Errorf("comment must keep the chart marker, got:\n%s", body)
+	}
+	if !strings.Contains(body, "my-app: prod=Changed dev=No Changes qa=Unchanged (inputs identical) ") {
+		t.Errorf("custom template not used, got:\n%s", body)
+	}
+	if !strings.Contains(body, "_Posted by chart-val_") {
+		t.Errorf("templates missing from dir should keep defaults, got:\n%s", body)
+	}
+}
+
+func TestRenderTemplate_FallsBackToDefault(t *testing.T) {
+	broken, err := DefaultTemplates().WithOverrides(map[string]string{TemplatePRComment: "{{ .Missing }}"})
+	if err != nil {
+		t.Fatalf("WithOverrides() error: %v", err)
+	}
+
+	a := New(nil, "chart-val", "", Options{Templates: broken})
+	body := a.FormatPRComment([]domain.DiffResult{{ChartName: "my-app", Environment: "prod", Status: domain.StatusSuccess}})
+
+	if !strings.Contains(body, "## 📊 Helm Diff Report: `my-app`") {
+		t.Errorf("failing template should fall back to the default, got:\n%s", body)
+	}
+}
+
+func TestFormatCheckRun_RunHistoryLink(t *testing.T) {
+	pr := domain.PRContext{Owner: "org", Repo: "charts", HeadSHA: "abc123"}
+	results := []domain.DiffResult{{ChartName: "my-app", Environment: "prod", Status: domain.StatusChanges}}
+	const link = "[View full diffs](https://chart-val.example.com/ui/runs/latest?repo=org%2Fcharts&sha=abc123)"
+
+	tests := []struct {
+		name     string
+		appURL   string
+		link     bool
+		wantLink bool
+	}{
+		{name: "linked", appURL: "https://chart-val.example.com/", link: true, wantLink: true},
+		{name: "disabled", appURL: "https://chart-val.example.com/", link: false, wantLink: false},
+		{name: "no app URL", appURL: "", link: true, wantLink: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a := New(nil, "chart-val", tt.appURL, Options{LinkRunHistory: tt.link})
+			_, summary, _ := a.formatCheckRun(defaultTemplates, pr, results)
+			if got := strings.Contains(summary, link); got != tt.wantLink {
+				t.Errorf("summary contains link = %v, want %v:\n%s", got, tt.wantLink, summary)
+			}
+		})
+	}
+}
+
+func TestFormatCheckRun_ArtifactLinks(t *testing.T) {
+	results := []domain.DiffResult{{
+		ChartName: "my-app", Environment: "prod", Status: domain.StatusChanges, UnifiedDiff: "-a\n+b",
+		Artifacts: []domain.ManifestArtifact{
+			{Side: domain.ArtifactBase, URL: "https://artifacts.example.com/base.yaml"},
+			{Side: domain.ArtifactHead, URL: "https://artifacts.example.com/head.yaml"},
+		},
+	}}
+
+	a := New(nil, "chart-val", "", Options{})
+	_, _, text := a.formatCheckRun(defaultTemplates, domain.PRContext{}, results)
+
+	const want = "📦 Rendered manifests: [base](https://artifacts.example.com/base.yaml) [head](https://artifacts.example.com/head.yaml)\n"
+	if !strings.Contains(text, want) {
+		t.Errorf("check run text missing artifact links:\n%s", text)
+	}
+}
+
+func TestReports_AffectedVia(t *testing.T) {
+	via := []string{"common", "base"}
+	results := []domain.DiffResult{
+		{ChartName: "api", Environment: "prod", Status: domain.StatusChanges, UnifiedDiff: "-a\n+b", AffectedVia: via},
+		{ChartName: "web", Environment: "prod", Status: domain.StatusSuccess, AffectedVia: []string{"common"}},
+	}
+
+	a := New(nil, "chart-val", "", Options{})
+	_, _, text := a.formatCheckRun(defaultTemplates, domain.PRContext{}, results)
+	for _, want := range []string{"_Affected via `common`, `base`_\n", "- `web` (affected via `common`)\n"} {
+		if !strings.Contains(text, want) {
+			t.Errorf("check run text missing %q:\n%s", want, text)
+		}
+	}
+
+	if body := a.FormatPRComment(results[:1]); !strings.Contains(body, "🔗 Affected via `common`, `base`\n") {
+		t.Errorf("PR comment missing affected-via line:\n%s", body)
+	}
+}
+
+func TestFormatCheckRun_EnvSource(t *testing.T) {
+	results := []domain.DiffResult{
+		{ChartName: "api", Environment: "prod", EnvSource: "payments", Status: domain.StatusChanges, UnifiedDiff: "-a\n+b"},
+		{ChartName: "api", Environment: "dev", Status: domain.StatusChanges, UnifiedDiff: "-a\n+b"},
+	}
+
+	a := New(nil, "chart-val", "", Options{})
+	_, _, text := a.formatCheckRun(defaultTemplates, domain.PRContext{}, results)
+	if !strings.Contains(text, " · from payments</summary>") {
+		t.Errorf("check run text missing the environment source:\n%s", text)
+	}
+	if strings.Count(text, " · from ") != 1 {
+		t.Errorf("check run text names a source for an environment without one:\n%s", text)
+	}
+}
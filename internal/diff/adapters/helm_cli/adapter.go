@@ -9,32 +9,77 @@ import (
 	"os"
 	"os/exec"
 	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/nathantilsley/chart-val/internal/diff/domain"
 )
 
+// releaseName is the release name passed to `helm template` unless the
+// environment sets its own.
+const releaseName = "chart-val-render"
+
+// Options configures the adapter.
+type Options struct {
+	// DependencyCacheDir is an offline mirror of packaged remote chart
+	// dependencies named {name}-{version}.tgz, as written by `helm pull`.
+	// Empty means unvendored remote dependencies fail to render.
+	DependencyCacheDir string
+}
+
 // Adapter implements ports.RendererPort by shelling out to the helm CLI.
 type Adapter struct {
-	helmBin string
+	helmBin            string
+	dependencyCacheDir string
 }
 
 // New creates a new Helm CLI adapter. It verifies that the helm binary
 // is available on PATH at construction time.
-func New() (*Adapter, error) {
+func New(opts Options) (*Adapter, error) {
 	helmBin, err := exec.LookPath("helm")
 	if err != nil {
 		return nil, fmt.Errorf("helm binary not found: %w", err)
 	}
-	return &Adapter{helmBin: helmBin}, nil
+	return &Adapter{helmBin: helmBin, dependencyCacheDir: opts.DependencyCacheDir}, nil
 }
 
 // Render runs `helm template` on the given chart directory with the
-// specified value files and returns the rendered manifest bytes.
-func (a *Adapter) Render(ctx context.Context, chartDir string, valueFiles []string) ([]byte, error) {
-	args := make([]string, 0, 3+2*len(valueFiles))
-	args = append(args, "template", "chart-val-render", chartDir)
-	for _, vf := range valueFiles {
-		args = append(args, "-f", filepath.Join(chartDir, vf))
+// specified value files (chart-relative or absolute) and options, and
+// returns the rendered manifest bytes. Chart dependencies that aren't
+// vendored in charts/ are resolved first (see prepareChart), and the
+// resolved dependencies are listed in a comment header so dependency
+// changes appear in the diff.
+func (a *Adapter) Render(
+	ctx context.Context,
+	chartDir string,
+	valueFiles []string,
+	opts domain.HelmOptions,
+) ([]byte, error) {
+	renderDir, deps, cleanup, err := a.prepareChart(chartDir, opts.CheckoutDir)
+	if err != nil {
+		return nil, fmt.Errorf("resolving chart dependencies: %w", err)
+	}
+	defer cleanup()
+
+	var inlineValues string
+	if opts.Values != "" {
+		f, err := os.CreateTemp("", "chart-val-values-*.yaml")
+		if err != nil {
+			return nil, fmt.Errorf("creating inline values file: %w", err)
+		}
+		inlineValues = f.Name()
+		defer func() { _ = os.Remove(inlineValues) }()
+		_, err = f.WriteString(opts.Values)
+		if closeErr := f.Close(); err == nil {
+			err = closeErr
+		}
+		if err != nil {
+			return nil, fmt.Errorf("writing inline values file: %w", err)
+		}
 	}
 
+	args := templateArgs(renderDir, chartDir, valueFiles, inlineValues, opts)
+
 	logger := slog.New(slog.NewTextHandler(os.Stderr, nil))
 	logger.Info("running helm template", "chartDir", chartDir, "valueFiles", valueFiles, "args", args)
 
@@ -52,5 +97,73 @@ func (a *Adapter) Render(ctx context.Context, chartDir string, valueFiles []stri
 	}
 
 	logger.Info("helm command completed", "outputSize", len(stdout.Bytes()))
-	return stdout.Bytes(), nil
+	return append(dependencyHeader(deps), stdout.Bytes()...), nil
+}
+
+// templateArgs builds the `helm template` arguments. Values are layered the
+// way Argo CD layers them: value files in order, then the inline values
+// file (if any), then parameters.
+func templateArgs(renderDir, chartDir string, valueFiles []string, inlineValues string, opts domain.HelmOptions) []string {
+	release := opts.ReleaseName
+	if release == "" {
+		release = releaseName
+	}
+
+	args := make([]string, 0, 3+2*len(valueFiles)+2*len(opts.Parameters)+5)
+	args = append(args, "template", release, renderDir)
+	if opts.Namespace != "" {
+		args = append(args, "--namespace", opts.Namespace)
+	}
+	if opts.IncludeCRDs {
+		args = append(args, "--include-crds")
+	}
+	for _, vf := range valueFiles {
+		if !filepath.IsAbs(vf) {
+			vf = filepath.Join(chartDir, vf) // Chart-relative
+		}
+		args = append(args, "-f", vf)
+	}
+	if inlineValues != "" {
+		args = append(args, "-f", inlineValues)
+	}
+	for _, p := range opts.Parameters {
+		flag := "--set"
+		if p.ForceString {
+			flag = "--set-string"
+		}
+		args = append(args, flag, p.Name+"="+escapeSetValue(p.Value))
+	}
+	return args
+}
+
+// unescapedComma matches a comma not already escaped with a backslash.
+var unescapedComma = regexp.MustCompile(`(^|[^\\]),`)
+
+// escapeSetValue escapes commas so helm reads the value as a single item
+// rather than a list of assignments, as Argo CD does.
+func escapeSetValue(v string) string {
+	// Applied twice so adjacent commas are both escaped
+	return unescapedComma.ReplaceAllString(unescapedComma.ReplaceAllString(v, `$1\,`), `$1\,`)
+}
+
+// Version returns the helm client version. Together with the release name it
+// identifies the render options for caches keyed on render inputs.
+func (a *Adapter) Version(ctx context.Context) (string, error) {
+	//nolint:gosec // G204: helmBin is resolved from PATH at construction
+	out, err := exec.CommandContext(ctx, a.helmBin, "version", "--short").Output()
+	if err != nil {
+		return "", fmt.Errorf("helm version failed: %w", err)
+	}
+	return strings.TrimSpace(string(out)), nil
+}
+
+// CacheSalt identifies this renderer's output for render caches: the helm
+// version and the default template options. Per-environment options are
+// part of the cache key itself.
+func (a *Adapter) CacheSalt(ctx context.Context) (string, error) {
+	version, err := a.Version(ctx)
+	if err != nil {
+		return "", err
+	}
+	return "helm " + version + " template release=" + releaseName, nil
 }
@@ -0,0 +1,77 @@
+package helmcli
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/nathantilsley/chart-val/internal/diff/domain"
+)
+
+func TestTemplateArgs(t *testing.T) {
+	tests := []struct {
+		name         string
+		valueFiles   []string
+		inlineValues string
+		opts         domain.HelmOptions
+		want         []string
+	}{
+		{
+			name:       "value files only",
+			valueFiles: []string{"env/prod-values.yaml", "/checkouts/config/prod.yaml"},
+			want: []string{
+				"template", releaseName, "/render",
+				"-f", "/chart/env/prod-values.yaml",
+				"-f", "/checkouts/config/prod.yaml",
+			},
+		},
+		{
+			name:         "argo application settings",
+			valueFiles:   []string{"values-prod.yaml"},
+			inlineValues: "/tmp/inline.yaml",
+			opts: domain.HelmOptions{
+				Values: "replicas: 3\n",
+				Parameters: []domain.HelmParameter{
+					{Name: "image.tag", Value: "v1"},
+					{Name: "hosts", Value: "a.example.com,b.example.com", ForceString: true},
+				},
+				ReleaseName: "my-app-prod",
+				Namespace:   "my-app",
+				IncludeCRDs: true,
+			},
+			want: []string{
+				"template", "my-app-prod", "/render",
+				"--namespace", "my-app",
+				"--include-crds",
+				"-f", "/chart/values-prod.yaml",
+				"-f", "/tmp/inline.yaml",
+				"--set", "image.tag=v1",
+				"--set-string", `hosts=a.example.com\,b.example.com`,
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := templateArgs("/render", "/chart", tt.valueFiles, tt.inlineValues, tt.opts)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("templateArgs() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestEscapeSetValue(t *testing.T) {
+	tests := map[string]string{
+		"plain":     "plain",
+		"a,b":       `a\,b`,
+		`a\,b`:      `a\,b`,
+		"a,,b":      `a\,\,b`,
+		",leading":  `\,leading`,
+		"trailing,": `trailing\,`,
+	}
+	for in, want := range tests {
+		if got := escapeSetValue(in); got != want {
+			t.Errorf("escapeSetValue(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
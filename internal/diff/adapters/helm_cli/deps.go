@@ -0,0 +1,230 @@
+package helmcli
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+
+	"github.com/nathantilsley/chart-val/internal/diff/charttree"
+	"github.com/nathantilsley/chart-val/internal/diff/domain"
+)
+
+// maxDependencyDepth bounds file:// dependency chains, which also catches cycles.
+const maxDependencyDepth = 10
+
+// resolvedDependency describes how one dependency was made available.
+type resolvedDependency struct {
+	label  string // Dependency chain and description, e.g. "common > base 1.0.0 (file://../base)"
+	source string // "vendored", "local ../common", or "cache redis-17.3.2.tgz"
+}
+
+// prepareChart returns the directory to render chartDir from. Charts whose
+// dependencies are all vendored in charts/ render in place. Otherwise the
+// chart is copied to a staging directory and its dependencies are vendored
+// there: file:// dependencies from the checkout at checkoutDir (recursively)
+// and remote ones from the dependency cache. An empty checkoutDir confines
+// file:// dependencies to chartDir. The checkout itself is never modified,
+// so caches keyed on its content stay valid.
+func (a *Adapter) prepareChart(chartDir, checkoutDir string) (string, []resolvedDependency, func(), error) {
+	noop := func() {}
+	if checkoutDir == "" {
+		checkoutDir = chartDir
+	}
+
+	deps, err := charttree.ReadDependencies(chartDir)
+	if err != nil {
+		return "", nil, nil, err
+	}
+	if len(deps) == 0 {
+		return chartDir, nil, noop, nil
+	}
+
+	allVendored := true
+	for _, dep := range deps {
+		if !charttree.IsVendored(chartDir, dep) {
+			allVendored = false
+			break
+		}
+	}
+	if allVendored {
+		resolved := make([]resolvedDependency, 0, len(deps))
+		for _, dep := range deps {
+			resolved = append(resolved, resolvedDependency{label: dep.String(), source: "vendored"})
+		}
+		return chartDir, resolved, noop, nil
+	}
+
+	staging, err := os.MkdirTemp("", "chart-val-deps-*")
+	if err != nil {
+		return "", nil, nil, fmt.Errorf("creating dependency staging dir: %w", err)
+	}
+	cleanup := func() { _ = os.RemoveAll(staging) }
+
+	dir := filepath.Join(staging, filepath.Base(chartDir))
+	resolved, err := a.vendor(chartDir, dir, checkoutDir, "", 0)
+	if err != nil {
+		cleanup()
+		return "", nil, nil, err
+	}
+	return dir, resolved, cleanup, nil
+}
+
+// vendor copies the chart at src to dest and vendors its dependencies into
+// dest/charts. file:// dependencies outside checkoutDir are rejected. prefix
+// is the dependency chain leading to src, for labels.
+func (a *Adapter) vendor(src, dest, checkoutDir, prefix string, depth int) ([]resolvedDependency, error) {
+	if depth > maxDependencyDepth {
+		return nil, fmt.Errorf("dependency chain deeper than %d at %s (cycle?)", maxDependencyDepth, prefix)
+	}
+	if err := copyDir(src, dest); err != nil {
+		return nil, fmt.Errorf("copying chart: %w", err)
+	}
+
+	deps, err := charttree.ReadDependencies(src)
+	if err != nil {
+		return nil, err
+	}
+	locked, err := readLockedVersions(src)
+	if err != nil {
+		return nil, err
+	}
+
+	var resolved []resolvedDependency
+	for _, dep := range deps {
+		label := prefix + dep.String()
+		if charttree.IsVendored(dest, dep) {
+			resolved = append(resolved, resolvedDependency{label: label, source: "vendored"})
+			continue
+		}
+
+		if localPath, ok := dep.LocalPath(); ok {
+			depSrc := filepath.Join(src, filepath.FromSlash(localPath))
+			if rel, err := filepath.Rel(checkoutDir, depSrc); err != nil || !filepath.IsLocal(rel) {
+				return nil, fmt.Errorf("dependency %s: %s is outside the repository", dep.Name, dep.Repository)
+			}
+			if _, err := os.Stat(filepath.Join(depSrc, "Chart.yaml")); err != nil {
+				return nil, fmt.Errorf("dependency %s: no chart at %s", dep.Name, dep.Repository)
+			}
+			resolved = append(resolved, resolvedDependency{label: label, source: "local " + localPath})
+			nested, err := a.vendor(depSrc, filepath.Join(dest, "charts", dep.Name), checkoutDir, prefix+dep.Name+" > ", depth+1)
+			if err != nil {
+				return nil, err
+			}
+			resolved = append(resolved, nested...)
+			continue
+		}
+
+		file, err := a.cachedDependency(dep, locked[dep.Name])
+		if err != nil {
+			return nil, err
+		}
+		if err := copyFile(file, filepath.Join(dest, "charts", filepath.Base(file))); err != nil {
+			return nil, fmt.Errorf("vendoring dependency %s: %w", dep.Name, err)
+		}
+		resolved = append(resolved, resolvedDependency{label: label, source: "cache " + filepath.Base(file)})
+	}
+	return resolved, nil
+}
+
+// cachedDependency finds a remote dependency's package in the dependency
+// cache. The version comes from the lock file, or from Chart.yaml when it
+// pins an exact version.
+func (a *Adapter) cachedDependency(dep domain.ChartDependency, lockedVersion string) (string, error) {
+	if a.dependencyCacheDir == "" {
+		return "", fmt.Errorf("dependency %s (%s) is not vendored in charts/ and no dependency cache is configured",
+			dep.Name, dep.Repository)
+	}
+
+	version, ok := dep.ResolvedVersion(lockedVersion)
+	if !ok {
+		return "", fmt.Errorf("dependency %s: version range %q needs a Chart.lock or a vendored charts/ directory",
+			dep.Name, dep.Version)
+	}
+
+	archive := dep.ArchiveName(version)
+	if !filepath.IsLocal(archive) {
+		return "", fmt.Errorf("dependency %s %s: package %s is outside the dependency cache", dep.Name, version, archive)
+	}
+	file := filepath.Join(a.dependencyCacheDir, archive)
+	if _, err := os.Stat(file); err != nil {
+		return "", fmt.Errorf("dependency %s %s not found in dependency cache: %w", dep.Name, version, err)
+	}
+	return file, nil
+}
+
+// readLockedVersions maps dependency names to versions from Chart.lock or
+// requirements.lock.
+func readLockedVersions(chartDir string) (map[string]string, error) {
+	lock, err := charttree.ReadLock(chartDir)
+	if err != nil {
+		return nil, err
+	}
+	versions := make(map[string]string, len(lock.Dependencies))
+	for _, d := range lock.Dependencies {
+		versions[d.Name] = d.Version
+	}
+	return versions, nil
+}
+
+// dependencyHeader lists the resolved dependencies as YAML comments, which
+// are prepended to the rendered manifest so dependency changes (a new
+// version, a different source) show up in the diff.
+func dependencyHeader(deps []resolvedDependency) []byte {
+	if len(deps) == 0 {
+		return nil
+	}
+	var b bytes.Buffer
+	b.WriteString("# Chart dependencies:\n")
+	for _, d := range deps {
+		fmt.Fprintf(&b, "#   %s: %s\n", d.label, d.source)
+	}
+	return b.Bytes()
+}
+
+// copyDir copies the regular files and directories under src to dest.
+func copyDir(src, dest string) error {
+	return filepath.WalkDir(src, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(src, path)
+		if err != nil {
+			return err
+		}
+		target := filepath.Join(dest, rel)
+		switch {
+		case d.IsDir():
+			return os.MkdirAll(target, 0o750)
+		case d.Type().IsRegular():
+			return copyFile(path, target)
+		default:
+			return nil // Symlinks and special files are skipped
+		}
+	})
+}
+
+func copyFile(src, dest string) error {
+	if err := os.MkdirAll(filepath.Dir(dest), 0o750); err != nil {
+		return err
+	}
+	//nolint:gosec // G304: src is inside a chart checkout or the configured dependency cache
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = in.Close() }()
+
+	//nolint:gosec // G304: dest is inside a staging dir created by prepareChart
+	out, err := os.Create(dest)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(out, in); err != nil {
+		_ = out.Close()
+		return err
+	}
+	return out.Close()
+}
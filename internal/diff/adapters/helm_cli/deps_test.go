@@ -0,0 +1,201 @@
+package helmcli
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func writeFiles(t *testing.T, root string, files map[string]string) {
+	t.Helper()
+	for name, content := range files {
+		path := filepath.Join(root, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0o750); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestPrepareChart(t *testing.T) {
+	tests := []struct {
+		name        string
+		files       map[string]string // Temp dir contents; the chart is charts/app
+		checkout    string            // Checkout root within the temp dir, holding charts/app; "" is the temp dir
+		noCheckout  bool              // Prepare without a checkout root
+		cache       map[string]string // Dependency cache contents
+		wantInPlace bool
+		wantFiles   []string // Files expected in the render dir
+		wantHeader  []string
+		wantErr     string
+	}{
+		{
+			name:        "no dependencies",
+			files:       map[string]string{"charts/app/Chart.yaml": "name: app\n"},
+			wantInPlace: true,
+		},
+		{
+			name: "already vendored",
+			files: map[string]string{
+				"charts/app/Chart.yaml":              "name: app\ndependencies:\n  - name: redis\n    version: 17.3.2\n    repository: https://charts.example.com\n",
+				"charts/app/charts/redis-17.3.2.tgz": "packaged",
+			},
+			wantInPlace: true,
+			wantHeader:  []string{"redis 17.3.2 (https://charts.example.com): vendored"},
+		},
+		{
+			name: "local library chart with its own local dependency",
+			files: map[string]string{
+				"charts/app/Chart.yaml":          "name: app\ndependencies:\n  - name: common\n    version: 1.x.x\n    repository: file://../common\n",
+				"charts/common/Chart.yaml":       "name: common\ntype: library\ndependencies:\n  - name: base\n    version: 1.0.0\n    repository: file://../base\n",
+				"charts/common/templates/_h.tpl": "{{- define \"common.name\" -}}x{{- end -}}",
+				"charts/base/Chart.yaml":         "name: base\n",
+			},
+			wantFiles: []string{"charts/common/templates/_h.tpl", "charts/common/charts/base/Chart.yaml"},
+			wantHeader: []string{
+				"common 1.x.x (file://../common): local ../common",
+				"common > base 1.0.0 (file://../base): local ../base",
+			},
+		},
+		{
+			name: "remote dependency from cache via lock file",
+			files: map[string]string{
+				"charts/app/Chart.yaml": "name: app\ndependencies:\n  - name: redis\n    version: ^17.0.0\n    repository: https://charts.example.com\n",
+				"charts/app/Chart.lock": "dependencies:\n  - name: redis\n    version: 17.3.2\n    repository: https://charts.example.com\n",
+			},
+			cache:      map[string]string{"redis-17.3.2.tgz": "packaged"},
+			wantFiles:  []string{"charts/redis-17.3.2.tgz"},
+			wantHeader: []string{"redis ^17.0.0 (https://charts.example.com): cache redis-17.3.2.tgz"},
+		},
+		{
+			name: "version range without lock",
+			files: map[string]string{
+				"charts/app/Chart.yaml": "name: app\ndependencies:\n  - name: redis\n    version: ^17.0.0\n    repository: https://charts.example.com\n",
+			},
+			cache:   map[string]string{"redis-17.3.2.tgz": "packaged"},
+			wantErr: "needs a Chart.lock",
+		},
+		{
+			name: "remote dependency missing from cache",
+			files: map[string]string{
+				"charts/app/Chart.yaml": "name: app\ndependencies:\n  - name: redis\n    version: 17.3.2\n    repository: https://charts.example.com\n",
+			},
+			cache:   map[string]string{},
+			wantErr: "not found in dependency cache",
+		},
+		{
+			name: "remote dependency without cache",
+			files: map[string]string{
+				"charts/app/Chart.yaml": "name: app\ndependencies:\n  - name: redis\n    version: 17.3.2\n    repository: https://charts.example.com\n",
+			},
+			wantErr: "no dependency cache is configured",
+		},
+		{
+			name: "missing local dependency",
+			files: map[string]string{
+				"charts/app/Chart.yaml": "name: app\ndependencies:\n  - name: common\n    repository: file://../common\n",
+			},
+			wantErr: "no chart at file://../common",
+		},
+		{
+			name: "local dependency outside the checkout",
+			files: map[string]string{
+				"checkout/charts/app/Chart.yaml": "name: app\ndependencies:\n  - name: lib\n    repository: file://../../../lib\n",
+				"lib/Chart.yaml":                 "name: lib\ntype: library\n",
+			},
+			checkout: "checkout",
+			wantErr:  "file://../../../lib is outside the repository",
+		},
+		{
+			name: "nested local dependency outside the checkout",
+			files: map[string]string{
+				"checkout/charts/app/Chart.yaml":    "name: app\ndependencies:\n  - name: common\n    repository: file://../common\n",
+				"checkout/charts/common/Chart.yaml": "name: common\ndependencies:\n  - name: lib\n    repository: file://../../../lib\n",
+				"lib/Chart.yaml":                    "name: lib\ntype: library\n",
+			},
+			checkout: "checkout",
+			wantErr:  "file://../../../lib is outside the repository",
+		},
+		{
+			name: "local dependency without a checkout stays in the chart",
+			files: map[string]string{
+				"charts/app/Chart.yaml":    "name: app\ndependencies:\n  - name: common\n    repository: file://../common\n",
+				"charts/common/Chart.yaml": "name: common\n",
+			},
+			noCheckout: true,
+			wantErr:    "file://../common is outside the repository",
+		},
+		{
+			name: "remote dependency package outside the cache",
+			files: map[string]string{
+				"charts/app/Chart.yaml": "name: app\ndependencies:\n  - name: ../../secret\n    version: 1.0.0\n    repository: https://charts.example.com\n",
+			},
+			cache:   map[string]string{},
+			wantErr: "outside the dependency cache",
+		},
+		{
+			name: "dependency cycle",
+			files: map[string]string{
+				"charts/app/Chart.yaml": "name: app\ndependencies:\n  - name: a\n    repository: file://../a\n",
+				"charts/a/Chart.yaml":   "name: a\ndependencies:\n  - name: b\n    repository: file://../b\n",
+				"charts/b/Chart.yaml":   "name: b\ndependencies:\n  - name: a\n    repository: file://../a\n",
+			},
+			wantErr: "cycle",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			root := t.TempDir()
+			writeFiles(t, root, tt.files)
+			checkoutDir := filepath.Join(root, tt.checkout)
+			chartDir := filepath.Join(checkoutDir, "charts", "app")
+			if tt.noCheckout {
+				checkoutDir = ""
+			}
+
+			a := &Adapter{}
+			if tt.cache != nil {
+				a.dependencyCacheDir = t.TempDir()
+				writeFiles(t, a.dependencyCacheDir, tt.cache)
+			}
+
+			dir, deps, cleanup, err := a.prepareChart(chartDir, checkoutDir)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("prepareChart() error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("prepareChart() error: %v", err)
+			}
+			defer cleanup()
+
+			if inPlace := dir == chartDir; inPlace != tt.wantInPlace {
+				t.Errorf("rendered in place = %v, want %v", inPlace, tt.wantInPlace)
+			}
+			for _, f := range tt.wantFiles {
+				if _, err := os.Stat(filepath.Join(dir, f)); err != nil {
+					t.Errorf("expected %s in render dir: %v", f, err)
+				}
+			}
+			if _, err := os.Stat(filepath.Join(chartDir, "charts")); err == nil && !tt.wantInPlace {
+				t.Error("the checkout must not be modified")
+			}
+
+			header := string(dependencyHeader(deps))
+			for _, line := range tt.wantHeader {
+				if !strings.Contains(header, "#   "+line+"\n") {
+					t.Errorf("header missing %q:\n%s", line, header)
+				}
+			}
+			if len(tt.wantHeader) == 0 && header != "" {
+				t.Errorf("expected no header, got:\n%s", header)
+			}
+		})
+	}
+}
@@ -0,0 +1,155 @@
+// Package jsonfile writes diff results to JSON files for other tooling to consume.
+package jsonfile
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/nathantilsley/chart-val/internal/diff/domain"
+)
+
+// Adapter implements ports.ReportingPort by writing one JSON document per
+// PR head commit into a directory. Only final results are written; check run
+// and comment calls are no-ops.
+type Adapter struct {
+	dir string
+}
+
+// New creates a JSON file reporter writing into dir, which must exist.
+func New(dir string) *Adapter {
+	return &Adapter{dir: dir}
+}
+
+// Report is the document written for each run.
+type Report struct {
+	Owner       string         `json:"owner"`
+	Repo        string         `json:"repo"`
+	PRNumber    int            `json:"prNumber"`
+	BaseRef     string         `json:"baseRef"`
+	HeadRef     string         `json:"headRef"`
+	HeadSHA     string         `json:"headSha"`
+	GeneratedAt time.Time      `json:"generatedAt"`
+	Results     []ResultReport `json:"results"`
+}
+
+// ResultReport is a single chart + environment result.
+type ResultReport struct {
+	Chart        string         `json:"chart"`
+	Environment  string         `json:"environment"`
+	EnvSource    string         `json:"envSource,omitempty"` // Gitops source that supplied the environment
+	Status       string         `json:"status"`
+	Summary      string         `json:"summary"`
+	BaseOnly     bool           `json:"baseOnly,omitempty"`
+	Unchanged    bool           `json:"inputsUnchanged,omitempty"` // Skipped: render inputs identical
+	AffectedVia  []string       `json:"affectedVia,omitempty"`     // Changed dependency charts
+	Warnings     []string       `json:"warnings,omitempty"`
+	Resources    ResourceReport `json:"resources"`
+	UnifiedDiff  string         `json:"unifiedDiff,omitempty"`
+	SemanticDiff string         `json:"semanticDiff,omitempty"`
+	Artifacts    []ArtifactLink `json:"artifacts,omitempty"`
+}
+
+// ArtifactLink locates a stored rendered manifest.
+type ArtifactLink struct {
+	Side string `json:"side"` // "base" or "head"
+	Key  string `json:"key"`
+	URL  string `json:"url,omitempty"`
+}
+
+// ResourceReport counts changed Kubernetes resources.
+type ResourceReport struct {
+	Added    int `json:"added"`
+	Removed  int `json:"removed"`
+	Modified int `json:"modified"`
+}
+
+// CreateInProgressCheck is a no-op; only final results are written.
+func (a *Adapter) CreateInProgressCheck(_ context.Context, _ domain.PRContext) (int64, error) {
+	return 0, nil
+}
+
+// UpdateCheckWithResults writes {owner}-{repo}-{pr}-{sha}.json, replacing
+// any earlier file for the same commit. The file is written to a temp file
+// first so readers never see a partial document.
+func (a *Adapter) UpdateCheckWithResults(
+	_ context.Context,
+	pr domain.PRContext,
+	_ int64,
+	results []domain.DiffResult,
+) error {
+	report := Report{
+		Owner:       pr.Owner,
+		Repo:        pr.Repo,
+		PRNumber:    pr.PRNumber,
+		BaseRef:     pr.BaseRef,
+		HeadRef:     pr.HeadRef,
+		HeadSHA:     pr.HeadSHA,
+		GeneratedAt: time.Now().UTC(),
+		Results:     make([]ResultReport, 0, len(results)),
+	}
+	for _, r := range results {
+		var artifacts []ArtifactLink
+		for _, a := range r.Artifacts {
+			artifacts = append(artifacts, ArtifactLink{Side: a.Side, Key: a.Key, URL: a.URL})
+		}
+		report.Results = append(report.Results, ResultReport{
+			Chart:        r.ChartName,
+			Environment:  r.Environment,
+			EnvSource:    r.EnvSource,
+			Status:       string(r.Outcome()),
+			Summary:      r.Summary,
+			BaseOnly:     r.BaseOnly,
+			Unchanged:    r.InputsUnchanged,
+			AffectedVia:  r.AffectedVia,
+			Warnings:     r.Warnings,
+			Resources:    ResourceReport(r.Resources),
+			UnifiedDiff:  r.UnifiedDiff,
+			SemanticDiff: r.SemanticDiff,
+			Artifacts:    artifacts,
+		})
+	}
+
+	data, err := json.MarshalIndent(report, "", "  ")
+	if err != nil {
+		return fmt.Errorf("encoding report: %w", err)
+	}
+
+	tmp, err := os.CreateTemp(a.dir, ".report-*.json")
+	if err != nil {
+		return fmt.Errorf("creating temp file: %w", err)
+	}
+	defer func() { _ = os.Remove(tmp.Name()) }()
+
+	if _, err := tmp.Write(data); err != nil {
+		_ = tmp.Close()
+		return fmt.Errorf("writing report: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("closing report: %w", err)
+	}
+
+	name := fmt.Sprintf("%s-%s-%d-%s.json", pr.Owner, pr.Repo, pr.PRNumber, pr.HeadSHA)
+	if err := os.Rename(tmp.Name(), filepath.Join(a.dir, name)); err != nil {
+		return fmt.Errorf("renaming report: %w", err)
+	}
+	return nil
+}
+
+// PostComment is a no-op; results are written once per run.
+func (a *Adapter) PostComment(_ context.Context, _ domain.PRContext, _ []domain.DiffResult) error {
+	return nil
+}
+
+// ResolveComment is a no-op.
+func (a *Adapter) ResolveComment(_ context.Context, _ domain.PRContext, _ string) error {
+	return nil
+}
+
+// CommentedCharts returns nothing; reports post no comments.
+func (a *Adapter) CommentedCharts(_ context.Context, _ domain.PRContext) ([]string, error) {
+	return nil, nil
+}
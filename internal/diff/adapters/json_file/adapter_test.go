@@ -0,0 +1,52 @@
+package jsonfile
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/nathantilsley/chart-val/internal/diff/domain"
+)
+
+func TestUpdateCheckWithResults_WritesReport(t *testing.T) {
+	dir := t.TempDir()
+	a := New(dir)
+	pr := domain.PRContext{Owner: "org", Repo: "charts", PRNumber: 7, HeadSHA: "abc123"}
+
+	results := []domain.DiffResult{
+		{
+			ChartName: "my-app", Environment: "prod", Status: domain.StatusChanges, UnifiedDiff: "-a\n+b",
+			Resources: domain.ResourceChanges{Modified: 1},
+		},
+		{ChartName: "my-app", Environment: "dev", Status: domain.StatusError, Summary: "render failed"},
+	}
+	if err := a.UpdateCheckWithResults(context.Background(), pr, 0, results); err != nil {
+		t.Fatalf("UpdateCheckWithResults() error: %v", err)
+	}
+
+	data, err := os.ReadFile(filepath.Join(dir, "org-charts-7-abc123.json"))
+	if err != nil {
+		t.Fatalf("reading report: %v", err)
+	}
+	var report Report
+	if err := json.Unmarshal(data, &report); err != nil {
+		t.Fatalf("decoding report: %v", err)
+	}
+
+	if len(report.Results) != 2 {
+		t.Fatalf("report has %d results, want 2", len(report.Results))
+	}
+	if got := report.Results[0]; got.Status != "changes" || got.Resources.Modified != 1 || got.UnifiedDiff == "" {
+		t.Errorf("prod result = %+v", got)
+	}
+	if got := report.Results[1]; got.Status != "error" || got.Summary != "render failed" {
+		t.Errorf("dev result = %+v", got)
+	}
+
+	entries, _ := os.ReadDir(dir)
+	if len(entries) != 1 {
+		t.Errorf("dir has %d entries, want only the report (temp file should be gone)", len(entries))
+	}
+}
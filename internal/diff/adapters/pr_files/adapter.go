@@ -6,36 +6,68 @@ import (
 	"errors"
 	"fmt"
 	"log/slog"
-	"path/filepath"
-	"strings"
+	"sort"
+	"sync"
 
 	"github.com/google/go-github/v68/github"
 	"gopkg.in/yaml.v3"
 
 	"github.com/nathantilsley/chart-val/internal/diff/domain"
+	"github.com/nathantilsley/chart-val/internal/diff/ports"
 )
 
 // Adapter implements ports.ChangedChartsPort by querying the GitHub API
 // for files changed in a pull request, detecting Chart.yaml changes,
-// and reading chart names from the file content.
+// and reading chart names from the file content. Charts that depend on a
+// changed chart through file:// dependencies (e.g. on a shared library
+// chart) are included too, marked with the charts they're affected via, and
+// so are charts whose environments use a changed values file kept outside
+// the chart directory.
+//
+// Chart.yaml files are read by the blob SHA the tree listing gives them and
+// kept per repository while they are in the tree, so after the first PR of a
+// repository only the Chart.yaml files a push changed are fetched.
 type Adapter struct {
-	client   *github.Client
-	logger   *slog.Logger
-	chartDir string
+	client     *github.Client
+	logger     *slog.Logger
+	layout     domain.ChartLayout
+	valueIndex ports.ValueFileIndexPort
+
+	mu     sync.Mutex
+	charts map[string]map[string]chartFile // "owner/repo" -> Chart.yaml blob SHA -> parsed file
+}
+
+// chartFile is the part of a Chart.yaml used to find changed and affected
+// charts. err is set when the file can't be parsed.
+type chartFile struct {
+	name string
+	deps []domain.ChartDependency
+	err  error
 }
 
-// New creates a new PR files adapter.
-func New(client *github.Client, logger *slog.Logger, chartDir string) *Adapter {
+// New creates a new PR files adapter. Charts are the directories holding a
+// Chart.yaml that fall within layout. valueIndex is optional (can be nil);
+// if provided, it maps changed files to the charts using them as values.
+func New(
+	client *github.Client,
+	logger *slog.Logger,
+	layout domain.ChartLayout,
+	valueIndex ports.ValueFileIndexPort,
+) *Adapter {
 	return &Adapter{
-		client:   client,
-		logger:   logger,
-		chartDir: chartDir,
+		client:     client,
+		logger:     logger,
+		layout:     layout,
+		valueIndex: valueIndex,
+		charts:     make(map[string]map[string]chartFile),
 	}
 }
 
-// GetChangedCharts returns charts that were modified in the PR.
-// It lists changed files, finds Chart.yaml changes, fetches each one,
-// and parses the chart name from the YAML content.
+// GetChangedCharts returns charts that were modified in the PR (sorted by
+// path), followed by charts affected through their dependencies and then
+// charts affected through their value files.
+// It lists changed files, maps each one to the chart directory containing it,
+// and reads the chart name from each chart's Chart.yaml.
 func (a *Adapter) GetChangedCharts(ctx context.Context, pr domain.PRContext) ([]domain.ChangedChart, error) {
 	// Get all changed files from GitHub
 	changedFiles, err := a.listChangedFiles(ctx, pr.Owner, pr.Repo, pr.PRNumber)
@@ -45,47 +77,180 @@ func (a *Adapter) GetChangedCharts(ctx context.Context, pr domain.PRContext) ([]
 
 	a.logger.Debug("found changed files in PR", "count", len(changedFiles), "files", changedFiles)
 
-	// Find unique chart directories from any changed file under {chartDir}/{name}/
-	chartDirs := make(map[string]struct{})
+	allChartDirs, chartFiles, err := a.listCharts(ctx, pr)
+	if err != nil {
+		return nil, fmt.Errorf("listing charts: %w", err)
+	}
+
+	// Find the unique charts containing any changed file
+	seen := make(map[string]bool)
+	var chartDirs []string
 	for _, file := range changedFiles {
-		if dir := a.extractChartDir(file); dir != "" {
-			chartDirs[dir] = struct{}{}
-			a.logger.Debug("detected chart directory from changed file", "file", file, "chartDir", dir)
+		dir := domain.OwningChart(file, allChartDirs)
+		if dir == "" || seen[dir] {
+			continue
 		}
+		seen[dir] = true
+		chartDirs = append(chartDirs, dir)
+		a.logger.Debug("detected chart directory from changed file", "file", file, "chartDir", dir)
 	}
+	sort.Strings(chartDirs)
 
 	a.logger.Debug("extracted chart directories", "count", len(chartDirs))
 
-	if len(chartDirs) == 0 {
-		return nil, nil
+	// For each Chart.yaml, read the chart name
+	var charts []domain.ChangedChart
+	for _, chartDir := range chartDirs {
+		chart, ok := chartFiles[chartDir]
+		if !ok {
+			continue
+		}
+		a.logger.Debug("found chart", "name", chart.name, "path", chartDir)
+		charts = append(charts, domain.ChangedChart{
+			Name: chart.name,
+			Path: chartDir,
+		})
 	}
 
-	// For each Chart.yaml, fetch and parse the chart name
-	var charts []domain.ChangedChart
-	for chartDir := range chartDirs {
-		chartYamlPath := filepath.Join(chartDir, "Chart.yaml")
+	charts = append(charts, a.affectedCharts(allChartDirs, chartFiles, charts)...)
+	return append(charts, a.valueFileUsers(ctx, pr, changedFiles, charts)...), nil
+}
 
-		a.logger.Debug("fetching Chart.yaml", "path", chartYamlPath, "ref", pr.HeadRef)
-		content, err := a.fetchFile(ctx, pr.Owner, pr.Repo, pr.HeadRef, chartYamlPath)
-		if err != nil {
-			a.logger.Warn("failed to fetch Chart.yaml", "path", chartYamlPath, "ref", pr.HeadRef, "error", err)
+// valueFileUsers returns the charts, other than those already listed, whose
+// environments use one of the changed files as a value file. The lookup is
+// best-effort: index errors are logged and no charts are added.
+func (a *Adapter) valueFileUsers(
+	ctx context.Context,
+	pr domain.PRContext,
+	changedFiles []string,
+	listed []domain.ChangedChart,
+) []domain.ChangedChart {
+	if a.valueIndex == nil || len(changedFiles) == 0 {
+		return nil
+	}
+
+	users, err := a.valueIndex.ChartsUsingFiles(ctx, pr, changedFiles)
+	if err != nil {
+		a.logger.Warn("failed to look up charts using changed value files", "error", err)
+		return nil
+	}
+
+	seen := make(map[string]bool, len(listed))
+	for _, c := range listed {
+		seen[c.Path] = true
+	}
+	var affected []domain.ChangedChart
+	for _, c := range users {
+		if seen[c.Path] {
 			continue
 		}
+		seen[c.Path] = true
+		a.logger.Debug("found chart affected by value file change", "path", c.Path, "via", c.AffectedVia)
+		affected = append(affected, c)
+	}
+	return affected
+}
 
-		name, err := parseChartName(content)
-		if err != nil {
-			a.logger.Warn("failed to parse chart name", "path", chartYamlPath, "error", err)
+// listCharts returns every chart directory in the layout at the PR's head
+// commit, from a single recursive tree listing, and the parsed Chart.yaml of
+// each. Chart.yaml files not read before are fetched by blob SHA; charts
+// whose Chart.yaml can't be fetched or parsed are logged and left out of the
+// map.
+func (a *Adapter) listCharts(ctx context.Context, pr domain.PRContext) ([]string, map[string]chartFile, error) {
+	ref := pr.HeadSHA
+	if ref == "" {
+		ref = pr.HeadRef
+	}
+	tree, _, err := a.client.Git.GetTree(ctx, pr.Owner, pr.Repo, ref, true)
+	if err != nil {
+		return nil, nil, fmt.Errorf("getting tree %s: %w", ref, err)
+	}
+	if tree.GetTruncated() {
+		a.logger.Warn("repository tree listing truncated; some charts may not be discovered", "ref", ref)
+	}
+
+	files := make([]string, 0, len(tree.Entries))
+	blobs := make(map[string]string, len(tree.Entries)) // path -> blob SHA
+	for _, entry := range tree.Entries {
+		if entry.GetType() == "blob" {
+			files = append(files, entry.GetPath())
+			blobs[entry.GetPath()] = entry.GetSHA()
+		}
+	}
+	chartDirs := a.layout.ChartDirs(files)
+
+	repoKey := pr.Owner + "/" + pr.Repo
+	a.mu.Lock()
+	cached := a.charts[repoKey]
+	a.mu.Unlock()
+
+	// Only Chart.yaml files still in the tree are kept for the next listing
+	current := make(map[string]chartFile, len(chartDirs))
+	charts := make(map[string]chartFile, len(chartDirs))
+	for _, dir := range chartDirs {
+		sha := blobs[dir+"/Chart.yaml"]
+		chart, ok := cached[sha]
+		if !ok {
+			content, _, err := a.client.Git.GetBlobRaw(ctx, pr.Owner, pr.Repo, sha)
+			if err != nil {
+				a.logger.Warn("failed to fetch Chart.yaml", "path", dir, "ref", ref, "error", err)
+				continue
+			}
+			chart = parseChartFile(content)
+		}
+		current[sha] = chart
+		if chart.err != nil {
+			a.logger.Warn("failed to parse Chart.yaml", "path", dir, "error", chart.err)
 			continue
 		}
+		charts[dir] = chart
+	}
 
-		a.logger.Debug("found chart", "name", name, "path", chartDir)
-		charts = append(charts, domain.ChangedChart{
-			Name: name,
-			Path: chartDir,
-		})
+	a.mu.Lock()
+	a.charts[repoKey] = current
+	a.mu.Unlock()
+	return chartDirs, charts, nil
+}
+
+// affectedCharts returns the unchanged charts among chartDirs that depend on
+// one of the changed charts, using the dependencies in chartFiles. Charts
+// missing from chartFiles are left out of the graph.
+func (a *Adapter) affectedCharts(
+	chartDirs []string,
+	chartFiles map[string]chartFile,
+	changed []domain.ChangedChart,
+) []domain.ChangedChart {
+	if len(changed) == 0 {
+		return nil
+	}
+
+	graph := domain.ChartGraph{}
+	for _, chartPath := range chartDirs {
+		if chart, ok := chartFiles[chartPath]; ok {
+			graph.AddChart(chartPath, chart.deps)
+		}
+	}
+
+	changedPaths := make([]string, 0, len(changed))
+	for _, c := range changed {
+		changedPaths = append(changedPaths, c.Path)
 	}
 
-	return charts, nil
+	var affected []domain.ChangedChart
+	for chartPath, via := range graph.Dependents(changedPaths) {
+		viaNames := make([]string, 0, len(via))
+		for _, v := range via {
+			viaNames = append(viaNames, chartFiles[v].name)
+		}
+		a.logger.Debug("found chart affected by dependency change", "path", chartPath, "via", viaNames)
+		affected = append(affected, domain.ChangedChart{
+			Name:        chartFiles[chartPath].name,
+			Path:        chartPath,
+			AffectedVia: viaNames,
+		})
+	}
+	sort.Slice(affected, func(i, j int) bool { return affected[i].Path < affected[j].Path })
+	return affected
 }
 
 // listChangedFiles returns all file paths modified in the PR.
@@ -112,40 +277,17 @@ func (a *Adapter) listChangedFiles(ctx context.Context, owner, repo string, prNu
 	return changedFiles, nil
 }
 
-// fetchFile fetches a single file from the repository at the given ref.
-func (a *Adapter) fetchFile(ctx context.Context, owner, repo, ref, filePath string) ([]byte, error) {
-	opts := &github.RepositoryContentGetOptions{Ref: ref}
-	fileContent, _, _, err := a.client.Repositories.GetContents(ctx, owner, repo, filePath, opts)
+// parseChartFile reads the chart name and dependencies from Chart.yaml content.
+func parseChartFile(content []byte) chartFile {
+	name, err := parseChartName(content)
 	if err != nil {
-		return nil, fmt.Errorf("fetching file %s: %w", filePath, err)
-	}
-
-	if fileContent == nil {
-		return nil, fmt.Errorf("file not found: %s", filePath)
+		return chartFile{err: err}
 	}
-
-	content, err := fileContent.GetContent()
+	deps, err := domain.ParseChartDependencies(content)
 	if err != nil {
-		return nil, fmt.Errorf("decoding file content: %w", err)
-	}
-
-	return []byte(content), nil
-}
-
-// extractChartDir returns the chart directory (e.g., "charts/my-app") from a file path,
-// or empty string if the file is not under the configured chart directory.
-// E.g., "charts/my-app/env/prod-values.yaml" -> "charts/my-app"
-func (a *Adapter) extractChartDir(filePath string) string {
-	prefix := a.chartDir + "/"
-	if !strings.HasPrefix(filePath, prefix) {
-		return ""
-	}
-	rest := filePath[len(prefix):]
-	parts := strings.SplitN(rest, "/", 2)
-	if len(parts) < 1 || parts[0] == "" {
-		return ""
+		return chartFile{err: err}
 	}
-	return a.chartDir + "/" + parts[0]
+	return chartFile{name: name, deps: deps}
 }
 
 // parseChartName extracts the chart name from Chart.yaml content.
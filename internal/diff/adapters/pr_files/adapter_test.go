@@ -0,0 +1,247 @@
+package prfiles
+
+import (
+	"context"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"reflect"
+	"sync/atomic"
+	"testing"
+
+	"github.com/google/go-github/v68/github"
+
+	"github.com/nathantilsley/chart-val/internal/diff/domain"
+)
+
+// fakeGitHub serves the PR files, tree and blob endpoints. Like a git blob
+// SHA, each Chart.yaml's blob ID is derived from its content. Blob fetches
+// are counted in the returned counter.
+func fakeGitHub(t *testing.T, changedFiles []string, charts map[string]string) (*github.Client, *atomic.Int32) {
+	t.Helper()
+	blobSHA := func(content string) string {
+		return hex.EncodeToString([]byte(content))
+	}
+	blobs := make(map[string]string)
+	for _, content := range charts {
+		blobs[blobSHA(content)] = content
+	}
+	var blobFetches atomic.Int32
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /repos/org/repo/pulls/1/files", func(w http.ResponseWriter, _ *http.Request) {
+		var files []map[string]string
+		for _, f := range changedFiles {
+			files = append(files, map[string]string{"filename": f})
+		}
+		_ = json.NewEncoder(w).Encode(files)
+	})
+	mux.HandleFunc("GET /repos/org/repo/git/trees/abc", func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("recursive") == "" {
+			t.Error("tree should be listed recursively")
+		}
+		entries := []map[string]string{{"type": "blob", "path": "README.md", "sha": blobSHA("readme")}}
+		for dir, content := range charts {
+			entries = append(entries,
+				map[string]string{"type": "tree", "path": dir},
+				map[string]string{"type": "blob", "path": dir + "/Chart.yaml", "sha": blobSHA(content)},
+			)
+		}
+		_ = json.NewEncoder(w).Encode(map[string]any{"sha": "abc", "tree": entries})
+	})
+	mux.HandleFunc("GET /repos/org/repo/git/blobs/{sha}", func(w http.ResponseWriter, r *http.Request) {
+		blobFetches.Add(1)
+		content, ok := blobs[r.PathValue("sha")]
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		_, _ = io.WriteString(w, content)
+	})
+	srv := httptest.NewServer(mux)
+	t.Cleanup(srv.Close)
+
+	client := github.NewClient(nil)
+	client.BaseURL, _ = url.Parse(srv.URL + "/")
+	return client, &blobFetches
+}
+
+func TestGetChangedCharts_IncludesDependents(t *testing.T) {
+	layout := domain.ChartLayout{Include: []string{"charts/*"}}
+	pr := domain.PRContext{Owner: "org", Repo: "repo", PRNumber: 1, HeadRef: "feat", HeadSHA: "abc"}
+	charts := map[string]string{
+		"charts/common": "name: common\ntype: library\n",
+		"charts/base":   "name: base\ndependencies:\n  - name: common\n    repository: file://../common\n",
+		"charts/api":    "name: api\ndependencies:\n  - name: base\n    repository: file://../base\n",
+		"charts/web":    "name: web\ndependencies:\n  - name: common\n    repository: file://../common\n",
+		"charts/worker": "name: worker\n",
+	}
+
+	tests := []struct {
+		name         string
+		changedFiles []string
+		want         []domain.ChangedChart
+	}{
+		{
+			name:         "library change",
+			changedFiles: []string{"charts/common/templates/_helpers.tpl"},
+			want: []domain.ChangedChart{
+				{Name: "common", Path: "charts/common"},
+				{Name: "api", Path: "charts/api", AffectedVia: []string{"common"}},
+				{Name: "base", Path: "charts/base", AffectedVia: []string{"common"}},
+				{Name: "web", Path: "charts/web", AffectedVia: []string{"common"}},
+			},
+		},
+		{
+			name:         "leaf change",
+			changedFiles: []string{"charts/worker/values.yaml", "README.md"},
+			want:         []domain.ChangedChart{{Name: "worker", Path: "charts/worker"}},
+		},
+		{
+			name:         "no chart changes",
+			changedFiles: []string{"README.md"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client, _ := fakeGitHub(t, tt.changedFiles, charts)
+			a := New(client, slog.New(slog.NewTextHandler(io.Discard, nil)), layout, nil)
+			got, err := a.GetChangedCharts(context.Background(), pr)
+			if err != nil {
+				t.Fatalf("GetChangedCharts() error: %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("GetChangedCharts() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestGetChangedCharts_ReadsEachChartYamlOnce(t *testing.T) {
+	layout := domain.ChartLayout{Include: []string{"charts/*"}}
+	pr := domain.PRContext{Owner: "org", Repo: "repo", PRNumber: 1, HeadRef: "feat", HeadSHA: "abc"}
+	charts := map[string]string{
+		"charts/common": "name: common\ntype: library\n",
+		"charts/api":    "name: api\ndependencies:\n  - name: common\n    repository: file://../common\n",
+		"charts/worker": "name: worker\n",
+	}
+
+	client, blobFetches := fakeGitHub(t, []string{"charts/common/values.yaml"}, charts)
+	a := New(client, slog.New(slog.NewTextHandler(io.Discard, nil)), layout, nil)
+	want := []domain.ChangedChart{
+		{Name: "common", Path: "charts/common"},
+		{Name: "api", Path: "charts/api", AffectedVia: []string{"common"}},
+	}
+	for i := range 2 {
+		got, err := a.GetChangedCharts(context.Background(), pr)
+		if err != nil {
+			t.Fatalf("GetChangedCharts() error: %v", err)
+		}
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("GetChangedCharts() call %d = %+v, want %+v", i+1, got, want)
+		}
+	}
+	if n := blobFetches.Load(); n != int32(len(charts)) {
+		t.Errorf("fetched %d Chart.yaml blobs over two events, want %d (one per chart)", n, len(charts))
+	}
+
+	// A push that edits one Chart.yaml fetches only that one
+	charts["charts/worker"] = "name: worker\nversion: 2.0.0\n"
+	client, blobFetches = fakeGitHub(t, []string{"charts/worker/Chart.yaml"}, charts)
+	a.client = client
+	got, err := a.GetChangedCharts(context.Background(), pr)
+	if err != nil {
+		t.Fatalf("GetChangedCharts() error: %v", err)
+	}
+	if want := []domain.ChangedChart{{Name: "worker", Path: "charts/worker"}}; !reflect.DeepEqual(got, want) {
+		t.Errorf("GetChangedCharts() after edit = %+v, want %+v", got, want)
+	}
+	if n := blobFetches.Load(); n != 1 {
+		t.Errorf("fetched %d Chart.yaml blobs after editing one, want 1", n)
+	}
+}
+
+func TestGetChangedCharts_Layout(t *testing.T) {
+	layout := domain.ChartLayout{
+		Include: []string{"services/*/deploy/chart", "platform/charts/*"},
+		Exclude: []string{"platform/charts/deprecated-*"},
+	}
+	pr := domain.PRContext{Owner: "org", Repo: "repo", PRNumber: 1, HeadRef: "feat", HeadSHA: "abc"}
+	charts := map[string]string{
+		"services/api/deploy/chart":          "name: api\n",
+		"services/web/deploy/chart":          "name: web\n",
+		"platform/charts/ingress":            "name: ingress\n",
+		"platform/charts/deprecated-dns":     "name: dns\n",
+		"services/api/deploy/chart/charts/x": "name: x\n", // Vendored subchart, not a chart location
+	}
+	changedFiles := []string{
+		"services/api/deploy/chart/charts/x/values.yaml",
+		"services/api/src/main.go",
+		"platform/charts/ingress/values.yaml",
+		"platform/charts/deprecated-dns/values.yaml",
+	}
+
+	client, _ := fakeGitHub(t, changedFiles, charts)
+	a := New(client, slog.New(slog.NewTextHandler(io.Discard, nil)), layout, nil)
+	got, err := a.GetChangedCharts(context.Background(), pr)
+	if err != nil {
+		t.Fatalf("GetChangedCharts() error: %v", err)
+	}
+	want := []domain.ChangedChart{
+		{Name: "ingress", Path: "platform/charts/ingress"},
+		{Name: "api", Path: "services/api/deploy/chart"},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("GetChangedCharts() = %+v, want %+v", got, want)
+	}
+}
+
+// staticValueIndex reports fixed charts for any of the files it knows.
+type staticValueIndex map[string]domain.ChangedChart // value file -> chart using it
+
+func (s staticValueIndex) ChartsUsingFiles(
+	_ context.Context,
+	_ domain.PRContext,
+	files []string,
+) ([]domain.ChangedChart, error) {
+	var charts []domain.ChangedChart
+	for _, f := range files {
+		if c, ok := s[f]; ok {
+			charts = append(charts, c)
+		}
+	}
+	return charts, nil
+}
+
+func TestGetChangedCharts_ValueFileUsers(t *testing.T) {
+	layout := domain.ChartLayout{Include: []string{"charts/*"}}
+	pr := domain.PRContext{Owner: "org", Repo: "repo", PRNumber: 1, HeadRef: "feat", HeadSHA: "abc"}
+	charts := map[string]string{
+		"charts/api": "name: api\n",
+		"charts/web": "name: web\n",
+	}
+	index := staticValueIndex{
+		"deploy/values/api-prod.yaml": {Name: "api", Path: "charts/api", AffectedVia: []string{"deploy/values/api-prod.yaml"}},
+		"deploy/values/web-prod.yaml": {Name: "web", Path: "charts/web", AffectedVia: []string{"deploy/values/web-prod.yaml"}},
+	}
+	changedFiles := []string{"charts/web/values.yaml", "deploy/values/api-prod.yaml", "deploy/values/web-prod.yaml"}
+
+	client, _ := fakeGitHub(t, changedFiles, charts)
+	a := New(client, slog.New(slog.NewTextHandler(io.Discard, nil)), layout, index)
+	got, err := a.GetChangedCharts(context.Background(), pr)
+	if err != nil {
+		t.Fatalf("GetChangedCharts() error: %v", err)
+	}
+	want := []domain.ChangedChart{
+		{Name: "web", Path: "charts/web"},
+		{Name: "api", Path: "charts/api", AffectedVia: []string{"deploy/values/api-prod.yaml"}},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("GetChangedCharts() = %+v, want %+v", got, want)
+	}
+}
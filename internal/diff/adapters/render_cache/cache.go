@@ -0,0 +1,187 @@
+// Package rendercache caches rendered manifests in front of a RendererPort.
+// Renders are keyed by a content hash of the chart tree, the value files and
+// the renderer identity, so identical inputs (e.g. the base branch rendered
+// for every PR that targets it) are served from memory or disk.
+package rendercache
+
+import (
+	"context"
+	"log/slog"
+	"sync"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+
+	"github.com/nathantilsley/chart-val/internal/diff/domain"
+	"github.com/nathantilsley/chart-val/internal/diff/ports"
+)
+
+// Options configures the cache tiers.
+type Options struct {
+	// MemoryBytes bounds the in-memory LRU tier. Zero disables the tier.
+	MemoryBytes int64
+
+	// DiskDir holds the on-disk tier. Empty disables the tier.
+	DiskDir string
+
+	// DiskTTL expires disk entries. Zero keeps them forever.
+	DiskTTL time.Duration
+
+	// Salt identifies the renderer (e.g. helm version and render flags) and is
+	// part of every key, so upgrading the renderer invalidates the cache.
+	Salt string
+
+	// ExcludeDirs are chart subdirectories left out of the tree hash, such
+	// as the per-environment values directory. Files in them only affect a
+	// render when passed as value files, which are always hashed.
+	ExcludeDirs []string
+
+	// DependencyCacheDir is the renderer's cache of packaged remote
+	// dependencies. The archives a chart resolves to are hashed into its
+	// key, so a re-published dependency version invalidates its renders.
+	DependencyCacheDir string
+}
+
+// Renderer implements ports.RendererPort by serving cached renders and
+// delegating misses to the wrapped renderer. Concurrent renders of the same
+// key share one underlying render. Failed renders are never cached.
+type Renderer struct {
+	inner  ports.RendererPort
+	opts   Options
+	memory *memoryTier // nil when disabled
+	disk   *diskTier   // nil when disabled
+	logger *slog.Logger
+
+	mu       sync.Mutex
+	inflight map[string]*call
+
+	requests metric.Int64Counter
+}
+
+// call is a render in progress that other callers for the same key wait on.
+type call struct {
+	done     chan struct{}
+	manifest []byte
+	err      error
+}
+
+// New wraps inner with a render cache.
+func New(
+	logger *slog.Logger,
+	meter metric.Meter,
+	metricPrefix string,
+	inner ports.RendererPort,
+	opts Options,
+) (*Renderer, error) {
+	requests, _ := meter.Int64Counter(metricPrefix+".render_cache.requests",
+		metric.WithUnit("{request}"),
+		metric.WithDescription("Render cache lookups by result (memory_hit, disk_hit, miss, shared)"),
+	)
+
+	r := &Renderer{
+		inner:    inner,
+		opts:     opts,
+		logger:   logger,
+		inflight: make(map[string]*call),
+		requests: requests,
+	}
+	if opts.MemoryBytes > 0 {
+		r.memory = newMemoryTier(opts.MemoryBytes)
+	}
+	if opts.DiskDir != "" {
+		disk, err := newDiskTier(opts.DiskDir, opts.DiskTTL)
+		if err != nil {
+			return nil, err
+		}
+		r.disk = disk
+	}
+	return r, nil
+}
+
+// Render returns the cached manifest for these inputs, rendering on a miss.
+// If the inputs can't be hashed the render bypasses the cache.
+func (r *Renderer) Render(
+	ctx context.Context,
+	chartDir string,
+	valueFiles []string,
+	opts domain.HelmOptions,
+) ([]byte, error) {
+	key, err := cacheKey(r.opts, chartDir, valueFiles, opts)
+	if err != nil {
+		r.logger.Warn("render cache bypassed", "chartDir", chartDir, "error", err)
+		return r.inner.Render(ctx, chartDir, valueFiles, opts)
+	}
+
+	if manifest, ok := r.lookup(ctx, key); ok {
+		return manifest, nil
+	}
+
+	r.mu.Lock()
+	if c, ok := r.inflight[key]; ok {
+		r.mu.Unlock()
+		r.record(ctx, "shared")
+		select {
+		case <-c.done:
+			return c.manifest, c.err
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+	c := &call{done: make(chan struct{})}
+	r.inflight[key] = c
+	r.mu.Unlock()
+
+	r.record(ctx, "miss")
+	c.manifest, c.err = r.inner.Render(ctx, chartDir, valueFiles, opts)
+	if c.err == nil {
+		r.store(key, c.manifest)
+	}
+
+	r.mu.Lock()
+	delete(r.inflight, key)
+	r.mu.Unlock()
+	close(c.done)
+
+	return c.manifest, c.err
+}
+
+// lookup checks the memory tier, then the disk tier, promoting disk hits
+// into memory.
+func (r *Renderer) lookup(ctx context.Context, key string) ([]byte, bool) {
+	if r.memory != nil {
+		if manifest, ok := r.memory.get(key); ok {
+			r.record(ctx, "memory_hit")
+			return manifest, true
+		}
+	}
+	if r.disk != nil {
+		manifest, ok, err := r.disk.get(key)
+		if err != nil {
+			r.logger.Warn("render cache disk read failed", "error", err)
+		}
+		if ok {
+			if r.memory != nil {
+				r.memory.put(key, manifest)
+			}
+			r.record(ctx, "disk_hit")
+			return manifest, true
+		}
+	}
+	return nil, false
+}
+
+func (r *Renderer) store(key string, manifest []byte) {
+	if r.memory != nil {
+		r.memory.put(key, manifest)
+	}
+	if r.disk != nil {
+		if err := r.disk.put(key, manifest); err != nil {
+			r.logger.Warn("render cache disk write failed", "error", err)
+		}
+	}
+}
+
+func (r *Renderer) record(ctx context.Context, result string) {
+	r.requests.Add(ctx, 1, metric.WithAttributes(attribute.String("result", result)))
+}
@@ -0,0 +1,303 @@
+package rendercache
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+
+	noopmetric "go.opentelemetry.io/otel/metric/noop"
+
+	"github.com/nathantilsley/chart-val/internal/diff/domain"
+)
+
+// countingRenderer returns the chart dir's template contents and counts calls.
+type countingRenderer struct {
+	mu    sync.Mutex
+	calls int
+	err   error
+}
+
+func (c *countingRenderer) Render(
+	_ context.Context,
+	chartDir string,
+	valueFiles []string,
+	_ domain.HelmOptions,
+) ([]byte, error) {
+	c.mu.Lock()
+	c.calls++
+	c.mu.Unlock()
+	if c.err != nil {
+		return nil, c.err
+	}
+	out, err := os.ReadFile(filepath.Join(chartDir, "templates", "cm.yaml"))
+	for _, vf := range valueFiles {
+		v, _ := os.ReadFile(filepath.Join(chartDir, vf))
+		out = append(out, v...)
+	}
+	return out, err
+}
+
+// writeChart creates a chart checkout in a fresh temp dir, like
+// SourceControlPort does for every PR.
+func writeChart(t *testing.T, files map[string]string) string {
+	t.Helper()
+	dir := t.TempDir()
+	for name, content := range files {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0o750); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return dir
+}
+
+func baseChart() map[string]string {
+	return map[string]string{
+		"Chart.yaml":              "name: app\nversion: 1.0.0\n",
+		"values.yaml":             "replicas: 1\n",
+		"templates/cm.yaml":       "kind: ConfigMap\n",
+		"env/dev-values.yaml":     "replicas: 2\n",
+		"env/prod-values.yaml":    "replicas: 3\n",
+		"charts/common-1.0.0.tgz": "dependency archive",
+	}
+}
+
+func newTestRenderer(t *testing.T, inner *countingRenderer, opts Options) *Renderer {
+	t.Helper()
+	if opts.MemoryBytes == 0 && opts.DiskDir == "" {
+		opts.MemoryBytes = 1 << 20
+	}
+	opts.ExcludeDirs = []string{"env"}
+	r, err := New(slog.New(slog.NewTextHandler(io.Discard, nil)), noopmetric.NewMeterProvider().Meter("test"), "test", inner, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return r
+}
+
+func TestRenderer_CacheKeys(t *testing.T) {
+	prodValues := []string{"env/prod-values.yaml"}
+
+	tests := []struct {
+		name      string
+		change    func(files map[string]string)
+		wantCalls int // Renders after rendering the base chart and then the changed one
+	}{
+		{name: "identical checkout in another dir", change: func(map[string]string) {}, wantCalls: 1},
+		{name: "template changed", change: func(f map[string]string) { f["templates/cm.yaml"] = "kind: Secret\n" }, wantCalls: 2},
+		{name: "dependency changed", change: func(f map[string]string) { f["charts/common-1.0.0.tgz"] = "v2" }, wantCalls: 2},
+		{name: "default values changed", change: func(f map[string]string) { f["values.yaml"] = "replicas: 5\n" }, wantCalls: 2},
+		{name: "used env values changed", change: func(f map[string]string) { f["env/prod-values.yaml"] = "replicas: 9\n" }, wantCalls: 2},
+		{name: "other env values changed", change: func(f map[string]string) { f["env/dev-values.yaml"] = "replicas: 9\n" }, wantCalls: 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			inner := &countingRenderer{}
+			r := newTestRenderer(t, inner, Options{})
+			ctx := context.Background()
+
+			if _, err := r.Render(ctx, writeChart(t, baseChart()), prodValues, domain.HelmOptions{}); err != nil {
+				t.Fatal(err)
+			}
+			changed := baseChart()
+			tt.change(changed)
+			if _, err := r.Render(ctx, writeChart(t, changed), prodValues, domain.HelmOptions{}); err != nil {
+				t.Fatal(err)
+			}
+
+			if inner.calls != tt.wantCalls {
+				t.Errorf("inner renders = %d, want %d", inner.calls, tt.wantCalls)
+			}
+		})
+	}
+}
+
+func TestRenderer_ValueFileOrderAndSalt(t *testing.T) {
+	dir := writeChart(t, baseChart())
+	ctx := context.Background()
+	inner := &countingRenderer{}
+	r := newTestRenderer(t, inner, Options{Salt: "helm v3.14"})
+
+	_, _ = r.Render(ctx, dir, []string{"env/dev-values.yaml", "env/prod-values.yaml"}, domain.HelmOptions{})
+	_, _ = r.Render(ctx, dir, []string{"env/prod-values.yaml", "env/dev-values.yaml"}, domain.HelmOptions{})
+	if inner.calls != 2 {
+		t.Errorf("value file order should be part of the key; renders = %d, want 2", inner.calls)
+	}
+
+	other := newTestRenderer(t, inner, Options{Salt: "helm v3.15"})
+	other.memory = r.memory // Same storage, different renderer identity
+	_, _ = other.Render(ctx, dir, []string{"env/dev-values.yaml", "env/prod-values.yaml"}, domain.HelmOptions{})
+	if inner.calls != 3 {
+		t.Errorf("salt should be part of the key; renders = %d, want 3", inner.calls)
+	}
+}
+
+func TestRenderer_ExternalValueFiles(t *testing.T) {
+	chart := writeChart(t, baseChart())
+	ctx := context.Background()
+	inner := &countingRenderer{}
+	r := newTestRenderer(t, inner, Options{})
+
+	external := func(content string) string {
+		return filepath.Join(writeChart(t, map[string]string{"deploy/prod.yaml": content}), "deploy", "prod.yaml")
+	}
+	for _, vf := range []string{external("replicas: 3\n"), external("replicas: 3\n"), external("replicas: 4\n")} {
+		if _, err := r.Render(ctx, chart, []string{"env/prod-values.yaml", vf}, domain.HelmOptions{}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if inner.calls != 2 {
+		t.Errorf("external value files should be keyed by content only; renders = %d, want 2", inner.calls)
+	}
+}
+
+func TestRenderer_HelmOptionsInKey(t *testing.T) {
+	chart := writeChart(t, baseChart())
+	ctx := context.Background()
+	inner := &countingRenderer{}
+	r := newTestRenderer(t, inner, Options{})
+
+	options := []domain.HelmOptions{
+		{},
+		{Values: "replicas: 4\n"},
+		{Parameters: []domain.HelmParameter{{Name: "image.tag", Value: "v2"}}},
+		{Parameters: []domain.HelmParameter{{Name: "image.tag", Value: "v2", ForceString: true}}},
+		{ReleaseName: "my-app-prod"},
+		{Namespace: "prod"},
+		{IncludeCRDs: true},
+	}
+	for _, opts := range options {
+		for range 2 {
+			if _, err := r.Render(ctx, chart, nil, opts); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+	if inner.calls != len(options) {
+		t.Errorf("each option set should have its own entry; renders = %d, want %d", inner.calls, len(options))
+	}
+}
+
+func TestRenderer_DiskTier(t *testing.T) {
+	cacheDir := t.TempDir()
+	chart := writeChart(t, baseChart())
+	ctx := context.Background()
+	inner := &countingRenderer{}
+
+	first := newTestRenderer(t, inner, Options{DiskDir: cacheDir})
+	want, err := first.Render(ctx, chart, nil, domain.HelmOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// A new process with an empty memory tier reads the disk entry
+	second := newTestRenderer(t, inner, Options{MemoryBytes: 1 << 20, DiskDir: cacheDir})
+	got, err := second.Render(ctx, chart, nil, domain.HelmOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if inner.calls != 1 || string(got) != string(want) {
+		t.Errorf("renders = %d, manifest = %q; want 1 render and %q from disk", inner.calls, got, want)
+	}
+}
+
+func TestRenderer_ErrorsNotCached(t *testing.T) {
+	chart := writeChart(t, baseChart())
+	inner := &countingRenderer{err: errors.New("helm failed")}
+	r := newTestRenderer(t, inner, Options{})
+
+	for range 2 {
+		if _, err := r.Render(context.Background(), chart, nil, domain.HelmOptions{}); err == nil {
+			t.Fatal("expected render error")
+		}
+	}
+	if inner.calls != 2 {
+		t.Errorf("failed renders should not be cached; renders = %d, want 2", inner.calls)
+	}
+}
+
+func TestMemoryTier_EvictsLeastRecentlyUsed(t *testing.T) {
+	m := newMemoryTier(10)
+	m.put("a", []byte("aaaa"))
+	m.put("b", []byte("bbbb"))
+	m.get("a") // a is now more recent than b
+	m.put("c", []byte("cccc"))
+
+	if _, ok := m.get("b"); ok {
+		t.Error("b should have been evicted")
+	}
+	for _, k := range []string{"a", "c"} {
+		if _, ok := m.get(k); !ok {
+			t.Errorf("%s should still be cached", k)
+		}
+	}
+	m.put("huge", make([]byte, 11))
+	if _, ok := m.get("huge"); ok {
+		t.Error("values larger than the tier should not be stored")
+	}
+}
+
+func TestRenderer_LocalDependenciesInKey(t *testing.T) {
+	files := func(helper string) map[string]string {
+		return map[string]string{
+			"app/Chart.yaml":                "name: app\ndependencies:\n  - name: common\n    repository: file://../common\n",
+			"app/templates/cm.yaml":         "kind: ConfigMap\n",
+			"common/Chart.yaml":             "name: common\ntype: library\n",
+			"common/templates/_helpers.tpl": helper,
+		}
+	}
+	ctx := context.Background()
+	inner := &countingRenderer{}
+	r := newTestRenderer(t, inner, Options{})
+
+	for _, helper := range []string{"v1", "v1", "v2"} {
+		repo := writeChart(t, files(helper))
+		if _, err := r.Render(ctx, filepath.Join(repo, "app"), nil, domain.HelmOptions{}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if inner.calls != 2 {
+		t.Errorf("changes to a file:// dependency should change the key; renders = %d, want 2", inner.calls)
+	}
+}
+
+func TestRenderer_RemoteDependencies(t *testing.T) {
+	files := baseChart()
+	files["Chart.yaml"] = "name: app\nversion: 1.0.0\ndependencies:\n" +
+		"- name: redis\n  version: 17.3.2\n  repository: https://charts.example.com\n"
+	chartDir := writeChart(t, files)
+	depCache := writeChart(t, map[string]string{"redis-17.3.2.tgz": "redis archive"})
+	ctx := context.Background()
+	prodValues := []string{"env/prod-values.yaml"}
+
+	inner := &countingRenderer{}
+	r := newTestRenderer(t, inner, Options{DependencyCacheDir: depCache})
+	for range 2 {
+		if _, err := r.Render(ctx, chartDir, prodValues, domain.HelmOptions{}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if inner.calls != 1 {
+		t.Fatalf("inner renders = %d, want 1", inner.calls)
+	}
+
+	// A re-published archive for the same version changes the key
+	if err := os.WriteFile(filepath.Join(depCache, "redis-17.3.2.tgz"), []byte("rebuilt"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := r.Render(ctx, chartDir, prodValues, domain.HelmOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	if inner.calls != 2 {
+		t.Errorf("inner renders after the archive changed = %d, want 2", inner.calls)
+	}
+}
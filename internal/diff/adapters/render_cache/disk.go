@@ -0,0 +1,96 @@
+package rendercache
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// diskTier stores one file per key. Entries older than ttl are misses and
+// are deleted when encountered, or at startup.
+type diskTier struct {
+	dir string
+	ttl time.Duration
+	now func() time.Time
+}
+
+func newDiskTier(dir string, ttl time.Duration) (*diskTier, error) {
+	if err := os.MkdirAll(dir, 0o750); err != nil {
+		return nil, fmt.Errorf("creating render cache dir: %w", err)
+	}
+	d := &diskTier{dir: dir, ttl: ttl, now: time.Now}
+	d.prune()
+	return d, nil
+}
+
+// prune deletes expired entries left over from earlier processes.
+func (d *diskTier) prune() {
+	if d.ttl <= 0 {
+		return
+	}
+	_ = filepath.WalkDir(d.dir, func(path string, e fs.DirEntry, err error) error {
+		if err != nil || e.IsDir() {
+			return nil //nolint:nilerr // Best effort; unreadable entries are skipped
+		}
+		if info, err := e.Info(); err == nil && d.now().Sub(info.ModTime()) > d.ttl {
+			_ = os.Remove(path)
+		}
+		return nil
+	})
+}
+
+func (d *diskTier) path(key string) string {
+	// Two-level fan-out keeps directories small
+	return filepath.Join(d.dir, key[:2], key+".yaml")
+}
+
+func (d *diskTier) get(key string) ([]byte, bool, error) {
+	path := d.path(key)
+	info, err := os.Stat(path)
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return nil, false, nil
+		}
+		return nil, false, fmt.Errorf("reading render cache: %w", err)
+	}
+	if d.ttl > 0 && d.now().Sub(info.ModTime()) > d.ttl {
+		_ = os.Remove(path)
+		return nil, false, nil
+	}
+
+	//nolint:gosec // G304: path is derived from a hex hash inside the cache dir
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, false, fmt.Errorf("reading render cache: %w", err)
+	}
+	return data, true, nil
+}
+
+// put writes through a temp file so concurrent readers never see a partial entry.
+func (d *diskTier) put(key string, value []byte) error {
+	path := d.path(key)
+	if err := os.MkdirAll(filepath.Dir(path), 0o750); err != nil {
+		return fmt.Errorf("writing render cache: %w", err)
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".render-*")
+	if err != nil {
+		return fmt.Errorf("writing render cache: %w", err)
+	}
+	defer func() { _ = os.Remove(tmp.Name()) }()
+
+	if _, err := tmp.Write(value); err != nil {
+		_ = tmp.Close()
+		return fmt.Errorf("writing render cache: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("writing render cache: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return fmt.Errorf("writing render cache: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,50 @@
+package rendercache
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+
+	"github.com/nathantilsley/chart-val/internal/diff/charttree"
+	"github.com/nathantilsley/chart-val/internal/diff/domain"
+)
+
+// keyVersion changes whenever the key derivation changes, invalidating
+// entries written by older versions.
+const keyVersion = "v5"
+
+// cacheKey hashes everything that determines a render's output: the key
+// version and salt (renderer identity), the chart's inputs on disk (see
+// charttree.WriteInputs), the remote dependencies it resolves to (see
+// charttree.WriteRemoteDependencies) and the environment's helm options.
+func cacheKey(
+	cacheOpts Options,
+	chartDir string,
+	valueFiles []string,
+	opts domain.HelmOptions,
+) (string, error) {
+	h := sha256.New()
+	charttree.WriteField(h, keyVersion)
+	charttree.WriteField(h, cacheOpts.Salt)
+
+	// A missing value file fails the key: helm will fail too, and the error
+	// isn't cached.
+	if err := charttree.WriteInputs(h, chartDir, cacheOpts.ExcludeDirs, valueFiles); err != nil {
+		return "", err
+	}
+	if err := charttree.WriteRemoteDependencies(h, chartDir, cacheOpts.DependencyCacheDir); err != nil {
+		return "", err
+	}
+
+	charttree.WriteField(h, "options")
+	charttree.WriteField(h, opts.Values)
+	for _, p := range opts.Parameters {
+		charttree.WriteField(h, fmt.Sprintf("%s=%s string=%t", p.Name, p.Value, p.ForceString))
+	}
+	charttree.WriteField(h, "release")
+	charttree.WriteField(h, opts.ReleaseName)
+	charttree.WriteField(h, opts.Namespace)
+	charttree.WriteField(h, fmt.Sprint(opts.IncludeCRDs))
+
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
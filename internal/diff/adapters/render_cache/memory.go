@@ -0,0 +1,68 @@
+package rendercache
+
+import (
+	"container/list"
+	"sync"
+)
+
+// memoryTier is an LRU cache bounded by the total size of its values.
+type memoryTier struct {
+	maxBytes int64
+
+	mu      sync.Mutex
+	size    int64
+	order   *list.List // Front is most recently used
+	entries map[string]*list.Element
+}
+
+type memoryEntry struct {
+	key   string
+	value []byte
+}
+
+func newMemoryTier(maxBytes int64) *memoryTier {
+	return &memoryTier{
+		maxBytes: maxBytes,
+		order:    list.New(),
+		entries:  make(map[string]*list.Element),
+	}
+}
+
+func (m *memoryTier) get(key string) ([]byte, bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	el, ok := m.entries[key]
+	if !ok {
+		return nil, false
+	}
+	m.order.MoveToFront(el)
+	return el.Value.(*memoryEntry).value, true
+}
+
+// put stores value, evicting least recently used entries to stay within
+// maxBytes. Values larger than the whole tier aren't stored.
+func (m *memoryTier) put(key string, value []byte) {
+	size := int64(len(value))
+	if size > m.maxBytes {
+		return
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if el, ok := m.entries[key]; ok {
+		m.size -= int64(len(el.Value.(*memoryEntry).value))
+		m.order.Remove(el)
+	}
+	m.entries[key] = m.order.PushFront(&memoryEntry{key: key, value: value})
+	m.size += size
+
+	for m.size > m.maxBytes {
+		oldest := m.order.Back()
+		entry := oldest.Value.(*memoryEntry)
+		m.order.Remove(oldest)
+		delete(m.entries, entry.key)
+		m.size -= int64(len(entry.value))
+	}
+}
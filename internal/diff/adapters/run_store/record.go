@@ -0,0 +1,156 @@
+package runstore
+
+import (
+	"time"
+
+	"github.com/nathantilsley/chart-val/internal/diff/domain"
+)
+
+// runRecord is the stored form of a domain.Run. Field names are part of the
+// stored JSON format; add fields rather than renaming them.
+type runRecord struct {
+	ID         int64          `json:"id"`
+	Owner      string         `json:"owner"`
+	Repo       string         `json:"repo"`
+	PRNumber   int            `json:"prNumber"`
+	BaseRef    string         `json:"baseRef"`
+	HeadRef    string         `json:"headRef"`
+	HeadSHA    string         `json:"headSha"`
+	StartedAt  time.Time      `json:"startedAt"`
+	DurationNS int64          `json:"durationNs"`
+	Error      string         `json:"error,omitempty"`
+	Results    []resultRecord `json:"results"`
+}
+
+type resultRecord struct {
+	Chart        string   `json:"chart"`
+	Environment  string   `json:"environment"`
+	EnvSource    string   `json:"envSource,omitempty"`
+	BaseRef      string   `json:"baseRef"`
+	HeadRef      string   `json:"headRef"`
+	Status       int      `json:"status"`
+	Summary      string   `json:"summary"`
+	UnifiedDiff  string   `json:"unifiedDiff,omitempty"`
+	SemanticDiff string   `json:"semanticDiff,omitempty"`
+	BaseOnly     bool     `json:"baseOnly,omitempty"`
+	Unchanged    bool     `json:"inputsUnchanged,omitempty"`
+	AffectedVia  []string `json:"affectedVia,omitempty"`
+	Warnings     []string `json:"warnings,omitempty"`
+	Added        int      `json:"added,omitempty"`
+	Removed      int      `json:"removed,omitempty"`
+	Modified     int      `json:"modified,omitempty"`
+	DurationNS   int64    `json:"durationNs"`
+
+	ResourceDiffs []resourceDiffRecord `json:"resourceDiffs,omitempty"`
+	Artifacts     []artifactRecord     `json:"artifacts,omitempty"`
+}
+
+type artifactRecord struct {
+	Side string `json:"side"`
+	Key  string `json:"key"`
+	URL  string `json:"url,omitempty"`
+}
+
+type resourceDiffRecord struct {
+	Resource string `json:"resource"`
+	Change   string `json:"change"`
+	Diff     string `json:"diff"`
+}
+
+func toRecord(run domain.Run) runRecord {
+	rec := runRecord{
+		ID:         run.ID,
+		Owner:      run.PR.Owner,
+		Repo:       run.PR.Repo,
+		PRNumber:   run.PR.PRNumber,
+		BaseRef:    run.PR.BaseRef,
+		HeadRef:    run.PR.HeadRef,
+		HeadSHA:    run.PR.HeadSHA,
+		StartedAt:  run.StartedAt,
+		DurationNS: int64(run.Duration),
+		Error:      run.Error,
+		Results:    make([]resultRecord, 0, len(run.Results)),
+	}
+	for _, r := range run.Results {
+		var diffs []resourceDiffRecord
+		for _, d := range r.ResourceDiffs {
+			diffs = append(diffs, resourceDiffRecord{Resource: d.Resource, Change: string(d.Change), Diff: d.Diff})
+		}
+		var artifacts []artifactRecord
+		for _, a := range r.Artifacts {
+			artifacts = append(artifacts, artifactRecord{Side: a.Side, Key: a.Key, URL: a.URL})
+		}
+		rec.Results = append(rec.Results, resultRecord{
+			Chart:        r.ChartName,
+			Environment:  r.Environment,
+			EnvSource:    r.EnvSource,
+			BaseRef:      r.BaseRef,
+			HeadRef:      r.HeadRef,
+			Status:       int(r.Status),
+			Summary:      r.Summary,
+			UnifiedDiff:  r.UnifiedDiff,
+			SemanticDiff: r.SemanticDiff,
+			BaseOnly:     r.BaseOnly,
+			Unchanged:    r.InputsUnchanged,
+			AffectedVia:  r.AffectedVia,
+			Warnings:     r.Warnings,
+			Added:        r.Resources.Added,
+			Removed:      r.Resources.Removed,
+			Modified:     r.Resources.Modified,
+			DurationNS:   int64(r.Duration),
+
+			ResourceDiffs: diffs,
+			Artifacts:     artifacts,
+		})
+	}
+	return rec
+}
+
+func (rec runRecord) toDomain() domain.Run {
+	run := domain.Run{
+		ID: rec.ID,
+		PR: domain.PRContext{
+			Owner:    rec.Owner,
+			Repo:     rec.Repo,
+			PRNumber: rec.PRNumber,
+			BaseRef:  rec.BaseRef,
+			HeadRef:  rec.HeadRef,
+			HeadSHA:  rec.HeadSHA,
+		},
+		StartedAt: rec.StartedAt,
+		Duration:  time.Duration(rec.DurationNS),
+		Error:     rec.Error,
+		Results:   make([]domain.DiffResult, 0, len(rec.Results)),
+	}
+	for _, r := range rec.Results {
+		var diffs []domain.ResourceDiff
+		for _, d := range r.ResourceDiffs {
+			diffs = append(diffs, domain.ResourceDiff{Resource: d.Resource, Change: domain.ChangeType(d.Change), Diff: d.Diff})
+		}
+		var artifacts []domain.ManifestArtifact
+		for _, a := range r.Artifacts {
+			artifacts = append(artifacts, domain.ManifestArtifact{Side: a.Side, Key: a.Key, URL: a.URL})
+		}
+		run.Results = append(run.Results, domain.DiffResult{
+			ChartName:       r.Chart,
+			Environment:     r.Environment,
+			EnvSource:       r.EnvSource,
+			BaseRef:         r.BaseRef,
+			HeadRef:         r.HeadRef,
+			Status:          domain.Status(r.Status),
+			Summary:         r.Summary,
+			UnifiedDiff:     r.UnifiedDiff,
+			SemanticDiff:    r.SemanticDiff,
+			BaseOnly:        r.BaseOnly,
+			InputsUnchanged: r.Unchanged,
+			AffectedVia:     r.AffectedVia,
+			Warnings:        r.Warnings,
+			Resources:       domain.ResourceChanges{Added: r.Added, Removed: r.Removed, Modified: r.Modified},
+			Duration:        time.Duration(r.DurationNS),
+
+			ResourceDiffs: diffs,
+			Artifacts:     artifacts,
+		})
+	}
+	return run
+}
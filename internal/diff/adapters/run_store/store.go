@@ -0,0 +1,228 @@
+// Package runstore persists run history in an embedded SQLite database.
+package runstore
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+
+	_ "modernc.org/sqlite" // Pure-Go SQLite driver, registered as "sqlite"
+
+	"github.com/nathantilsley/chart-val/internal/diff/domain"
+)
+
+// schema creates the run tables. A run's full record is stored as JSON in
+// runs.data; the indexed columns and the results table exist to filter on.
+const schema = `
+CREATE TABLE IF NOT EXISTS runs (
+	id         INTEGER PRIMARY KEY AUTOINCREMENT,
+	repo       TEXT    NOT NULL,
+	pr_number  INTEGER NOT NULL,
+	head_sha   TEXT    NOT NULL,
+	created_at INTEGER NOT NULL,
+	data       BLOB    NOT NULL
+);
+CREATE INDEX IF NOT EXISTS runs_repo ON runs (repo, id);
+CREATE INDEX IF NOT EXISTS runs_head_sha ON runs (head_sha);
+CREATE INDEX IF NOT EXISTS runs_created_at ON runs (created_at);
+
+CREATE TABLE IF NOT EXISTS results (
+	run_id      INTEGER NOT NULL REFERENCES runs (id) ON DELETE CASCADE,
+	chart       TEXT    NOT NULL,
+	environment TEXT    NOT NULL,
+	status      TEXT    NOT NULL,
+	search      TEXT    NOT NULL
+);
+CREATE INDEX IF NOT EXISTS results_run ON results (run_id);
+CREATE INDEX IF NOT EXISTS results_chart ON results (chart, run_id);
+CREATE INDEX IF NOT EXISTS results_environment ON results (environment, run_id);
+CREATE INDEX IF NOT EXISTS results_status ON results (status, run_id);
+`
+
+// Store implements ports.RunHistoryPort. Run IDs increase monotonically, so
+// ordering by ID yields newest first.
+type Store struct {
+	db *sql.DB
+}
+
+// Open opens (or creates) the database file at path.
+func Open(path string) (*Store, error) {
+	dsn := "file:" + path + "?_pragma=busy_timeout(5000)&_pragma=journal_mode(WAL)&_pragma=foreign_keys(1)"
+	db, err := sql.Open("sqlite", dsn)
+	if err != nil {
+		return nil, fmt.Errorf("opening run history database: %w", err)
+	}
+	// SQLite allows a single writer; one connection avoids SQLITE_BUSY.
+	db.SetMaxOpenConns(1)
+
+	if _, err := db.Exec(schema); err != nil {
+		_ = db.Close()
+		return nil, fmt.Errorf("initializing run history database: %w", err)
+	}
+
+	return &Store{db: db}, nil
+}
+
+// Close closes the database.
+func (s *Store) Close() error {
+	return s.db.Close()
+}
+
+// SaveRun stores a run and returns its assigned ID.
+func (s *Store) SaveRun(ctx context.Context, run domain.Run) (id int64, err error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, fmt.Errorf("saving run: %w", err)
+	}
+	defer func() {
+		if err != nil {
+			_ = tx.Rollback()
+		}
+	}()
+
+	// The ID is part of the stored record, so insert first and fill in data
+	// once it is known.
+	res, err := tx.ExecContext(ctx,
+		`INSERT INTO runs (repo, pr_number, head_sha, created_at, data) VALUES (?, ?, ?, ?, '')`,
+		run.PR.Owner+"/"+run.PR.Repo, run.PR.PRNumber, run.PR.HeadSHA, run.StartedAt.UnixNano())
+	if err != nil {
+		return 0, fmt.Errorf("saving run: %w", err)
+	}
+	if id, err = res.LastInsertId(); err != nil {
+		return 0, fmt.Errorf("saving run: %w", err)
+	}
+
+	run.ID = id
+	data, err := json.Marshal(toRecord(run))
+	if err != nil {
+		return 0, fmt.Errorf("encoding run: %w", err)
+	}
+	if _, err = tx.ExecContext(ctx, `UPDATE runs SET data = ? WHERE id = ?`, data, id); err != nil {
+		return 0, fmt.Errorf("saving run: %w", err)
+	}
+
+	for _, r := range run.Results {
+		_, err = tx.ExecContext(ctx,
+			`INSERT INTO results (run_id, chart, environment, status, search) VALUES (?, ?, ?, ?, ?)`,
+			id, r.ChartName, r.Environment, string(r.Outcome()), searchText(r))
+		if err != nil {
+			return 0, fmt.Errorf("saving run results: %w", err)
+		}
+	}
+
+	if err = tx.Commit(); err != nil {
+		return 0, fmt.Errorf("saving run: %w", err)
+	}
+	return id, nil
+}
+
+// ListRuns returns runs matching the filter, newest first.
+func (s *Store) ListRuns(ctx context.Context, filter domain.RunFilter) ([]domain.Run, error) {
+	query, args := listQuery(filter)
+	rows, err := s.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("listing runs: %w", err)
+	}
+	defer func() { _ = rows.Close() }()
+
+	var runs []domain.Run
+	for rows.Next() {
+		var data []byte
+		if err := rows.Scan(&data); err != nil {
+			return nil, fmt.Errorf("listing runs: %w", err)
+		}
+		run, err := decode(data)
+		if err != nil {
+			return nil, err
+		}
+		runs = append(runs, run)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("listing runs: %w", err)
+	}
+	return runs, nil
+}
+
+// GetRun returns a single run.
+func (s *Store) GetRun(ctx context.Context, id int64) (domain.Run, error) {
+	var data []byte
+	err := s.db.QueryRowContext(ctx, `SELECT data FROM runs WHERE id = ?`, id).Scan(&data)
+	if errors.Is(err, sql.ErrNoRows) {
+		return domain.Run{}, domain.NewNotFoundError(fmt.Sprintf("run %d", id), "")
+	}
+	if err != nil {
+		return domain.Run{}, fmt.Errorf("getting run %d: %w", id, err)
+	}
+	return decode(data)
+}
+
+// listQuery builds the SELECT for a filter. Result filters are combined in a
+// single EXISTS so they must all match the same result, as in
+// domain.RunFilter.Matches.
+func listQuery(filter domain.RunFilter) (string, []any) {
+	var where []string
+	var args []any
+
+	if filter.Repo != "" {
+		where = append(where, "repo = ?")
+		args = append(args, filter.Repo)
+	}
+	if filter.HeadSHA != "" {
+		where = append(where, "head_sha = ?")
+		args = append(args, filter.HeadSHA)
+	}
+	if filter.Before > 0 {
+		where = append(where, "id < ?")
+		args = append(args, filter.Before)
+	}
+
+	var result []string
+	if filter.Chart != "" {
+		result = append(result, "r.chart = ?")
+		args = append(args, filter.Chart)
+	}
+	if filter.Environment != "" {
+		result = append(result, "r.environment = ?")
+		args = append(args, filter.Environment)
+	}
+	if filter.Outcome != "" {
+		result = append(result, "r.status = ?")
+		args = append(args, string(filter.Outcome))
+	}
+	if filter.Search != "" {
+		result = append(result, "instr(r.search, ?) > 0")
+		args = append(args, strings.ToLower(filter.Search))
+	}
+	if len(result) > 0 {
+		where = append(where,
+			"EXISTS (SELECT 1 FROM results r WHERE r.run_id = runs.id AND "+strings.Join(result, " AND ")+")")
+	}
+
+	query := "SELECT data FROM runs"
+	if len(where) > 0 {
+		query += " WHERE " + strings.Join(where, " AND ")
+	}
+	query += " ORDER BY id DESC"
+	if filter.Limit > 0 {
+		query += " LIMIT ?"
+		args = append(args, filter.Limit)
+	}
+	return query, args
+}
+
+// searchText is the lower-cased text RunFilter.Search matches against. The
+// fields are NUL-separated so a term can't match across two of them.
+func searchText(r domain.DiffResult) string {
+	return strings.ToLower(strings.Join([]string{r.ChartName, r.Environment, r.UnifiedDiff, r.SemanticDiff}, "\x00"))
+}
+
+func decode(data []byte) (domain.Run, error) {
+	var rec runRecord
+	if err := json.Unmarshal(data, &rec); err != nil {
+		return domain.Run{}, fmt.Errorf("decoding run: %w", err)
+	}
+	return rec.toDomain(), nil
+}
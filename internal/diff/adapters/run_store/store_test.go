@@ -0,0 +1,125 @@
+package runstore
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/nathantilsley/chart-val/internal/diff/domain"
+)
+
+func openTestStore(t *testing.T) *Store {
+	t.Helper()
+	s, err := Open(filepath.Join(t.TempDir(), "runs.db"))
+	if err != nil {
+		t.Fatalf("Open() error: %v", err)
+	}
+	t.Cleanup(func() { _ = s.Close() })
+	return s
+}
+
+func TestStore_SaveAndGet(t *testing.T) {
+	s := openTestStore(t)
+	ctx := context.Background()
+
+	run := domain.Run{
+		PR:        domain.PRContext{Owner: "org", Repo: "charts", PRNumber: 1, HeadSHA: "abc"},
+		StartedAt: time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC),
+		Duration:  1500 * time.Millisecond,
+		Results: []domain.DiffResult{{
+			ChartName: "app", Environment: "prod", Status: domain.StatusChanges,
+			UnifiedDiff: "-a\n+b", Warnings: []string{"w"}, AffectedVia: []string{"common"},
+			Resources: domain.ResourceChanges{Modified: 1}, Duration: time.Second,
+			ResourceDiffs: []domain.ResourceDiff{{Resource: "ConfigMap/app", Change: domain.ChangeModified, Diff: "-a\n+b"}},
+		}},
+	}
+
+	id, err := s.SaveRun(ctx, run)
+	if err != nil {
+		t.Fatalf("SaveRun() error: %v", err)
+	}
+
+	got, err := s.GetRun(ctx, id)
+	if err != nil {
+		t.Fatalf("GetRun() error: %v", err)
+	}
+	if got.ID != id || got.PR != run.PR || !got.StartedAt.Equal(run.StartedAt) || got.Duration != run.Duration {
+		t.Errorf("GetRun() = %+v", got)
+	}
+	if len(got.Results) != 1 {
+		t.Fatalf("GetRun() returned %d results, want 1", len(got.Results))
+	}
+	r := got.Results[0]
+	if r.Status != domain.StatusChanges || r.UnifiedDiff != "-a\n+b" || r.Resources.Modified != 1 || r.Duration != time.Second {
+		t.Errorf("result = %+v", r)
+	}
+	if len(r.AffectedVia) != 1 || r.AffectedVia[0] != "common" {
+		t.Errorf("affected via = %v, want [common]", r.AffectedVia)
+	}
+	if len(r.ResourceDiffs) != 1 || r.ResourceDiffs[0] != run.Results[0].ResourceDiffs[0] {
+		t.Errorf("resource diffs = %+v, want %+v", r.ResourceDiffs, run.Results[0].ResourceDiffs)
+	}
+
+	if _, err := s.GetRun(ctx, id+1); !domain.IsNotFound(err) {
+		t.Errorf("GetRun(missing) error = %v, want NotFoundError", err)
+	}
+}
+
+func TestStore_ListRuns(t *testing.T) {
+	s := openTestStore(t)
+	ctx := context.Background()
+
+	save := func(repo, chart, env string, status domain.Status) int64 {
+		id, err := s.SaveRun(ctx, domain.Run{
+			PR:      domain.PRContext{Owner: "org", Repo: repo},
+			Results: []domain.DiffResult{{ChartName: chart, Environment: env, Status: status}},
+		})
+		if err != nil {
+			t.Fatalf("SaveRun() error: %v", err)
+		}
+		return id
+	}
+	id1 := save("charts", "app", "prod", domain.StatusChanges)
+	id2 := save("charts", "app", "dev", domain.StatusSuccess)
+	id3 := save("other", "app", "prod", domain.StatusError)
+	id4 := save("charts", "web", "prod", domain.StatusChanges)
+
+	tests := []struct {
+		name   string
+		filter domain.RunFilter
+		want   []int64
+	}{
+		{"all newest first", domain.RunFilter{}, []int64{id4, id3, id2, id1}},
+		{"limit", domain.RunFilter{Limit: 2}, []int64{id4, id3}},
+		{"before", domain.RunFilter{Before: id3}, []int64{id2, id1}},
+		{"before past end", domain.RunFilter{Before: id4 + 10}, []int64{id4, id3, id2, id1}},
+		{"repo", domain.RunFilter{Repo: "org/other"}, []int64{id3}},
+		{"chart and env", domain.RunFilter{Chart: "app", Environment: "prod"}, []int64{id3, id1}},
+		{"outcome", domain.RunFilter{Outcome: domain.OutcomeChanges}, []int64{id4, id1}},
+		{"env and outcome on same result", domain.RunFilter{Environment: "dev", Outcome: domain.OutcomeChanges}, nil},
+		{"search", domain.RunFilter{Search: "WE"}, []int64{id4}},
+		{"filters with limit", domain.RunFilter{Environment: "prod", Limit: 1, Before: id4}, []int64{id3}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			runs, err := s.ListRuns(ctx, tt.filter)
+			if err != nil {
+				t.Fatalf("ListRuns() error: %v", err)
+			}
+			var got []int64
+			for _, r := range runs {
+				got = append(got, r.ID)
+			}
+			if len(got) != len(tt.want) {
+				t.Fatalf("ListRuns() IDs = %v, want %v", got, tt.want)
+			}
+			for i := range got {
+				if got[i] != tt.want[i] {
+					t.Fatalf("ListRuns() IDs = %v, want %v", got, tt.want)
+				}
+			}
+		})
+	}
+}
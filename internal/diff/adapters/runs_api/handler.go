@@ -0,0 +1,219 @@
+// Package runsapi serves the run history over a read-only JSON HTTP API.
+package runsapi
+
+import (
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/nathantilsley/chart-val/internal/diff/domain"
+	"github.com/nathantilsley/chart-val/internal/diff/ports"
+	"github.com/nathantilsley/chart-val/internal/platform/httpauth"
+)
+
+// Handler serves GET /api/runs and GET /api/runs/{id}.
+type Handler struct {
+	useCase ports.RunQueryUseCase
+	token   string
+	logger  *slog.Logger
+}
+
+// NewHandler creates a run history API handler. Runs carry rendered diffs,
+// which may include Secret and ConfigMap data, so requests must carry token
+// as a bearer token.
+func NewHandler(uc ports.RunQueryUseCase, token string, logger *slog.Logger) *Handler {
+	return &Handler{
+		useCase: uc,
+		token:   token,
+		logger:  logger,
+	}
+}
+
+// Register adds the API routes to mux.
+func (h *Handler) Register(mux *http.ServeMux) {
+	mux.HandleFunc("GET /api/runs", httpauth.Require(h.token, h.ListRuns))
+	mux.HandleFunc("GET /api/runs/{id}", httpauth.Require(h.token, h.GetRun))
+}
+
+// ListRuns returns runs newest first, without diffs. Query parameters:
+// repo (owner/repo), sha (head commit), chart, env, status (no-changes,
+// changes, error, base-only), q (search term), before (run ID, for paging),
+// and limit.
+func (h *Handler) ListRuns(w http.ResponseWriter, r *http.Request) {
+	filter, err := parseFilter(r)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	runs, err := h.useCase.ListRuns(r.Context(), filter)
+	if err != nil {
+		h.logger.Error("failed to list runs", "error", err)
+		writeError(w, http.StatusInternalServerError, "failed to list runs")
+		return
+	}
+
+	resp := listResponse{Runs: make([]runJSON, 0, len(runs))}
+	for _, run := range runs {
+		resp.Runs = append(resp.Runs, toJSON(run, false))
+	}
+	if len(runs) > 0 {
+		resp.NextBefore = runs[len(runs)-1].ID
+	}
+	writeJSON(w, http.StatusOK, resp)
+}
+
+// GetRun returns a single run including diffs.
+func (h *Handler) GetRun(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseInt(r.PathValue("id"), 10, 64)
+	if err != nil || id <= 0 {
+		writeError(w, http.StatusBadRequest, "invalid run id")
+		return
+	}
+
+	run, err := h.useCase.GetRun(r.Context(), id)
+	if err != nil {
+		if domain.IsNotFound(err) {
+			writeError(w, http.StatusNotFound, "run not found")
+			return
+		}
+		h.logger.Error("failed to get run", "id", id, "error", err)
+		writeError(w, http.StatusInternalServerError, "failed to get run")
+		return
+	}
+
+	writeJSON(w, http.StatusOK, toJSON(run, true))
+}
+
+func parseFilter(r *http.Request) (domain.RunFilter, error) {
+	q := r.URL.Query()
+	filter := domain.RunFilter{
+		Repo:        q.Get("repo"),
+		HeadSHA:     q.Get("sha"),
+		Chart:       q.Get("chart"),
+		Environment: q.Get("env"),
+		Outcome:     domain.Outcome(q.Get("status")),
+		Search:      q.Get("q"),
+	}
+
+	switch filter.Outcome {
+	case "", domain.OutcomeNoChanges, domain.OutcomeChanges, domain.OutcomeError, domain.OutcomeBaseOnly:
+	default:
+		return domain.RunFilter{}, fmt.Errorf("invalid status %q", filter.Outcome)
+	}
+
+	if v := q.Get("before"); v != "" {
+		n, err := strconv.ParseInt(v, 10, 64)
+		if err != nil || n < 1 {
+			return domain.RunFilter{}, fmt.Errorf("invalid before %q", v)
+		}
+		filter.Before = n
+	}
+	if v := q.Get("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 {
+			return domain.RunFilter{}, fmt.Errorf("invalid limit %q", v)
+		}
+		filter.Limit = n
+	}
+	return filter, nil
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	//nolint:errcheck // Response write errors are not actionable
+	_ = json.NewEncoder(w).Encode(v)
+}
+
+func writeError(w http.ResponseWriter, status int, msg string) {
+	writeJSON(w, status, map[string]string{"error": msg})
+}
+
+type listResponse struct {
+	Runs       []runJSON `json:"runs"`
+	NextBefore int64     `json:"nextBefore,omitempty"` // Pass as ?before= for the next page; absent when empty
+}
+
+type runJSON struct {
+	ID         int64        `json:"id"`
+	Owner      string       `json:"owner"`
+	Repo       string       `json:"repo"`
+	PRNumber   int          `json:"prNumber"`
+	PRURL      string       `json:"prUrl"`
+	BaseRef    string       `json:"baseRef"`
+	HeadRef    string       `json:"headRef"`
+	HeadSHA    string       `json:"headSha"`
+	StartedAt  time.Time    `json:"startedAt"`
+	DurationMS int64        `json:"durationMs"`
+	Error      string       `json:"error,omitempty"`
+	Results    []resultJSON `json:"results"`
+}
+
+type resultJSON struct {
+	Chart        string   `json:"chart"`
+	Environment  string   `json:"environment"`
+	EnvSource    string   `json:"envSource,omitempty"` // Gitops source that supplied the environment
+	Status       string   `json:"status"`              // Outcome: no-changes, changes, error, base-only
+	Summary      string   `json:"summary"`
+	Warnings     []string `json:"warnings,omitempty"`
+	AffectedVia  []string `json:"affectedVia,omitempty"`
+	Added        int      `json:"resourcesAdded"`
+	Removed      int      `json:"resourcesRemoved"`
+	Modified     int      `json:"resourcesModified"`
+	DurationMS   int64    `json:"durationMs"`
+	UnifiedDiff  string   `json:"unifiedDiff,omitempty"`
+	SemanticDiff string   `json:"semanticDiff,omitempty"`
+
+	Artifacts []artifactJSON `json:"artifacts,omitempty"`
+}
+
+type artifactJSON struct {
+	Side string `json:"side"`
+	Key  string `json:"key"`
+	URL  string `json:"url,omitempty"`
+}
+
+func toJSON(run domain.Run, withDiffs bool) runJSON {
+	out := runJSON{
+		ID:         run.ID,
+		Owner:      run.PR.Owner,
+		Repo:       run.PR.Repo,
+		PRNumber:   run.PR.PRNumber,
+		PRURL:      run.PR.URL(),
+		BaseRef:    run.PR.BaseRef,
+		HeadRef:    run.PR.HeadRef,
+		HeadSHA:    run.PR.HeadSHA,
+		StartedAt:  run.StartedAt,
+		DurationMS: run.Duration.Milliseconds(),
+		Error:      run.Error,
+		Results:    make([]resultJSON, 0, len(run.Results)),
+	}
+	for _, r := range run.Results {
+		res := resultJSON{
+			Chart:       r.ChartName,
+			Environment: r.Environment,
+			EnvSource:   r.EnvSource,
+			Status:      string(r.Outcome()),
+			Summary:     r.Summary,
+			Warnings:    r.Warnings,
+			AffectedVia: r.AffectedVia,
+			Added:       r.Resources.Added,
+			Removed:     r.Resources.Removed,
+			Modified:    r.Resources.Modified,
+			DurationMS:  r.Duration.Milliseconds(),
+		}
+		for _, a := range r.Artifacts {
+			res.Artifacts = append(res.Artifacts, artifactJSON{Side: a.Side, Key: a.Key, URL: a.URL})
+		}
+		if withDiffs {
+			res.UnifiedDiff = r.UnifiedDiff
+			res.SemanticDiff = r.SemanticDiff
+		}
+		out.Results = append(out.Results, res)
+	}
+	return out
+}
@@ -0,0 +1,186 @@
+package runsapi
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/nathantilsley/chart-val/internal/diff/domain"
+)
+
+type mockQuery struct {
+	runs       []domain.Run
+	lastFilter domain.RunFilter
+}
+
+func (m *mockQuery) ListRuns(_ context.Context, filter domain.RunFilter) ([]domain.Run, error) {
+	m.lastFilter = filter
+	return m.runs, nil
+}
+
+func (m *mockQuery) GetRun(_ context.Context, id int64) (domain.Run, error) {
+	for _, r := range m.runs {
+		if r.ID == id {
+			return r, nil
+		}
+	}
+	return domain.Run{}, domain.NewNotFoundError("run", "")
+}
+
+func (m *mockQuery) ArtifactURL(context.Context, string) (string, error) {
+	return "", domain.NewNotFoundError("artifact", "")
+}
+
+func newTestServer(t *testing.T, q *mockQuery) *httptest.Server {
+	t.Helper()
+	mux := http.NewServeMux()
+	NewHandler(q, testToken, slog.New(slog.NewTextHandler(io.Discard, nil))).Register(mux)
+	srv := httptest.NewServer(mux)
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+const testToken = "s3cret"
+
+// get requests url with auth as the Authorization header, if set.
+func get(t *testing.T, url, auth string) *http.Response {
+	t.Helper()
+	req, err := http.NewRequestWithContext(context.Background(), http.MethodGet, url, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if auth != "" {
+		req.Header.Set("Authorization", auth)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = resp.Body.Close() })
+	return resp
+}
+
+func TestListRuns(t *testing.T) {
+	q := &mockQuery{runs: []domain.Run{{
+		ID: 9,
+		PR: domain.PRContext{Owner: "org", Repo: "charts", PRNumber: 5},
+		Results: []domain.DiffResult{
+			{ChartName: "app", Environment: "prod", Status: domain.StatusChanges, UnifiedDiff: "-a\n+b"},
+		},
+	}}}
+	srv := newTestServer(t, q)
+
+	tests := []struct {
+		name       string
+		query      string
+		wantStatus int
+		wantFilter domain.RunFilter
+	}{
+		{
+			name:       "filters",
+			query:      "?repo=org/charts&chart=app&env=prod&status=changes&before=10&limit=5",
+			wantStatus: http.StatusOK,
+			wantFilter: domain.RunFilter{
+				Repo: "org/charts", Chart: "app", Environment: "prod",
+				Outcome: domain.OutcomeChanges, Before: 10, Limit: 5,
+			},
+		},
+		{name: "invalid status", query: "?status=broken", wantStatus: http.StatusBadRequest},
+		{name: "invalid limit", query: "?limit=0", wantStatus: http.StatusBadRequest},
+		{name: "invalid before", query: "?before=x", wantStatus: http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp := get(t, srv.URL+"/api/runs"+tt.query, "Bearer "+testToken)
+
+			if resp.StatusCode != tt.wantStatus {
+				t.Fatalf("status = %d, want %d", resp.StatusCode, tt.wantStatus)
+			}
+			if tt.wantStatus != http.StatusOK {
+				return
+			}
+			if q.lastFilter != tt.wantFilter {
+				t.Errorf("filter = %+v, want %+v", q.lastFilter, tt.wantFilter)
+			}
+
+			var body listResponse
+			if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+				t.Fatal(err)
+			}
+			if len(body.Runs) != 1 || body.NextBefore != 9 {
+				t.Fatalf("body = %+v", body)
+			}
+			if got := body.Runs[0].Results[0]; got.Status != "changes" || got.UnifiedDiff != "" {
+				t.Errorf("list result = %+v, want status changes without diff", got)
+			}
+		})
+	}
+}
+
+func TestGetRun(t *testing.T) {
+	q := &mockQuery{runs: []domain.Run{{
+		ID:      3,
+		PR:      domain.PRContext{Owner: "org", Repo: "charts", PRNumber: 5},
+		Results: []domain.DiffResult{{ChartName: "app", Status: domain.StatusChanges, UnifiedDiff: "-a\n+b"}},
+	}}}
+	srv := newTestServer(t, q)
+
+	tests := []struct {
+		path       string
+		wantStatus int
+	}{
+		{"/api/runs/3", http.StatusOK},
+		{"/api/runs/4", http.StatusNotFound},
+		{"/api/runs/abc", http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			resp := get(t, srv.URL+tt.path, "Bearer "+testToken)
+
+			if resp.StatusCode != tt.wantStatus {
+				t.Fatalf("status = %d, want %d", resp.StatusCode, tt.wantStatus)
+			}
+			if tt.wantStatus != http.StatusOK {
+				return
+			}
+			var run runJSON
+			if err := json.NewDecoder(resp.Body).Decode(&run); err != nil {
+				t.Fatal(err)
+			}
+			if run.PRURL != "https://github.com/org/charts/pull/5" || run.Results[0].UnifiedDiff != "-a\n+b" {
+				t.Errorf("run = %+v", run)
+			}
+		})
+	}
+}
+
+func TestAuth(t *testing.T) {
+	q := &mockQuery{runs: []domain.Run{{ID: 3}}}
+	srv := newTestServer(t, q)
+
+	tests := []struct {
+		name string
+		path string
+		auth string
+	}{
+		{name: "list without token", path: "/api/runs"},
+		{name: "run without token", path: "/api/runs/3"},
+		{name: "wrong token", path: "/api/runs/3", auth: "Bearer guess"},
+		{name: "not a bearer token", path: "/api/runs", auth: testToken},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp := get(t, srv.URL+tt.path, tt.auth)
+			if resp.StatusCode != http.StatusUnauthorized {
+				t.Errorf("status = %d, want %d", resp.StatusCode, http.StatusUnauthorized)
+			}
+		})
+	}
+}
@@ -0,0 +1,54 @@
+package webui
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/nathantilsley/chart-val/internal/diff/domain"
+	"github.com/nathantilsley/chart-val/internal/diff/ports"
+)
+
+// ArtifactLinks wraps an artifact store so the download links handed out
+// for its artifacts point at the UI's /ui/artifacts route rather than the
+// store's expiring signed URLs. The links end up in PR comments, check runs
+// and the run history, which outlive any expiry; the route signs a fresh
+// link on every visit, behind the UI's authentication.
+type ArtifactLinks struct {
+	ports.ArtifactStorePort
+	appURL string
+}
+
+// NewArtifactLinks wraps store with links under appURL, the server's public
+// URL.
+func NewArtifactLinks(store ports.ArtifactStorePort, appURL string) *ArtifactLinks {
+	return &ArtifactLinks{ArtifactStorePort: store, appURL: strings.TrimSuffix(appURL, "/")}
+}
+
+// URL returns the UI link for the artifact stored under key.
+func (l *ArtifactLinks) URL(_ context.Context, key string) (string, error) {
+	// Keys are already path-escaped by domain.ArtifactKey; escape them again
+	// so the server's unescaping of the request path yields the key verbatim.
+	segments := strings.Split(key, "/")
+	for i, seg := range segments {
+		segments[i] = url.PathEscape(seg)
+	}
+	return l.appURL + "/ui/artifacts/" + strings.Join(segments, "/"), nil
+}
+
+// ShowArtifact redirects to a freshly signed download link for an artifact.
+func (h *Handler) ShowArtifact(w http.ResponseWriter, r *http.Request) {
+	key := r.PathValue("key")
+	link, err := h.useCase.ArtifactURL(r.Context(), key)
+	if err != nil {
+		if domain.IsNotFound(err) {
+			h.renderError(w, http.StatusNotFound, "Artifact not found")
+			return
+		}
+		h.logger.Error("failed to link artifact", "key", key, "error", err)
+		h.renderError(w, http.StatusInternalServerError, "Failed to link artifact")
+		return
+	}
+	http.Redirect(w, r, link, http.StatusFound)
+}
@@ -0,0 +1,127 @@
+package webui
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Line kinds in a parsed unified diff. They double as CSS class names.
+const (
+	lineContext = "ctx"
+	lineAdd     = "add"
+	lineDel     = "del"
+	lineHunk    = "hunk"
+)
+
+// diffLine is one line of a unified diff with its line numbers in the base
+// (Old) and head (New) manifests; zero means the line is absent on that side.
+type diffLine struct {
+	Kind  string
+	Text  string
+	Old   int
+	New   int
+	Match bool // Contains the search term
+}
+
+// splitRow is one row of the side-by-side view. A nil side renders blank.
+type splitRow struct {
+	Hunk  string // Set for hunk header rows, which span both sides
+	Left  *diffLine
+	Right *diffLine
+}
+
+// parseUnifiedDiff parses a unified diff into lines, dropping the file
+// headers. Lines containing search (case-insensitive) are marked.
+func parseUnifiedDiff(diff, search string) []diffLine {
+	search = strings.ToLower(search)
+	var lines []diffLine
+	var oldNum, newNum int
+	inHunk := false
+
+	for _, text := range strings.Split(diff, "\n") {
+		if strings.HasPrefix(text, "@@") {
+			inHunk = true
+			oldNum, newNum = parseHunkHeader(text)
+			lines = append(lines, diffLine{Kind: lineHunk, Text: text})
+			continue
+		}
+		if !inHunk {
+			continue // "---" / "+++" file headers
+		}
+
+		line := diffLine{Kind: lineContext}
+		switch {
+		case strings.HasPrefix(text, "+"):
+			line.Kind, line.New = lineAdd, newNum
+			newNum++
+		case strings.HasPrefix(text, "-"):
+			line.Kind, line.Old = lineDel, oldNum
+			oldNum++
+		default:
+			line.Old, line.New = oldNum, newNum
+			oldNum++
+			newNum++
+		}
+		if text != "" {
+			text = text[1:]
+		}
+		line.Text = text
+		line.Match = search != "" && strings.Contains(strings.ToLower(text), search)
+		lines = append(lines, line)
+	}
+	return lines
+}
+
+// parseHunkHeader returns the starting base and head line numbers of a
+// "@@ -a,b +c,d @@" header.
+func parseHunkHeader(header string) (oldStart, newStart int) {
+	var oldCount, newCount int
+	if _, err := fmt.Sscanf(header, "@@ -%d,%d +%d,%d @@", &oldStart, &oldCount, &newStart, &newCount); err != nil {
+		// Single-line hunks omit the count ("@@ -3 +3 @@")
+		_, _ = fmt.Sscanf(header, "@@ -%d +%d @@", &oldStart, &newStart)
+	}
+	return oldStart, newStart
+}
+
+// splitRows arranges parsed lines side by side: context lines appear on both
+// sides and each run of deletions is paired row by row with the additions
+// that follow it.
+func splitRows(lines []diffLine) []splitRow {
+	var rows []splitRow
+	var dels, adds []*diffLine
+
+	flush := func() {
+		for i := 0; i < len(dels) || i < len(adds); i++ {
+			var row splitRow
+			if i < len(dels) {
+				row.Left = dels[i]
+			}
+			if i < len(adds) {
+				row.Right = adds[i]
+			}
+			rows = append(rows, row)
+		}
+		dels, adds = nil, nil
+	}
+
+	for i := range lines {
+		l := &lines[i]
+		switch l.Kind {
+		case lineDel:
+			if len(adds) > 0 {
+				flush()
+			}
+			dels = append(dels, l)
+		case lineAdd:
+			adds = append(adds, l)
+		case lineHunk:
+			flush()
+			rows = append(rows, splitRow{Hunk: l.Text})
+		default:
+			flush()
+			rows = append(rows, splitRow{Left: l, Right: l})
+		}
+	}
+	flush()
+	return rows
+}
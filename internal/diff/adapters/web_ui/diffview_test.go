@@ -0,0 +1,58 @@
+package webui
+
+import "testing"
+
+const sampleDiff = `--- app/prod (main)
++++ app/prod (feature)
+@@ -1,4 +1,5 @@
+ kind: Deployment
+-replicas: 1
+-image: app:1
++replicas: 3
++image: app:2
++debug: true
+ name: web`
+
+func TestParseUnifiedDiff(t *testing.T) {
+	lines := parseUnifiedDiff(sampleDiff, "REPLICAS")
+
+	want := []diffLine{
+		{Kind: lineHunk, Text: "@@ -1,4 +1,5 @@"},
+		{Kind: lineContext, Text: "kind: Deployment", Old: 1, New: 1},
+		{Kind: lineDel, Text: "replicas: 1", Old: 2, Match: true},
+		{Kind: lineDel, Text: "image: app:1", Old: 3},
+		{Kind: lineAdd, Text: "replicas: 3", New: 2, Match: true},
+		{Kind: lineAdd, Text: "image: app:2", New: 3},
+		{Kind: lineAdd, Text: "debug: true", New: 4},
+		{Kind: lineContext, Text: "name: web", Old: 4, New: 5},
+	}
+	if len(lines) != len(want) {
+		t.Fatalf("parseUnifiedDiff() returned %d lines, want %d: %+v", len(lines), len(want), lines)
+	}
+	for i := range want {
+		if lines[i] != want[i] {
+			t.Errorf("line %d = %+v, want %+v", i, lines[i], want[i])
+		}
+	}
+}
+
+func TestSplitRows(t *testing.T) {
+	rows := splitRows(parseUnifiedDiff(sampleDiff, ""))
+
+	// hunk, context, 3 paired change rows (2 del+add, 1 add only), context
+	if len(rows) != 6 {
+		t.Fatalf("splitRows() returned %d rows, want 6", len(rows))
+	}
+	if rows[0].Hunk == "" {
+		t.Error("first row should be the hunk header")
+	}
+	if rows[1].Left != rows[1].Right || rows[1].Left.Text != "kind: Deployment" {
+		t.Errorf("context row = %+v, want the same line on both sides", rows[1])
+	}
+	if rows[2].Left.Text != "replicas: 1" || rows[2].Right.Text != "replicas: 3" {
+		t.Errorf("row 2 = %s | %s, want deletion paired with addition", rows[2].Left.Text, rows[2].Right.Text)
+	}
+	if rows[4].Left != nil || rows[4].Right.Text != "debug: true" {
+		t.Errorf("row 4 = %+v, want unpaired addition on the right", rows[4])
+	}
+}
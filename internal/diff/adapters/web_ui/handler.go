@@ -0,0 +1,383 @@
+// Package webui serves a browser UI for the run history: run lists with
+// search, per-run chart and environment overviews, and unified,
+// side-by-side, and per-resource diff views.
+package webui
+
+import (
+	"bytes"
+	"embed"
+	"html/template"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/nathantilsley/chart-val/internal/diff/domain"
+	"github.com/nathantilsley/chart-val/internal/diff/ports"
+	"github.com/nathantilsley/chart-val/internal/platform/httpauth"
+)
+
+//go:embed templates/*.html
+var templateFS embed.FS
+
+// Diff views selectable with ?view=.
+const (
+	viewUnified   = "unified"
+	viewSplit     = "split"
+	viewResources = "resources"
+	viewSemantic  = "semantic"
+)
+
+// pageSize is the number of runs per list page.
+const pageSize = 50
+
+var templateFuncs = template.FuncMap{
+	"shortSHA": func(sha string) string {
+		if len(sha) > 7 {
+			return sha[:7]
+		}
+		return sha
+	},
+	"duration": func(d time.Duration) string { return d.Round(time.Millisecond).String() },
+	"timestamp": func(t time.Time) string {
+		return t.UTC().Format("2006-01-02 15:04:05 UTC")
+	},
+	"outcome": func(r domain.DiffResult) string { return string(r.Outcome()) },
+}
+
+// pages maps each page template to its parsed set (the page plus the shared layout).
+var pages = parsePages("runs.html", "run.html", "diff.html", "error.html")
+
+func parsePages(names ...string) map[string]*template.Template {
+	m := make(map[string]*template.Template, len(names))
+	for _, name := range names {
+		m[name] = template.Must(template.New(name).Funcs(templateFuncs).
+			ParseFS(templateFS, "templates/layout.html", "templates/"+name))
+	}
+	return m
+}
+
+// Handler serves the web UI under /ui/.
+type Handler struct {
+	useCase ports.RunQueryUseCase
+	appName string
+	token   string
+	logger  *slog.Logger
+}
+
+// NewHandler creates a web UI handler. Pages show rendered diffs, so every
+// route requires token; browsers prompt for it as the basic auth password.
+func NewHandler(uc ports.RunQueryUseCase, appName, token string, logger *slog.Logger) *Handler {
+	return &Handler{
+		useCase: uc,
+		appName: appName,
+		token:   token,
+		logger:  logger,
+	}
+}
+
+// Register adds the UI routes to mux.
+func (h *Handler) Register(mux *http.ServeMux) {
+	mux.Handle("GET /ui/{$}", http.RedirectHandler("/ui/runs", http.StatusFound))
+	mux.HandleFunc("GET /ui/runs", httpauth.Require(h.token, h.ListRuns))
+	mux.HandleFunc("GET /ui/runs/latest", httpauth.Require(h.token, h.LatestRun))
+	mux.HandleFunc("GET /ui/runs/{id}", httpauth.Require(h.token, h.ShowRun))
+	mux.HandleFunc("GET /ui/runs/{id}/results/{index}", httpauth.Require(h.token, h.ShowDiff))
+	mux.HandleFunc("GET /ui/artifacts/{key...}", httpauth.Require(h.token, h.ShowArtifact))
+}
+
+// page is the data shared by every page template.
+type page struct {
+	AppName string
+	Title   string
+}
+
+type runsPage struct {
+	page
+	Repo, SHA, Chart, Env, Status, Query string
+	Statuses                             []domain.Outcome // Status filter options
+	Runs                                 []runRow
+	NextURL                              string
+}
+
+type runRow struct {
+	domain.Run
+	Changes int
+	Errors  int
+}
+
+// ListRuns renders the run list. It accepts the same filters as the JSON
+// API (repo, sha, chart, env, status, q, before).
+func (h *Handler) ListRuns(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	filter := domain.RunFilter{
+		Repo:        q.Get("repo"),
+		HeadSHA:     q.Get("sha"),
+		Chart:       q.Get("chart"),
+		Environment: q.Get("env"),
+		Outcome:     domain.Outcome(q.Get("status")),
+		Search:      q.Get("q"),
+		Limit:       pageSize,
+	}
+	switch filter.Outcome {
+	case "", domain.OutcomeNoChanges, domain.OutcomeChanges, domain.OutcomeError, domain.OutcomeBaseOnly:
+	default:
+		h.renderError(w, http.StatusBadRequest, "Invalid status "+strconv.Quote(string(filter.Outcome)))
+		return
+	}
+	if v := q.Get("before"); v != "" {
+		n, err := strconv.ParseInt(v, 10, 64)
+		if err != nil || n < 1 {
+			h.renderError(w, http.StatusBadRequest, "Invalid before "+strconv.Quote(v))
+			return
+		}
+		filter.Before = n
+	}
+
+	runs, err := h.useCase.ListRuns(r.Context(), filter)
+	if err != nil {
+		h.logger.Error("failed to list runs", "error", err)
+		h.renderError(w, http.StatusInternalServerError, "Failed to list runs")
+		return
+	}
+
+	data := runsPage{
+		page:   page{AppName: h.appName, Title: "Runs"},
+		Repo:   filter.Repo,
+		SHA:    filter.HeadSHA,
+		Chart:  filter.Chart,
+		Env:    filter.Environment,
+		Status: string(filter.Outcome),
+		Query:  filter.Search,
+		Statuses: []domain.Outcome{
+			domain.OutcomeChanges, domain.OutcomeError, domain.OutcomeNoChanges, domain.OutcomeBaseOnly,
+		},
+	}
+	for _, run := range runs {
+		_, changes, errorCount := domain.CountByStatus(run.Results)
+		data.Runs = append(data.Runs, runRow{Run: run, Changes: changes, Errors: errorCount})
+	}
+	if len(runs) == pageSize {
+		next := r.URL.Query()
+		next.Set("before", strconv.FormatInt(runs[len(runs)-1].ID, 10))
+		data.NextURL = "/ui/runs?" + next.Encode()
+	}
+	h.render(w, "runs.html", data)
+}
+
+// LatestRun redirects to the newest run for a repo's head commit. Check run
+// summaries link here because the run ID isn't known when they're written.
+func (h *Handler) LatestRun(w http.ResponseWriter, r *http.Request) {
+	repo, sha := r.URL.Query().Get("repo"), r.URL.Query().Get("sha")
+	if repo == "" || sha == "" {
+		h.renderError(w, http.StatusBadRequest, "Both repo and sha are required")
+		return
+	}
+
+	runs, err := h.useCase.ListRuns(r.Context(), domain.RunFilter{Repo: repo, HeadSHA: sha, Limit: 1})
+	if err != nil {
+		h.logger.Error("failed to find latest run", "repo", repo, "sha", sha, "error", err)
+		h.renderError(w, http.StatusInternalServerError, "Failed to find run")
+		return
+	}
+	if len(runs) == 0 {
+		h.renderError(w, http.StatusNotFound, "No run found for "+repo+"@"+sha)
+		return
+	}
+	http.Redirect(w, r, "/ui/runs/"+strconv.FormatInt(runs[0].ID, 10), http.StatusFound)
+}
+
+type runPage struct {
+	page
+	Run    domain.Run
+	Charts []chartGroup
+}
+
+type chartGroup struct {
+	Name    string
+	Results []indexedResult
+}
+
+// indexedResult is a result with its position in Run.Results, which
+// identifies it in diff page URLs.
+type indexedResult struct {
+	Index int
+	domain.DiffResult
+}
+
+// ShowRun renders a run's charts and environments.
+func (h *Handler) ShowRun(w http.ResponseWriter, r *http.Request) {
+	run, ok := h.loadRun(w, r)
+	if !ok {
+		return
+	}
+
+	data := runPage{
+		page: page{AppName: h.appName, Title: "Run #" + strconv.FormatInt(run.ID, 10)},
+		Run:  run,
+	}
+	groupIdx := make(map[string]int)
+	for i, res := range run.Results {
+		idx, exists := groupIdx[res.ChartName]
+		if !exists {
+			idx = len(data.Charts)
+			groupIdx[res.ChartName] = idx
+			data.Charts = append(data.Charts, chartGroup{Name: res.ChartName})
+		}
+		data.Charts[idx].Results = append(data.Charts[idx].Results, indexedResult{Index: i, DiffResult: res})
+	}
+	h.render(w, "run.html", data)
+}
+
+type diffPage struct {
+	page
+	Run       domain.Run
+	Result    indexedResult
+	View      string
+	Query     string
+	Lines     []diffLine     // unified view
+	Rows      []splitRow     // split view
+	Resources []resourceView // resources view
+	Views     []viewLink
+}
+
+type resourceView struct {
+	domain.ResourceDiff
+	Lines []diffLine
+}
+
+type viewLink struct {
+	Name   string
+	Label  string
+	URL    string
+	Active bool
+}
+
+// ShowDiff renders one chart/environment result. Query parameters: view
+// (unified, split, resources, semantic) and q, which highlights matching
+// lines and, in the resources view, hides resources that don't match.
+func (h *Handler) ShowDiff(w http.ResponseWriter, r *http.Request) {
+	run, ok := h.loadRun(w, r)
+	if !ok {
+		return
+	}
+	index, err := strconv.Atoi(r.PathValue("index"))
+	if err != nil || index < 0 || index >= len(run.Results) {
+		h.renderError(w, http.StatusNotFound, "Result not found")
+		return
+	}
+	result := run.Results[index]
+
+	view := r.URL.Query().Get("view")
+	if view == "" {
+		view = viewUnified
+	}
+	query := r.URL.Query().Get("q")
+
+	data := diffPage{
+		page:   page{AppName: h.appName, Title: result.ChartName + "/" + result.Environment},
+		Run:    run,
+		Result: indexedResult{Index: index, DiffResult: result},
+		View:   view,
+		Query:  query,
+		Views:  viewLinks(r.URL, view, result),
+	}
+
+	switch view {
+	case viewUnified:
+		data.Lines = parseUnifiedDiff(result.UnifiedDiff, query)
+	case viewSplit:
+		data.Rows = splitRows(parseUnifiedDiff(result.UnifiedDiff, query))
+	case viewResources:
+		needle := strings.ToLower(query)
+		for _, rd := range result.ResourceDiffs {
+			if needle != "" &&
+				!strings.Contains(strings.ToLower(rd.Resource), needle) &&
+				!strings.Contains(strings.ToLower(rd.Diff), needle) {
+				continue
+			}
+			data.Resources = append(data.Resources, resourceView{ResourceDiff: rd, Lines: parseUnifiedDiff(rd.Diff, query)})
+		}
+	case viewSemantic:
+	default:
+		h.renderError(w, http.StatusBadRequest, "Unknown view "+strconv.Quote(view))
+		return
+	}
+	h.render(w, "diff.html", data)
+}
+
+// viewLinks builds the view switcher, keeping the search term. The semantic
+// view is only offered when a semantic diff was recorded.
+func viewLinks(u *url.URL, active string, result domain.DiffResult) []viewLink {
+	views := []viewLink{
+		{Name: viewUnified, Label: "Unified"},
+		{Name: viewSplit, Label: "Side by side"},
+		{Name: viewResources, Label: "Per resource"},
+	}
+	if result.SemanticDiff != "" {
+		views = append(views, viewLink{Name: viewSemantic, Label: "Semantic"})
+	}
+	for i := range views {
+		q := u.Query()
+		q.Set("view", views[i].Name)
+		views[i].URL = u.Path + "?" + q.Encode()
+		views[i].Active = views[i].Name == active
+	}
+	return views
+}
+
+// loadRun fetches the run named by the {id} path value, rendering an error
+// page and returning false when it can't.
+func (h *Handler) loadRun(w http.ResponseWriter, r *http.Request) (domain.Run, bool) {
+	id, err := strconv.ParseInt(r.PathValue("id"), 10, 64)
+	if err != nil || id <= 0 {
+		h.renderError(w, http.StatusNotFound, "Run not found")
+		return domain.Run{}, false
+	}
+	run, err := h.useCase.GetRun(r.Context(), id)
+	if err != nil {
+		if domain.IsNotFound(err) {
+			h.renderError(w, http.StatusNotFound, "Run not found")
+			return domain.Run{}, false
+		}
+		h.logger.Error("failed to get run", "id", id, "error", err)
+		h.renderError(w, http.StatusInternalServerError, "Failed to load run")
+		return domain.Run{}, false
+	}
+	return run, true
+}
+
+type errorPage struct {
+	page
+	Status  int
+	Message string
+}
+
+func (h *Handler) renderError(w http.ResponseWriter, status int, msg string) {
+	h.renderStatus(w, status, "error.html", errorPage{
+		page:    page{AppName: h.appName, Title: http.StatusText(status)},
+		Status:  status,
+		Message: msg,
+	})
+}
+
+func (h *Handler) render(w http.ResponseWriter, name string, data any) {
+	h.renderStatus(w, http.StatusOK, name, data)
+}
+
+// renderStatus executes the page into a buffer first so a template error
+// produces a clean 500 rather than a half-written page.
+func (h *Handler) renderStatus(w http.ResponseWriter, status int, name string, data any) {
+	var buf bytes.Buffer
+	if err := pages[name].ExecuteTemplate(&buf, name, data); err != nil {
+		h.logger.Error("failed to render page", "page", name, "error", err)
+		http.Error(w, "failed to render page", http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.WriteHeader(status)
+	//nolint:errcheck // Response write errors are not actionable
+	_, _ = buf.WriteTo(w)
+}
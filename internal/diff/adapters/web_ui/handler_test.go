@@ -0,0 +1,265 @@
+package webui
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/nathantilsley/chart-val/internal/diff/domain"
+)
+
+type mockQuery struct {
+	runs []domain.Run
+}
+
+func (m *mockQuery) ListRuns(_ context.Context, filter domain.RunFilter) ([]domain.Run, error) {
+	var out []domain.Run
+	for _, r := range m.runs {
+		if filter.Matches(r) {
+			out = append(out, r)
+		}
+	}
+	return out, nil
+}
+
+func (m *mockQuery) GetRun(_ context.Context, id int64) (domain.Run, error) {
+	for _, r := range m.runs {
+		if r.ID == id {
+			return r, nil
+		}
+	}
+	return domain.Run{}, domain.NewNotFoundError("run", "")
+}
+
+func (m *mockQuery) ArtifactURL(_ context.Context, key string) (string, error) {
+	for _, r := range m.runs {
+		for _, res := range r.Results {
+			for _, a := range res.Artifacts {
+				if a.Key == key {
+					return "https://artifacts.example.com/" + key + "?signature=fresh", nil
+				}
+			}
+		}
+	}
+	return "", domain.NewNotFoundError("artifact", key)
+}
+
+func newTestServer(t *testing.T) *httptest.Server {
+	t.Helper()
+	q := &mockQuery{runs: []domain.Run{{
+		ID: 7,
+		PR: domain.PRContext{Owner: "org", Repo: "charts", PRNumber: 12, BaseRef: "main", HeadRef: "feature", HeadSHA: "abc1234def"},
+		Results: []domain.DiffResult{
+			{ChartName: "app", Environment: "dev", Status: domain.StatusSuccess, Summary: "No changes"},
+			{
+				ChartName: "app", Environment: "prod", Status: domain.StatusChanges, UnifiedDiff: sampleDiff,
+				Resources: domain.ResourceChanges{Modified: 1, Added: 1},
+				ResourceDiffs: []domain.ResourceDiff{
+					{Resource: "Deployment/web", Change: domain.ChangeModified, Diff: sampleDiff},
+					{Resource: "Service/web", Change: domain.ChangeAdded, Diff: "@@ -0,0 +1 @@\n+kind: Service"},
+				},
+				Artifacts: []domain.ManifestArtifact{{Side: domain.ArtifactHead, Key: testArtifactKey, URL: "https://artifacts.example.com/head.yaml"}},
+			},
+		},
+	}}}
+
+	mux := http.NewServeMux()
+	NewHandler(q, "chart-val", testToken, slog.New(slog.NewTextHandler(io.Discard, nil))).Register(mux)
+	srv := httptest.NewServer(mux)
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+const testToken = "s3cret"
+
+// testArtifactKey has a chart name that domain.ArtifactKey escapes.
+var testArtifactKey = domain.ArtifactKey(domain.PRContext{Owner: "org", Repo: "charts", HeadSHA: "abc1234def"}, "my app", "prod", domain.ArtifactHead)
+
+// get requests url with client, signed in as a browser would be when
+// password is set.
+func get(t *testing.T, client *http.Client, url, password string) *http.Response {
+	t.Helper()
+	req, err := http.NewRequestWithContext(context.Background(), http.MethodGet, url, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if password != "" {
+		req.SetBasicAuth("reviewer", password)
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = resp.Body.Close() })
+	return resp
+}
+
+func TestHandler_Pages(t *testing.T) {
+	srv := newTestServer(t)
+
+	tests := []struct {
+		name       string
+		path       string
+		wantStatus int
+		want       []string
+		notWant    []string
+	}{
+		{
+			name:       "run list",
+			path:       "/ui/runs",
+			wantStatus: http.StatusOK,
+			want:       []string{`href="/ui/runs/7"`, "org/charts#12", "abc1234<", "1 changed"},
+		},
+		{
+			name:       "search without match",
+			path:       "/ui/runs?q=ingress",
+			wantStatus: http.StatusOK,
+			want:       []string{"No runs match."},
+		},
+		{
+			name:       "invalid status",
+			path:       "/ui/runs?status=broken",
+			wantStatus: http.StatusBadRequest,
+		},
+		{
+			name:       "run overview",
+			path:       "/ui/runs/7",
+			wantStatus: http.StatusOK,
+			want:       []string{"https://github.com/org/charts/pull/12", `href="/ui/runs/7/results/1"`, "+1 −0 ~1"},
+		},
+		{
+			name:       "missing run",
+			path:       "/ui/runs/8",
+			wantStatus: http.StatusNotFound,
+		},
+		{
+			name:       "unified view",
+			path:       "/ui/runs/7/results/1",
+			wantStatus: http.StatusOK,
+			want:       []string{`<tr class="add">`, "replicas: 3", `<a href="https://artifacts.example.com/head.yaml">head</a>`},
+			notWant:    []string{"Semantic"},
+		},
+		{
+			name:       "split view",
+			path:       "/ui/runs/7/results/1?view=split",
+			wantStatus: http.StatusOK,
+			want:       []string{`<td class="del">replicas: 1</td><td class="num">2</td><td class="add">replicas: 3</td>`},
+		},
+		{
+			name:       "resources view filtered by search",
+			path:       "/ui/runs/7/results/1?view=resources&q=service",
+			wantStatus: http.StatusOK,
+			want:       []string{"Service/web"},
+			notWant:    []string{"Deployment/web"},
+		},
+		{
+			name:       "unknown view",
+			path:       "/ui/runs/7/results/1?view=fancy",
+			wantStatus: http.StatusBadRequest,
+		},
+		{
+			name:       "result out of range",
+			path:       "/ui/runs/7/results/5",
+			wantStatus: http.StatusNotFound,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp := get(t, http.DefaultClient, srv.URL+tt.path, testToken)
+			body, _ := io.ReadAll(resp.Body)
+
+			if resp.StatusCode != tt.wantStatus {
+				t.Fatalf("status = %d, want %d\n%s", resp.StatusCode, tt.wantStatus, body)
+			}
+			for _, w := range tt.want {
+				if !strings.Contains(string(body), w) {
+					t.Errorf("page missing %q:\n%s", w, body)
+				}
+			}
+			for _, w := range tt.notWant {
+				if strings.Contains(string(body), w) {
+					t.Errorf("page should not contain %q", w)
+				}
+			}
+		})
+	}
+}
+
+func TestHandler_LatestRun(t *testing.T) {
+	srv := newTestServer(t)
+	client := &http.Client{CheckRedirect: func(*http.Request, []*http.Request) error { return http.ErrUseLastResponse }}
+
+	tests := []struct {
+		query        string
+		wantStatus   int
+		wantLocation string
+	}{
+		{"?repo=org/charts&sha=abc1234def", http.StatusFound, "/ui/runs/7"},
+		{"?repo=org/charts&sha=fff", http.StatusNotFound, ""},
+		{"?repo=org/charts", http.StatusBadRequest, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.query, func(t *testing.T) {
+			resp := get(t, client, srv.URL+"/ui/runs/latest"+tt.query, testToken)
+
+			if resp.StatusCode != tt.wantStatus {
+				t.Fatalf("status = %d, want %d", resp.StatusCode, tt.wantStatus)
+			}
+			if got := resp.Header.Get("Location"); got != tt.wantLocation {
+				t.Errorf("Location = %q, want %q", got, tt.wantLocation)
+			}
+		})
+	}
+}
+
+func TestHandler_Unauthorized(t *testing.T) {
+	srv := newTestServer(t)
+
+	paths := []string{
+		"/ui/runs", "/ui/runs/latest?repo=org/charts&sha=abc1234def", "/ui/runs/7", "/ui/runs/7/results/1",
+		"/ui/artifacts/org/charts/abc1234def/app/prod/head.yaml",
+	}
+	for _, path := range paths {
+		for _, password := range []string{"", "guess"} {
+			resp := get(t, http.DefaultClient, srv.URL+path, password)
+			if resp.StatusCode != http.StatusUnauthorized {
+				t.Errorf("GET %s with password %q: status = %d, want %d", path, password, resp.StatusCode, http.StatusUnauthorized)
+			}
+			if !strings.HasPrefix(resp.Header.Values("WWW-Authenticate")[1], "Basic ") {
+				t.Errorf("GET %s: no basic auth challenge for browsers: %v", path, resp.Header.Values("WWW-Authenticate"))
+			}
+		}
+	}
+}
+
+func TestHandler_Artifact(t *testing.T) {
+	srv := newTestServer(t)
+	client := &http.Client{CheckRedirect: func(*http.Request, []*http.Request) error { return http.ErrUseLastResponse }}
+
+	link, err := NewArtifactLinks(nil, srv.URL+"/").URL(context.Background(), testArtifactKey)
+	if err != nil {
+		t.Fatalf("URL() error: %v", err)
+	}
+	if !strings.HasPrefix(link, srv.URL+"/ui/artifacts/") || strings.Contains(link, "signature") {
+		t.Fatalf("URL() = %q, want an unsigned UI link", link)
+	}
+
+	resp := get(t, client, link, testToken)
+	if resp.StatusCode != http.StatusFound {
+		t.Fatalf("status = %d, want %d", resp.StatusCode, http.StatusFound)
+	}
+	if got, want := resp.Header.Get("Location"), "https://artifacts.example.com/"+testArtifactKey+"?signature=fresh"; got != want {
+		t.Errorf("Location = %q, want %q", got, want)
+	}
+
+	resp = get(t, client, srv.URL+"/ui/artifacts/org/charts/abc1234def/other/prod/head.yaml", testToken)
+	if resp.StatusCode != http.StatusNotFound {
+		t.Errorf("unknown artifact: status = %d, want %d", resp.StatusCode, http.StatusNotFound)
+	}
+}
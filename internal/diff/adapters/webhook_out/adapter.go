@@ -0,0 +1,239 @@
+// Package webhookout posts signed run events to arbitrary HTTP endpoints.
+package webhookout
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/nathantilsley/chart-val/internal/diff/domain"
+)
+
+// Headers sent with every delivery. The signature is the hex HMAC-SHA256 of
+// the request body keyed with the shared secret, in the same "sha256=" form
+// GitHub uses for X-Hub-Signature-256.
+const (
+	HeaderEvent     = "X-Chart-Val-Event"
+	HeaderDelivery  = "X-Chart-Val-Delivery"
+	HeaderSignature = "X-Chart-Val-Signature-256"
+)
+
+// Event types.
+const (
+	EventRunStarted   = "run.started"
+	EventRunCompleted = "run.completed"
+)
+
+const maxBackoff = 30 * time.Second
+
+// Adapter implements ports.ReportingPort by POSTing run events to every
+// configured URL. Failed deliveries are retried with exponential backoff;
+// events that still can't be delivered are appended to a dead-letter file.
+type Adapter struct {
+	httpClient     *http.Client
+	appName        string
+	urls           []string
+	secret         []byte
+	maxAttempts    int
+	deadLetterFile string
+	initialBackoff time.Duration
+
+	deadLetterMu sync.Mutex
+}
+
+// New creates a webhook reporter. An empty secret sends unsigned requests and
+// an empty deadLetterFile drops undeliverable events after logging them.
+func New(httpClient *http.Client, appName string, urls []string, secret string, maxAttempts int, deadLetterFile string) *Adapter {
+	if maxAttempts < 1 {
+		maxAttempts = 1
+	}
+	return &Adapter{
+		httpClient:     httpClient,
+		appName:        appName,
+		urls:           urls,
+		secret:         []byte(secret),
+		maxAttempts:    maxAttempts,
+		deadLetterFile: deadLetterFile,
+		initialBackoff: time.Second,
+	}
+}
+
+// CreateInProgressCheck sends a run.started event.
+func (a *Adapter) CreateInProgressCheck(ctx context.Context, pr domain.PRContext) (int64, error) {
+	return 0, a.send(ctx, newEvent(EventRunStarted, a.appName, pr, nil))
+}
+
+// UpdateCheckWithResults sends a run.completed event with every result.
+func (a *Adapter) UpdateCheckWithResults(
+	ctx context.Context,
+	pr domain.PRContext,
+	_ int64,
+	results []domain.DiffResult,
+) error {
+	return a.send(ctx, newEvent(EventRunCompleted, a.appName, pr, results))
+}
+
+// PostComment is a no-op; results are sent once per run.
+func (a *Adapter) PostComment(_ context.Context, _ domain.PRContext, _ []domain.DiffResult) error {
+	return nil
+}
+
+// ResolveComment is a no-op.
+func (a *Adapter) ResolveComment(_ context.Context, _ domain.PRContext, _ string) error {
+	return nil
+}
+
+// CommentedCharts returns nothing; webhooks post no comments.
+func (a *Adapter) CommentedCharts(_ context.Context, _ domain.PRContext) ([]string, error) {
+	return nil, nil
+}
+
+// send delivers the event to every URL, dead-lettering failed deliveries.
+func (a *Adapter) send(ctx context.Context, event Event) error {
+	logger := slog.New(slog.NewTextHandler(os.Stderr, nil))
+
+	body, err := json.Marshal(event)
+	if err != nil {
+		return fmt.Errorf("encoding event: %w", err)
+	}
+
+	var errs []error
+	for _, url := range a.urls {
+		if err := a.deliver(ctx, url, event, body); err != nil {
+			logger.Error("webhook delivery failed", "url", url, "event", event.Type, "id", event.ID, "error", err)
+			if dlErr := a.deadLetter(url, event, body, err); dlErr != nil {
+				errs = append(errs, dlErr)
+			}
+			errs = append(errs, fmt.Errorf("delivering %s to %s: %w", event.Type, url, err))
+			continue
+		}
+		logger.Info("webhook delivered", "url", url, "event", event.Type, "id", event.ID)
+	}
+	return errors.Join(errs...)
+}
+
+// deliver POSTs body to url, retrying network errors, 429s, and 5xx
+// responses with exponential backoff. Other 4xx responses fail immediately.
+func (a *Adapter) deliver(ctx context.Context, url string, event Event, body []byte) error {
+	backoff := a.initialBackoff
+	var lastErr error
+
+	for attempt := 1; attempt <= a.maxAttempts; attempt++ {
+		retry, err := a.post(ctx, url, event, body)
+		if err == nil {
+			return nil
+		}
+		lastErr = fmt.Errorf("attempt %d: %w", attempt, err)
+		if !retry || attempt == a.maxAttempts {
+			break
+		}
+
+		select {
+		case <-ctx.Done():
+			return errors.Join(lastErr, ctx.Err())
+		case <-time.After(backoff):
+		}
+		backoff = min(backoff*2, maxBackoff)
+	}
+	return lastErr
+}
+
+// post makes a single delivery attempt and reports whether a failure is retryable.
+func (a *Adapter) post(ctx context.Context, url string, event Event, body []byte) (retry bool, err error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return false, fmt.Errorf("creating request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(HeaderEvent, event.Type)
+	req.Header.Set(HeaderDelivery, event.ID)
+	if len(a.secret) > 0 {
+		req.Header.Set(HeaderSignature, Sign(a.secret, body))
+	}
+
+	resp, err := a.httpClient.Do(req)
+	if err != nil {
+		return true, fmt.Errorf("posting webhook: %w", err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+		return false, nil
+	}
+	msg, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+	retry = resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= 500
+	return retry, fmt.Errorf("webhook returned %d: %s", resp.StatusCode, strings.TrimSpace(string(msg)))
+}
+
+// deadLetter appends an undeliverable event to the dead-letter file as one JSON line.
+func (a *Adapter) deadLetter(url string, event Event, body []byte, cause error) error {
+	if a.deadLetterFile == "" {
+		return nil
+	}
+
+	line, err := json.Marshal(deadLetterEntry{
+		FailedAt: time.Now().UTC(),
+		URL:      url,
+		Event:    event.Type,
+		ID:       event.ID,
+		Error:    cause.Error(),
+		Payload:  body,
+	})
+	if err != nil {
+		return fmt.Errorf("encoding dead letter: %w", err)
+	}
+
+	a.deadLetterMu.Lock()
+	defer a.deadLetterMu.Unlock()
+
+	//nolint:gosec // G304: path is from trusted config, not user input
+	f, err := os.OpenFile(a.deadLetterFile, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o600)
+	if err != nil {
+		return fmt.Errorf("opening dead-letter file: %w", err)
+	}
+	if _, err := f.Write(append(line, '\n')); err != nil {
+		_ = f.Close()
+		return fmt.Errorf("writing dead letter: %w", err)
+	}
+	if err := f.Close(); err != nil {
+		return fmt.Errorf("closing dead-letter file: %w", err)
+	}
+	return nil
+}
+
+// deadLetterEntry is one line of the dead-letter file. Payload is the exact
+// body that was sent, so entries can be replayed with the same signature.
+type deadLetterEntry struct {
+	FailedAt time.Time       `json:"failedAt"`
+	URL      string          `json:"url"`
+	Event    string          `json:"event"`
+	ID       string          `json:"id"`
+	Error    string          `json:"error"`
+	Payload  json.RawMessage `json:"payload"`
+}
+
+// Sign returns the signature header value for body.
+func Sign(secret, body []byte) string {
+	mac := hmac.New(sha256.New, secret)
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+func newDeliveryID() string {
+	b := make([]byte, 16)
+	_, _ = rand.Read(b)
+	return hex.EncodeToString(b)
+}
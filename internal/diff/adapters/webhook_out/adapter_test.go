@@ -0,0 +1,122 @@
+package webhookout
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/nathantilsley/chart-val/internal/diff/domain"
+)
+
+func newTestAdapter(urls []string, maxAttempts int, deadLetterFile string) *Adapter {
+	a := New(http.DefaultClient, "chart-val", urls, "s3cret", maxAttempts, deadLetterFile)
+	a.initialBackoff = time.Millisecond
+	return a
+}
+
+func TestUpdateCheckWithResults_RetriesAndSigns(t *testing.T) {
+	var calls atomic.Int32
+	var got Event
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if calls.Add(1) < 3 {
+			http.Error(w, "try later", http.StatusServiceUnavailable)
+			return
+		}
+		body, _ := io.ReadAll(r.Body)
+		if sig := r.Header.Get(HeaderSignature); sig != Sign([]byte("s3cret"), body) {
+			t.Errorf("signature = %q, want HMAC of body", sig)
+		}
+		if r.Header.Get(HeaderEvent) != EventRunCompleted {
+			t.Errorf("event header = %q", r.Header.Get(HeaderEvent))
+		}
+		_ = json.Unmarshal(body, &got)
+	}))
+	defer srv.Close()
+
+	a := newTestAdapter([]string{srv.URL}, 5, "")
+	pr := domain.PRContext{Owner: "org", Repo: "charts", PRNumber: 3, HeadSHA: "abc"}
+	results := []domain.DiffResult{
+		{ChartName: "app", Environment: "prod", Status: domain.StatusChanges, Resources: domain.ResourceChanges{Added: 2}},
+		{ChartName: "app", Environment: "dev", Status: domain.StatusSuccess},
+	}
+
+	if err := a.UpdateCheckWithResults(context.Background(), pr, 0, results); err != nil {
+		t.Fatalf("UpdateCheckWithResults() error: %v", err)
+	}
+	if calls.Load() != 3 {
+		t.Errorf("server called %d times, want 3 (two retries)", calls.Load())
+	}
+	if got.Summary == nil || got.Summary.Changed != 1 || got.Summary.Environments != 2 {
+		t.Errorf("summary = %+v", got.Summary)
+	}
+	if len(got.Results) != 2 || got.Results[0].Outcome != "changes" || got.Results[0].Resources.Added != 2 {
+		t.Errorf("results = %+v", got.Results)
+	}
+	if got.PullRequest.URL != "https://github.com/org/charts/pull/3" {
+		t.Errorf("PR URL = %q", got.PullRequest.URL)
+	}
+}
+
+func TestUpdateCheckWithResults_DeadLetter(t *testing.T) {
+	tests := []struct {
+		name      string
+		status    int
+		wantCalls int32
+	}{
+		{"client error is not retried", http.StatusBadRequest, 1},
+		{"server error exhausts retries", http.StatusInternalServerError, 3},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var calls atomic.Int32
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+				calls.Add(1)
+				http.Error(w, "nope", tt.status)
+			}))
+			defer srv.Close()
+
+			deadLetters := filepath.Join(t.TempDir(), "dead-letters.jsonl")
+			a := newTestAdapter([]string{srv.URL}, 3, deadLetters)
+
+			err := a.UpdateCheckWithResults(context.Background(), domain.PRContext{}, 0, nil)
+			if err == nil {
+				t.Fatal("UpdateCheckWithResults() should fail")
+			}
+			if calls.Load() != tt.wantCalls {
+				t.Errorf("server called %d times, want %d", calls.Load(), tt.wantCalls)
+			}
+
+			f, err := os.Open(deadLetters)
+			if err != nil {
+				t.Fatalf("opening dead-letter file: %v", err)
+			}
+			defer func() { _ = f.Close() }()
+
+			var entries []deadLetterEntry
+			scanner := bufio.NewScanner(f)
+			for scanner.Scan() {
+				var e deadLetterEntry
+				if err := json.Unmarshal(scanner.Bytes(), &e); err != nil {
+					t.Fatalf("decoding dead letter: %v", err)
+				}
+				entries = append(entries, e)
+			}
+			if len(entries) != 1 || entries[0].URL != srv.URL || entries[0].Event != EventRunCompleted {
+				t.Fatalf("dead letters = %+v", entries)
+			}
+			var payload Event
+			if err := json.Unmarshal(entries[0].Payload, &payload); err != nil || payload.ID != entries[0].ID {
+				t.Errorf("dead letter payload should be the original event, got %s", entries[0].Payload)
+			}
+		})
+	}
+}
@@ -0,0 +1,100 @@
+package webhookout
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/nathantilsley/chart-val/internal/diff/domain"
+)
+
+// Event is the JSON body of every webhook delivery.
+type Event struct {
+	ID          string       `json:"id"`   // Unique per event; repeated across retries and URLs
+	Type        string       `json:"type"` // EventRunStarted or EventRunCompleted
+	Timestamp   time.Time    `json:"timestamp"`
+	App         string       `json:"app"`
+	PullRequest PullRequest  `json:"pullRequest"`
+	Summary     *Summary     `json:"summary,omitempty"` // run.completed only
+	Results     []ResultInfo `json:"results,omitempty"` // run.completed only
+}
+
+// PullRequest identifies the PR and commit the run was for.
+type PullRequest struct {
+	Owner   string `json:"owner"`
+	Repo    string `json:"repo"`
+	Number  int    `json:"number"`
+	URL     string `json:"url"`
+	BaseRef string `json:"baseRef"`
+	HeadRef string `json:"headRef"`
+	HeadSHA string `json:"headSha"`
+}
+
+// Summary counts results by outcome.
+type Summary struct {
+	Charts       int `json:"charts"`
+	Environments int `json:"environments"`
+	Changed      int `json:"changed"`
+	Errors       int `json:"errors"`
+}
+
+// ResultInfo is a single chart + environment result.
+type ResultInfo struct {
+	Chart        string       `json:"chart"`
+	Environment  string       `json:"environment"`
+	Outcome      string       `json:"outcome"` // no-changes, changes, error, base-only
+	Summary      string       `json:"summary"`
+	Warnings     []string     `json:"warnings,omitempty"`
+	Resources    ResourceInfo `json:"resources"`
+	UnifiedDiff  string       `json:"unifiedDiff,omitempty"`
+	SemanticDiff string       `json:"semanticDiff,omitempty"`
+}
+
+// ResourceInfo counts changed Kubernetes resources.
+type ResourceInfo struct {
+	Added    int `json:"added"`
+	Removed  int `json:"removed"`
+	Modified int `json:"modified"`
+}
+
+func newEvent(eventType, appName string, pr domain.PRContext, results []domain.DiffResult) Event {
+	event := Event{
+		ID:        newDeliveryID(),
+		Type:      eventType,
+		Timestamp: time.Now().UTC(),
+		App:       appName,
+		PullRequest: PullRequest{
+			Owner:   pr.Owner,
+			Repo:    pr.Repo,
+			Number:  pr.PRNumber,
+			URL:     fmt.Sprintf("https://github.com/%s/%s/pull/%d", pr.Owner, pr.Repo, pr.PRNumber),
+			BaseRef: pr.BaseRef,
+			HeadRef: pr.HeadRef,
+			HeadSHA: pr.HeadSHA,
+		},
+	}
+	if eventType != EventRunCompleted {
+		return event
+	}
+
+	_, changes, errs := domain.CountByStatus(results)
+	event.Summary = &Summary{
+		Charts:       len(domain.GroupByChart(results)),
+		Environments: len(results),
+		Changed:      changes,
+		Errors:       errs,
+	}
+	event.Results = make([]ResultInfo, 0, len(results))
+	for _, r := range results {
+		event.Results = append(event.Results, ResultInfo{
+			Chart:        r.ChartName,
+			Environment:  r.Environment,
+			Outcome:      string(r.Outcome()),
+			Summary:      r.Summary,
+			Warnings:     r.Warnings,
+			Resources:    ResourceInfo(r.Resources),
+			UnifiedDiff:  r.UnifiedDiff,
+			SemanticDiff: r.SemanticDiff,
+		})
+	}
+	return event
+}
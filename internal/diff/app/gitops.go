@@ -0,0 +1,221 @@
+package app
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/metric"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/nathantilsley/chart-val/internal/diff/domain"
+	"github.com/nathantilsley/chart-val/internal/diff/ports"
+)
+
+// GitopsDiffService implements ports.DiffUseCase for setups with a gitops
+// repository. Pull requests to the gitops repository render every
+// deployment they change with its base and head settings and report the
+// difference; pull requests to other repositories are chart diffs.
+type GitopsDiffService struct {
+	charts      *DiffService
+	deployments ports.DeploymentChangesPort
+}
+
+// NewGitopsDiffService creates a GitopsDiffService. charts runs chart diffs
+// and provides the rendering, diffing and reporting for deployment diffs.
+func NewGitopsDiffService(charts *DiffService, deployments ports.DeploymentChangesPort) *GitopsDiffService {
+	return &GitopsDiffService{charts: charts, deployments: deployments}
+}
+
+// Execute runs the deployment diff for a pull request to the gitops
+// repository and the chart diff for any other pull request.
+func (g *GitopsDiffService) Execute(ctx context.Context, pr domain.PRContext) error {
+	if !g.deployments.IsGitopsRepo(pr) {
+		return g.charts.Execute(ctx, pr)
+	}
+
+	s := g.charts
+	ctx, span := s.tracer.Start(ctx, "ExecuteGitops",
+		trace.WithAttributes(
+			attribute.String("pr.owner", pr.Owner),
+			attribute.String("pr.repo", pr.Repo),
+			attribute.Int("pr.number", pr.PRNumber),
+		),
+	)
+	defer span.End()
+
+	start := time.Now()
+	s.execCounter.Add(ctx, 1)
+	defer func() {
+		s.execDuration.Record(ctx, time.Since(start).Seconds())
+	}()
+
+	changes, err := g.deployments.ChangedDeployments(ctx, pr)
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, "getting changed deployments")
+		err = fmt.Errorf("getting changed deployments: %w", err)
+		s.recordRun(ctx, pr, start, nil, err)
+		return err
+	}
+
+	if len(changes) == 0 {
+		s.logger.Info("no deployments changed")
+		s.resolveStaleComments(ctx, pr, nil)
+		return nil
+	}
+
+	span.SetAttributes(attribute.Int("deployments.count", len(changes)))
+	s.logger.Info("found changed deployments", "count", len(changes))
+
+	checkRunID, err := s.reporter.CreateInProgressCheck(ctx, pr)
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, "creating in-progress check")
+		err = fmt.Errorf("creating in-progress check: %w", err)
+		s.recordRun(ctx, pr, start, nil, err)
+		return err
+	}
+
+	results := make([]domain.DiffResult, 0, len(changes))
+	validated := make(map[string]bool)
+	for _, change := range changes {
+		result := g.diffDeployment(ctx, pr, change)
+		validated[result.ChartKey()] = true
+		results = append(results, result)
+	}
+
+	s.publish(ctx, pr, start, checkRunID, validated, results)
+	return nil
+}
+
+// diffDeployment renders a changed deployment with its base and head
+// settings and diffs the results. Added deployments have no base render
+// and removed ones no head render. Deployments that can't be rendered get a
+// result with a warning naming them, so they aren't silently left out.
+func (g *GitopsDiffService) diffDeployment(
+	ctx context.Context,
+	pr domain.PRContext,
+	change domain.DeploymentChange,
+) domain.DiffResult {
+	s := g.charts
+	envName := deploymentEnv(change)
+
+	ctx, span := s.tracer.Start(ctx, "diffDeployment",
+		trace.WithAttributes(
+			attribute.String("deployment.name", change.Name),
+			attribute.String("environment", envName),
+		),
+	)
+	defer span.End()
+
+	s.logger.Info("diffing deployment",
+		"deployment", change.Name,
+		"file", change.File,
+		"env", envName,
+		"added", change.Base == nil,
+		"removed", change.Head == nil,
+	)
+
+	start := time.Now()
+	fail := func(err error) domain.DiffResult {
+		s.logger.Error("deployment diff failed", "deployment", change.Name, "env", envName, "error", err)
+		span.RecordError(err)
+		span.SetStatus(codes.Error, "diffing deployment")
+		s.diffStatus.Add(ctx, 1, metric.WithAttributes(
+			attribute.String("chart", change.Name),
+			attribute.String("environment", envName),
+			attribute.String("status", domain.StatusError.String()),
+		))
+		return domain.DiffResult{
+			ChartName:   change.Name,
+			Environment: envName,
+			BaseRef:     pr.BaseRef,
+			HeadRef:     pr.HeadRef,
+			Status:      domain.StatusError,
+			Summary:     err.Error(),
+			Duration:    time.Since(start),
+		}
+	}
+
+	if change.Unsupported != "" {
+		s.diffStatus.Add(ctx, 1, metric.WithAttributes(
+			attribute.String("chart", change.Name),
+			attribute.String("environment", envName),
+			attribute.String("status", domain.StatusSuccess.String()),
+		))
+		return domain.DiffResult{
+			ChartName:   change.Name,
+			Environment: envName,
+			BaseRef:     pr.BaseRef,
+			HeadRef:     pr.HeadRef,
+			Status:      domain.StatusSuccess,
+			Summary:     fmt.Sprintf("%s was not rendered: %s.", change.Name, change.Unsupported),
+			Warnings:    []string{change.Unsupported},
+			Duration:    time.Since(start),
+		}
+	}
+
+	var baseManifest, headManifest []byte
+	var err error
+	if change.Base != nil {
+		if baseManifest, err = g.render(ctx, *change.Base); err != nil {
+			return fail(fmt.Errorf("failed to render base branch: %w", err))
+		}
+	}
+	if change.Head != nil {
+		if headManifest, err = g.render(ctx, *change.Head); err != nil {
+			return fail(fmt.Errorf("failed to render PR changes: %w", err))
+		}
+	}
+
+	result := s.diffManifests(ctx, pr, change.Name, envName, change.Base != nil, baseManifest, headManifest)
+	if change.Head == nil {
+		// Nothing is rendered for a removed deployment; that is the point
+		result.Warnings = nil
+		result.Summary = fmt.Sprintf("%s is removed in this PR; its resources would be deleted.", change.Name)
+	}
+	result.Duration = time.Since(start)
+	span.SetAttributes(attribute.String("diff.status", result.Status.String()))
+	return result
+}
+
+// render fetches a deployment's chart at its ref and renders it with the
+// deployment's value files and settings. Value files in the chart's
+// repository are read at the same ref.
+func (g *GitopsDiffService) render(ctx context.Context, src domain.DeploymentSource) ([]byte, error) {
+	s := g.charts
+
+	chartDir, cleanup, err := s.sourceControl.FetchChartFiles(ctx, src.Owner, src.Repo, src.Ref, src.Path)
+	if err != nil {
+		return nil, fmt.Errorf("fetching chart %s/%s@%s:%s: %w", src.Owner, src.Repo, src.Ref, src.Path, err)
+	}
+	defer cleanup()
+
+	values := newValueFileResolver(s.sourceControl, domain.PRContext{Owner: src.Owner, Repo: src.Repo}, src.Path)
+	defer values.cleanup()
+	files, err := values.resolve(ctx, src.Ref, src.Environment.ValueFiles)
+	if err != nil {
+		return nil, fmt.Errorf("resolving value files: %w", err)
+	}
+
+	s.logger.Info("rendering deployment",
+		"repo", src.Owner+"/"+src.Repo,
+		"ref", src.Ref,
+		"path", src.Path,
+		"env", src.Environment.Name,
+		"valueFiles", files,
+	)
+	return s.renderer.Render(ctx, chartDir, files, checkoutOptions(src.Environment.Helm, chartDir, src.Path))
+}
+
+// deploymentEnv returns the environment a changed deployment is in, on the
+// head side unless it is removed.
+func deploymentEnv(change domain.DeploymentChange) string {
+	if change.Head != nil {
+		return change.Head.Environment.Name
+	}
+	return change.Base.Environment.Name
+}
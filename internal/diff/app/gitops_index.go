@@ -0,0 +1,52 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+
+	"github.com/nathantilsley/chart-val/internal/diff/domain"
+	"github.com/nathantilsley/chart-val/internal/diff/ports"
+)
+
+// GitopsIndexService implements ports.GitopsIndexUseCase on top of the
+// configured gitops indexes.
+type GitopsIndexService struct {
+	indexes []ports.GitopsIndexPort
+	logger  *slog.Logger
+}
+
+// NewGitopsIndexService creates a GitopsIndexService.
+func NewGitopsIndexService(logger *slog.Logger, indexes ...ports.GitopsIndexPort) *GitopsIndexService {
+	return &GitopsIndexService{indexes: indexes, logger: logger}
+}
+
+// RequestSync schedules a sync of every index built from owner/repo and
+// reports whether there are any.
+func (s *GitopsIndexService) RequestSync(owner, repo string) bool {
+	requested := false
+	for _, index := range s.indexes {
+		if index.RequestSync(owner, repo) {
+			requested = true
+		}
+	}
+	return requested
+}
+
+// Sync syncs every index and returns their status. An index that fails to
+// sync keeps serving its previous contents; the failures are returned
+// together once all indexes have been synced.
+func (s *GitopsIndexService) Sync(ctx context.Context) ([]domain.IndexStatus, error) {
+	statuses := make([]domain.IndexStatus, 0, len(s.indexes))
+	var errs []error
+	for _, index := range s.indexes {
+		status, err := index.Sync(ctx)
+		if err != nil {
+			s.logger.Error("gitops index sync failed", "source", status.Source, "repo", status.Repo, "error", err)
+			errs = append(errs, fmt.Errorf("syncing %s index: %w", status.Source, err))
+		}
+		statuses = append(statuses, status)
+	}
+	return statuses, errors.Join(errs...)
+}
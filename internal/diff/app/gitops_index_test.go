@@ -0,0 +1,68 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/nathantilsley/chart-val/internal/diff/domain"
+	"github.com/nathantilsley/chart-val/internal/platform/logger"
+)
+
+type mockIndex struct {
+	source    string
+	repo      string
+	err       error
+	requested int
+}
+
+func (m *mockIndex) RequestSync(_, repo string) bool {
+	if repo != m.repo {
+		return false
+	}
+	m.requested++
+	return true
+}
+
+func (m *mockIndex) Sync(context.Context) (domain.IndexStatus, error) {
+	status := domain.IndexStatus{Source: m.source, Repo: m.repo}
+	if m.err != nil {
+		status.LastError = m.err.Error()
+	}
+	return status, m.err
+}
+
+func TestGitopsIndexService_RequestSync(t *testing.T) {
+	t.Parallel()
+
+	argo := &mockIndex{source: "argo", repo: "gitops"}
+	flux := &mockIndex{source: "flux", repo: "clusters"}
+	svc := NewGitopsIndexService(logger.New("error"), argo, flux)
+
+	if !svc.RequestSync("example", "gitops") {
+		t.Error("RequestSync(gitops) = false, want true")
+	}
+	if svc.RequestSync("example", "charts") {
+		t.Error("RequestSync(charts) = true, want false")
+	}
+	if argo.requested != 1 || flux.requested != 0 {
+		t.Errorf("requested argo=%d flux=%d, want 1 and 0", argo.requested, flux.requested)
+	}
+}
+
+func TestGitopsIndexService_Sync(t *testing.T) {
+	t.Parallel()
+
+	svc := NewGitopsIndexService(logger.New("error"),
+		&mockIndex{source: "argo", repo: "gitops", err: errors.New("git pull failed")},
+		&mockIndex{source: "flux", repo: "clusters"},
+	)
+
+	statuses, err := svc.Sync(context.Background())
+	if err == nil || err.Error() != "syncing argo index: git pull failed" {
+		t.Errorf("Sync error = %v, want the argo failure", err)
+	}
+	if len(statuses) != 2 || statuses[0].LastError == "" || statuses[1].Source != "flux" {
+		t.Errorf("statuses = %+v, want both indexes, argo with its error", statuses)
+	}
+}
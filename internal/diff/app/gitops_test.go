@@ -0,0 +1,170 @@
+package app
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	noopmetric "go.opentelemetry.io/otel/metric/noop"
+	nooptrace "go.opentelemetry.io/otel/trace/noop"
+
+	"github.com/nathantilsley/chart-val/internal/diff/domain"
+	"github.com/nathantilsley/chart-val/internal/platform/logger"
+)
+
+type mockDeployments struct {
+	repo    string
+	changes []domain.DeploymentChange
+}
+
+func (m *mockDeployments) IsGitopsRepo(pr domain.PRContext) bool {
+	return pr.Repo == m.repo
+}
+
+func (m *mockDeployments) ChangedDeployments(context.Context, domain.PRContext) ([]domain.DeploymentChange, error) {
+	return m.changes, nil
+}
+
+// deployment returns a deployment of charts/api in example/charts at ref.
+func deployment(ref, env string) *domain.DeploymentSource {
+	return &domain.DeploymentSource{
+		Owner:       "example",
+		Repo:        "charts",
+		Ref:         ref,
+		Path:        "charts/api",
+		Environment: domain.EnvironmentConfig{Name: env},
+	}
+}
+
+func TestGitopsDiffService(t *testing.T) {
+	t.Parallel()
+
+	gitopsPR := domain.PRContext{Owner: "example", Repo: "gitops", PRNumber: 7, BaseRef: "main", HeadRef: "bump-api"}
+
+	tests := []struct {
+		name       string
+		pr         domain.PRContext
+		changes    []domain.DeploymentChange
+		want       []domain.Status
+		wantSumm   []string
+		wantChecks int64
+	}{
+		{
+			name: "target revision bump",
+			pr:   gitopsPR,
+			changes: []domain.DeploymentChange{
+				{Name: "api-prod", Base: deployment("v1", "prod"), Head: deployment("v2", "prod")},
+			},
+			want:       []domain.Status{domain.StatusChanges},
+			wantSumm:   []string{"Changes detected in api-prod"},
+			wantChecks: 1,
+		},
+		{
+			name: "settings change rendering the same manifest",
+			pr:   gitopsPR,
+			changes: []domain.DeploymentChange{
+				{Name: "api-dev", Base: deployment("v1", "dev"), Head: deployment("v1", "dev")},
+			},
+			want:       []domain.Status{domain.StatusSuccess},
+			wantSumm:   []string{noChangesMessage},
+			wantChecks: 1,
+		},
+		{
+			name: "added and removed deployments",
+			pr:   gitopsPR,
+			changes: []domain.DeploymentChange{
+				{Name: "api-qa", Head: deployment("v2", "qa")},
+				{Name: "api-old", Base: deployment("v1", "old")},
+			},
+			want:       []domain.Status{domain.StatusChanges, domain.StatusChanges},
+			wantSumm:   []string{"Changes detected in api-qa", "api-old is removed"},
+			wantChecks: 1,
+		},
+		{
+			name: "chart missing at the new revision",
+			pr:   gitopsPR,
+			changes: []domain.DeploymentChange{
+				{Name: "api-prod", Base: deployment("v1", "prod"), Head: deployment("v9", "prod")},
+			},
+			want:       []domain.Status{domain.StatusError},
+			wantSumm:   []string{"failed to render PR changes"},
+			wantChecks: 1,
+		},
+		{
+			name: "chart outside git is reported with a warning",
+			pr:   gitopsPR,
+			changes: []domain.DeploymentChange{
+				{
+					Name:        "redis-prod",
+					Base:        &domain.DeploymentSource{Path: "redis", Ref: "18.0.0"},
+					Head:        &domain.DeploymentSource{Path: "redis", Ref: "18.1.0"},
+					Unsupported: "chart from a Helm repository",
+				},
+			},
+			want:       []domain.Status{domain.StatusSuccess},
+			wantSumm:   []string{"redis-prod was not rendered: chart from a Helm repository"},
+			wantChecks: 1,
+		},
+		{
+			name: "nothing changed",
+			pr:   gitopsPR,
+		},
+		{
+			name: "other repositories get chart diffs",
+			pr:   domain.PRContext{Owner: "example", Repo: "charts", PRNumber: 3, BaseRef: "main", HeadRef: "feat"},
+			changes: []domain.DeploymentChange{
+				{Name: "api-prod", Base: deployment("v1", "prod"), Head: deployment("v2", "prod")},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			srcCtrl := &mockSourceControl{charts: map[string]bool{
+				"v1:charts/api": true,
+				"v2:charts/api": true,
+			}}
+			renderer := &mockRenderer{manifests: map[string]string{
+				"v1:charts/api": "image: api:v1",
+				"v2:charts/api": "image: api:v2",
+			}}
+			reporter := &mockReporter{}
+			charts := NewDiffService(
+				srcCtrl, &mockChangedCharts{}, nil, &mockEnvConfig{}, renderer, reporter, nil, nil,
+				&mockDiff{}, &mockDiff{}, logger.New("error"),
+				noopmetric.NewMeterProvider().Meter("test"),
+				nooptrace.NewTracerProvider().Tracer("test"),
+				"chart_val",
+			)
+			svc := NewGitopsDiffService(charts, &mockDeployments{repo: "gitops", changes: tt.changes})
+
+			if err := svc.Execute(context.Background(), tt.pr); err != nil {
+				t.Fatalf("Execute failed: %v", err)
+			}
+
+			if reporter.checkRunID != tt.wantChecks {
+				t.Errorf("check runs = %d, want %d", reporter.checkRunID, tt.wantChecks)
+			}
+			if len(reporter.results) != len(tt.want) {
+				t.Fatalf("got %d results, want %d: %+v", len(reporter.results), len(tt.want), reporter.results)
+			}
+			for i, r := range reporter.results {
+				if r.Status != tt.want[i] {
+					t.Errorf("result %d status = %v, want %v (%s)", i, r.Status, tt.want[i], r.Summary)
+				}
+				if !strings.Contains(r.Summary, tt.wantSumm[i]) {
+					t.Errorf("result %d summary = %q, want it to contain %q", i, r.Summary, tt.wantSumm[i])
+				}
+				if unsupported := tt.changes[i].Unsupported; unsupported != "" &&
+					(len(r.Warnings) != 1 || r.Warnings[0] != unsupported) {
+					t.Errorf("result %d warnings = %q, want [%q]", i, r.Warnings, unsupported)
+				}
+				if r.ChartName != tt.changes[i].Name {
+					t.Errorf("result %d name = %q, want %q", i, r.ChartName, tt.changes[i].Name)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,70 @@
+package app
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/nathantilsley/chart-val/internal/diff/domain"
+	"github.com/nathantilsley/chart-val/internal/diff/ports"
+)
+
+// Run history query limits.
+const (
+	defaultRunLimit = 50
+	maxRunLimit     = 500
+)
+
+// RunQueryService implements ports.RunQueryUseCase on top of the run history
+// store and, when configured, the artifact store.
+type RunQueryService struct {
+	history   ports.RunHistoryPort
+	artifacts ports.ArtifactStorePort // Optional: signs artifact download links
+}
+
+// NewRunQueryService creates a RunQueryService. artifacts is optional (can
+// be nil).
+func NewRunQueryService(history ports.RunHistoryPort, artifacts ports.ArtifactStorePort) *RunQueryService {
+	return &RunQueryService{history: history, artifacts: artifacts}
+}
+
+// ListRuns returns runs matching the filter, newest first. The limit defaults
+// to 50 and is capped at 500.
+func (s *RunQueryService) ListRuns(ctx context.Context, filter domain.RunFilter) ([]domain.Run, error) {
+	switch {
+	case filter.Limit <= 0:
+		filter.Limit = defaultRunLimit
+	case filter.Limit > maxRunLimit:
+		filter.Limit = maxRunLimit
+	}
+
+	runs, err := s.history.ListRuns(ctx, filter)
+	if err != nil {
+		return nil, fmt.Errorf("listing runs: %w", err)
+	}
+	return runs, nil
+}
+
+// GetRun returns a single run.
+func (s *RunQueryService) GetRun(ctx context.Context, id int64) (domain.Run, error) {
+	run, err := s.history.GetRun(ctx, id)
+	if err != nil {
+		return domain.Run{}, fmt.Errorf("getting run %d: %w", id, err)
+	}
+	return run, nil
+}
+
+// ArtifactURL returns a freshly signed download link for a stored rendered
+// manifest.
+func (s *RunQueryService) ArtifactURL(ctx context.Context, key string) (string, error) {
+	if s.artifacts == nil {
+		return "", domain.NewNotFoundError("artifact "+key, "")
+	}
+	link, err := s.artifacts.URL(ctx, key)
+	if err != nil {
+		return "", fmt.Errorf("linking artifact %s: %w", key, err)
+	}
+	if link == "" {
+		return "", domain.NewNotFoundError("artifact "+key, "")
+	}
+	return link, nil
+}
@@ -0,0 +1,87 @@
+package app
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"io/fs"
+	"path/filepath"
+	"strings"
+
+	"github.com/nathantilsley/chart-val/internal/diff/charttree"
+	"github.com/nathantilsley/chart-val/internal/diff/domain"
+)
+
+// inputsUnchangedMessage is the summary for environments skipped because
+// their render inputs are byte-for-byte identical in base and head.
+const inputsUnchangedMessage = "Unchanged (inputs identical)."
+
+// valueDirs returns the top-level chart subdirectories holding environment
+// value files (e.g. "env"). Files there only affect an environment's render
+// when passed as one of its value files, so they're left out of the shared
+// inputs. Value files at the chart root or outside the chart are ignored.
+func valueDirs(envs []domain.EnvironmentConfig) []string {
+	seen := make(map[string]bool)
+	var dirs []string
+	for _, env := range envs {
+		for _, vf := range env.ValueFiles {
+			if ref, err := domain.ParseValueFileRef(vf); err != nil || ref.External() {
+				continue
+			}
+			vf = filepath.Clean(vf)
+			if !filepath.IsLocal(vf) {
+				continue
+			}
+			dir, _, found := strings.Cut(filepath.ToSlash(vf), "/")
+			if !found || seen[dir] {
+				continue
+			}
+			seen[dir] = true
+			dirs = append(dirs, dir)
+		}
+	}
+	return dirs
+}
+
+// envInputsIdentical reports whether everything that determines an
+// environment's render is identical in the base and head checkouts: every
+// chart file outside valueDirs (templates, Chart.yaml, Chart.lock, vendored
+// dependencies, default values, ...) and the environment's value files, as
+// resolved for each side: chart-relative, or absolute for files fetched from
+// elsewhere. Any value file that is missing or reaches outside the chart
+// through a relative path makes the inputs count as changed, so the
+// renderer still gets to report the problem.
+func envInputsIdentical(baseDir, headDir string, valueDirs, baseFiles, headFiles []string) (bool, error) {
+	if len(baseFiles) != len(headFiles) {
+		return false, nil
+	}
+	for _, vf := range append(append([]string(nil), baseFiles...), headFiles...) {
+		if !filepath.IsAbs(vf) && !filepath.IsLocal(filepath.Clean(vf)) {
+			return false, nil
+		}
+	}
+
+	base, err := inputFingerprint(baseDir, valueDirs, baseFiles)
+	if err != nil {
+		return false, fmt.Errorf("hashing base inputs: %w", err)
+	}
+	head, err := inputFingerprint(headDir, valueDirs, headFiles)
+	if err != nil {
+		return false, fmt.Errorf("hashing head inputs: %w", err)
+	}
+	return base != nil && head != nil && bytes.Equal(base, head), nil
+}
+
+// inputFingerprint hashes the chart's inputs (see charttree.WriteInputs).
+// It returns nil when a value file doesn't exist.
+func inputFingerprint(chartDir string, valueDirs, valueFiles []string) ([]byte, error) {
+	h := sha256.New()
+	if err := charttree.WriteInputs(h, chartDir, valueDirs, valueFiles); err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return h.Sum(nil), nil
+}
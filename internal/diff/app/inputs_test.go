@@ -0,0 +1,196 @@
+package app
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	noopmetric "go.opentelemetry.io/otel/metric/noop"
+	nooptrace "go.opentelemetry.io/otel/trace/noop"
+
+	"github.com/nathantilsley/chart-val/internal/diff/domain"
+	"github.com/nathantilsley/chart-val/internal/platform/logger"
+)
+
+func writeChartFiles(t *testing.T, files map[string]string) string {
+	t.Helper()
+	dir := t.TempDir()
+	for name, content := range files {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0o750); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return dir
+}
+
+func testChartFiles() map[string]string {
+	return map[string]string{
+		"Chart.yaml":           "name: app\nversion: 1.0.0\n",
+		"values.yaml":          "replicas: 1\n",
+		"templates/cm.yaml":    "kind: ConfigMap\n",
+		"env/dev-values.yaml":  "replicas: 2\n",
+		"env/prod-values.yaml": "replicas: 3\n",
+	}
+}
+
+func TestValueDirs(t *testing.T) {
+	envs := []domain.EnvironmentConfig{
+		{Name: "dev", ValueFiles: []string{"env/dev-values.yaml"}},
+		{Name: "prod", ValueFiles: []string{"values-prod.yaml", "env/prod-values.yaml", "overrides/prod.yaml"}},
+		{Name: "shared", ValueFiles: []string{"../shared/values.yaml"}},
+		{Name: "external", ValueFiles: []string{"/deploy/values/prod.yaml", "org/config@main:values/prod.yaml"}},
+	}
+	if got, want := valueDirs(envs), []string{"env", "overrides"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("valueDirs() = %v, want %v", got, want)
+	}
+}
+
+func TestEnvInputsIdentical(t *testing.T) {
+	prod := []string{"env/prod-values.yaml"}
+
+	tests := []struct {
+		name       string
+		change     func(files map[string]string)
+		valueFiles []string
+		want       bool
+	}{
+		{name: "nothing changed", change: func(map[string]string) {}, valueFiles: prod, want: true},
+		{name: "other environment's values changed", change: func(f map[string]string) { f["env/dev-values.yaml"] = "replicas: 9\n" }, valueFiles: prod, want: true},
+		{name: "own values changed", change: func(f map[string]string) { f["env/prod-values.yaml"] = "replicas: 9\n" }, valueFiles: prod, want: false},
+		{name: "template changed", change: func(f map[string]string) { f["templates/cm.yaml"] = "kind: Secret\n" }, valueFiles: prod, want: false},
+		{name: "template added", change: func(f map[string]string) { f["templates/svc.yaml"] = "kind: Service\n" }, valueFiles: prod, want: false},
+		{name: "Chart.yaml changed", change: func(f map[string]string) { f["Chart.yaml"] = "name: app\nversion: 1.1.0\n" }, valueFiles: prod, want: false},
+		{name: "shared values changed", change: func(f map[string]string) { f["values.yaml"] = "replicas: 5\n" }, valueFiles: prod, want: false},
+		{name: "value file missing", change: func(f map[string]string) {}, valueFiles: []string{"env/qa-values.yaml"}, want: false},
+		{name: "value file outside chart", change: func(map[string]string) {}, valueFiles: []string{"../values.yaml"}, want: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			base := writeChartFiles(t, testChartFiles())
+			headFiles := testChartFiles()
+			tt.change(headFiles)
+			head := writeChartFiles(t, headFiles)
+
+			got, err := envInputsIdentical(base, head, []string{"env"}, tt.valueFiles, tt.valueFiles)
+			if err != nil {
+				t.Fatalf("envInputsIdentical() error: %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("envInputsIdentical() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+// dirSourceControl serves chart checkouts from real directories.
+type dirSourceControl struct {
+	dirs map[string]string // "ref:chartPath" -> dir
+}
+
+func (d *dirSourceControl) FetchChartFiles(_ context.Context, _, _, ref, chartPath string) (string, func(), error) {
+	dir, ok := d.dirs[ref+":"+chartPath]
+	if !ok {
+		return "", nil, domain.NewNotFoundError(chartPath, ref)
+	}
+	return dir, func() {}, nil
+}
+
+// countingRenderer renders every chart dir to the same manifest and counts calls.
+type countingRenderer struct {
+	calls map[string]int // value files -> renders
+}
+
+func (c *countingRenderer) Render(
+	_ context.Context,
+	_ string,
+	valueFiles []string,
+	_ domain.HelmOptions,
+) ([]byte, error) {
+	c.calls[filepath.Join(valueFiles...)]++
+	return []byte("kind: ConfigMap\n"), nil
+}
+
+func TestEnvInputsIdentical_LocalDependencies(t *testing.T) {
+	files := func(helper string) map[string]string {
+		f := testChartFiles()
+		f["Chart.yaml"] = "name: app\ndependencies:\n  - name: common\n    repository: file://../common\n"
+		f["../common/Chart.yaml"] = "name: common\ntype: library\n"
+		f["../common/templates/_helpers.tpl"] = helper
+		return f
+	}
+	chartIn := func(files map[string]string) string {
+		dir := filepath.Join(t.TempDir(), "app")
+		for name, content := range files {
+			path := filepath.Join(dir, name)
+			if err := os.MkdirAll(filepath.Dir(path), 0o750); err != nil {
+				t.Fatal(err)
+			}
+			if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+				t.Fatal(err)
+			}
+		}
+		return dir
+	}
+
+	base := chartIn(files("v1"))
+	for helper, want := range map[string]bool{"v1": true, "v2": false} {
+		prod := []string{"env/prod-values.yaml"}
+		got, err := envInputsIdentical(base, chartIn(files(helper)), []string{"env"}, prod, prod)
+		if err != nil {
+			t.Fatalf("envInputsIdentical() error: %v", err)
+		}
+		if got != want {
+			t.Errorf("library helper %s: envInputsIdentical() = %v, want %v", helper, got, want)
+		}
+	}
+}
+
+func TestService_SkipsEnvironmentsWithUnchangedInputs(t *testing.T) {
+	headFiles := testChartFiles()
+	headFiles["env/dev-values.yaml"] = "replicas: 4\n"
+	srcCtrl := &dirSourceControl{dirs: map[string]string{
+		"main:charts/app": writeChartFiles(t, testChartFiles()),
+		"feat:charts/app": writeChartFiles(t, headFiles),
+	}}
+	envConfig := &mockEnvConfig{config: domain.ChartConfig{Path: "charts/app", Environments: []domain.EnvironmentConfig{
+		{Name: "dev", ValueFiles: []string{"env/dev-values.yaml"}},
+		{Name: "prod", ValueFiles: []string{"env/prod-values.yaml"}},
+	}}}
+	renderer := &countingRenderer{calls: make(map[string]int)}
+	reporter := &mockReporter{}
+
+	svc := NewDiffService(
+		srcCtrl, &mockChangedCharts{charts: []domain.ChangedChart{{Name: "app", Path: "charts/app"}}},
+		nil, envConfig, renderer, reporter, nil, nil,
+		&mockDiff{}, &mockDiff{}, logger.New("error"),
+		noopmetric.NewMeterProvider().Meter("test"),
+		nooptrace.NewTracerProvider().Tracer("test"),
+		"chart_val",
+	)
+
+	pr := domain.PRContext{Owner: "org", Repo: "charts", PRNumber: 1, BaseRef: "main", HeadRef: "feat", HeadSHA: "abc"}
+	if err := svc.Execute(context.Background(), pr); err != nil {
+		t.Fatalf("Execute failed: %v", err)
+	}
+
+	if renderer.calls["env/dev-values.yaml"] != 2 || renderer.calls["env/prod-values.yaml"] != 0 {
+		t.Errorf("renders = %v; want dev rendered for base and head and prod skipped", renderer.calls)
+	}
+	if len(reporter.results) != 2 {
+		t.Fatalf("expected 2 results, got %d", len(reporter.results))
+	}
+	prod := reporter.results[1]
+	if !prod.InputsUnchanged || prod.Status != domain.StatusSuccess || prod.Summary != inputsUnchangedMessage {
+		t.Errorf("prod result = %+v; want success with inputs unchanged", prod)
+	}
+	if reporter.results[0].InputsUnchanged {
+		t.Error("dev result should not be marked as inputs unchanged")
+	}
+}
@@ -26,11 +26,11 @@ const (
 )
 
 func TestIntegration_FullDiffFlow(t *testing.T) {
-	if _, err := helmcli.New(); err != nil {
+	if _, err := helmcli.New(helmcli.Options{}); err != nil {
 		t.Skipf("helm not on PATH, skipping integration test: %v", err)
 	}
 
-	renderer, err := helmcli.New()
+	renderer, err := helmcli.New(helmcli.Options{})
 	if err != nil {
 		t.Fatalf("creating helm adapter: %v", err)
 	}
@@ -57,12 +57,12 @@ func TestIntegration_FullDiffFlow(t *testing.T) {
 
 	for _, env := range envs {
 		t.Run(env.Name, func(t *testing.T) {
-			baseManifest, err := renderer.Render(ctx, baseChartDir, env.ValueFiles)
+			baseManifest, err := renderer.Render(ctx, baseChartDir, env.ValueFiles, env.Helm)
 			if err != nil {
 				t.Fatalf("rendering base for %s: %v", env.Name, err)
 			}
 
-			headManifest, err := renderer.Render(ctx, headChartDir, env.ValueFiles)
+			headManifest, err := renderer.Render(ctx, headChartDir, env.ValueFiles, env.Helm)
 			if err != nil {
 				t.Fatalf("rendering head for %s: %v", env.Name, err)
 			}
@@ -104,7 +104,7 @@ func TestIntegration_FullDiffFlow(t *testing.T) {
 	}
 
 	// Generate grouped check run markdown (one per chart) - using production code
-	reporter := githubout.New(nil, "chart-val", "")
+	reporter := githubout.New(nil, "chart-val", "", githubout.Options{})
 	checkRunMD := reporter.FormatCheckRunMarkdown(allResults)
 	goldenFile := filepath.Join(goldenDir, "check-run-my-app.md")
 	compareOrUpdateGolden(t, goldenFile, checkRunMD)
@@ -118,11 +118,11 @@ func TestIntegration_FullDiffFlow(t *testing.T) {
 // TestIntegration_NewChart tests the scenario where a chart is being added
 // for the first time (exists in HEAD but not in BASE).
 func TestIntegration_NewChart(t *testing.T) {
-	if _, err := helmcli.New(); err != nil {
+	if _, err := helmcli.New(helmcli.Options{}); err != nil {
 		t.Skipf("helm not on PATH, skipping integration test: %v", err)
 	}
 
-	renderer, err := helmcli.New()
+	renderer, err := helmcli.New(helmcli.Options{})
 	if err != nil {
 		t.Fatalf("creating helm adapter: %v", err)
 	}
@@ -158,14 +158,14 @@ func TestIntegration_NewChart(t *testing.T) {
 			// For a new chart, base manifest should be empty
 			var baseManifest []byte
 			if _, err := os.Stat(baseChartDir); err == nil {
-				baseManifest, err = renderer.Render(ctx, baseChartDir, env.ValueFiles)
+				baseManifest, err = renderer.Render(ctx, baseChartDir, env.ValueFiles, env.Helm)
 				if err != nil {
 					t.Fatalf("rendering base for %s: %v", env.Name, err)
 				}
 			}
 			// else: baseManifest remains empty (nil/empty byte slice)
 
-			headManifest, err := renderer.Render(ctx, headChartDir, env.ValueFiles)
+			headManifest, err := renderer.Render(ctx, headChartDir, env.ValueFiles, env.Helm)
 			if err != nil {
 				t.Fatalf("rendering head for %s: %v", env.Name, err)
 			}
@@ -207,7 +207,7 @@ func TestIntegration_NewChart(t *testing.T) {
 	}
 
 	// Generate grouped check run markdown - using production code
-	reporter := githubout.New(nil, "chart-val", "")
+	reporter := githubout.New(nil, "chart-val", "", githubout.Options{})
 	checkRunMD := reporter.FormatCheckRunMarkdown(allResults)
 	goldenFile := filepath.Join(goldenDir, "check-run-new-chart.md")
 	compareOrUpdateGolden(t, goldenFile, checkRunMD)
@@ -220,11 +220,11 @@ func TestIntegration_NewChart(t *testing.T) {
 // - Check run groups changed/unchanged correctly
 // - Only the changed chart gets a PR comment
 func TestIntegration_ThreeChartsOneChanged(t *testing.T) {
-	if _, err := helmcli.New(); err != nil {
+	if _, err := helmcli.New(helmcli.Options{}); err != nil {
 		t.Skipf("helm not on PATH, skipping integration test: %v", err)
 	}
 
-	renderer, err := helmcli.New()
+	renderer, err := helmcli.New(helmcli.Options{})
 	if err != nil {
 		t.Fatalf("creating helm adapter: %v", err)
 	}
@@ -261,12 +261,12 @@ func TestIntegration_ThreeChartsOneChanged(t *testing.T) {
 		}
 
 		for _, env := range envs {
-			baseManifest, err := renderer.Render(ctx, baseChartDir, env.ValueFiles)
+			baseManifest, err := renderer.Render(ctx, baseChartDir, env.ValueFiles, env.Helm)
 			if err != nil {
 				t.Fatalf("rendering base for %s/%s: %v", chart.name, env.Name, err)
 			}
 
-			headManifest, err := renderer.Render(ctx, headChartDir, env.ValueFiles)
+			headManifest, err := renderer.Render(ctx, headChartDir, env.ValueFiles, env.Helm)
 			if err != nil {
 				t.Fatalf("rendering head for %s/%s: %v", chart.name, env.Name, err)
 			}
@@ -318,7 +318,7 @@ func TestIntegration_ThreeChartsOneChanged(t *testing.T) {
 	}
 
 	// Check run should show all charts (changed + unchanged)
-	reporter := githubout.New(nil, "chart-val", "")
+	reporter := githubout.New(nil, "chart-val", "", githubout.Options{})
 	checkRunMD := reporter.FormatCheckRunMarkdown(allResults)
 	goldenFile := filepath.Join(goldenDir, "check-run-three-charts.md")
 	compareOrUpdateGolden(t, goldenFile, checkRunMD)
@@ -0,0 +1,72 @@
+package app
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"github.com/nathantilsley/chart-val/internal/diff/ports"
+)
+
+// ArtifactRetention periodically prunes stored manifests older than a
+// maximum age.
+type ArtifactRetention struct {
+	store    ports.ArtifactStorePort
+	maxAge   time.Duration
+	interval time.Duration
+	logger   *slog.Logger
+	stopCh   chan struct{}
+	now      func() time.Time
+}
+
+// NewArtifactRetention creates a retention policy. Call Start to begin pruning.
+func NewArtifactRetention(
+	store ports.ArtifactStorePort,
+	maxAge, interval time.Duration,
+	logger *slog.Logger,
+) *ArtifactRetention {
+	return &ArtifactRetention{
+		store:    store,
+		maxAge:   maxAge,
+		interval: interval,
+		logger:   logger,
+		stopCh:   make(chan struct{}),
+		now:      time.Now,
+	}
+}
+
+// Start prunes once and then every interval in a background goroutine.
+func (r *ArtifactRetention) Start() {
+	go func() {
+		ticker := time.NewTicker(r.interval)
+		defer ticker.Stop()
+
+		for {
+			r.Prune(context.Background())
+			select {
+			case <-ticker.C:
+			case <-r.stopCh:
+				return
+			}
+		}
+	}()
+}
+
+// Stop signals the background pruning loop to stop.
+func (r *ArtifactRetention) Stop() {
+	close(r.stopCh)
+}
+
+// Prune deletes every artifact older than the maximum age. Failures are
+// logged and retried on the next tick.
+func (r *ArtifactRetention) Prune(ctx context.Context) {
+	cutoff := r.now().Add(-r.maxAge)
+	n, err := r.store.Prune(ctx, cutoff)
+	if err != nil {
+		r.logger.Error("failed to prune manifest artifacts", "cutoff", cutoff, "error", err)
+		return
+	}
+	if n > 0 {
+		r.logger.Info("pruned manifest artifacts", "deleted", n, "cutoff", cutoff)
+	}
+}
@@ -0,0 +1,22 @@
+package app
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/nathantilsley/chart-val/internal/platform/logger"
+)
+
+func TestArtifactRetention_Prune(t *testing.T) {
+	store := &mockArtifacts{}
+	r := NewArtifactRetention(store, 72*time.Hour, time.Hour, logger.New("error"))
+	now := time.Date(2026, 3, 10, 12, 0, 0, 0, time.UTC)
+	r.now = func() time.Time { return now }
+
+	r.Prune(context.Background())
+
+	if want := now.Add(-72 * time.Hour); !store.cutoff.Equal(want) {
+		t.Errorf("Prune cutoff = %s, want %s", store.cutoff, want)
+	}
+}
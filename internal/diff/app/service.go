@@ -4,7 +4,6 @@ import (
 	"context"
 	"fmt"
 	"log/slog"
-	"path/filepath"
 	"time"
 
 	"go.opentelemetry.io/otel/attribute"
@@ -16,22 +15,26 @@ import (
 	"github.com/nathantilsley/chart-val/internal/diff/ports"
 )
 
-const noChangesMessage = "No changes detected."
+const (
+	noChangesMessage   = "No changes detected."
+	emptyRenderWarning = "Rendered manifest is empty; the chart produces no resources for this environment."
+)
 
 // DiffService implements ports.DiffUseCase by orchestrating the full
 // chart diff workflow: discover charts, fetch chart files, render, compute diffs, and report.
 type DiffService struct {
-	sourceControl ports.SourceControlPort
-	changedCharts ports.ChangedChartsPort
-	argoEnvConfig ports.EnvironmentConfigPort // Optional: Argo CD apps (source of truth)
-	fsEnvConfig   ports.EnvironmentConfigPort // Fallback: discovers from chart's env/ folder
-	renderer      ports.RendererPort
-	reporter      ports.ReportingPort
-	semanticDiff  ports.DiffPort // Semantic YAML diff (e.g., dyff)
-	unifiedDiff   ports.DiffPort // Line-based diff (e.g., go-difflib)
-	logger        *slog.Logger
-	tracer        trace.Tracer
-	chartDir      string // Top-level chart directory (e.g., "charts")
+	sourceControl   ports.SourceControlPort
+	changedCharts   ports.ChangedChartsPort
+	gitopsEnvConfig ports.EnvironmentConfigPort // Optional: Argo CD / Flux apps (source of truth)
+	fsEnvConfig     ports.EnvironmentConfigPort // Fallback: discovers from chart's env/ folder
+	renderer        ports.RendererPort
+	reporter        ports.ReportingPort
+	history         ports.RunHistoryPort    // Optional: persists every run for auditing
+	artifacts       ports.ArtifactStorePort // Optional: keeps rendered manifests for download
+	semanticDiff    ports.DiffPort          // Semantic YAML diff (e.g., dyff)
+	unifiedDiff     ports.DiffPort          // Line-based diff (e.g., go-difflib)
+	logger          *slog.Logger
+	tracer          trace.Tracer
 
 	// Pre-created metric instruments (created once, reused per call)
 	execCounter  metric.Int64Counter
@@ -40,20 +43,23 @@ type DiffService struct {
 }
 
 // NewDiffService creates a new DiffService wired with all driven ports.
-// argoEnvConfig is optional (can be nil) - if provided, it's used as source of truth with filesystem as fallback.
+// gitopsEnvConfig is optional (can be nil) - if provided, it's used as source of truth with filesystem as fallback.
+// history is optional (can be nil) - if provided, every run is recorded there.
+// artifacts is optional (can be nil) - if provided, rendered manifests are stored there.
 func NewDiffService(
 	sc ports.SourceControlPort,
 	cc ports.ChangedChartsPort,
-	argoEnvConfig ports.EnvironmentConfigPort,
+	gitopsEnvConfig ports.EnvironmentConfigPort,
 	fsEnvConfig ports.EnvironmentConfigPort,
 	rn ports.RendererPort,
 	rp ports.ReportingPort,
+	history ports.RunHistoryPort,
+	artifacts ports.ArtifactStorePort,
 	semanticDiff ports.DiffPort,
 	unifiedDiff ports.DiffPort,
 	logger *slog.Logger,
 	meter metric.Meter,
 	tracer trace.Tracer,
-	chartDir string,
 	metricPrefix string,
 ) *DiffService {
 	execCounter, _ := meter.Int64Counter(metricPrefix+".executions",
@@ -70,20 +76,21 @@ func NewDiffService(
 	)
 
 	return &DiffService{
-		sourceControl: sc,
-		changedCharts: cc,
-		argoEnvConfig: argoEnvConfig,
-		fsEnvConfig:   fsEnvConfig,
-		renderer:      rn,
-		reporter:      rp,
-		semanticDiff:  semanticDiff,
-		unifiedDiff:   unifiedDiff,
-		logger:        logger,
-		tracer:        tracer,
-		chartDir:      chartDir,
-		execCounter:   execCounter,
-		execDuration:  execDuration,
-		diffStatus:    diffStatus,
+		sourceControl:   sc,
+		changedCharts:   cc,
+		gitopsEnvConfig: gitopsEnvConfig,
+		fsEnvConfig:     fsEnvConfig,
+		renderer:        rn,
+		reporter:        rp,
+		history:         history,
+		artifacts:       artifacts,
+		semanticDiff:    semanticDiff,
+		unifiedDiff:     unifiedDiff,
+		logger:          logger,
+		tracer:          tracer,
+		execCounter:     execCounter,
+		execDuration:    execDuration,
+		diffStatus:      diffStatus,
 	}
 }
 
@@ -109,11 +116,14 @@ func (s *DiffService) Execute(ctx context.Context, pr domain.PRContext) error {
 	if err != nil {
 		span.RecordError(err)
 		span.SetStatus(codes.Error, "getting changed charts")
-		return fmt.Errorf("getting changed charts: %w", err)
+		err = fmt.Errorf("getting changed charts: %w", err)
+		s.recordRun(ctx, pr, start, nil, err)
+		return err
 	}
 
 	if len(changedCharts) == 0 {
 		s.logger.Info("no charts to validate")
+		s.resolveStaleComments(ctx, pr, nil)
 		return nil
 	}
 
@@ -125,44 +135,126 @@ func (s *DiffService) Execute(ctx context.Context, pr domain.PRContext) error {
 	if err != nil {
 		span.RecordError(err)
 		span.SetStatus(codes.Error, "creating in-progress check")
-		return fmt.Errorf("creating in-progress check: %w", err)
+		err = fmt.Errorf("creating in-progress check: %w", err)
+		s.recordRun(ctx, pr, start, nil, err)
+		return err
 	}
 
 	// Process each changed chart, collecting all results
 	var allResults []domain.DiffResult
-	chartResults := make(map[string][]domain.DiffResult) // grouped by chart name
-
 	for _, chart := range changedCharts {
-		s.logger.Info("processing chart", "chartName", chart.Name, "path", chart.Path)
+		s.logger.Info("processing chart", "chartName", chart.Name, "path", chart.Path, "affectedVia", chart.AffectedVia)
 
-		config, err := s.getChartConfig(ctx, pr, chart.Name)
+		config, err := s.getChartConfig(ctx, pr, chart)
 		if err != nil {
 			s.logger.Error("failed to get chart config", "chart", chart.Name, "error", err)
 			continue
 		}
 
-		results := s.processChart(ctx, pr, config)
+		envSources := make(map[string]string, len(config.Environments))
+		for _, env := range config.Environments {
+			envSources[env.Name] = env.Source
+		}
+		results := s.processChart(ctx, pr, chart.Name, config)
+		for i := range results {
+			results[i].ChartPath = chart.Path
+			results[i].AffectedVia = chart.AffectedVia
+			results[i].EnvSource = envSources[results[i].Environment]
+		}
 		allResults = append(allResults, results...)
-		chartResults[chart.Name] = results
 	}
 
-	// Update check run with all results
+	validated := make(map[string]bool, len(changedCharts))
+	for _, chart := range changedCharts {
+		validated[chart.Path] = true
+	}
+	s.publish(ctx, pr, start, checkRunID, validated, allResults)
+	return nil
+}
+
+// publish completes the check run with all results, records the run and
+// posts a comment per chart with changes. Comments of charts whose changes
+// have since gone away, or that the PR no longer changes at all, are
+// resolved. Charts are told apart by DiffResult.ChartKey, and validated
+// holds the keys of every chart the run validated.
+func (s *DiffService) publish(
+	ctx context.Context,
+	pr domain.PRContext,
+	start time.Time,
+	checkRunID int64,
+	validated map[string]bool,
+	allResults []domain.DiffResult,
+) {
 	if err := s.reporter.UpdateCheckWithResults(ctx, pr, checkRunID, allResults); err != nil {
 		s.logger.Error("failed to update check run", "checkRunID", checkRunID, "error", err)
 	}
+	s.recordRun(ctx, pr, start, allResults, nil)
 
-	// Post per-chart comment only for charts with changes
-	for chartName, results := range chartResults {
+	for _, results := range domain.GroupByChart(allResults) {
+		chart := results[0].ChartKey()
 		if hasChanges(results) {
 			if err := s.reporter.PostComment(ctx, pr, results); err != nil {
-				s.logger.Error("failed to post PR comment", "chart", chartName, "error", err)
+				s.logger.Error("failed to post PR comment", "chart", chart, "error", err)
 			}
 		} else {
-			s.logger.Info("no changes for chart, skipping comment", "chart", chartName)
+			s.logger.Info("no changes for chart, resolving any previous comment", "chart", chart)
+			if err := s.reporter.ResolveComment(ctx, pr, chart); err != nil {
+				s.logger.Error("failed to resolve PR comment", "chart", chart, "error", err)
+			}
 		}
 	}
+	s.resolveStaleComments(ctx, pr, validated)
+}
 
-	return nil
+// resolveStaleComments resolves the comments of charts outside validated,
+// e.g. charts a later push reverted completely and so dropped out of the
+// PR's changed charts.
+func (s *DiffService) resolveStaleComments(ctx context.Context, pr domain.PRContext, validated map[string]bool) {
+	charts, err := s.reporter.CommentedCharts(ctx, pr)
+	if err != nil {
+		s.logger.Error("failed to list PR comments", "error", err)
+		return
+	}
+	for _, chart := range charts {
+		if validated[chart] {
+			continue
+		}
+		s.logger.Info("chart no longer changed by the PR, resolving its comment", "chart", chart)
+		if err := s.reporter.ResolveComment(ctx, pr, chart); err != nil {
+			s.logger.Error("failed to resolve PR comment", "chart", chart, "error", err)
+		}
+	}
+}
+
+// recordRun saves the run to history, if configured. Failures are logged
+// rather than returned so auditing never fails a run.
+func (s *DiffService) recordRun(
+	ctx context.Context,
+	pr domain.PRContext,
+	start time.Time,
+	results []domain.DiffResult,
+	runErr error,
+) {
+	if s.history == nil {
+		return
+	}
+
+	run := domain.Run{
+		PR:        pr,
+		StartedAt: start,
+		Duration:  time.Since(start),
+		Results:   results,
+	}
+	if runErr != nil {
+		run.Error = runErr.Error()
+	}
+
+	id, err := s.history.SaveRun(ctx, run)
+	if err != nil {
+		s.logger.Error("failed to record run history", "pr", pr.PRNumber, "error", err)
+		return
+	}
+	s.logger.Info("run recorded", "runID", id, "pr", pr.PRNumber, "results", len(results))
 }
 
 // getChartConfig gets environment configuration using the composite strategy:
@@ -172,35 +264,34 @@ func (s *DiffService) Execute(ctx context.Context, pr domain.PRContext) error {
 func (s *DiffService) getChartConfig(
 	ctx context.Context,
 	pr domain.PRContext,
-	chartName string,
+	chart domain.ChangedChart,
 ) (domain.ChartConfig, error) {
+	chartName := chart.Name
 	ctx, span := s.tracer.Start(ctx, "getChartConfig",
 		trace.WithAttributes(attribute.String("chart.name", chartName)),
 	)
 	defer span.End()
 
-	chartPath := s.chartDir + "/" + chartName
-
-	// Try Argo apps first (if configured)
-	if s.argoEnvConfig != nil {
-		config, err := s.argoEnvConfig.GetEnvironmentConfig(ctx, pr, chartName)
+	// Try gitops apps first (if configured)
+	if s.gitopsEnvConfig != nil {
+		config, err := s.gitopsEnvConfig.GetEnvironmentConfig(ctx, pr, chart)
 		if err == nil && len(config.Environments) > 0 {
 			s.logger.Info(
-				"using argo apps for environment config",
+				"using gitops apps for environment config",
 				"chartName",
 				chartName,
 				"envCount",
 				len(config.Environments),
 			)
-			span.SetAttributes(attribute.String("config.source", "argo"))
+			span.SetAttributes(attribute.String("config.source", "gitops"))
 			return config, nil
 		}
 	}
 
 	// Fall back to discovering from chart's env/ directory
-	s.logger.Info("no argo apps found, falling back to filesystem discovery", "chartName", chartName)
+	s.logger.Info("no gitops apps found, falling back to filesystem discovery", "chartName", chartName)
 
-	config, err := s.fsEnvConfig.GetEnvironmentConfig(ctx, pr, chartName)
+	config, err := s.fsEnvConfig.GetEnvironmentConfig(ctx, pr, chart)
 	if err != nil {
 		span.RecordError(err)
 		span.SetStatus(codes.Error, "discovering environments")
@@ -221,7 +312,7 @@ func (s *DiffService) getChartConfig(
 	s.logger.Info("no environment overrides found, using default values", "chartName", chartName)
 	span.SetAttributes(attribute.String("config.source", "default"))
 	return domain.ChartConfig{
-		Path: chartPath,
+		Path: chart.Path,
 		Environments: []domain.EnvironmentConfig{{
 			Name: "default",
 		}},
@@ -233,10 +324,10 @@ func (s *DiffService) getChartConfig(
 func (s *DiffService) processChart(
 	ctx context.Context,
 	pr domain.PRContext,
+	chartName string,
 	config domain.ChartConfig,
 ) []domain.DiffResult {
 	var results []domain.DiffResult
-	chartName := extractChartNameFromPath(config.Path)
 	chartPath := config.Path
 
 	ctx, span := s.tracer.Start(ctx, "processChart",
@@ -305,9 +396,15 @@ func (s *DiffService) processChart(
 	}
 	defer headCleanup()
 
+	// Value files outside the chart are fetched on first use and shared by
+	// every environment of the chart
+	values := newValueFileResolver(s.sourceControl, pr, chartPath)
+	defer values.cleanup()
+
 	// Use environments from config (not discovered)
 	envs := config.Environments
 	s.logger.Info("processing environments from config", "chart", chartName, "envCount", len(envs))
+	sharedExcludes := valueDirs(envs)
 
 	// Diff each environment
 	for _, env := range envs {
@@ -329,6 +426,7 @@ func (s *DiffService) processChart(
 				HeadRef:     pr.HeadRef,
 				Status:      domain.StatusSuccess,
 				Summary:     env.Message,
+				BaseOnly:    true,
 			})
 			continue
 		}
@@ -336,11 +434,44 @@ func (s *DiffService) processChart(
 		s.logger.Info("diffing chart",
 			"chart", chartName,
 			"env", env.Name,
+			"source", env.Source,
 			"base", pr.BaseRef,
 			"head", pr.HeadRef,
 		)
 
-		result, err := s.diffChartEnv(ctx, pr, chartName, baseDir, headDir, baseExists, env)
+		envStart := time.Now()
+		baseFiles, headFiles, err := resolveEnvValueFiles(ctx, values, pr, baseExists, env.ValueFiles)
+
+		if err == nil && baseExists {
+			identical, cmpErr := envInputsIdentical(baseDir, headDir, sharedExcludes, baseFiles, headFiles)
+			if cmpErr != nil {
+				// Fall back to rendering; the comparison is only an optimization
+				s.logger.Warn("comparing environment inputs failed", "chart", chartName, "env", env.Name, "error", cmpErr)
+			}
+			if identical {
+				s.logger.Info("environment inputs unchanged, skipping render", "chart", chartName, "env", env.Name)
+				s.diffStatus.Add(ctx, 1, metric.WithAttributes(
+					attribute.String("chart", chartName),
+					attribute.String("environment", env.Name),
+					attribute.String("status", domain.StatusSuccess.String()),
+				))
+				results = append(results, domain.DiffResult{
+					ChartName:       chartName,
+					Environment:     env.Name,
+					BaseRef:         pr.BaseRef,
+					HeadRef:         pr.HeadRef,
+					Status:          domain.StatusSuccess,
+					Summary:         inputsUnchangedMessage,
+					InputsUnchanged: true,
+				})
+				continue
+			}
+		}
+
+		var result domain.DiffResult
+		if err == nil {
+			result, err = s.diffChartEnv(ctx, pr, chartName, chartPath, baseDir, headDir, baseExists, env, baseFiles, headFiles)
+		}
 		if err != nil {
 			s.logger.Error("diff failed",
 				"chart", chartName,
@@ -359,9 +490,11 @@ func (s *DiffService) processChart(
 				HeadRef:     pr.HeadRef,
 				Status:      domain.StatusError,
 				Summary:     err.Error(),
+				Duration:    time.Since(envStart),
 			})
 			continue
 		}
+		result.Duration = time.Since(envStart)
 		s.logger.Info("appending diff result", "chart", chartName, "env", env.Name, "status", result.Status)
 		results = append(results, result)
 	}
@@ -369,12 +502,36 @@ func (s *DiffService) processChart(
 	return results
 }
 
+// resolveEnvValueFiles resolves an environment's value files for the base
+// render (when the chart exists in base) and the head render.
+func resolveEnvValueFiles(
+	ctx context.Context,
+	values *valueFileResolver,
+	pr domain.PRContext,
+	baseExists bool,
+	valueFiles []string,
+) (baseFiles, headFiles []string, err error) {
+	if baseExists {
+		if baseFiles, err = values.resolve(ctx, pr.BaseRef, valueFiles); err != nil {
+			return nil, nil, fmt.Errorf("resolving base value files: %w", err)
+		}
+	}
+	if headFiles, err = values.resolve(ctx, pr.HeadRef, valueFiles); err != nil {
+		return nil, nil, fmt.Errorf("resolving head value files: %w", err)
+	}
+	return baseFiles, headFiles, nil
+}
+
+// diffChartEnv renders the chart for one environment in base and head and
+// diffs the results. baseFiles and headFiles are the environment's value
+// files as resolved for each side.
 func (s *DiffService) diffChartEnv(
 	ctx context.Context,
 	pr domain.PRContext,
-	chartName, baseDir, headDir string,
+	chartName, chartPath, baseDir, headDir string,
 	baseExists bool,
 	env domain.EnvironmentConfig,
+	baseFiles, headFiles []string,
 ) (domain.DiffResult, error) {
 	ctx, span := s.tracer.Start(ctx, "diffChartEnv",
 		trace.WithAttributes(
@@ -397,9 +554,9 @@ func (s *DiffService) diffChartEnv(
 			"baseDir",
 			baseDir,
 			"valueFiles",
-			env.ValueFiles,
+			baseFiles,
 		)
-		baseManifest, err = s.renderer.Render(ctx, baseDir, env.ValueFiles)
+		baseManifest, err = s.renderer.Render(ctx, baseDir, baseFiles, checkoutOptions(env.Helm, baseDir, chartPath))
 		if err != nil {
 			span.RecordError(err)
 			span.SetStatus(codes.Error, "rendering base")
@@ -419,9 +576,9 @@ func (s *DiffService) diffChartEnv(
 		"headDir",
 		headDir,
 		"valueFiles",
-		env.ValueFiles,
+		headFiles,
 	)
-	headManifest, err := s.renderer.Render(ctx, headDir, env.ValueFiles)
+	headManifest, err := s.renderer.Render(ctx, headDir, headFiles, checkoutOptions(env.Helm, headDir, chartPath))
 	if err != nil {
 		span.RecordError(err)
 		span.SetStatus(codes.Error, "rendering head")
@@ -429,46 +586,123 @@ func (s *DiffService) diffChartEnv(
 	}
 	s.logger.Info("head manifest rendered", "chart", chartName, "env", env.Name, "size", len(headManifest))
 
-	s.logger.Info("computing diffs", "chart", chartName, "env", env.Name)
-	baseName := domain.DiffLabel(chartName, env.Name, pr.BaseRef)
-	headName := domain.DiffLabel(chartName, env.Name, pr.HeadRef)
+	result := s.diffManifests(ctx, pr, chartName, env.Name, baseExists, baseManifest, headManifest)
+	span.SetAttributes(attribute.String("diff.status", result.Status.String()))
+	return result, nil
+}
+
+// diffManifests diffs the rendered base and head manifests of one chart and
+// environment, records the outcome and stores the manifests as artifacts.
+func (s *DiffService) diffManifests(
+	ctx context.Context,
+	pr domain.PRContext,
+	chartName, envName string,
+	baseExists bool,
+	baseManifest, headManifest []byte,
+) domain.DiffResult {
+	s.logger.Info("computing diffs", "chart", chartName, "env", envName)
+	baseName := domain.DiffLabel(chartName, envName, pr.BaseRef)
+	headName := domain.DiffLabel(chartName, envName, pr.HeadRef)
 
 	// Compute semantic diff (dyff) - may be empty if dyff not available
 	semanticDiff := s.semanticDiff.ComputeDiff(baseName, headName, baseManifest, headManifest)
-	s.logger.Info("semantic diff computed", "chart", chartName, "env", env.Name, "size", len(semanticDiff))
+	s.logger.Info("semantic diff computed", "chart", chartName, "env", envName, "size", len(semanticDiff))
 
 	// Always compute unified diff as fallback
 	unifiedDiff := s.unifiedDiff.ComputeDiff(baseName, headName, baseManifest, headManifest)
-	s.logger.Info("unified diff computed", "chart", chartName, "env", env.Name, "size", len(unifiedDiff))
+	s.logger.Info("unified diff computed", "chart", chartName, "env", envName, "size", len(unifiedDiff))
 
 	var status domain.Status
 	var summary string
 
 	if unifiedDiff != "" || semanticDiff != "" {
 		status = domain.StatusChanges
-		summary = fmt.Sprintf("Changes detected in %s for environment %s.", chartName, env.Name)
+		summary = fmt.Sprintf("Changes detected in %s for environment %s.", chartName, envName)
 	} else {
 		status = domain.StatusSuccess
 		summary = noChangesMessage
 	}
 
-	span.SetAttributes(attribute.String("diff.status", status.String()))
+	changedResources := domain.ChangedResources(baseManifest, headManifest)
+	resourceDiffs := make([]domain.ResourceDiff, 0, len(changedResources))
+	for _, c := range changedResources {
+		resourceDiffs = append(resourceDiffs, domain.ResourceDiff{
+			Resource: c.Resource,
+			Change:   c.Change,
+			Diff: s.unifiedDiff.ComputeDiff(
+				domain.DiffLabel(chartName, envName+" "+c.Resource, pr.BaseRef),
+				domain.DiffLabel(chartName, envName+" "+c.Resource, pr.HeadRef),
+				c.Base, c.Head,
+			),
+		})
+	}
+
+	var warnings []string
+	if domain.IsEmptyManifest(headManifest) {
+		warnings = append(warnings, emptyRenderWarning)
+	}
+
 	s.diffStatus.Add(ctx, 1, metric.WithAttributes(
 		attribute.String("chart", chartName),
-		attribute.String("environment", env.Name),
+		attribute.String("environment", envName),
 		attribute.String("status", status.String()),
 	))
 
 	return domain.DiffResult{
-		ChartName:    chartName,
-		Environment:  env.Name,
-		BaseRef:      pr.BaseRef,
-		HeadRef:      pr.HeadRef,
-		Status:       status,
-		UnifiedDiff:  unifiedDiff,
-		SemanticDiff: semanticDiff,
-		Summary:      summary,
-	}, nil
+		ChartName:     chartName,
+		Environment:   envName,
+		BaseRef:       pr.BaseRef,
+		HeadRef:       pr.HeadRef,
+		Status:        status,
+		UnifiedDiff:   unifiedDiff,
+		SemanticDiff:  semanticDiff,
+		Summary:       summary,
+		Warnings:      warnings,
+		Resources:     domain.CountChangedResources(changedResources),
+		ResourceDiffs: resourceDiffs,
+		Artifacts:     s.storeArtifacts(ctx, pr, chartName, envName, baseExists, baseManifest, headManifest),
+	}
+}
+
+// storeArtifacts saves the rendered manifests to the artifact store, if
+// configured. Failures are logged rather than returned so storage problems
+// never fail a diff.
+func (s *DiffService) storeArtifacts(
+	ctx context.Context,
+	pr domain.PRContext,
+	chartName, envName string,
+	baseExists bool,
+	baseManifest, headManifest []byte,
+) []domain.ManifestArtifact {
+	if s.artifacts == nil {
+		return nil
+	}
+
+	sides := []struct {
+		name     string
+		manifest []byte
+	}{
+		{domain.ArtifactBase, baseManifest},
+		{domain.ArtifactHead, headManifest},
+	}
+	if !baseExists {
+		sides = sides[1:]
+	}
+
+	var stored []domain.ManifestArtifact
+	for _, side := range sides {
+		key := domain.ArtifactKey(pr, chartName, envName, side.name)
+		if err := s.artifacts.Put(ctx, key, side.manifest); err != nil {
+			s.logger.Warn("failed to store rendered manifest", "chart", chartName, "env", envName, "key", key, "error", err)
+			continue
+		}
+		link, err := s.artifacts.URL(ctx, key)
+		if err != nil {
+			s.logger.Warn("failed to create manifest download link", "key", key, "error", err)
+		}
+		stored = append(stored, domain.ManifestArtifact{Side: side.name, Key: key, URL: link})
+	}
+	return stored
 }
 
 // hasChanges returns true if any result has changes or errors.
@@ -480,9 +714,3 @@ func hasChanges(results []domain.DiffResult) bool {
 	}
 	return false
 }
-
-// extractChartNameFromPath extracts the chart name from a path.
-// E.g., "charts/my-app" -> "my-app"
-func extractChartNameFromPath(path string) string {
-	return filepath.Base(path)
-}
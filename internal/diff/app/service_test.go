@@ -2,9 +2,11 @@ package app
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"strings"
 	"testing"
+	"time"
 
 	noopmetric "go.opentelemetry.io/otel/metric/noop"
 	nooptrace "go.opentelemetry.io/otel/trace/noop"
@@ -44,18 +46,19 @@ func (m *mockChangedCharts) GetChangedCharts(
 
 type mockEnvConfig struct {
 	config  domain.ChartConfig            // default config
-	configs map[string]domain.ChartConfig // per-chart configs
+	configs map[string]domain.ChartConfig // per-chart configs, by chart path or name
 }
 
 func (m *mockEnvConfig) GetEnvironmentConfig(
 	_ context.Context,
 	_ domain.PRContext,
-	chartName string,
+	chart domain.ChangedChart,
 ) (domain.ChartConfig, error) {
-	if m.configs != nil {
-		if cfg, ok := m.configs[chartName]; ok {
-			return cfg, nil
-		}
+	if cfg, ok := m.configs[chart.Path]; ok {
+		return cfg, nil
+	}
+	if cfg, ok := m.configs[chart.Name]; ok {
+		return cfg, nil
 	}
 	return m.config, nil
 }
@@ -64,7 +67,7 @@ type mockRenderer struct {
 	manifests map[string]string // chartDir -> rendered manifest
 }
 
-func (m *mockRenderer) Render(_ context.Context, chartDir string, _ []string) ([]byte, error) {
+func (m *mockRenderer) Render(_ context.Context, chartDir string, _ []string, _ domain.HelmOptions) ([]byte, error) {
 	if m.manifests != nil {
 		if content, ok := m.manifests[chartDir]; ok {
 			return []byte(content), nil
@@ -74,9 +77,11 @@ func (m *mockRenderer) Render(_ context.Context, chartDir string, _ []string) ([
 }
 
 type mockReporter struct {
-	results      []domain.DiffResult
-	checkRunID   int64
-	commentCount int
+	results       []domain.DiffResult
+	checkRunID    int64
+	commentCount  int
+	resolvedCount int
+	comments      map[string]bool // chart key -> unresolved comment on the PR
 }
 
 func (m *mockReporter) CreateInProgressCheck(_ context.Context, _ domain.PRContext) (int64, error) {
@@ -97,12 +102,73 @@ func (m *mockReporter) UpdateCheckWithResults(
 func (m *mockReporter) PostComment(
 	_ context.Context,
 	_ domain.PRContext,
-	_ []domain.DiffResult,
+	results []domain.DiffResult,
 ) error {
 	m.commentCount++
+	if m.comments == nil {
+		m.comments = make(map[string]bool)
+	}
+	m.comments[results[0].ChartKey()] = true
+	return nil
+}
+
+func (m *mockReporter) ResolveComment(_ context.Context, _ domain.PRContext, chart string) error {
+	m.resolvedCount++
+	delete(m.comments, chart)
 	return nil
 }
 
+func (m *mockReporter) CommentedCharts(_ context.Context, _ domain.PRContext) ([]string, error) {
+	var charts []string
+	for chart := range m.comments {
+		charts = append(charts, chart)
+	}
+	return charts, nil
+}
+
+type mockHistory struct {
+	runs []domain.Run
+}
+
+func (m *mockHistory) SaveRun(_ context.Context, run domain.Run) (int64, error) {
+	m.runs = append(m.runs, run)
+	return int64(len(m.runs)), nil
+}
+
+func (m *mockHistory) ListRuns(_ context.Context, _ domain.RunFilter) ([]domain.Run, error) {
+	return m.runs, nil
+}
+
+func (m *mockHistory) GetRun(_ context.Context, id int64) (domain.Run, error) {
+	return m.runs[id-1], nil
+}
+
+type mockArtifacts struct {
+	err    error
+	blobs  map[string]string
+	cutoff time.Time // Last Prune cutoff
+}
+
+func (m *mockArtifacts) Put(_ context.Context, key string, data []byte) error {
+	if m.err != nil {
+		return m.err
+	}
+	if m.blobs == nil {
+		m.blobs = make(map[string]string)
+	}
+	m.blobs[key] = string(data)
+	return nil
+}
+
+func (m *mockArtifacts) URL(_ context.Context, key string) (string, error) {
+	return "https://artifacts.example.com/" + key, nil
+}
+
+func (m *mockArtifacts) Prune(_ context.Context, cutoff time.Time) (int, error) {
+	m.cutoff = cutoff
+	return 0, m.err
+}
+
 type mockDiff struct{}
 
 func (m *mockDiff) ComputeDiff(baseName, headName string, base, head []byte) string {
@@ -129,11 +195,11 @@ func TestService_NoChartChanges(t *testing.T) {
 	log := logger.New("error")
 
 	svc := NewDiffService(
-		srcCtrl, changedCharts, nil, envConfig, renderer, reporter,
+		srcCtrl, changedCharts, nil, envConfig, renderer, reporter, nil, nil,
 		semanticDiff, unifiedDiff, log,
 		noopmetric.NewMeterProvider().Meter("test"),
 		nooptrace.NewTracerProvider().Tracer("test"),
-		"charts", "chart_val",
+		"chart_val",
 	)
 
 	pr := domain.PRContext{
@@ -188,11 +254,11 @@ func TestService_NewChartNotInBase(t *testing.T) {
 	log := logger.New("error")
 
 	svc := NewDiffService(
-		srcCtrl, changedCharts, nil, envConfig, renderer, reporter,
+		srcCtrl, changedCharts, nil, envConfig, renderer, reporter, nil, nil,
 		semanticDiff, unifiedDiff, log,
 		noopmetric.NewMeterProvider().Meter("test"),
 		nooptrace.NewTracerProvider().Tracer("test"),
-		"charts", "chart_val",
+		"chart_val",
 	)
 
 	pr := domain.PRContext{
@@ -272,16 +338,17 @@ func TestService_ThreeChartsOneChanged(t *testing.T) {
 		},
 	}
 	reporter := &mockReporter{}
+	history := &mockHistory{}
 	semanticDiff := &mockDiff{}
 	unifiedDiff := &mockDiff{}
 	log := logger.New("error")
 
 	svc := NewDiffService(
-		srcCtrl, changedCharts, nil, envConfig, renderer, reporter,
+		srcCtrl, changedCharts, nil, envConfig, renderer, reporter, history, nil,
 		semanticDiff, unifiedDiff, log,
 		noopmetric.NewMeterProvider().Meter("test"),
 		nooptrace.NewTracerProvider().Tracer("test"),
-		"charts", "chart_val",
+		"chart_val",
 	)
 
 	pr := domain.PRContext{
@@ -314,6 +381,16 @@ func TestService_ThreeChartsOneChanged(t *testing.T) {
 		t.Errorf("expected 1 comment (only for changed chart), got %d", reporter.commentCount)
 	}
 
+	// app-b and app-c get a resolve call so stale comments from earlier pushes are cleared
+	if reporter.resolvedCount != 2 {
+		t.Errorf("expected 2 resolve calls (unchanged charts), got %d", reporter.resolvedCount)
+	}
+
+	// The run is recorded with every result
+	if len(history.runs) != 1 || len(history.runs[0].Results) != 3 || history.runs[0].PR != pr {
+		t.Errorf("expected 1 recorded run with 3 results, got %+v", history.runs)
+	}
+
 	// Verify which charts have changes
 	changesCount := 0
 	noChangesCount := 0
@@ -338,6 +415,171 @@ func TestService_ThreeChartsOneChanged(t *testing.T) {
 	t.Logf("✓ 3 charts, 1 changed: 1 check run, 1 comment, 2 silent")
 }
 
+func TestService_RevertedChartResolvesComment(t *testing.T) {
+	srcCtrl := &mockSourceControl{charts: map[string]bool{
+		"main:charts/app-a": true, "feat:charts/app-a": true,
+		"main:charts/app-b": true, "feat:charts/app-b": true,
+	}}
+	changedCharts := &mockChangedCharts{charts: []domain.ChangedChart{
+		{Name: "app-a", Path: "charts/app-a"},
+		{Name: "app-b", Path: "charts/app-b"},
+	}}
+	envs := []domain.EnvironmentConfig{{Name: "prod", ValueFiles: []string{"env/prod-values.yaml"}}}
+	envConfig := &mockEnvConfig{configs: map[string]domain.ChartConfig{
+		"app-a": {Path: "charts/app-a", Environments: envs},
+		"app-b": {Path: "charts/app-b", Environments: envs},
+	}}
+	renderer := &mockRenderer{manifests: map[string]string{
+		"main:charts/app-a": "replicas: 1", "feat:charts/app-a": "replicas: 3",
+		"main:charts/app-b": "replicas: 1", "feat:charts/app-b": "replicas: 2",
+	}}
+	reporter := &mockReporter{}
+
+	svc := NewDiffService(
+		srcCtrl, changedCharts, nil, envConfig, renderer, reporter, nil, nil,
+		&mockDiff{}, &mockDiff{}, logger.New("error"),
+		noopmetric.NewMeterProvider().Meter("test"),
+		nooptrace.NewTracerProvider().Tracer("test"),
+		"chart_val",
+	)
+	pr := domain.PRContext{Owner: "org", Repo: "charts", PRNumber: 1, BaseRef: "main", HeadRef: "feat"}
+
+	// First push changes both charts
+	if err := svc.Execute(context.Background(), pr); err != nil {
+		t.Fatalf("Execute failed: %v", err)
+	}
+	if !reporter.comments["charts/app-a"] || !reporter.comments["charts/app-b"] {
+		t.Fatalf("comments after first push = %v, want app-a and app-b", reporter.comments)
+	}
+
+	// Second push reverts app-b completely, so it is no longer a changed chart
+	changedCharts.charts = changedCharts.charts[:1]
+	if err := svc.Execute(context.Background(), pr); err != nil {
+		t.Fatalf("Execute failed: %v", err)
+	}
+	if !reporter.comments["charts/app-a"] || reporter.comments["charts/app-b"] {
+		t.Errorf("comments after reverting app-b = %v, want only app-a", reporter.comments)
+	}
+
+	// Third push reverts app-a too: no charts left to validate
+	changedCharts.charts = nil
+	if err := svc.Execute(context.Background(), pr); err != nil {
+		t.Fatalf("Execute failed: %v", err)
+	}
+	if len(reporter.comments) != 0 {
+		t.Errorf("comments after reverting every chart = %v, want none", reporter.comments)
+	}
+}
+
+func TestService_SameNamedCharts(t *testing.T) {
+	// Two charts named web in different directories
+	srcCtrl := &mockSourceControl{charts: map[string]bool{
+		"main:apps/a/web": true, "feat:apps/a/web": true,
+		"main:apps/b/web": true, "feat:apps/b/web": true,
+	}}
+	changedCharts := &mockChangedCharts{charts: []domain.ChangedChart{
+		{Name: "web", Path: "apps/a/web"},
+		{Name: "web", Path: "apps/b/web"},
+	}}
+	envs := []domain.EnvironmentConfig{{Name: "prod"}}
+	envConfig := &mockEnvConfig{configs: map[string]domain.ChartConfig{
+		"apps/a/web": {Path: "apps/a/web", Environments: envs},
+		"apps/b/web": {Path: "apps/b/web", Environments: envs},
+	}}
+	renderer := &mockRenderer{manifests: map[string]string{
+		"main:apps/a/web": "replicas: 1", "feat:apps/a/web": "replicas: 3",
+		"main:apps/b/web": "replicas: 1", "feat:apps/b/web": "replicas: 2",
+	}}
+	reporter := &mockReporter{}
+
+	svc := NewDiffService(
+		srcCtrl, changedCharts, nil, envConfig, renderer, reporter, nil, nil,
+		&mockDiff{}, &mockDiff{}, logger.New("error"),
+		noopmetric.NewMeterProvider().Meter("test"),
+		nooptrace.NewTracerProvider().Tracer("test"),
+		"chart_val",
+	)
+	pr := domain.PRContext{Owner: "org", Repo: "charts", PRNumber: 1, BaseRef: "main", HeadRef: "feat"}
+
+	if err := svc.Execute(context.Background(), pr); err != nil {
+		t.Fatalf("Execute failed: %v", err)
+	}
+	if len(reporter.results) != 2 || reporter.results[0].ChartPath != "apps/a/web" ||
+		reporter.results[1].ChartPath != "apps/b/web" {
+		t.Fatalf("results = %+v, want one per chart directory", reporter.results)
+	}
+	if reporter.commentCount != 2 || !reporter.comments["apps/a/web"] || !reporter.comments["apps/b/web"] {
+		t.Fatalf("comments = %v (%d posted), want one per chart directory", reporter.comments, reporter.commentCount)
+	}
+
+	// Reverting one of them resolves only its comment
+	changedCharts.charts = changedCharts.charts[:1]
+	if err := svc.Execute(context.Background(), pr); err != nil {
+		t.Fatalf("Execute failed: %v", err)
+	}
+	if !reporter.comments["apps/a/web"] || reporter.comments["apps/b/web"] {
+		t.Errorf("comments after reverting apps/b/web = %v, want only apps/a/web", reporter.comments)
+	}
+}
+
+func TestService_StoresArtifacts(t *testing.T) {
+	tests := []struct {
+		name          string
+		store         *mockArtifacts
+		wantArtifacts int
+	}{
+		{name: "stores base and head", store: &mockArtifacts{}, wantArtifacts: 2},
+		{name: "store failures don't fail the diff", store: &mockArtifacts{err: errors.New("bucket gone")}, wantArtifacts: 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srcCtrl := &mockSourceControl{
+				charts: map[string]bool{"main:charts/app": true, "feat:charts/app": true},
+			}
+			changedCharts := &mockChangedCharts{charts: []domain.ChangedChart{{Name: "app", Path: "charts/app"}}}
+			envConfig := &mockEnvConfig{configs: map[string]domain.ChartConfig{
+				"app": {Path: "charts/app", Environments: []domain.EnvironmentConfig{{Name: "prod"}}},
+			}}
+			renderer := &mockRenderer{manifests: map[string]string{
+				"main:charts/app": "replicas: 1",
+				"feat:charts/app": "replicas: 3",
+			}}
+			reporter := &mockReporter{}
+
+			svc := NewDiffService(
+				srcCtrl, changedCharts, nil, envConfig, renderer, reporter, nil, tt.store,
+				&mockDiff{}, &mockDiff{}, logger.New("error"),
+				noopmetric.NewMeterProvider().Meter("test"),
+				nooptrace.NewTracerProvider().Tracer("test"),
+				"chart_val",
+			)
+
+			pr := domain.PRContext{Owner: "org", Repo: "charts", PRNumber: 1, BaseRef: "main", HeadRef: "feat", HeadSHA: "abc"}
+			if err := svc.Execute(context.Background(), pr); err != nil {
+				t.Fatalf("Execute failed: %v", err)
+			}
+			if len(reporter.results) != 1 || reporter.results[0].Status != domain.StatusChanges {
+				t.Fatalf("expected 1 changed result, got %+v", reporter.results)
+			}
+
+			artifacts := reporter.results[0].Artifacts
+			if len(artifacts) != tt.wantArtifacts {
+				t.Fatalf("expected %d artifacts, got %+v", tt.wantArtifacts, artifacts)
+			}
+			if tt.wantArtifacts == 0 {
+				return
+			}
+			if got := tt.store.blobs["org/charts/abc/app/prod/head.yaml"]; got != "replicas: 3" {
+				t.Errorf("stored head manifest = %q, want %q", got, "replicas: 3")
+			}
+			if artifacts[0].Side != domain.ArtifactBase || artifacts[1].URL != "https://artifacts.example.com/org/charts/abc/app/prod/head.yaml" {
+				t.Errorf("unexpected artifacts %+v", artifacts)
+			}
+		})
+	}
+}
+
 func TestExtractChartNames(t *testing.T) {
 	tests := []struct {
 		name     string
@@ -0,0 +1,109 @@
+package app
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/nathantilsley/chart-val/internal/diff/domain"
+	"github.com/nathantilsley/chart-val/internal/diff/ports"
+)
+
+// valueFileResolver turns EnvironmentConfig value files into paths the
+// renderer accepts. Chart-relative files pass through unchanged as long as
+// they stay inside the chart's repository; files elsewhere in the PR's
+// repository or in other repositories are fetched through source control
+// and returned as absolute paths. Each repository is fetched at most once
+// per ref.
+type valueFileResolver struct {
+	sourceControl ports.SourceControlPort
+	pr            domain.PRContext
+	chartPath     string            // Chart directory within the repository
+	roots         map[string]string // "owner/repo@ref" -> checkout root
+	cleanups      []func()
+}
+
+func newValueFileResolver(sc ports.SourceControlPort, pr domain.PRContext, chartPath string) *valueFileResolver {
+	return &valueFileResolver{
+		sourceControl: sc,
+		pr:            pr,
+		chartPath:     chartPath,
+		roots:         make(map[string]string),
+	}
+}
+
+// resolve returns the value files for a render at ref (the PR's base or
+// head ref). Files in the PR's repository are read at that ref; files in
+// other repositories at the ref named in the reference.
+func (r *valueFileResolver) resolve(ctx context.Context, ref string, valueFiles []string) ([]string, error) {
+	resolved := make([]string, 0, len(valueFiles))
+	for _, vf := range valueFiles {
+		vfRef, err := domain.ParseValueFileRef(vf)
+		if err != nil {
+			return nil, err
+		}
+		if !vfRef.External() {
+			// The renderer joins these onto the chart directory; keep them in
+			// the checkout so a PR can't read files elsewhere on the server
+			if !filepath.IsLocal(filepath.FromSlash(path.Join(r.chartPath, vfRef.Path))) {
+				return nil, fmt.Errorf("value file %s is outside the repository", vf)
+			}
+			resolved = append(resolved, vf)
+			continue
+		}
+
+		owner, repo, fileRef := r.pr.Owner, r.pr.Repo, ref
+		if vfRef.Repo != "" && !vfRef.InPRRepo(r.pr) {
+			owner, repo, fileRef = vfRef.Owner, vfRef.Repo, vfRef.Ref
+		}
+		root, err := r.root(ctx, owner, repo, fileRef)
+		if err != nil {
+			return nil, fmt.Errorf("fetching value file %s: %w", vf, err)
+		}
+
+		file := filepath.Join(root, filepath.FromSlash(vfRef.Path))
+		if _, err := os.Stat(file); err != nil {
+			return nil, domain.NewNotFoundError("value file "+vfRef.Path+" in "+owner+"/"+repo, fileRef)
+		}
+		resolved = append(resolved, file)
+	}
+	return resolved, nil
+}
+
+// root returns a checkout of the repository at ref, fetching it on first use.
+func (r *valueFileResolver) root(ctx context.Context, owner, repo, ref string) (string, error) {
+	key := owner + "/" + repo + "@" + ref
+	if dir, ok := r.roots[key]; ok {
+		return dir, nil
+	}
+	dir, cleanup, err := r.sourceControl.FetchChartFiles(ctx, owner, repo, ref, ".")
+	if err != nil {
+		return "", err
+	}
+	r.roots[key] = dir
+	r.cleanups = append(r.cleanups, cleanup)
+	return dir, nil
+}
+
+// cleanup removes every checkout fetched by the resolver.
+func (r *valueFileResolver) cleanup() {
+	for _, c := range r.cleanups {
+		c()
+	}
+}
+
+// checkoutOptions returns opts with CheckoutDir set to the root of the
+// checkout chartDir was fetched into, chartPath being the chart's path in
+// the repository.
+func checkoutOptions(opts domain.HelmOptions, chartDir, chartPath string) domain.HelmOptions {
+	opts.CheckoutDir = chartDir
+	if rel := filepath.FromSlash(path.Clean(chartPath)); rel != "." {
+		if root, ok := strings.CutSuffix(chartDir, string(filepath.Separator)+rel); ok {
+			opts.CheckoutDir = root
+		}
+	}
+	return opts
+}
@@ -0,0 +1,164 @@
+package app
+
+import (
+	"context"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	noopmetric "go.opentelemetry.io/otel/metric/noop"
+	nooptrace "go.opentelemetry.io/otel/trace/noop"
+
+	"github.com/nathantilsley/chart-val/internal/diff/domain"
+	"github.com/nathantilsley/chart-val/internal/platform/logger"
+)
+
+// repoCheckouts writes a repository tree per ref and serves it, and the
+// chart at chartPath inside it, through a dirSourceControl.
+func repoCheckouts(t *testing.T, chartPath string, refs map[string]map[string]string) *dirSourceControl {
+	t.Helper()
+	sc := &dirSourceControl{dirs: make(map[string]string)}
+	for ref, files := range refs {
+		root := writeChartFiles(t, files)
+		sc.dirs[ref+":."] = root
+		sc.dirs[ref+":"+chartPath] = filepath.Join(root, filepath.FromSlash(chartPath))
+	}
+	return sc
+}
+
+func TestValueFileResolver(t *testing.T) {
+	sc := repoCheckouts(t, "charts/app", map[string]map[string]string{
+		"main":   {"deploy/prod.yaml": "replicas: 1\n"},
+		"feat":   {"deploy/prod.yaml": "replicas: 2\n"},
+		"v1.0.0": {"values/prod.yaml": "region: eu\n"},
+	})
+	pr := domain.PRContext{Owner: "org", Repo: "charts", BaseRef: "main", HeadRef: "feat"}
+
+	tests := []struct {
+		name       string
+		ref        string
+		valueFiles []string
+		want       []string
+		wantErr    bool
+	}{
+		{
+			name:       "chart relative files pass through",
+			ref:        "feat",
+			valueFiles: []string{"env/prod-values.yaml", "../shared.yaml"},
+			want:       []string{"env/prod-values.yaml", "../shared.yaml"},
+		},
+		{
+			name:       "repo root files follow the render's ref",
+			ref:        "main",
+			valueFiles: []string{"/deploy/prod.yaml"},
+			want:       []string{filepath.Join(sc.dirs["main:."], "deploy", "prod.yaml")},
+		},
+		{
+			name:       "pr repository on its base branch follows the render's ref",
+			ref:        "feat",
+			valueFiles: []string{"org/charts@main:deploy/prod.yaml"},
+			want:       []string{filepath.Join(sc.dirs["feat:."], "deploy", "prod.yaml")},
+		},
+		{
+			name:       "other repository at its own ref",
+			ref:        "feat",
+			valueFiles: []string{"env/prod-values.yaml", "org/config@v1.0.0:values/prod.yaml"},
+			want:       []string{"env/prod-values.yaml", filepath.Join(sc.dirs["v1.0.0:."], "values", "prod.yaml")},
+		},
+		{name: "missing file", ref: "feat", valueFiles: []string{"/deploy/qa.yaml"}, wantErr: true},
+		{name: "missing ref", ref: "feat", valueFiles: []string{"org/config@v2:values/prod.yaml"}, wantErr: true},
+		{name: "invalid reference", ref: "feat", valueFiles: []string{"/../prod.yaml"}, wantErr: true},
+		{name: "chart relative escape", ref: "feat", valueFiles: []string{"../../../etc/passwd"}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := newValueFileResolver(sc, pr, "charts/app")
+			defer r.cleanup()
+
+			got, err := r.resolve(context.Background(), tt.ref, tt.valueFiles)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("resolve() = %v, want error", got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("resolve() error: %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("resolve() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+// recordingRenderer records the value files and checkout of every render.
+type recordingRenderer struct {
+	calls     [][]string
+	checkouts []string
+}
+
+func (r *recordingRenderer) Render(
+	_ context.Context,
+	_ string,
+	valueFiles []string,
+	opts domain.HelmOptions,
+) ([]byte, error) {
+	r.calls = append(r.calls, valueFiles)
+	r.checkouts = append(r.checkouts, opts.CheckoutDir)
+	return []byte("kind: ConfigMap\n"), nil
+}
+
+func TestService_RepoRootValueFiles(t *testing.T) {
+	repo := func(prod string) map[string]string {
+		files := make(map[string]string)
+		for name, content := range testChartFiles() {
+			files["charts/app/"+name] = content
+		}
+		files["deploy/values/prod.yaml"] = prod
+		files["deploy/values/dev.yaml"] = "replicas: 1\n"
+		return files
+	}
+	sc := repoCheckouts(t, "charts/app", map[string]map[string]string{
+		"main": repo("replicas: 3\n"),
+		"feat": repo("replicas: 5\n"),
+	})
+	envConfig := &mockEnvConfig{config: domain.ChartConfig{Path: "charts/app", Environments: []domain.EnvironmentConfig{
+		{Name: "dev", ValueFiles: []string{"/deploy/values/dev.yaml"}},
+		{Name: "prod", ValueFiles: []string{"env/prod-values.yaml", "/deploy/values/prod.yaml"}},
+	}}}
+	renderer := &recordingRenderer{}
+	reporter := &mockReporter{}
+
+	svc := NewDiffService(
+		sc, &mockChangedCharts{charts: []domain.ChangedChart{{Name: "app", Path: "charts/app"}}},
+		nil, envConfig, renderer, reporter, nil, nil,
+		&mockDiff{}, &mockDiff{}, logger.New("error"),
+		noopmetric.NewMeterProvider().Meter("test"),
+		nooptrace.NewTracerProvider().Tracer("test"),
+		"chart_val",
+	)
+
+	pr := domain.PRContext{Owner: "org", Repo: "charts", PRNumber: 1, BaseRef: "main", HeadRef: "feat", HeadSHA: "abc"}
+	if err := svc.Execute(context.Background(), pr); err != nil {
+		t.Fatalf("Execute failed: %v", err)
+	}
+
+	if len(reporter.results) != 2 {
+		t.Fatalf("expected 2 results, got %d", len(reporter.results))
+	}
+	if dev := reporter.results[0]; !dev.InputsUnchanged {
+		t.Errorf("dev result = %+v; want inputs unchanged", dev)
+	}
+	want := [][]string{
+		{"env/prod-values.yaml", filepath.Join(sc.dirs["main:."], "deploy", "values", "prod.yaml")},
+		{"env/prod-values.yaml", filepath.Join(sc.dirs["feat:."], "deploy", "values", "prod.yaml")},
+	}
+	if !reflect.DeepEqual(renderer.calls, want) {
+		t.Errorf("renders = %v, want %v", renderer.calls, want)
+	}
+	if wantCheckouts := []string{sc.dirs["main:."], sc.dirs["feat:."]}; !reflect.DeepEqual(renderer.checkouts, wantCheckouts) {
+		t.Errorf("render checkouts = %v, want %v", renderer.checkouts, wantCheckouts)
+	}
+}
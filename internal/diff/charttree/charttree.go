@@ -0,0 +1,284 @@
+// Package charttree reads chart directories on disk: their declared
+// dependencies and the files a render reads. It is shared by the app layer
+// (skipping environments whose inputs are unchanged) and the adapters (render
+// cache keys, dependency vendoring), so they agree on what a chart's inputs
+// are.
+package charttree
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+
+	"github.com/nathantilsley/chart-val/internal/diff/domain"
+)
+
+// ReadDependencies returns the dependencies declared in Chart.yaml and, for
+// apiVersion v1 charts, requirements.yaml.
+func ReadDependencies(chartDir string) ([]domain.ChartDependency, error) {
+	var deps []domain.ChartDependency
+	for _, name := range []string{"Chart.yaml", "requirements.yaml"} {
+		d, err := readDependencyFile(filepath.Join(chartDir, name))
+		if err != nil {
+			return nil, err
+		}
+		deps = append(deps, d...)
+	}
+	return deps, nil
+}
+
+// ReadLock returns Chart.lock or, for apiVersion v1 charts,
+// requirements.lock. Charts without a lock file return an empty lock.
+func ReadLock(chartDir string) (domain.ChartLock, error) {
+	for _, name := range []string{"Chart.lock", "requirements.lock"} {
+		//nolint:gosec // G304: path is inside a chart checkout from source control
+		data, err := os.ReadFile(filepath.Join(chartDir, name))
+		if errors.Is(err, fs.ErrNotExist) {
+			continue
+		}
+		if err != nil {
+			return domain.ChartLock{}, fmt.Errorf("reading %s: %w", name, err)
+		}
+		lock, err := domain.ParseChartLock(data)
+		if err != nil {
+			return domain.ChartLock{}, fmt.Errorf("%s: %w", name, err)
+		}
+		return lock, nil
+	}
+	return domain.ChartLock{}, nil
+}
+
+// IsVendored reports whether charts/ already holds the dependency, either
+// unpacked (charts/{name}/) or packaged (charts/{name}-{version}.tgz).
+func IsVendored(chartDir string, dep domain.ChartDependency) bool {
+	if info, err := os.Stat(filepath.Join(chartDir, "charts", dep.Name, "Chart.yaml")); err == nil && !info.IsDir() {
+		return true
+	}
+	matches, _ := filepath.Glob(filepath.Join(chartDir, "charts", dep.Name+"-[0-9]*.tgz"))
+	return len(matches) > 0
+}
+
+// readDependencyFile parses a dependencies list; missing files have none.
+func readDependencyFile(file string) ([]domain.ChartDependency, error) {
+	//nolint:gosec // G304: path is inside a chart checkout from source control
+	data, err := os.ReadFile(file)
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("reading %s: %w", filepath.Base(file), err)
+	}
+	deps, err := domain.ParseChartDependencies(data)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", filepath.Base(file), err)
+	}
+	return deps, nil
+}
+
+// LocalDependencyDirs returns the directories of the chart's transitive
+// file:// dependencies, relative to chartDir, in discovery order. Each
+// directory is listed once, so dependency cycles terminate.
+func LocalDependencyDirs(chartDir string) ([]string, error) {
+	seen := make(map[string]bool)
+	var dirs []string
+	var visit func(rel string) error
+	visit = func(rel string) error {
+		deps, err := ReadDependencies(filepath.Join(chartDir, filepath.FromSlash(rel)))
+		if err != nil {
+			return err
+		}
+		for _, dep := range deps {
+			local, ok := dep.LocalPath()
+			if !ok {
+				continue
+			}
+			depRel := path.Join(rel, local)
+			if seen[depRel] {
+				continue
+			}
+			seen[depRel] = true
+			dirs = append(dirs, depRel)
+			if err := visit(depRel); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	if err := visit("."); err != nil {
+		return nil, err
+	}
+	return dirs, nil
+}
+
+// WriteInputs writes everything a render of chartDir reads from disk to w:
+// every file in the chart tree except the excluded top-level directories
+// (templates, Chart.yaml, Chart.lock, vendored dependencies in charts/,
+// default values, ...), the trees of file:// dependencies, and the ordered
+// value files. Value files may live in excluded directories or outside the
+// chart, so they're written explicitly; absolute ones point into per-run
+// checkouts, so only their content is. A missing value file returns an
+// error wrapping fs.ErrNotExist.
+func WriteInputs(w io.Writer, chartDir string, excludeDirs, valueFiles []string) error {
+	WriteField(w, "tree")
+	if err := writeTree(w, chartDir, excludeDirs); err != nil {
+		return err
+	}
+
+	deps, err := LocalDependencyDirs(chartDir)
+	if err != nil {
+		return err
+	}
+	for _, dep := range deps {
+		WriteField(w, "dependency")
+		WriteField(w, dep)
+		if err := writeTree(w, filepath.Join(chartDir, filepath.FromSlash(dep)), nil); err != nil {
+			return err
+		}
+	}
+
+	WriteField(w, "values")
+	for _, vf := range valueFiles {
+		file := filepath.Join(chartDir, vf)
+		if filepath.IsAbs(vf) {
+			file = vf
+			vf = "external"
+		}
+		WriteField(w, filepath.ToSlash(vf))
+		if err := writeFile(w, file); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// WriteRemoteDependencies writes the remote dependencies a render of
+// chartDir pulls in from outside the chart tree: for the chart and each of
+// its file:// dependencies, the lock digest, and for every remote dependency
+// not vendored in charts/ its repository, resolved version and the content
+// of its archive in the dependency cache, as the helm renderer vendors it. Archives
+// missing from the cache are marked as such; the render will fail.
+func WriteRemoteDependencies(w io.Writer, chartDir, cacheDir string) error {
+	local, err := LocalDependencyDirs(chartDir)
+	if err != nil {
+		return err
+	}
+	for _, rel := range append([]string{"."}, local...) {
+		dir := filepath.Join(chartDir, filepath.FromSlash(rel))
+		deps, err := ReadDependencies(dir)
+		if err != nil {
+			return err
+		}
+		lock, err := ReadLock(dir)
+		if err != nil {
+			return err
+		}
+		locked := make(map[string]domain.ChartDependency, len(lock.Dependencies))
+		for _, d := range lock.Dependencies {
+			locked[d.Name] = d
+		}
+
+		WriteField(w, "remote")
+		WriteField(w, rel)
+		WriteField(w, lock.Digest)
+		for _, dep := range deps {
+			if _, ok := dep.LocalPath(); ok || IsVendored(dir, dep) {
+				continue
+			}
+			repo := dep.Repository
+			if l := locked[dep.Name]; l.Repository != "" {
+				repo = l.Repository
+			}
+			WriteField(w, dep.Name)
+			WriteField(w, repo)
+			version, ok := dep.ResolvedVersion(locked[dep.Name].Version)
+			if !ok {
+				WriteField(w, "unresolved")
+				continue
+			}
+			WriteField(w, version)
+			if cacheDir == "" {
+				WriteField(w, "uncached")
+				continue
+			}
+			if err := writeFile(w, filepath.Join(cacheDir, dep.ArchiveName(version))); err != nil {
+				if !errors.Is(err, fs.ErrNotExist) {
+					return err
+				}
+				WriteField(w, "missing")
+			}
+		}
+	}
+	return nil
+}
+
+// writeTree writes every regular file under dir, in path order, skipping
+// the excluded subdirectories.
+func writeTree(w io.Writer, dir string, excludeDirs []string) error {
+	excluded := make(map[string]bool, len(excludeDirs))
+	for _, d := range excludeDirs {
+		excluded[filepath.ToSlash(filepath.Clean(d))] = true
+	}
+
+	var files []string
+	err := filepath.WalkDir(dir, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(dir, p)
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			if excluded[filepath.ToSlash(rel)] {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if d.Type().IsRegular() {
+			files = append(files, rel)
+		}
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("walking chart dir: %w", err)
+	}
+	sort.Strings(files)
+
+	for _, rel := range files {
+		WriteField(w, filepath.ToSlash(rel))
+		if err := writeFile(w, filepath.Join(dir, rel)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// WriteField writes a length-prefixed string so adjacent fields can't collide.
+func WriteField(w io.Writer, s string) {
+	_, _ = fmt.Fprintf(w, "%d:%s;", len(s), s)
+}
+
+// writeFile writes a file's size and content.
+func writeFile(w io.Writer, file string) error {
+	//nolint:gosec // G304: path is inside a chart checkout created by this process
+	f, err := os.Open(file)
+	if err != nil {
+		return fmt.Errorf("hashing %s: %w", file, err)
+	}
+	defer func() { _ = f.Close() }()
+
+	info, err := f.Stat()
+	if err != nil {
+		return fmt.Errorf("hashing %s: %w", file, err)
+	}
+	WriteField(w, fmt.Sprint(info.Size()))
+	if _, err := io.Copy(w, f); err != nil {
+		return fmt.Errorf("hashing %s: %w", file, err)
+	}
+	return nil
+}
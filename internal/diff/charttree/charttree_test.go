@@ -0,0 +1,93 @@
+package charttree
+
+import (
+	"bytes"
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func writeFiles(t *testing.T, root string, files map[string]string) {
+	t.Helper()
+	for name, content := range files {
+		file := filepath.Join(root, name)
+		if err := os.MkdirAll(filepath.Dir(file), 0o750); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(file, []byte(content), 0o600); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestLocalDependencyDirs(t *testing.T) {
+	repo := t.TempDir()
+	writeFiles(t, repo, map[string]string{
+		"charts/app/Chart.yaml": "dependencies:\n- name: common\n  repository: file://../common\n" +
+			"- name: redis\n  repository: https://charts.example.com\n",
+		// base and common depend on each other; the walk must still end
+		"charts/common/Chart.yaml":      "dependencies:\n- name: base\n  repository: file://../base\n",
+		"charts/base/requirements.yaml": "dependencies:\n- name: common\n  repository: file://../common\n",
+	})
+
+	got, err := LocalDependencyDirs(filepath.Join(repo, "charts", "app"))
+	if err != nil {
+		t.Fatalf("LocalDependencyDirs() error: %v", err)
+	}
+	want := []string{"../common", "../base"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("LocalDependencyDirs() = %v, want %v", got, want)
+	}
+}
+
+func TestWriteInputs(t *testing.T) {
+	files := map[string]string{
+		"Chart.yaml":           "name: app\n",
+		"templates/cm.yaml":    "kind: ConfigMap\n",
+		"env/dev-values.yaml":  "replicas: 2\n",
+		"env/prod-values.yaml": "replicas: 3\n",
+	}
+	inputs := func(t *testing.T, change map[string]string, valueFiles ...string) ([]byte, error) {
+		t.Helper()
+		dir := t.TempDir()
+		writeFiles(t, dir, files)
+		writeFiles(t, dir, change)
+		var b bytes.Buffer
+		err := WriteInputs(&b, dir, []string{"env"}, valueFiles)
+		return b.Bytes(), err
+	}
+
+	base, err := inputs(t, nil, "env/prod-values.yaml")
+	if err != nil {
+		t.Fatalf("WriteInputs() error: %v", err)
+	}
+	tests := []struct {
+		name   string
+		change map[string]string
+		same   bool
+	}{
+		{name: "unchanged", same: true},
+		{name: "other environment's values", change: map[string]string{"env/dev-values.yaml": "replicas: 5\n"}, same: true},
+		{name: "own values", change: map[string]string{"env/prod-values.yaml": "replicas: 5\n"}},
+		{name: "template", change: map[string]string{"templates/cm.yaml": "kind: Secret\n"}},
+		{name: "new file", change: map[string]string{"templates/svc.yaml": "kind: Service\n"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := inputs(t, tt.change, "env/prod-values.yaml")
+			if err != nil {
+				t.Fatalf("WriteInputs() error: %v", err)
+			}
+			if same := bytes.Equal(got, base); same != tt.same {
+				t.Errorf("inputs identical = %v, want %v", same, tt.same)
+			}
+		})
+	}
+
+	if _, err := inputs(t, nil, "env/staging-values.yaml"); !errors.Is(err, fs.ErrNotExist) {
+		t.Errorf("WriteInputs() with a missing value file error = %v, want fs.ErrNotExist", err)
+	}
+}
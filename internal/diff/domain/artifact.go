@@ -0,0 +1,30 @@
+package domain
+
+import (
+	"net/url"
+	"strings"
+)
+
+// Manifest sides stored as artifacts.
+const (
+	ArtifactBase = "base"
+	ArtifactHead = "head"
+)
+
+// ManifestArtifact is a rendered manifest kept in the artifact store.
+type ManifestArtifact struct {
+	Side string // ArtifactBase or ArtifactHead
+	Key  string // Blob key, see ArtifactKey
+	URL  string // Download link; empty if none could be generated
+}
+
+// ArtifactKey returns the blob key for a rendered manifest:
+// "{owner}/{repo}/{headSHA}/{chart}/{env}/{side}.yaml". Each segment is
+// path-escaped so names can't introduce extra path levels.
+func ArtifactKey(pr PRContext, chart, env, side string) string {
+	segments := []string{pr.Owner, pr.Repo, pr.HeadSHA, chart, env, side + ".yaml"}
+	for i, s := range segments {
+		segments[i] = url.PathEscape(s)
+	}
+	return strings.Join(segments, "/")
+}
@@ -0,0 +1,153 @@
+package domain
+
+import (
+	"fmt"
+	"path"
+	"regexp"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// localRepoPrefix marks a dependency stored in the same repository.
+const localRepoPrefix = "file://"
+
+// exactVersion matches a plain semantic version, as opposed to a range.
+var exactVersion = regexp.MustCompile(`^v?\d+\.\d+\.\d+([-+][0-9A-Za-z.+-]*)?$`)
+
+// ChartDependency is one entry of a Chart.yaml dependencies list.
+type ChartDependency struct {
+	Name       string `yaml:"name"`
+	Version    string `yaml:"version"`
+	Repository string `yaml:"repository"`
+	Alias      string `yaml:"alias,omitempty"`
+}
+
+// LocalPath returns the dependency's directory relative to the depending
+// chart for file:// repositories (e.g. "../common").
+func (d ChartDependency) LocalPath() (string, bool) {
+	if !strings.HasPrefix(d.Repository, localRepoPrefix) {
+		return "", false
+	}
+	return path.Clean(strings.TrimPrefix(d.Repository, localRepoPrefix)), true
+}
+
+// ResolvedVersion returns the version a remote dependency resolves to: the
+// version locked in Chart.lock, or the declared one when it pins an exact
+// version. It reports false for version ranges without a lock.
+func (d ChartDependency) ResolvedVersion(locked string) (string, bool) {
+	if locked != "" {
+		return locked, true
+	}
+	if exactVersion.MatchString(d.Version) {
+		return d.Version, true
+	}
+	return "", false
+}
+
+// ArchiveName returns the file name of the dependency packaged at version,
+// as written by helm package and helm pull (e.g. "redis-17.3.2.tgz").
+func (d ChartDependency) ArchiveName(version string) string {
+	return d.Name + "-" + strings.TrimPrefix(version, "v") + ".tgz"
+}
+
+// String describes the dependency for reports, e.g. "common 1.2.0 (file://../common)".
+func (d ChartDependency) String() string {
+	s := d.Name
+	if d.Alias != "" {
+		s += " as " + d.Alias
+	}
+	if d.Version != "" {
+		s += " " + d.Version
+	}
+	if d.Repository != "" {
+		s += " (" + d.Repository + ")"
+	}
+	return s
+}
+
+// ParseChartDependencies returns the dependencies declared in Chart.yaml
+// (apiVersion v2) or requirements.yaml (apiVersion v1) content.
+func ParseChartDependencies(data []byte) ([]ChartDependency, error) {
+	var chart struct {
+		Dependencies []ChartDependency `yaml:"dependencies"`
+	}
+	if err := yaml.Unmarshal(data, &chart); err != nil {
+		return nil, fmt.Errorf("parsing chart dependencies: %w", err)
+	}
+	return chart.Dependencies, nil
+}
+
+// ChartLock is the content of Chart.lock (or requirements.lock for
+// apiVersion v1 charts): the resolved dependencies and helm's digest of them.
+type ChartLock struct {
+	Digest       string            `yaml:"digest"`
+	Dependencies []ChartDependency `yaml:"dependencies"`
+}
+
+// ParseChartLock parses Chart.lock or requirements.lock content.
+func ParseChartLock(data []byte) (ChartLock, error) {
+	var lock ChartLock
+	if err := yaml.Unmarshal(data, &lock); err != nil {
+		return ChartLock{}, fmt.Errorf("parsing chart lock: %w", err)
+	}
+	return lock, nil
+}
+
+// ChartGraph maps each chart path (e.g. "charts/my-app") to the chart paths
+// it depends on through file:// dependencies.
+type ChartGraph map[string][]string
+
+// AddChart records the file:// dependencies of the chart at chartPath.
+func (g ChartGraph) AddChart(chartPath string, deps []ChartDependency) {
+	if _, ok := g[chartPath]; !ok {
+		g[chartPath] = nil
+	}
+	for _, d := range deps {
+		if local, ok := d.LocalPath(); ok {
+			g[chartPath] = append(g[chartPath], path.Join(chartPath, local))
+		}
+	}
+}
+
+// Dependents returns every chart that depends, directly or transitively, on
+// one of the changed charts, mapped to the sorted changed charts it is
+// affected through. Changed charts themselves are not included.
+func (g ChartGraph) Dependents(changed []string) map[string][]string {
+	dependents := make(map[string][]string) // dependency -> charts depending on it directly
+	for chart, deps := range g {
+		for _, dep := range deps {
+			dependents[dep] = append(dependents[dep], chart)
+		}
+	}
+
+	isChanged := make(map[string]bool, len(changed))
+	for _, c := range changed {
+		isChanged[c] = true
+	}
+
+	affected := make(map[string][]string)
+	for _, c := range changed {
+		seen := map[string]bool{c: true}
+		queue := []string{c}
+		for len(queue) > 0 {
+			next := queue[0]
+			queue = queue[1:]
+			for _, d := range dependents[next] {
+				if seen[d] {
+					continue
+				}
+				seen[d] = true
+				queue = append(queue, d)
+				if !isChanged[d] {
+					affected[d] = append(affected[d], c)
+				}
+			}
+		}
+	}
+	for d := range affected {
+		sort.Strings(affected[d])
+	}
+	return affected
+}
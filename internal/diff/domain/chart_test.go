@@ -0,0 +1,139 @@
+package domain
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseChartDependencies(t *testing.T) {
+	data := []byte(`apiVersion: v2
+name: my-app
+version: 1.0.0
+dependencies:
+  - name: common
+    version: 1.x.x
+    repository: file://../common/
+  - name: redis
+    version: 17.3.2
+    repository: https://charts.bitnami.com/bitnami
+    alias: cache
+`)
+	deps, err := ParseChartDependencies(data)
+	if err != nil {
+		t.Fatalf("ParseChartDependencies() error: %v", err)
+	}
+	want := []ChartDependency{
+		{Name: "common", Version: "1.x.x", Repository: "file://../common/"},
+		{Name: "redis", Version: "17.3.2", Repository: "https://charts.bitnami.com/bitnami", Alias: "cache"},
+	}
+	if !reflect.DeepEqual(deps, want) {
+		t.Fatalf("ParseChartDependencies() = %+v, want %+v", deps, want)
+	}
+
+	if p, ok := deps[0].LocalPath(); !ok || p != "../common" {
+		t.Errorf("LocalPath() = %q, %v; want ../common, true", p, ok)
+	}
+	if _, ok := deps[1].LocalPath(); ok {
+		t.Error("remote dependency should not have a local path")
+	}
+	if got := deps[1].String(); got != "redis as cache 17.3.2 (https://charts.bitnami.com/bitnami)" {
+		t.Errorf("String() = %q", got)
+	}
+
+	if _, err := ParseChartDependencies([]byte("dependencies: [")); err == nil {
+		t.Error("expected error for invalid YAML")
+	}
+}
+
+func TestChartGraph_Dependents(t *testing.T) {
+	g := ChartGraph{}
+	g.AddChart("charts/common", nil)
+	g.AddChart("charts/base", []ChartDependency{{Name: "common", Repository: "file://../common"}})
+	g.AddChart("charts/api", []ChartDependency{
+		{Name: "base", Repository: "file://../base"},
+		{Name: "redis", Repository: "https://charts.example.com"},
+	})
+	g.AddChart("charts/web", []ChartDependency{{Name: "common", Repository: "file://../common"}})
+	g.AddChart("charts/worker", nil)
+
+	tests := []struct {
+		name    string
+		changed []string
+		want    map[string][]string
+	}{
+		{
+			name:    "library change reaches direct and transitive dependents",
+			changed: []string{"charts/common"},
+			want: map[string][]string{
+				"charts/base": {"charts/common"},
+				"charts/api":  {"charts/common"},
+				"charts/web":  {"charts/common"},
+			},
+		},
+		{
+			name:    "changed dependents are not repeated",
+			changed: []string{"charts/common", "charts/base"},
+			want: map[string][]string{
+				"charts/api": {"charts/base", "charts/common"},
+				"charts/web": {"charts/common"},
+			},
+		},
+		{
+			name:    "leaf change affects nothing",
+			changed: []string{"charts/worker"},
+			want:    map[string][]string{},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := g.Dependents(tt.changed); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Dependents(%v) = %v, want %v", tt.changed, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestChartDependency_ResolvedVersion(t *testing.T) {
+	tests := []struct {
+		version, locked string
+		want            string
+		wantOK          bool
+	}{
+		{version: "17.3.2", want: "17.3.2", wantOK: true},
+		{version: "v1.0.0-rc.1", want: "v1.0.0-rc.1", wantOK: true},
+		{version: "^17.0.0", locked: "17.3.2", want: "17.3.2", wantOK: true},
+		{version: "17.x.x"},
+		{version: ">=1.0.0"},
+	}
+	for _, tt := range tests {
+		d := ChartDependency{Name: "redis", Version: tt.version}
+		got, ok := d.ResolvedVersion(tt.locked)
+		if got != tt.want || ok != tt.wantOK {
+			t.Errorf("ResolvedVersion(%q) for %q = %q, %v; want %q, %v", tt.locked, tt.version, got, ok, tt.want, tt.wantOK)
+		}
+	}
+	if got := (ChartDependency{Name: "redis"}).ArchiveName("v17.3.2"); got != "redis-17.3.2.tgz" {
+		t.Errorf("ArchiveName() = %q, want redis-17.3.2.tgz", got)
+	}
+}
+
+func TestParseChartLock(t *testing.T) {
+	lock, err := ParseChartLock([]byte(`dependencies:
+- name: redis
+  repository: https://charts.bitnami.com/bitnami
+  version: 17.3.2
+digest: sha256:abc
+generated: "2026-01-01T00:00:00Z"
+`))
+	if err != nil {
+		t.Fatalf("ParseChartLock() error: %v", err)
+	}
+	want := ChartLock{
+		Digest:       "sha256:abc",
+		Dependencies: []ChartDependency{{Name: "redis", Version: "17.3.2", Repository: "https://charts.bitnami.com/bitnami"}},
+	}
+	if !reflect.DeepEqual(lock, want) {
+		t.Errorf("ParseChartLock() = %+v, want %+v", lock, want)
+	}
+}
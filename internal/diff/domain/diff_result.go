@@ -1,6 +1,8 @@
 // Package domain contains core business entities and types for diff operations.
 package domain
 
+import "time"
+
 // Status represents the outcome of a diff operation.
 type Status int
 
@@ -30,14 +32,66 @@ var statusNames = [...]string{
 
 // DiffResult represents the diff output for a single chart + environment pair.
 type DiffResult struct {
-	ChartName    string
-	Environment  string
-	BaseRef      string
-	HeadRef      string
-	Status       Status // Outcome of the diff operation
-	UnifiedDiff  string // Traditional line-based diff (go-difflib)
-	SemanticDiff string // Semantic YAML diff (dyff) - may be empty if dyff unavailable
-	Summary      string // Human-readable summary (or error message if Status == StatusError)
+	ChartName       string
+	ChartPath       string // Chart directory in the PR's repository; empty for gitops deployments
+	Environment     string
+	EnvSource       string // Gitops source that supplied the environment when several are configured
+	BaseRef         string
+	HeadRef         string
+	Status          Status             // Outcome of the diff operation
+	UnifiedDiff     string             // Traditional line-based diff (go-difflib)
+	SemanticDiff    string             // Semantic YAML diff (dyff) - may be empty if dyff unavailable
+	Summary         string             // Human-readable summary (or error message if Status == StatusError)
+	BaseOnly        bool               // Environment is a base/library chart that isn't deployed (not rendered)
+	AffectedVia     []string           // Changed dependency charts this chart was included for (e.g., "common")
+	InputsUnchanged bool               // Render inputs are identical in base and head, so nothing was rendered
+	Warnings        []string           // Non-fatal validation findings (e.g., render produced no resources)
+	Resources       ResourceChanges    // Per-resource change counts between base and head manifests
+	ResourceDiffs   []ResourceDiff     // Line diff of each changed resource, sorted by resource
+	Artifacts       []ManifestArtifact // Stored base/head manifests, when an artifact store is configured
+	Duration        time.Duration      // Time spent rendering and diffing this environment
+}
+
+// Outcome classifies a DiffResult for reporting policies.
+type Outcome string
+
+const (
+	// OutcomeNoChanges indicates the environment rendered identically.
+	OutcomeNoChanges Outcome = "no-changes"
+	// OutcomeChanges indicates the rendered manifests differ.
+	OutcomeChanges Outcome = "changes"
+	// OutcomeError indicates fetching, rendering, or diffing failed.
+	OutcomeError Outcome = "error"
+	// OutcomeBaseOnly indicates a base/library chart that isn't deployed.
+	OutcomeBaseOnly Outcome = "base-only"
+	// OutcomeWarning matches any result carrying validation warnings,
+	// in addition to its primary outcome.
+	OutcomeWarning Outcome = "warning"
+)
+
+// Outcome returns the primary outcome of the result. Warnings are not a
+// primary outcome; check len(r.Warnings) to match OutcomeWarning.
+func (r DiffResult) Outcome() Outcome {
+	switch {
+	case r.Status == StatusError:
+		return OutcomeError
+	case r.BaseOnly:
+		return OutcomeBaseOnly
+	case r.Status == StatusChanges:
+		return OutcomeChanges
+	default:
+		return OutcomeNoChanges
+	}
+}
+
+// ChartKey identifies the result's chart among a PR's results: its path, so
+// charts sharing a name in different directories stay apart, or its name
+// when the result has no path.
+func (r DiffResult) ChartKey() string {
+	if r.ChartPath != "" {
+		return r.ChartPath
+	}
+	return r.ChartName
 }
 
 // PreferredDiff returns the semantic diff if available, otherwise the unified diff.
@@ -70,7 +124,7 @@ func DiffLabel(chartName, envName, ref string) string {
 	return chartName + "/" + envName + " (" + ref + ")"
 }
 
-// GroupByChart groups results by ChartName, preserving insertion order.
+// GroupByChart groups results by ChartKey, preserving insertion order.
 // Returns a slice of slices, where each inner slice contains all results
 // for a single chart.
 func GroupByChart(results []DiffResult) [][]DiffResult {
@@ -78,10 +132,10 @@ func GroupByChart(results []DiffResult) [][]DiffResult {
 	var groups [][]DiffResult
 
 	for _, r := range results {
-		idx, exists := order[r.ChartName]
+		idx, exists := order[r.ChartKey()]
 		if !exists {
 			idx = len(groups)
-			order[r.ChartName] = idx
+			order[r.ChartKey()] = idx
 			groups = append(groups, nil)
 		}
 		groups[idx] = append(groups[idx], r)
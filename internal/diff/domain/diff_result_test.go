@@ -244,6 +244,23 @@ func TestGroupByChart(t *testing.T) {
 				},
 			},
 		},
+		{
+			name: "same name in different directories",
+			results: []DiffResult{
+				{ChartName: "web", ChartPath: "apps/a/web", Environment: "prod"},
+				{ChartName: "web", ChartPath: "apps/b/web", Environment: "prod"},
+				{ChartName: "web", ChartPath: "apps/a/web", Environment: "staging"},
+			},
+			want: [][]DiffResult{
+				{
+					{ChartName: "web", ChartPath: "apps/a/web", Environment: "prod"},
+					{ChartName: "web", ChartPath: "apps/a/web", Environment: "staging"},
+				},
+				{
+					{ChartName: "web", ChartPath: "apps/b/web", Environment: "prod"},
+				},
+			},
+		},
 	}
 
 	for _, tt := range tests {
@@ -265,6 +282,10 @@ func TestGroupByChart(t *testing.T) {
 						t.Errorf("Group %d, result %d: ChartName = %q, want %q",
 							i, j, got[i][j].ChartName, tt.want[i][j].ChartName)
 					}
+					if got[i][j].ChartPath != tt.want[i][j].ChartPath {
+						t.Errorf("Group %d, result %d: ChartPath = %q, want %q",
+							i, j, got[i][j].ChartPath, tt.want[i][j].ChartPath)
+					}
 					if got[i][j].Environment != tt.want[i][j].Environment {
 						t.Errorf("Group %d, result %d: Environment = %q, want %q",
 							i, j, got[i][j].Environment, tt.want[i][j].Environment)
@@ -274,3 +295,26 @@ func TestGroupByChart(t *testing.T) {
 		})
 	}
 }
+
+func TestDiffResult_Outcome(t *testing.T) {
+	tests := []struct {
+		name   string
+		result DiffResult
+		want   Outcome
+	}{
+		{"no changes", DiffResult{Status: StatusSuccess}, OutcomeNoChanges},
+		{"changes", DiffResult{Status: StatusChanges}, OutcomeChanges},
+		{"error", DiffResult{Status: StatusError}, OutcomeError},
+		{"base only", DiffResult{Status: StatusSuccess, BaseOnly: true}, OutcomeBaseOnly},
+		{"error wins over base only", DiffResult{Status: StatusError, BaseOnly: true}, OutcomeError},
+		{"warnings keep primary outcome", DiffResult{Status: StatusChanges, Warnings: []string{"w"}}, OutcomeChanges},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.result.Outcome(); got != tt.want {
+				t.Errorf("Outcome() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -1,11 +1,38 @@
 package domain
 
+import "time"
+
 // EnvironmentConfig holds the specific environment context and
 // the ordered list of values file paths (Helm applies left-to-right).
 type EnvironmentConfig struct {
 	Name       string
 	ValueFiles []string
-	Message    string // Optional message (e.g., for base charts not deployed)
+	Helm       HelmOptions // Render settings beyond value files (e.g., from an Argo CD Application)
+	Message    string      // Optional message (e.g., for base charts not deployed)
+	Source     string      // Gitops source that supplied it when several are configured (e.g., "flux")
+}
+
+// HelmOptions are the per-environment render settings beyond value files.
+// The zero value renders with the value files alone.
+type HelmOptions struct {
+	Values      string          // Inline values YAML, layered after the value files
+	Parameters  []HelmParameter // Individual overrides, applied after Values
+	ReleaseName string          // Release name; empty uses the renderer's default
+	Namespace   string          // Release namespace; empty uses the renderer's default
+	IncludeCRDs bool            // Render the chart's crds/ directory too
+
+	// CheckoutDir is the root of the checkout holding the chart, set by the
+	// application for each render rather than by environment config.
+	// file:// dependencies must stay inside it; empty confines them to the
+	// chart directory.
+	CheckoutDir string
+}
+
+// HelmParameter is a single --set style override.
+type HelmParameter struct {
+	Name        string
+	Value       string
+	ForceString bool // Always a string, like --set-string
 }
 
 // ChartConfig defines a chart to validate and its environments.
@@ -16,6 +43,39 @@ type ChartConfig struct {
 
 // ChangedChart represents a chart that was modified in a PR.
 type ChangedChart struct {
-	Name string // Chart name from Chart.yaml (e.g., "my-app")
-	Path string // Path within repo (e.g., "charts/my-app")
+	Name        string   // Chart name from Chart.yaml (e.g., "my-app")
+	Path        string   // Path within repo (e.g., "charts/my-app")
+	AffectedVia []string // Changed dependency charts (e.g., "common") or value files when the chart itself is unchanged
+}
+
+// DeploymentSource is where and how a gitops deployment renders a chart:
+// the chart's repository, ref and path, and the environment settings.
+type DeploymentSource struct {
+	Owner       string // Chart repository owner
+	Repo        string // Chart repository name
+	Ref         string // Git ref the chart is read at (e.g., "main", "v1.2.0")
+	Path        string // Chart path within the repository
+	Environment EnvironmentConfig
+}
+
+// DeploymentChange is a gitops deployment (e.g., an Argo CD Application) that
+// a pull request to the gitops repository adds, removes or modifies.
+type DeploymentChange struct {
+	Name string            // Deployment name (e.g., "api-prod")
+	File string            // Manifest path within the gitops repository
+	Base *DeploymentSource // nil when the PR adds the deployment
+	Head *DeploymentSource // nil when the PR removes the deployment
+	// Unsupported explains why the deployment can't be rendered (e.g., its
+	// chart comes from a Helm repository rather than git); empty when it can.
+	Unsupported string
+}
+
+// IndexStatus describes a gitops index built from a synced repository clone.
+type IndexStatus struct {
+	Source       string    // Index name (e.g., "argo")
+	Repo         string    // Repository URL the index is built from
+	Applications int       // Deployments indexed
+	Charts       int       // Distinct charts indexed
+	LastSync     time.Time // Last successful sync; zero before the first
+	LastError    string    // Error of the last sync, empty when it succeeded
 }
@@ -5,13 +5,16 @@ import (
 	"fmt"
 )
 
-// NotFoundError represents a resource that was not found at a specific ref.
+// NotFoundError represents a resource that was not found, optionally at a specific ref.
 type NotFoundError struct {
 	Resource string
-	Ref      string
+	Ref      string // Empty for resources that aren't versioned (e.g. stored runs)
 }
 
 func (e *NotFoundError) Error() string {
+	if e.Ref == "" {
+		return e.Resource + " not found"
+	}
 	return fmt.Sprintf("%s not found at ref %s", e.Resource, e.Ref)
 }
 
@@ -13,6 +13,10 @@ func TestNotFoundError(t *testing.T) {
 	if err.Error() != expected {
 		t.Errorf("Error() = %q, want %q", err.Error(), expected)
 	}
+
+	if got := NewNotFoundError("run 42", "").Error(); got != "run 42 not found" {
+		t.Errorf("Error() without ref = %q, want %q", got, "run 42 not found")
+	}
 }
 
 func TestIsNotFound(t *testing.T) {
@@ -1,5 +1,7 @@
 package domain
 
+import "fmt"
+
 // PRContext holds the details of a pull request event.
 type PRContext struct {
 	Owner    string
@@ -9,3 +11,8 @@ type PRContext struct {
 	HeadRef  string
 	HeadSHA  string
 }
+
+// URL returns the pull request's GitHub web URL.
+func (pr PRContext) URL() string {
+	return fmt.Sprintf("https://github.com/%s/%s/pull/%d", pr.Owner, pr.Repo, pr.PRNumber)
+}
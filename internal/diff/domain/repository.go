@@ -1,6 +1,12 @@
 package domain
 
-import "strings"
+import (
+	"fmt"
+	"net/url"
+	"path"
+	"sort"
+	"strings"
+)
 
 // ExtractChartNames parses file paths and returns unique chart names
 // from paths matching {chartDir}/{name}/...
@@ -27,3 +33,113 @@ func ExtractChartNames(files []string, chartDir string) []string {
 	}
 	return names
 }
+
+// ChartLayout describes where charts live in a repository: directories
+// holding a Chart.yaml that match one of the Include globs and none of the
+// Exclude globs. Globs use path.Match syntax for each path segment, and "**"
+// matches any number of segments (e.g. "services/*/deploy/chart",
+// "platform/charts/*", "**/testdata/**").
+type ChartLayout struct {
+	Include []string
+	Exclude []string
+}
+
+// Contains reports whether dir is a chart location under this layout.
+func (l ChartLayout) Contains(dir string) bool {
+	return matchAny(l.Include, dir) && !matchAny(l.Exclude, dir)
+}
+
+// Validate checks every glob for syntax errors.
+func (l ChartLayout) Validate() error {
+	for _, pattern := range append(append([]string(nil), l.Include...), l.Exclude...) {
+		for _, segment := range strings.Split(pattern, "/") {
+			if _, err := path.Match(segment, ""); err != nil {
+				return fmt.Errorf("invalid chart glob %q: %w", pattern, err)
+			}
+		}
+	}
+	return nil
+}
+
+// ChartDirs returns the directories of the Chart.yaml files among
+// repository file paths that fall within the layout, sorted.
+func (l ChartLayout) ChartDirs(files []string) []string {
+	var dirs []string
+	for _, f := range files {
+		if path.Base(f) != "Chart.yaml" {
+			continue
+		}
+		if dir := path.Dir(f); l.Contains(dir) {
+			dirs = append(dirs, dir)
+		}
+	}
+	sort.Strings(dirs)
+	return dirs
+}
+
+// OwningChart returns the deepest chart directory containing file, or ""
+// when the file belongs to no chart.
+func OwningChart(file string, chartDirs []string) string {
+	owner := ""
+	for _, dir := range chartDirs {
+		if strings.HasPrefix(file, dir+"/") && len(dir) > len(owner) {
+			owner = dir
+		}
+	}
+	return owner
+}
+
+// MatchGlob reports whether the slash-separated path p matches pattern,
+// using path.Match syntax for each segment and "**" for any number of
+// segments.
+func MatchGlob(pattern, p string) bool {
+	return matchSegments(strings.Split(pattern, "/"), strings.Split(p, "/"))
+}
+
+func matchAny(patterns []string, dir string) bool {
+	for _, p := range patterns {
+		if MatchGlob(p, dir) {
+			return true
+		}
+	}
+	return false
+}
+
+// matchSegments matches path segments against glob segments, where "**"
+// matches zero or more segments.
+func matchSegments(pattern, segments []string) bool {
+	if len(pattern) == 0 {
+		return len(segments) == 0
+	}
+	if pattern[0] == "**" {
+		for i := 0; i <= len(segments); i++ {
+			if matchSegments(pattern[1:], segments[i:]) {
+				return true
+			}
+		}
+		return false
+	}
+	if len(segments) == 0 {
+		return false
+	}
+	ok, err := path.Match(pattern[0], segments[0])
+	return err == nil && ok && matchSegments(pattern[1:], segments[1:])
+}
+
+// RepoFromURL extracts the owner and repository name from a Git URL such as
+// https://github.com/org/repo.git, ssh://git@github.com/org/repo or
+// git@github.com:org/repo.git.
+func RepoFromURL(repoURL string) (owner, repo string, err error) {
+	p := repoURL
+	if u, parseErr := url.Parse(repoURL); parseErr == nil && u.Host != "" {
+		p = u.Path
+	} else if _, after, ok := strings.Cut(repoURL, ":"); ok && strings.Contains(repoURL, "@") {
+		p = after // scp-like syntax: git@host:org/repo.git
+	}
+
+	parts := strings.Split(strings.TrimSuffix(strings.Trim(p, "/"), ".git"), "/")
+	if len(parts) < 2 || parts[len(parts)-2] == "" || parts[len(parts)-1] == "" {
+		return "", "", fmt.Errorf("cannot determine repository from URL %q", repoURL)
+	}
+	return parts[len(parts)-2], parts[len(parts)-1], nil
+}
@@ -1,6 +1,9 @@
 package domain
 
-import "testing"
+import (
+	"reflect"
+	"testing"
+)
 
 func TestExtractChartNames(t *testing.T) {
 	tests := []struct {
@@ -96,3 +99,89 @@ func TestExtractChartNames(t *testing.T) {
 		})
 	}
 }
+
+func TestChartLayout(t *testing.T) {
+	layout := ChartLayout{
+		Include: []string{"charts/*", "services/*/deploy/chart", "platform/**"},
+		Exclude: []string{"**/testdata/**", "platform/charts/legacy-*"},
+	}
+
+	tests := []struct {
+		dir  string
+		want bool
+	}{
+		{dir: "charts/my-app", want: true},
+		{dir: "charts/my-app/charts/sub", want: false},
+		{dir: "services/api/deploy/chart", want: true},
+		{dir: "services/api/deploy", want: false},
+		{dir: "platform/charts/ingress", want: true},
+		{dir: "platform/charts/legacy-dns", want: false},
+		{dir: "platform/testdata/chart", want: false},
+		{dir: "other/chart", want: false},
+	}
+	for _, tt := range tests {
+		if got := layout.Contains(tt.dir); got != tt.want {
+			t.Errorf("Contains(%q) = %v, want %v", tt.dir, got, tt.want)
+		}
+	}
+
+	files := []string{
+		"charts/my-app/Chart.yaml",
+		"charts/my-app/templates/cm.yaml",
+		"charts/my-app/charts/sub/Chart.yaml",
+		"services/api/deploy/chart/Chart.yaml",
+		"other/chart/Chart.yaml",
+	}
+	dirs := layout.ChartDirs(files)
+	want := []string{"charts/my-app", "services/api/deploy/chart"}
+	if !reflect.DeepEqual(dirs, want) {
+		t.Errorf("ChartDirs() = %v, want %v", dirs, want)
+	}
+
+	if got := OwningChart("services/api/deploy/chart/env/prod-values.yaml", dirs); got != "services/api/deploy/chart" {
+		t.Errorf("OwningChart() = %q", got)
+	}
+	if got := OwningChart("services/api/main.go", dirs); got != "" {
+		t.Errorf("OwningChart() = %q, want none", got)
+	}
+
+	if err := (ChartLayout{Include: []string{"charts/["}}).Validate(); err == nil {
+		t.Error("Validate() should reject malformed globs")
+	}
+}
+
+func TestRepoFromURL(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		url       string
+		wantOwner string
+		wantRepo  string
+		wantErr   bool
+	}{
+		{url: "https://github.com/org/repo", wantOwner: "org", wantRepo: "repo"},
+		{url: "https://github.com/org/repo.git/", wantOwner: "org", wantRepo: "repo"},
+		{url: "ssh://git@github.com/org/repo.git", wantOwner: "org", wantRepo: "repo"},
+		{url: "git@github.com:org/repo.git", wantOwner: "org", wantRepo: "repo"},
+		{url: "https://github.example.com/org/repo", wantOwner: "org", wantRepo: "repo"},
+		{url: "https://github.com/org", wantErr: true},
+		{url: "", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.url, func(t *testing.T) {
+			t.Parallel()
+
+			owner, repo, err := RepoFromURL(tt.url)
+			if tt.wantErr {
+				if err == nil {
+					t.Errorf("RepoFromURL(%q) = %s/%s, want error", tt.url, owner, repo)
+				}
+				return
+			}
+			if err != nil || owner != tt.wantOwner || repo != tt.wantRepo {
+				t.Errorf("RepoFromURL(%q) = %s/%s, %v; want %s/%s", tt.url, owner, repo, err, tt.wantOwner, tt.wantRepo)
+			}
+		})
+	}
+}
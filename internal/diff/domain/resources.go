@@ -0,0 +1,151 @@
+package domain
+
+import (
+	"bytes"
+	"regexp"
+	"sort"
+
+	"gopkg.in/yaml.v3"
+)
+
+// ResourceChanges counts Kubernetes resources that differ between two
+// rendered manifests. Resources are identified by apiVersion, kind,
+// namespace, and name.
+type ResourceChanges struct {
+	Added    int
+	Removed  int
+	Modified int
+}
+
+// Total returns the number of resources that changed in any way.
+func (c ResourceChanges) Total() int {
+	return c.Added + c.Removed + c.Modified
+}
+
+// ChangeType describes how a single resource changed.
+type ChangeType string
+
+const (
+	// ChangeAdded indicates the resource only exists in the head manifest.
+	ChangeAdded ChangeType = "added"
+	// ChangeRemoved indicates the resource only exists in the base manifest.
+	ChangeRemoved ChangeType = "removed"
+	// ChangeModified indicates the resource exists in both but differs.
+	ChangeModified ChangeType = "modified"
+)
+
+// ChangedResource is one resource that differs between two manifests,
+// with its document from each side (nil when absent).
+type ChangedResource struct {
+	Resource string // "Kind/namespace/name", or "Kind/name" for cluster-scoped resources
+	Change   ChangeType
+	Base     []byte
+	Head     []byte
+}
+
+// ResourceDiff is the line diff of a single changed resource.
+type ResourceDiff struct {
+	Resource string
+	Change   ChangeType
+	Diff     string
+}
+
+// documentSeparator matches YAML document separators at the start of a line.
+var documentSeparator = regexp.MustCompile(`(?m)^---\s*$`)
+
+// IsEmptyManifest reports whether a manifest has no content besides
+// document separators and comments (such as a renderer's dependency header).
+func IsEmptyManifest(manifest []byte) bool {
+	for _, line := range bytes.Split(manifest, []byte("\n")) {
+		line = bytes.TrimSpace(line)
+		if len(line) > 0 && line[0] != '#' && !documentSeparator.Match(line) {
+			return false
+		}
+	}
+	return true
+}
+
+// CountResourceChanges compares two multi-document manifests resource by
+// resource. Documents that can't be identified (no kind or name) are ignored.
+func CountResourceChanges(base, head []byte) ResourceChanges {
+	return CountChangedResources(ChangedResources(base, head))
+}
+
+// CountChangedResources tallies changed resources by change type.
+func CountChangedResources(changed []ChangedResource) ResourceChanges {
+	var c ResourceChanges
+	for _, r := range changed {
+		switch r.Change {
+		case ChangeAdded:
+			c.Added++
+		case ChangeRemoved:
+			c.Removed++
+		case ChangeModified:
+			c.Modified++
+		}
+	}
+	return c
+}
+
+// ChangedResources returns every identifiable resource that was added,
+// removed, or modified between two manifests, sorted by resource name.
+func ChangedResources(base, head []byte) []ChangedResource {
+	baseDocs := indexResources(base)
+	headDocs := indexResources(head)
+
+	var changed []ChangedResource
+	for key, h := range headDocs {
+		b, ok := baseDocs[key]
+		switch {
+		case !ok:
+			changed = append(changed, ChangedResource{Resource: h.label, Change: ChangeAdded, Head: h.doc})
+		case !bytes.Equal(b.doc, h.doc):
+			changed = append(changed, ChangedResource{Resource: h.label, Change: ChangeModified, Base: b.doc, Head: h.doc})
+		}
+	}
+	for key, b := range baseDocs {
+		if _, ok := headDocs[key]; !ok {
+			changed = append(changed, ChangedResource{Resource: b.label, Change: ChangeRemoved, Base: b.doc})
+		}
+	}
+
+	sort.Slice(changed, func(i, j int) bool {
+		if changed[i].Resource != changed[j].Resource {
+			return changed[i].Resource < changed[j].Resource
+		}
+		return changed[i].Change < changed[j].Change
+	})
+	return changed
+}
+
+type resourceMeta struct {
+	APIVersion string `yaml:"apiVersion"`
+	Kind       string `yaml:"kind"`
+	Metadata   struct {
+		Name      string `yaml:"name"`
+		Namespace string `yaml:"namespace"`
+	} `yaml:"metadata"`
+}
+
+type indexedResource struct {
+	label string
+	doc   []byte
+}
+
+// indexResources maps each identifiable resource to its label and document text.
+func indexResources(manifest []byte) map[string]indexedResource {
+	docs := make(map[string]indexedResource)
+	for _, doc := range documentSeparator.Split(string(manifest), -1) {
+		var meta resourceMeta
+		if err := yaml.Unmarshal([]byte(doc), &meta); err != nil || meta.Kind == "" || meta.Metadata.Name == "" {
+			continue
+		}
+		key := meta.APIVersion + "/" + meta.Kind + "/" + meta.Metadata.Namespace + "/" + meta.Metadata.Name
+		label := meta.Kind + "/" + meta.Metadata.Name
+		if meta.Metadata.Namespace != "" {
+			label = meta.Kind + "/" + meta.Metadata.Namespace + "/" + meta.Metadata.Name
+		}
+		docs[key] = indexedResource{label: label, doc: bytes.TrimSpace([]byte(doc))}
+	}
+	return docs
+}
@@ -0,0 +1,122 @@
+package domain
+
+import "testing"
+
+func TestCountResourceChanges(t *testing.T) {
+	const deploy = `apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: web
+spec:
+  replicas: 1
+`
+	const deployScaled = `apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: web
+spec:
+  replicas: 3
+`
+	const svc = `apiVersion: v1
+kind: Service
+metadata:
+  name: web
+`
+	const cm = `apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: web
+  namespace: other
+`
+
+	tests := []struct {
+		name string
+		base string
+		head string
+		want ResourceChanges
+	}{
+		{
+			name: "identical",
+			base: deploy + "---\n" + svc,
+			head: deploy + "---\n" + svc,
+			want: ResourceChanges{},
+		},
+		{
+			name: "modified, added and removed",
+			base: "---\n" + deploy + "---\n" + svc,
+			head: "---\n" + deployScaled + "---\n" + cm,
+			want: ResourceChanges{Added: 1, Removed: 1, Modified: 1},
+		},
+		{
+			name: "new chart",
+			base: "",
+			head: deploy + "---\n" + svc,
+			want: ResourceChanges{Added: 2},
+		},
+		{
+			name: "ignores unidentifiable documents",
+			base: "# Source: empty.yaml\n",
+			head: "foo: bar\n---\n" + svc,
+			want: ResourceChanges{Added: 1},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := CountResourceChanges([]byte(tt.base), []byte(tt.head))
+			if got != tt.want {
+				t.Errorf("CountResourceChanges() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestChangedResources(t *testing.T) {
+	base := "apiVersion: v1\nkind: Service\nmetadata:\n  name: web\n---\n" +
+		"apiVersion: v1\nkind: ConfigMap\nmetadata:\n  name: cfg\n  namespace: apps\ndata:\n  a: \"1\"\n"
+	head := "apiVersion: v1\nkind: ConfigMap\nmetadata:\n  name: cfg\n  namespace: apps\ndata:\n  a: \"2\"\n---\n" +
+		"apiVersion: apps/v1\nkind: Deployment\nmetadata:\n  name: web\n"
+
+	got := ChangedResources([]byte(base), []byte(head))
+
+	want := []struct {
+		resource string
+		change   ChangeType
+		hasBase  bool
+		hasHead  bool
+	}{
+		{"ConfigMap/apps/cfg", ChangeModified, true, true},
+		{"Deployment/web", ChangeAdded, false, true},
+		{"Service/web", ChangeRemoved, true, false},
+	}
+	if len(got) != len(want) {
+		t.Fatalf("ChangedResources() returned %d resources, want %d: %+v", len(got), len(want), got)
+	}
+	for i, w := range want {
+		g := got[i]
+		if g.Resource != w.resource || g.Change != w.change || (g.Base != nil) != w.hasBase || (g.Head != nil) != w.hasHead {
+			t.Errorf("resource %d = {%s %s base=%t head=%t}, want %+v",
+				i, g.Resource, g.Change, g.Base != nil, g.Head != nil, w)
+		}
+	}
+}
+
+func TestIsEmptyManifest(t *testing.T) {
+	tests := []struct {
+		name     string
+		manifest string
+		want     bool
+	}{
+		{name: "nothing", manifest: "", want: true},
+		{name: "whitespace", manifest: "\n  \n", want: true},
+		{name: "separators and comments", manifest: "# Chart dependencies:\n#   common: vendored\n---\n# Source: x\n---\n", want: true},
+		{name: "resource", manifest: "---\n# Source: x\nkind: ConfigMap\n", want: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := IsEmptyManifest([]byte(tt.manifest)); got != tt.want {
+				t.Errorf("IsEmptyManifest(%q) = %v, want %v", tt.manifest, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,63 @@
+package domain
+
+import (
+	"strings"
+	"time"
+)
+
+// Run is a single Execute invocation for a pull request head commit.
+type Run struct {
+	ID        int64 // Assigned by the history store
+	PR        PRContext
+	StartedAt time.Time
+	Duration  time.Duration
+	Error     string       // Set when the run failed before producing results
+	Results   []DiffResult // One per chart + environment
+}
+
+// RunFilter narrows a run history query. Chart, Environment, Outcome, and
+// Search must all match the same result; empty fields match everything.
+type RunFilter struct {
+	Repo        string  // "owner/repo"
+	HeadSHA     string  // PR head commit
+	Chart       string  // Chart name
+	Environment string  // Environment name
+	Outcome     Outcome // Result outcome (e.g. OutcomeChanges)
+	Search      string  // Case-insensitive substring of the chart, environment, or diff
+	Before      int64   // Only runs with ID < Before (for paging); 0 means no bound
+	Limit       int     // Maximum runs returned
+}
+
+// Matches reports whether the run satisfies the filter (ignoring Before and Limit).
+func (f RunFilter) Matches(run Run) bool {
+	if f.Repo != "" && f.Repo != run.PR.Owner+"/"+run.PR.Repo {
+		return false
+	}
+	if f.HeadSHA != "" && f.HeadSHA != run.PR.HeadSHA {
+		return false
+	}
+	if f.Chart == "" && f.Environment == "" && f.Outcome == "" && f.Search == "" {
+		return true
+	}
+	search := strings.ToLower(f.Search)
+	for _, r := range run.Results {
+		if (f.Chart == "" || f.Chart == r.ChartName) &&
+			(f.Environment == "" || f.Environment == r.Environment) &&
+			(f.Outcome == "" || f.Outcome == r.Outcome()) &&
+			(search == "" || resultContains(r, search)) {
+			return true
+		}
+	}
+	return false
+}
+
+// resultContains reports whether the result's chart, environment, or diffs
+// contain the lower-cased search term.
+func resultContains(r DiffResult, search string) bool {
+	for _, s := range []string{r.ChartName, r.Environment, r.UnifiedDiff, r.SemanticDiff} {
+		if strings.Contains(strings.ToLower(s), search) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,39 @@
+package domain
+
+import "testing"
+
+func TestRunFilter_Matches(t *testing.T) {
+	run := Run{
+		PR: PRContext{Owner: "org", Repo: "charts", HeadSHA: "abc123"},
+		Results: []DiffResult{
+			{ChartName: "api", Environment: "prod", Status: StatusChanges, UnifiedDiff: "-replicas: 1\n+replicas: 3"},
+			{ChartName: "web", Environment: "dev", Status: StatusSuccess},
+		},
+	}
+
+	tests := []struct {
+		name   string
+		filter RunFilter
+		want   bool
+	}{
+		{name: "empty filter", filter: RunFilter{}, want: true},
+		{name: "repo", filter: RunFilter{Repo: "org/charts"}, want: true},
+		{name: "other repo", filter: RunFilter{Repo: "org/other"}, want: false},
+		{name: "head sha", filter: RunFilter{HeadSHA: "abc123"}, want: true},
+		{name: "other head sha", filter: RunFilter{HeadSHA: "def456"}, want: false},
+		{name: "chart and outcome on same result", filter: RunFilter{Chart: "api", Outcome: OutcomeChanges}, want: true},
+		{name: "chart and outcome on different results", filter: RunFilter{Chart: "web", Outcome: OutcomeChanges}, want: false},
+		{name: "search matches diff case-insensitively", filter: RunFilter{Search: "REPLICAS"}, want: true},
+		{name: "search matches environment", filter: RunFilter{Search: "dev"}, want: true},
+		{name: "search scoped to chart", filter: RunFilter{Chart: "web", Search: "replicas"}, want: false},
+		{name: "search without match", filter: RunFilter{Search: "ingress"}, want: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.filter.Matches(run); got != tt.want {
+				t.Errorf("Matches() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
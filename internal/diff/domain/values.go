@@ -0,0 +1,88 @@
+package domain
+
+import (
+	"fmt"
+	"path"
+	"regexp"
+	"strings"
+)
+
+// qualifiedValueFile matches "owner/repo@ref:path".
+var qualifiedValueFile = regexp.MustCompile(`^([\w.-]+)/([\w.-]+)@([^:\s]+):(.+)$`)
+
+// ValueFileRef is a parsed EnvironmentConfig value file. Three forms are
+// supported:
+//
+//	env/prod-values.yaml              relative to the chart directory
+//	/deploy/values/prod.yaml          relative to the chart repository's root
+//	org/config@main:values/prod.yaml  a file in a repository at a fixed ref
+type ValueFileRef struct {
+	Owner    string // Set for fully qualified references
+	Repo     string // Set for fully qualified references
+	Ref      string // Set for fully qualified references
+	Path     string // Cleaned path, relative to the chart or repository root
+	RepoRoot bool   // Path is relative to the chart repository's root
+}
+
+// ParseValueFileRef parses a value file entry.
+func ParseValueFileRef(s string) (ValueFileRef, error) {
+	var ref ValueFileRef
+	switch m := qualifiedValueFile.FindStringSubmatch(s); {
+	case m != nil:
+		ref = ValueFileRef{Owner: m[1], Repo: m[2], Ref: m[3], Path: m[4]}
+	case strings.HasPrefix(s, "/"):
+		ref = ValueFileRef{Path: s, RepoRoot: true}
+	default:
+		// Chart-relative paths may point outside the chart (e.g. "../shared.yaml")
+		return ValueFileRef{Path: path.Clean(s)}, nil
+	}
+
+	ref.Path = path.Clean(strings.TrimPrefix(ref.Path, "/"))
+	if ref.Path == "." || ref.Path == ".." || strings.HasPrefix(ref.Path, "../") {
+		return ValueFileRef{}, fmt.Errorf("invalid value file %q: path must be a file inside the repository", s)
+	}
+	return ref, nil
+}
+
+// External reports whether the file lives outside the chart checkout's
+// relative namespace and has to be resolved through source control.
+func (r ValueFileRef) External() bool {
+	return r.RepoRoot || r.Repo != ""
+}
+
+// InPRRepo reports whether a fully qualified reference points at the pull
+// request's repository on its base branch (or HEAD, the default branch PRs
+// normally target). Such files follow the PR like repo-root-relative ones:
+// base renders read them at the base ref and head renders at the head ref.
+func (r ValueFileRef) InPRRepo(pr PRContext) bool {
+	return r.Repo != "" &&
+		strings.EqualFold(r.Owner, pr.Owner) &&
+		strings.EqualFold(r.Repo, pr.Repo) &&
+		(r.Ref == pr.BaseRef || r.Ref == "HEAD")
+}
+
+// RepoPath returns the file's path within the pull request's repository for
+// a chart at chartPath, or false if the file lives in another repository
+// or at a fixed ref the PR doesn't change.
+func (r ValueFileRef) RepoPath(pr PRContext, chartPath string) (string, bool) {
+	switch {
+	case r.RepoRoot, r.InPRRepo(pr):
+		return r.Path, true
+	case r.Repo != "":
+		return "", false
+	default:
+		return path.Join(chartPath, r.Path), true
+	}
+}
+
+// String formats the reference the way it is written in configuration.
+func (r ValueFileRef) String() string {
+	switch {
+	case r.Repo != "":
+		return fmt.Sprintf("%s/%s@%s:%s", r.Owner, r.Repo, r.Ref, r.Path)
+	case r.RepoRoot:
+		return "/" + r.Path
+	default:
+		return r.Path
+	}
+}
@@ -0,0 +1,93 @@
+package domain
+
+import "testing"
+
+func TestParseValueFileRef(t *testing.T) {
+	pr := PRContext{Owner: "org", Repo: "charts", BaseRef: "main", HeadRef: "feat"}
+
+	tests := []struct {
+		name         string
+		input        string
+		want         ValueFileRef
+		wantErr      bool
+		wantExternal bool
+		wantRepoPath string // "" when the file isn't in the PR's repository
+	}{
+		{
+			name:         "chart relative",
+			input:        "env/prod-values.yaml",
+			want:         ValueFileRef{Path: "env/prod-values.yaml"},
+			wantRepoPath: "charts/app/env/prod-values.yaml",
+		},
+		{
+			name:         "chart relative outside the chart",
+			input:        "../../deploy/values/prod.yaml",
+			want:         ValueFileRef{Path: "../../deploy/values/prod.yaml"},
+			wantRepoPath: "deploy/values/prod.yaml",
+		},
+		{
+			name:         "repo root relative",
+			input:        "/deploy/values//prod.yaml",
+			want:         ValueFileRef{Path: "deploy/values/prod.yaml", RepoRoot: true},
+			wantExternal: true,
+			wantRepoPath: "deploy/values/prod.yaml",
+		},
+		{
+			name:         "other repository",
+			input:        "org/config@v1.2.0:values/prod.yaml",
+			want:         ValueFileRef{Owner: "org", Repo: "config", Ref: "v1.2.0", Path: "values/prod.yaml"},
+			wantExternal: true,
+		},
+		{
+			name:         "pr repository on its base branch",
+			input:        "Org/charts@main:/deploy/prod.yaml",
+			want:         ValueFileRef{Owner: "Org", Repo: "charts", Ref: "main", Path: "deploy/prod.yaml"},
+			wantExternal: true,
+			wantRepoPath: "deploy/prod.yaml",
+		},
+		{
+			name:         "pr repository at HEAD",
+			input:        "org/charts@HEAD:deploy/prod.yaml",
+			want:         ValueFileRef{Owner: "org", Repo: "charts", Ref: "HEAD", Path: "deploy/prod.yaml"},
+			wantExternal: true,
+			wantRepoPath: "deploy/prod.yaml",
+		},
+		{
+			name:         "pr repository at a fixed ref",
+			input:        "org/charts@release/1.0:deploy/prod.yaml",
+			want:         ValueFileRef{Owner: "org", Repo: "charts", Ref: "release/1.0", Path: "deploy/prod.yaml"},
+			wantExternal: true,
+		},
+		{name: "repo root escape", input: "/../prod.yaml", wantErr: true},
+		{name: "qualified escape", input: "org/config@main:../prod.yaml", wantErr: true},
+		{name: "repo root directory", input: "/", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseValueFileRef(tt.input)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("ParseValueFileRef(%q) = %+v, want error", tt.input, got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ParseValueFileRef(%q) error: %v", tt.input, err)
+			}
+			if got != tt.want {
+				t.Errorf("ParseValueFileRef(%q) = %+v, want %+v", tt.input, got, tt.want)
+			}
+			if got.External() != tt.wantExternal {
+				t.Errorf("External() = %v, want %v", got.External(), tt.wantExternal)
+			}
+			repoPath, ok := got.RepoPath(pr, "charts/app")
+			if ok != (tt.wantRepoPath != "") || repoPath != tt.wantRepoPath {
+				t.Errorf("RepoPath() = %q, %v; want %q", repoPath, ok, tt.wantRepoPath)
+			}
+			if reparsed, err := ParseValueFileRef(got.String()); err != nil || reparsed != got {
+				t.Errorf("ParseValueFileRef(String()) = %+v, %v; want %+v", reparsed, err, got)
+			}
+		})
+	}
+}
@@ -11,3 +11,24 @@ import (
 type DiffUseCase interface {
 	Execute(ctx context.Context, pr domain.PRContext) error
 }
+
+// RunQueryUseCase is the driving port for browsing run history.
+type RunQueryUseCase interface {
+	ListRuns(ctx context.Context, filter domain.RunFilter) ([]domain.Run, error)
+	GetRun(ctx context.Context, id int64) (domain.Run, error)
+
+	// ArtifactURL returns a freshly signed download link for a stored
+	// rendered manifest, or a domain.NotFoundError when none can be made.
+	ArtifactURL(ctx context.Context, key string) (string, error)
+}
+
+// GitopsIndexUseCase is the driving port for keeping gitops indexes in step
+// with their repositories.
+type GitopsIndexUseCase interface {
+	// RequestSync schedules a sync of the indexes built from owner/repo
+	// without waiting for it, and reports whether there are any.
+	RequestSync(owner, repo string) bool
+
+	// Sync syncs every index now and returns their status.
+	Sync(ctx context.Context) ([]domain.IndexStatus, error)
+}
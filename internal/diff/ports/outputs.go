@@ -2,6 +2,7 @@ package ports
 
 import (
 	"context"
+	"time"
 
 	"github.com/nathantilsley/chart-val/internal/diff/domain"
 )
@@ -12,9 +13,10 @@ type SourceControlPort interface {
 }
 
 // RendererPort abstracts Helm template rendering, separated from source control
-// so the rendering strategy is independently swappable.
+// so the rendering strategy is independently swappable. Value files are
+// chart-relative or absolute and applied in order, followed by opts.
 type RendererPort interface {
-	Render(ctx context.Context, chartDir string, valueFiles []string) ([]byte, error)
+	Render(ctx context.Context, chartDir string, valueFiles []string, opts domain.HelmOptions) ([]byte, error)
 }
 
 // ReportingPort abstracts posting diff results back to the pull request.
@@ -31,8 +33,17 @@ type ReportingPort interface {
 		results []domain.DiffResult,
 	) error
 
-	// PostComment posts a PR comment with diff results for a single chart.
+	// PostComment creates or updates the PR comment with diff results for a single chart.
 	PostComment(ctx context.Context, pr domain.PRContext, results []domain.DiffResult) error
+
+	// ResolveComment marks a chart's earlier PR comment as resolved when a later
+	// push leaves the chart without changes. Charts are identified by
+	// domain.DiffResult.ChartKey. It is a no-op if no comment exists.
+	ResolveComment(ctx context.Context, pr domain.PRContext, chart string) error
+
+	// CommentedCharts returns the keys of the charts with an unresolved PR
+	// comment, so comments of charts the PR no longer changes can be resolved.
+	CommentedCharts(ctx context.Context, pr domain.PRContext) ([]string, error)
 }
 
 // ChangedChartsPort abstracts detecting which charts were modified in a PR.
@@ -57,5 +68,61 @@ type DiffPort interface {
 // or the chart's env/ directory structure.
 type EnvironmentConfigPort interface {
 	// GetEnvironmentConfig returns deployment config (path + environments) for a given chart.
-	GetEnvironmentConfig(ctx context.Context, pr domain.PRContext, chartName string) (domain.ChartConfig, error)
+	GetEnvironmentConfig(ctx context.Context, pr domain.PRContext, chart domain.ChangedChart) (domain.ChartConfig, error)
+}
+
+// ValueFileIndexPort is implemented by environment config sources that know
+// every chart's value files up front (e.g. Argo CD Applications), so charts
+// are validated when only a values file outside their directory changes.
+type ValueFileIndexPort interface {
+	// ChartsUsingFiles returns the charts with an environment that uses one
+	// of the given files of the PR's repository as a value file, each with
+	// the files it uses in AffectedVia.
+	ChartsUsingFiles(ctx context.Context, pr domain.PRContext, files []string) ([]domain.ChangedChart, error)
+}
+
+// DeploymentChangesPort abstracts finding the deployments a pull request to
+// a gitops repository changes, with their settings on each side.
+type DeploymentChangesPort interface {
+	// IsGitopsRepo reports whether the PR is to the gitops repository.
+	IsGitopsRepo(pr domain.PRContext) bool
+
+	// ChangedDeployments returns the deployments the PR adds, removes or
+	// modifies.
+	ChangedDeployments(ctx context.Context, pr domain.PRContext) ([]domain.DeploymentChange, error)
+}
+
+// GitopsIndexPort is implemented by environment config sources that index
+// a synced clone of a gitops repository.
+type GitopsIndexPort interface {
+	// RequestSync schedules a sync without waiting for it if the index is
+	// built from owner/repo, and reports whether it is.
+	RequestSync(owner, repo string) bool
+
+	// Sync syncs the index now and returns its status.
+	Sync(ctx context.Context) (domain.IndexStatus, error)
+}
+
+// RunHistoryPort abstracts persisting completed runs and querying them later.
+type RunHistoryPort interface {
+	// SaveRun stores a run and returns its assigned ID.
+	SaveRun(ctx context.Context, run domain.Run) (int64, error)
+
+	// ListRuns returns runs matching the filter, newest first.
+	ListRuns(ctx context.Context, filter domain.RunFilter) ([]domain.Run, error)
+
+	// GetRun returns a single run, or a domain.NotFoundError if it doesn't exist.
+	GetRun(ctx context.Context, id int64) (domain.Run, error)
+}
+
+// ArtifactStorePort abstracts a blob store for rendered manifests.
+type ArtifactStorePort interface {
+	// Put stores data under key, replacing any existing blob.
+	Put(ctx context.Context, key string, data []byte) error
+
+	// URL returns a download link for the blob stored under key.
+	URL(ctx context.Context, key string) (string, error)
+
+	// Prune deletes blobs stored before cutoff and returns how many were deleted.
+	Prune(ctx context.Context, cutoff time.Time) (int, error)
 }
@@ -2,10 +2,14 @@
 package config
 
 import (
+	"crypto/hkdf"
+	"crypto/sha256"
+	"encoding/hex"
 	"errors"
 	"fmt"
 	"os"
 	"strconv"
+	"strings"
 	"time"
 )
 
@@ -17,22 +21,87 @@ type Config struct {
 	GitHubInstallationID int64
 	GitHubPrivateKey     string // PEM file contents
 	LogLevel             string
+	AdminToken           string // ADMIN_TOKEN (default: ""); token for the /admin, /api and /ui endpoints, "" disables them
 
 	// Argo CD integration (optional)
 	ArgoAppsRepo          string        // Git repo containing Argo apps (e.g., "https://github.com/org/gitops")
 	ArgoAppsLocalPath     string        // Local path for clone (e.g., "/tmp/chart-val-argocd")
 	ArgoAppsSyncInterval  time.Duration // How often to sync repo (e.g., 1h)
 	ArgoAppsFolderPattern string        // Folder structure pattern (e.g., "apps/{chartName}/{envName}")
+	ArgoAppsEnvKey        string        // ARGO_APPS_ENV_KEY; Application label/annotation naming the environment
+	ArgoAppsEnvFromDest   bool          // ARGO_APPS_ENV_FROM_DESTINATION; use spec.destination.name as the environment
+	ArgoAppsPRDiff        bool          // ARGO_APPS_PR_DIFF; diff the Applications changed by PRs to the gitops repo
+	ArgoAppsPRDiffRepos   []string      // ARGO_APPS_PR_DIFF_REPOS; further "owner/repo" globs PR Applications may render
+	ArgoSourcesFile       string        // ARGO_SOURCES_FILE (default: ""); YAML list of further gitops repos
+
+	// Flux integration (optional)
+	FluxRepo         string        // FLUX_REPO; Git repo containing Flux HelmReleases
+	FluxLocalPath    string        // FLUX_LOCAL_PATH (default: "/tmp/chart-val-flux")
+	FluxSyncInterval time.Duration // FLUX_SYNC_INTERVAL (default: 1h)
+	FluxEnvPattern   string        // FLUX_ENV_PATTERN (default: "clusters/{envName}"); environment directories
+	FluxToken        string        // FLUX_TOKEN; HTTPS access token for FLUX_REPO
+	FluxSSHKeyFile   string        // FLUX_SSH_KEY_FILE; SSH private key for FLUX_REPO
+
+	// Helmfile integration (optional)
+	HelmfilePaths []string // HELMFILE_PATHS (comma-separated); helmfile state files in the PR's repo
 
 	// OpenTelemetry (optional)
 	OTelEnabled bool // OTEL_ENABLED feature flag
 
 	// App identity and conventions (optional, sensible defaults)
-	AppName          string // APP_NAME (default: "chart-val")
-	AppURL           string // APP_URL (default: ""); footer link in PR comments
-	ChartDir         string // CHART_DIR (default: "charts"); top-level dir containing charts
-	EnvDir           string // ENV_DIR (default: "env"); subdirectory within chart for env overrides
-	ValuesFileSuffix string // VALUES_FILE_SUFFIX (default: "-values.yaml"); pattern for value files
+	AppName          string   // APP_NAME (default: "chart-val")
+	AppURL           string   // APP_URL (default: ""); footer link in PR comments, web UI base URL
+	ChartDir         string   // CHART_DIR (default: "charts"); top-level dir containing charts
+	ChartInclude     []string // CHART_INCLUDE (comma-separated globs, default: "{CHART_DIR}/*"); where charts live
+	ChartExclude     []string // CHART_EXCLUDE (comma-separated globs, default: ""); chart dirs to ignore
+	EnvDir           string   // ENV_DIR (default: "env"); subdirectory within chart for env overrides
+	ValuesFileSuffix string   // VALUES_FILE_SUFFIX (default: "-values.yaml"); pattern for value files
+
+	// Reporting (optional)
+	CommentMode          string   // COMMENT_MODE (default: "per-chart"); "per-chart" or "consolidated"
+	CheckRunNameTemplate string   // CHECK_RUN_NAME_TEMPLATE (default: ""); e.g. "{app} / {chart} / {env}"
+	RequiredCheckRuns    []string // CHECK_RUN_REQUIRED (comma-separated); template-rendered names reported skipped when not rendered
+	ConclusionPolicyFile string   // CONCLUSION_POLICY_FILE (default: ""); YAML outcome → conclusion rules
+	ReportTemplatesDir   string   // REPORT_TEMPLATES_DIR (default: ""); overrides for the embedded report templates
+	RepoTemplatesPath    string   // REPO_TEMPLATES_PATH (default: ".chart-val/templates"); per-repo overrides, "" disables
+	NotifyRoutesFile     string   // NOTIFY_ROUTES_FILE (default: ""); YAML Slack/Teams routing rules
+	ReportJSONDir        string   // REPORT_JSON_DIR (default: ""); directory for per-run JSON result files
+
+	// Outbound webhooks (optional)
+	OutboundWebhookURLs           []string // OUTBOUND_WEBHOOK_URLS (comma-separated)
+	OutboundWebhookSecret         string   // OUTBOUND_WEBHOOK_SECRET; HMAC key for X-Chart-Val-Signature-256
+	OutboundWebhookMaxAttempts    int      // OUTBOUND_WEBHOOK_MAX_ATTEMPTS (default: 5)
+	OutboundWebhookDeadLetterFile string   // OUTBOUND_WEBHOOK_DEAD_LETTER_FILE (default: ""); JSON lines
+
+	// Per-sink reporter timeouts. Secondary sinks (chat, JSON file, ...) default
+	// to ReporterTimeout; the GitHub sink is only bounded if listed explicitly.
+	ReporterTimeout  time.Duration            // REPORTER_TIMEOUT (default: 30s)
+	ReporterTimeouts map[string]time.Duration // REPORTER_TIMEOUTS (e.g. "github=2m,chat=10s")
+
+	// Run history (optional)
+	RunHistoryDB string // RUN_HISTORY_DB (default: ""); SQLite database file, "" disables history
+
+	// Rendered manifest artifacts (optional)
+	ArtifactStore             string        // ARTIFACT_STORE (default: ""); "filesystem" or "s3", "" disables
+	ArtifactDir               string        // ARTIFACT_DIR; required for "filesystem"
+	ArtifactLinkSecret        string        // ARTIFACT_LINK_SECRET (default: derived from WEBHOOK_SECRET); signs filesystem links
+	ArtifactLinkTTL           time.Duration // ARTIFACT_LINK_TTL (default: 168h); download link validity
+	ArtifactRetention         time.Duration // ARTIFACT_RETENTION (default: 720h); 0 keeps artifacts forever
+	ArtifactRetentionInterval time.Duration // ARTIFACT_RETENTION_INTERVAL (default: 1h)
+	ArtifactS3Endpoint        string        // ARTIFACT_S3_ENDPOINT; required for "s3"
+	ArtifactS3Region          string        // ARTIFACT_S3_REGION (default: "us-east-1")
+	ArtifactS3Bucket          string        // ARTIFACT_S3_BUCKET; required for "s3"
+	ArtifactS3Prefix          string        // ARTIFACT_S3_PREFIX (default: "")
+	ArtifactS3AccessKeyID     string        // ARTIFACT_S3_ACCESS_KEY_ID
+	ArtifactS3SecretAccessKey string        // ARTIFACT_S3_SECRET_ACCESS_KEY
+
+	// Helm chart dependencies
+	HelmDependencyCacheDir string // HELM_DEPENDENCY_CACHE_DIR (default: ""); packaged remote dependencies
+
+	// Render cache
+	RenderCacheMemoryMB int           // RENDER_CACHE_MEMORY_MB (default: 64); 0 disables the memory tier
+	RenderCacheDir      string        // RENDER_CACHE_DIR (default: ""); "" disables the disk tier
+	RenderCacheDiskTTL  time.Duration // RENDER_CACHE_DISK_TTL (default: 168h); 0 keeps entries forever
 }
 
 // Load reads configuration from environment variables, validates required
@@ -50,9 +119,28 @@ func Load() (Config, error) {
 	if err := loadArgoConfig(&cfg); err != nil {
 		return Config{}, err
 	}
+	if err := loadFluxConfig(&cfg); err != nil {
+		return Config{}, err
+	}
+	cfg.HelmfilePaths = parseList("HELMFILE_PATHS")
 
 	loadOTelConfig(&cfg)
 	loadAppConfig(&cfg)
+	if err := loadHistoryConfig(&cfg); err != nil {
+		return Config{}, err
+	}
+
+	if err := loadArtifactConfig(&cfg); err != nil {
+		return Config{}, err
+	}
+
+	if err := loadRenderCacheConfig(&cfg); err != nil {
+		return Config{}, err
+	}
+
+	if err := loadReportingConfig(&cfg); err != nil {
+		return Config{}, err
+	}
 
 	return cfg, nil
 }
@@ -90,11 +178,16 @@ func loadCoreConfig(cfg *Config) error {
 	if v := os.Getenv("LOG_LEVEL"); v != "" {
 		cfg.LogLevel = v
 	}
+	cfg.AdminToken = os.Getenv("ADMIN_TOKEN")
 
 	return nil
 }
 
 func loadArgoConfig(cfg *Config) error {
+	cfg.ArgoSourcesFile = os.Getenv("ARGO_SOURCES_FILE")
+	cfg.ArgoAppsPRDiff = os.Getenv("ARGO_APPS_PR_DIFF") == "true"
+	cfg.ArgoAppsPRDiffRepos = parseList("ARGO_APPS_PR_DIFF_REPOS")
+
 	cfg.ArgoAppsRepo = os.Getenv("ARGO_APPS_REPO")
 	if cfg.ArgoAppsRepo == "" {
 		return nil // Argo integration is optional
@@ -102,6 +195,8 @@ func loadArgoConfig(cfg *Config) error {
 
 	cfg.ArgoAppsLocalPath = getEnvOrDefault("ARGO_APPS_LOCAL_PATH", "/tmp/chart-val-argocd")
 	cfg.ArgoAppsFolderPattern = getEnvOrDefault("ARGO_APPS_FOLDER_PATTERN", "{chartName}/{envName}")
+	cfg.ArgoAppsEnvKey = os.Getenv("ARGO_APPS_ENV_KEY")
+	cfg.ArgoAppsEnvFromDest = os.Getenv("ARGO_APPS_ENV_FROM_DESTINATION") == "true"
 
 	dur, err := parseDurationOrDefault("ARGO_APPS_SYNC_INTERVAL", 1*time.Hour)
 	if err != nil {
@@ -112,6 +207,32 @@ func loadArgoConfig(cfg *Config) error {
 	return nil
 }
 
+func loadFluxConfig(cfg *Config) error {
+	cfg.FluxRepo = os.Getenv("FLUX_REPO")
+	if cfg.FluxRepo == "" {
+		return nil // Flux integration is optional
+	}
+
+	cfg.FluxLocalPath = getEnvOrDefault("FLUX_LOCAL_PATH", "/tmp/chart-val-flux")
+	cfg.FluxEnvPattern = getEnvOrDefault("FLUX_ENV_PATTERN", "clusters/{envName}")
+	if !strings.Contains(cfg.FluxEnvPattern, "{envName}") {
+		return fmt.Errorf("invalid FLUX_ENV_PATTERN %q: must contain {envName}", cfg.FluxEnvPattern)
+	}
+	cfg.FluxToken = os.Getenv("FLUX_TOKEN")
+	cfg.FluxSSHKeyFile = os.Getenv("FLUX_SSH_KEY_FILE")
+	if cfg.FluxToken != "" && cfg.FluxSSHKeyFile != "" {
+		return errors.New("set FLUX_TOKEN or FLUX_SSH_KEY_FILE, not both")
+	}
+
+	dur, err := parseDurationOrDefault("FLUX_SYNC_INTERVAL", 1*time.Hour)
+	if err != nil {
+		return err
+	}
+	cfg.FluxSyncInterval = dur
+
+	return nil
+}
+
 func parseRequiredInt64(envKey string) (int64, error) {
 	v := os.Getenv(envKey)
 	if v == "" {
@@ -139,8 +260,189 @@ func loadAppConfig(cfg *Config) {
 	cfg.AppName = getEnvOrDefault("APP_NAME", "chart-val")
 	cfg.AppURL = os.Getenv("APP_URL")
 	cfg.ChartDir = getEnvOrDefault("CHART_DIR", "charts")
+	cfg.ChartInclude = parseList("CHART_INCLUDE")
+	if len(cfg.ChartInclude) == 0 {
+		cfg.ChartInclude = []string{cfg.ChartDir + "/*"}
+	}
+	cfg.ChartExclude = parseList("CHART_EXCLUDE")
 	cfg.EnvDir = getEnvOrDefault("ENV_DIR", "env")
 	cfg.ValuesFileSuffix = getEnvOrDefault("VALUES_FILE_SUFFIX", "-values.yaml")
+	cfg.HelmDependencyCacheDir = os.Getenv("HELM_DEPENDENCY_CACHE_DIR")
+}
+
+func loadHistoryConfig(cfg *Config) error {
+	cfg.RunHistoryDB = os.Getenv("RUN_HISTORY_DB")
+	if cfg.RunHistoryDB != "" && cfg.AdminToken == "" {
+		// Stored runs include rendered diffs; never serve them unauthenticated.
+		return errors.New("ADMIN_TOKEN is required when RUN_HISTORY_DB is set")
+	}
+	return nil
+}
+
+// artifactLinkKeyInfo labels the artifact link key derived from
+// WEBHOOK_SECRET, separating it from any other use of that secret.
+const artifactLinkKeyInfo = "chart-val artifact link signing v1"
+
+func loadArtifactConfig(cfg *Config) error {
+	cfg.ArtifactStore = os.Getenv("ARTIFACT_STORE")
+	switch cfg.ArtifactStore {
+	case "":
+		return nil // Artifact storage is optional
+	case "filesystem":
+		cfg.ArtifactDir = os.Getenv("ARTIFACT_DIR")
+		if cfg.ArtifactDir == "" {
+			return errors.New("ARTIFACT_DIR is required when ARTIFACT_STORE is \"filesystem\"")
+		}
+		cfg.ArtifactLinkSecret = os.Getenv("ARTIFACT_LINK_SECRET")
+		if cfg.ArtifactLinkSecret == "" {
+			// Never sign links with the webhook secret itself: a key derived
+			// for this purpose alone can't be used to forge webhooks.
+			key, err := hkdf.Key(sha256.New, []byte(cfg.WebhookSecret), nil, artifactLinkKeyInfo, sha256.Size)
+			if err != nil {
+				return fmt.Errorf("deriving artifact link secret: %w", err)
+			}
+			cfg.ArtifactLinkSecret = hex.EncodeToString(key)
+		}
+	case "s3":
+		cfg.ArtifactS3Endpoint = os.Getenv("ARTIFACT_S3_ENDPOINT")
+		cfg.ArtifactS3Bucket = os.Getenv("ARTIFACT_S3_BUCKET")
+		if cfg.ArtifactS3Endpoint == "" || cfg.ArtifactS3Bucket == "" {
+			return errors.New("ARTIFACT_S3_ENDPOINT and ARTIFACT_S3_BUCKET are required when ARTIFACT_STORE is \"s3\"")
+		}
+		cfg.ArtifactS3Region = getEnvOrDefault("ARTIFACT_S3_REGION", "us-east-1")
+		cfg.ArtifactS3Prefix = os.Getenv("ARTIFACT_S3_PREFIX")
+		cfg.ArtifactS3AccessKeyID = os.Getenv("ARTIFACT_S3_ACCESS_KEY_ID")
+		cfg.ArtifactS3SecretAccessKey = os.Getenv("ARTIFACT_S3_SECRET_ACCESS_KEY")
+	default:
+		return fmt.Errorf("invalid ARTIFACT_STORE %q: must be \"filesystem\" or \"s3\"", cfg.ArtifactStore)
+	}
+
+	var err error
+	if cfg.ArtifactLinkTTL, err = parseDurationOrDefault("ARTIFACT_LINK_TTL", 7*24*time.Hour); err != nil {
+		return err
+	}
+	if cfg.ArtifactRetention, err = parseDurationOrDefault("ARTIFACT_RETENTION", 30*24*time.Hour); err != nil {
+		return err
+	}
+	if cfg.ArtifactRetentionInterval, err = parseDurationOrDefault("ARTIFACT_RETENTION_INTERVAL", time.Hour); err != nil {
+		return err
+	}
+	return nil
+}
+
+func loadRenderCacheConfig(cfg *Config) error {
+	cfg.RenderCacheMemoryMB = 64
+	if v := os.Getenv("RENDER_CACHE_MEMORY_MB"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 0 {
+			return fmt.Errorf("invalid RENDER_CACHE_MEMORY_MB %q: must be a non-negative integer", v)
+		}
+		cfg.RenderCacheMemoryMB = n
+	}
+	cfg.RenderCacheDir = os.Getenv("RENDER_CACHE_DIR")
+
+	var err error
+	cfg.RenderCacheDiskTTL, err = parseDurationOrDefault("RENDER_CACHE_DISK_TTL", 7*24*time.Hour)
+	return err
+}
+
+func loadReportingConfig(cfg *Config) error {
+	cfg.CommentMode = getEnvOrDefault("COMMENT_MODE", "per-chart")
+	if cfg.CommentMode != "per-chart" && cfg.CommentMode != "consolidated" {
+		return fmt.Errorf("invalid COMMENT_MODE %q: must be \"per-chart\" or \"consolidated\"", cfg.CommentMode)
+	}
+
+	cfg.CheckRunNameTemplate = os.Getenv("CHECK_RUN_NAME_TEMPLATE")
+	if cfg.CheckRunNameTemplate != "" &&
+		!strings.Contains(cfg.CheckRunNameTemplate, "{chart}") &&
+		!strings.Contains(cfg.CheckRunNameTemplate, "{env}") {
+		return fmt.Errorf("invalid CHECK_RUN_NAME_TEMPLATE %q: must contain {chart} or {env}", cfg.CheckRunNameTemplate)
+	}
+	cfg.RequiredCheckRuns = parseList("CHECK_RUN_REQUIRED")
+	if len(cfg.RequiredCheckRuns) > 0 && cfg.CheckRunNameTemplate == "" {
+		return errors.New("CHECK_RUN_REQUIRED requires CHECK_RUN_NAME_TEMPLATE")
+	}
+
+	cfg.ConclusionPolicyFile = os.Getenv("CONCLUSION_POLICY_FILE")
+	cfg.ReportTemplatesDir = os.Getenv("REPORT_TEMPLATES_DIR")
+	// Unlike other optional vars, an explicitly empty REPO_TEMPLATES_PATH disables repo overrides
+	cfg.RepoTemplatesPath = ".chart-val/templates"
+	if v, ok := os.LookupEnv("REPO_TEMPLATES_PATH"); ok {
+		cfg.RepoTemplatesPath = v
+	}
+
+	cfg.NotifyRoutesFile = os.Getenv("NOTIFY_ROUTES_FILE")
+	cfg.ReportJSONDir = os.Getenv("REPORT_JSON_DIR")
+
+	if err := loadOutboundWebhookConfig(cfg); err != nil {
+		return err
+	}
+
+	timeout, err := parseDurationOrDefault("REPORTER_TIMEOUT", 30*time.Second)
+	if err != nil {
+		return err
+	}
+	cfg.ReporterTimeout = timeout
+
+	timeouts, err := parseDurationMap("REPORTER_TIMEOUTS")
+	if err != nil {
+		return err
+	}
+	cfg.ReporterTimeouts = timeouts
+
+	return nil
+}
+
+func loadOutboundWebhookConfig(cfg *Config) error {
+	cfg.OutboundWebhookURLs = parseList("OUTBOUND_WEBHOOK_URLS")
+	if len(cfg.OutboundWebhookURLs) == 0 {
+		return nil // Outbound webhooks are optional
+	}
+
+	cfg.OutboundWebhookSecret = os.Getenv("OUTBOUND_WEBHOOK_SECRET")
+	cfg.OutboundWebhookDeadLetterFile = os.Getenv("OUTBOUND_WEBHOOK_DEAD_LETTER_FILE")
+
+	cfg.OutboundWebhookMaxAttempts = 5
+	if v := os.Getenv("OUTBOUND_WEBHOOK_MAX_ATTEMPTS"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 {
+			return fmt.Errorf("invalid OUTBOUND_WEBHOOK_MAX_ATTEMPTS %q: must be a positive integer", v)
+		}
+		cfg.OutboundWebhookMaxAttempts = n
+	}
+	return nil
+}
+
+// parseList parses a comma-separated list, dropping blank entries.
+func parseList(envKey string) []string {
+	var items []string
+	for _, item := range strings.Split(os.Getenv(envKey), ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+// parseDurationMap parses a comma-separated list of name=duration pairs.
+func parseDurationMap(envKey string) (map[string]time.Duration, error) {
+	v := os.Getenv(envKey)
+	if v == "" {
+		return nil, nil
+	}
+	m := make(map[string]time.Duration)
+	for _, pair := range strings.Split(v, ",") {
+		name, value, ok := strings.Cut(strings.TrimSpace(pair), "=")
+		if !ok || name == "" {
+			return nil, fmt.Errorf("invalid %s entry %q: want name=duration", envKey, pair)
+		}
+		dur, err := time.ParseDuration(value)
+		if err != nil {
+			return nil, fmt.Errorf("invalid %s entry %q: %w", envKey, pair, err)
+		}
+		m[name] = dur
+	}
+	return m, nil
 }
 
 func parseDurationOrDefault(envKey string, defaultValue time.Duration) (time.Duration, error) {
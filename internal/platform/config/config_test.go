@@ -2,7 +2,9 @@ package config
 
 import (
 	"os"
+	"reflect"
 	"testing"
+	"time"
 )
 
 func TestLoad(t *testing.T) {
@@ -23,6 +25,7 @@ func TestLoad(t *testing.T) {
 				_ = os.Setenv("GITHUB_PRIVATE_KEY", "test-key")
 				_ = os.Setenv("PORT", "9000")
 				_ = os.Setenv("LOG_LEVEL", "debug")
+				_ = os.Setenv("ADMIN_TOKEN", "admin-token")
 			},
 			cleanup: func() {
 				_ = os.Unsetenv("WEBHOOK_SECRET")
@@ -31,6 +34,7 @@ func TestLoad(t *testing.T) {
 				_ = os.Unsetenv("GITHUB_PRIVATE_KEY")
 				_ = os.Unsetenv("PORT")
 				_ = os.Unsetenv("LOG_LEVEL")
+				_ = os.Unsetenv("ADMIN_TOKEN")
 			},
 			want: Config{
 				Port:                 9000,
@@ -39,6 +43,7 @@ func TestLoad(t *testing.T) {
 				GitHubInstallationID: 789012,
 				GitHubPrivateKey:     "test-key",
 				LogLevel:             "debug",
+				AdminToken:           "admin-token",
 			},
 			wantErr: false,
 		},
@@ -180,6 +185,413 @@ func TestLoad(t *testing.T) {
 			wantErr: true,
 			errMsg:  "GITHUB_INSTALLATION_ID",
 		},
+		{
+			name: "invalid CHECK_RUN_NAME_TEMPLATE",
+			setup: func() {
+				_ = os.Setenv("WEBHOOK_SECRET", "test-secret")
+				_ = os.Setenv("GITHUB_APP_ID", "123456")
+				_ = os.Setenv("GITHUB_INSTALLATION_ID", "789012")
+				_ = os.Setenv("GITHUB_PRIVATE_KEY", "test-key")
+				_ = os.Setenv("CHECK_RUN_NAME_TEMPLATE", "{app}")
+			},
+			cleanup: func() {
+				_ = os.Unsetenv("WEBHOOK_SECRET")
+				_ = os.Unsetenv("GITHUB_APP_ID")
+				_ = os.Unsetenv("GITHUB_INSTALLATION_ID")
+				_ = os.Unsetenv("GITHUB_PRIVATE_KEY")
+				_ = os.Unsetenv("CHECK_RUN_NAME_TEMPLATE")
+			},
+			wantErr: true,
+			errMsg:  "CHECK_RUN_NAME_TEMPLATE",
+		},
+		{
+			name: "invalid REPORTER_TIMEOUTS",
+			setup: func() {
+				_ = os.Setenv("WEBHOOK_SECRET", "test-secret")
+				_ = os.Setenv("GITHUB_APP_ID", "123456")
+				_ = os.Setenv("GITHUB_INSTALLATION_ID", "789012")
+				_ = os.Setenv("GITHUB_PRIVATE_KEY", "test-key")
+				_ = os.Setenv("REPORTER_TIMEOUTS", "github=2m,chat")
+			},
+			cleanup: func() {
+				_ = os.Unsetenv("WEBHOOK_SECRET")
+				_ = os.Unsetenv("GITHUB_APP_ID")
+				_ = os.Unsetenv("GITHUB_INSTALLATION_ID")
+				_ = os.Unsetenv("GITHUB_PRIVATE_KEY")
+				_ = os.Unsetenv("REPORTER_TIMEOUTS")
+			},
+			wantErr: true,
+			errMsg:  "REPORTER_TIMEOUTS",
+		},
+		{
+			name: "invalid OUTBOUND_WEBHOOK_MAX_ATTEMPTS",
+			setup: func() {
+				_ = os.Setenv("WEBHOOK_SECRET", "test-secret")
+				_ = os.Setenv("GITHUB_APP_ID", "123456")
+				_ = os.Setenv("GITHUB_INSTALLATION_ID", "789012")
+				_ = os.Setenv("GITHUB_PRIVATE_KEY", "test-key")
+				_ = os.Setenv("OUTBOUND_WEBHOOK_URLS", "https://example.com/hook")
+				_ = os.Setenv("OUTBOUND_WEBHOOK_MAX_ATTEMPTS", "0")
+			},
+			cleanup: func() {
+				_ = os.Unsetenv("WEBHOOK_SECRET")
+				_ = os.Unsetenv("GITHUB_APP_ID")
+				_ = os.Unsetenv("GITHUB_INSTALLATION_ID")
+				_ = os.Unsetenv("GITHUB_PRIVATE_KEY")
+				_ = os.Unsetenv("OUTBOUND_WEBHOOK_URLS")
+				_ = os.Unsetenv("OUTBOUND_WEBHOOK_MAX_ATTEMPTS")
+			},
+			wantErr: true,
+			errMsg:  "OUTBOUND_WEBHOOK_MAX_ATTEMPTS",
+		},
+		{
+			name: "s3 artifact store without bucket",
+			setup: func() {
+				_ = os.Setenv("WEBHOOK_SECRET", "test-secret")
+				_ = os.Setenv("GITHUB_APP_ID", "123456")
+				_ = os.Setenv("GITHUB_INSTALLATION_ID", "789012")
+				_ = os.Setenv("GITHUB_PRIVATE_KEY", "test-key")
+				_ = os.Setenv("ARTIFACT_STORE", "s3")
+				_ = os.Setenv("ARTIFACT_S3_ENDPOINT", "http://localhost:9000")
+			},
+			cleanup: func() {
+				_ = os.Unsetenv("WEBHOOK_SECRET")
+				_ = os.Unsetenv("GITHUB_APP_ID")
+				_ = os.Unsetenv("GITHUB_INSTALLATION_ID")
+				_ = os.Unsetenv("GITHUB_PRIVATE_KEY")
+				_ = os.Unsetenv("ARTIFACT_STORE")
+				_ = os.Unsetenv("ARTIFACT_S3_ENDPOINT")
+			},
+			wantErr: true,
+			errMsg:  "ARTIFACT_S3_BUCKET",
+		},
+		{
+			name: "unknown artifact store",
+			setup: func() {
+				_ = os.Setenv("WEBHOOK_SECRET", "test-secret")
+				_ = os.Setenv("GITHUB_APP_ID", "123456")
+				_ = os.Setenv("GITHUB_INSTALLATION_ID", "789012")
+				_ = os.Setenv("GITHUB_PRIVATE_KEY", "test-key")
+				_ = os.Setenv("ARTIFACT_STORE", "gcs")
+			},
+			cleanup: func() {
+				_ = os.Unsetenv("WEBHOOK_SECRET")
+				_ = os.Unsetenv("GITHUB_APP_ID")
+				_ = os.Unsetenv("GITHUB_INSTALLATION_ID")
+				_ = os.Unsetenv("GITHUB_PRIVATE_KEY")
+				_ = os.Unsetenv("ARTIFACT_STORE")
+			},
+			wantErr: true,
+			errMsg:  "ARTIFACT_STORE",
+		},
+		{
+			name: "run history without admin token",
+			setup: func() {
+				_ = os.Setenv("WEBHOOK_SECRET", "test-secret")
+				_ = os.Setenv("GITHUB_APP_ID", "123456")
+				_ = os.Setenv("GITHUB_INSTALLATION_ID", "789012")
+				_ = os.Setenv("GITHUB_PRIVATE_KEY", "test-key")
+				_ = os.Setenv("RUN_HISTORY_DB", "/tmp/runs.db")
+			},
+			cleanup: func() {
+				_ = os.Unsetenv("WEBHOOK_SECRET")
+				_ = os.Unsetenv("GITHUB_APP_ID")
+				_ = os.Unsetenv("GITHUB_INSTALLATION_ID")
+				_ = os.Unsetenv("GITHUB_PRIVATE_KEY")
+				_ = os.Unsetenv("RUN_HISTORY_DB")
+			},
+			wantErr: true,
+			errMsg:  "ADMIN_TOKEN",
+		},
+		{
+			name: "required check runs without a template",
+			setup: func() {
+				_ = os.Setenv("WEBHOOK_SECRET", "test-secret")
+				_ = os.Setenv("GITHUB_APP_ID", "123456")
+				_ = os.Setenv("GITHUB_INSTALLATION_ID", "789012")
+				_ = os.Setenv("GITHUB_PRIVATE_KEY", "test-key")
+				_ = os.Setenv("CHECK_RUN_REQUIRED", "chart-val / api / prod")
+			},
+			cleanup: func() {
+				_ = os.Unsetenv("WEBHOOK_SECRET")
+				_ = os.Unsetenv("GITHUB_APP_ID")
+				_ = os.Unsetenv("GITHUB_INSTALLATION_ID")
+				_ = os.Unsetenv("GITHUB_PRIVATE_KEY")
+				_ = os.Unsetenv("CHECK_RUN_REQUIRED")
+			},
+			wantErr: true,
+			errMsg:  "CHECK_RUN_NAME_TEMPLATE",
+		},
+		{
+			name: "negative render cache size",
+			setup: func() {
+				_ = os.Setenv("WEBHOOK_SECRET", "test-secret")
+				_ = os.Setenv("GITHUB_APP_ID", "123456")
+				_ = os.Setenv("GITHUB_INSTALLATION_ID", "789012")
+				_ = os.Setenv("GITHUB_PRIVATE_KEY", "test-key")
+				_ = os.Setenv("RENDER_CACHE_MEMORY_MB", "-1")
+			},
+			cleanup: func() {
+				_ = os.Unsetenv("WEBHOOK_SECRET")
+				_ = os.Unsetenv("GITHUB_APP_ID")
+				_ = os.Unsetenv("GITHUB_INSTALLATION_ID")
+				_ = os.Unsetenv("GITHUB_PRIVATE_KEY")
+				_ = os.Unsetenv("RENDER_CACHE_MEMORY_MB")
+			},
+			wantErr: true,
+			errMsg:  "RENDER_CACHE_MEMORY_MB",
+		},
+		{
+			name: "chart include defaults to CHART_DIR",
+			setup: func() {
+				_ = os.Setenv("WEBHOOK_SECRET", "test-secret")
+				_ = os.Setenv("GITHUB_APP_ID", "123456")
+				_ = os.Setenv("GITHUB_INSTALLATION_ID", "789012")
+				_ = os.Setenv("GITHUB_PRIVATE_KEY", "test-key")
+				_ = os.Setenv("CHART_DIR", "helm")
+			},
+			cleanup: func() {
+				_ = os.Unsetenv("WEBHOOK_SECRET")
+				_ = os.Unsetenv("GITHUB_APP_ID")
+				_ = os.Unsetenv("GITHUB_INSTALLATION_ID")
+				_ = os.Unsetenv("GITHUB_PRIVATE_KEY")
+				_ = os.Unsetenv("CHART_DIR")
+			},
+			want: Config{
+				Port:                 8080,
+				WebhookSecret:        "test-secret",
+				GitHubAppID:          123456,
+				GitHubInstallationID: 789012,
+				GitHubPrivateKey:     "test-key",
+				LogLevel:             "info",
+				ChartInclude:         []string{"helm/*"},
+			},
+		},
+		{
+			name: "chart include and exclude globs",
+			setup: func() {
+				_ = os.Setenv("WEBHOOK_SECRET", "test-secret")
+				_ = os.Setenv("GITHUB_APP_ID", "123456")
+				_ = os.Setenv("GITHUB_INSTALLATION_ID", "789012")
+				_ = os.Setenv("GITHUB_PRIVATE_KEY", "test-key")
+				_ = os.Setenv("CHART_INCLUDE", "services/*/deploy/chart, platform/charts/*")
+				_ = os.Setenv("CHART_EXCLUDE", "**/testdata/**")
+			},
+			cleanup: func() {
+				_ = os.Unsetenv("WEBHOOK_SECRET")
+				_ = os.Unsetenv("GITHUB_APP_ID")
+				_ = os.Unsetenv("GITHUB_INSTALLATION_ID")
+				_ = os.Unsetenv("GITHUB_PRIVATE_KEY")
+				_ = os.Unsetenv("CHART_INCLUDE")
+				_ = os.Unsetenv("CHART_EXCLUDE")
+			},
+			want: Config{
+				Port:                 8080,
+				WebhookSecret:        "test-secret",
+				GitHubAppID:          123456,
+				GitHubInstallationID: 789012,
+				GitHubPrivateKey:     "test-key",
+				LogLevel:             "info",
+				ChartInclude:         []string{"services/*/deploy/chart", "platform/charts/*"},
+				ChartExclude:         []string{"**/testdata/**"},
+			},
+		},
+		{
+			name: "argo environment naming",
+			setup: func() {
+				_ = os.Setenv("WEBHOOK_SECRET", "test-secret")
+				_ = os.Setenv("GITHUB_APP_ID", "123456")
+				_ = os.Setenv("GITHUB_INSTALLATION_ID", "789012")
+				_ = os.Setenv("GITHUB_PRIVATE_KEY", "test-key")
+				_ = os.Setenv("ARGO_APPS_REPO", "https://github.com/org/gitops")
+				_ = os.Setenv("ARGO_APPS_ENV_KEY", "example.com/environment")
+				_ = os.Setenv("ARGO_APPS_ENV_FROM_DESTINATION", "true")
+				_ = os.Setenv("ARGO_APPS_PR_DIFF", "true")
+			},
+			cleanup: func() {
+				_ = os.Unsetenv("WEBHOOK_SECRET")
+				_ = os.Unsetenv("GITHUB_APP_ID")
+				_ = os.Unsetenv("GITHUB_INSTALLATION_ID")
+				_ = os.Unsetenv("GITHUB_PRIVATE_KEY")
+				_ = os.Unsetenv("ARGO_APPS_REPO")
+				_ = os.Unsetenv("ARGO_APPS_ENV_KEY")
+				_ = os.Unsetenv("ARGO_APPS_ENV_FROM_DESTINATION")
+				_ = os.Unsetenv("ARGO_APPS_PR_DIFF")
+			},
+			want: Config{
+				Port:                 8080,
+				WebhookSecret:        "test-secret",
+				GitHubAppID:          123456,
+				GitHubInstallationID: 789012,
+				GitHubPrivateKey:     "test-key",
+				LogLevel:             "info",
+				ArgoAppsEnvKey:       "example.com/environment",
+				ArgoAppsEnvFromDest:  true,
+				ArgoAppsPRDiff:       true,
+			},
+		},
+		{
+			name: "argo sources file without a single repo",
+			setup: func() {
+				_ = os.Setenv("WEBHOOK_SECRET", "test-secret")
+				_ = os.Setenv("GITHUB_APP_ID", "123456")
+				_ = os.Setenv("GITHUB_INSTALLATION_ID", "789012")
+				_ = os.Setenv("GITHUB_PRIVATE_KEY", "test-key")
+				_ = os.Setenv("ARGO_SOURCES_FILE", "/etc/chart-val/argo-sources.yaml")
+				_ = os.Setenv("ARGO_APPS_PR_DIFF", "true")
+				_ = os.Setenv("ARGO_APPS_PR_DIFF_REPOS", "org/charts, org/shared-*")
+			},
+			cleanup: func() {
+				_ = os.Unsetenv("WEBHOOK_SECRET")
+				_ = os.Unsetenv("GITHUB_APP_ID")
+				_ = os.Unsetenv("GITHUB_INSTALLATION_ID")
+				_ = os.Unsetenv("GITHUB_PRIVATE_KEY")
+				_ = os.Unsetenv("ARGO_SOURCES_FILE")
+				_ = os.Unsetenv("ARGO_APPS_PR_DIFF")
+				_ = os.Unsetenv("ARGO_APPS_PR_DIFF_REPOS")
+			},
+			want: Config{
+				Port:                 8080,
+				WebhookSecret:        "test-secret",
+				GitHubAppID:          123456,
+				GitHubInstallationID: 789012,
+				GitHubPrivateKey:     "test-key",
+				LogLevel:             "info",
+				ArgoAppsPRDiff:       true,
+				ArgoAppsPRDiffRepos:  []string{"org/charts", "org/shared-*"},
+				ArgoSourcesFile:      "/etc/chart-val/argo-sources.yaml",
+			},
+		},
+		{
+			name: "flux defaults",
+			setup: func() {
+				_ = os.Setenv("WEBHOOK_SECRET", "test-secret")
+				_ = os.Setenv("GITHUB_APP_ID", "123456")
+				_ = os.Setenv("GITHUB_INSTALLATION_ID", "789012")
+				_ = os.Setenv("GITHUB_PRIVATE_KEY", "test-key")
+				_ = os.Setenv("FLUX_REPO", "https://github.com/org/fleet")
+			},
+			cleanup: func() {
+				_ = os.Unsetenv("WEBHOOK_SECRET")
+				_ = os.Unsetenv("GITHUB_APP_ID")
+				_ = os.Unsetenv("GITHUB_INSTALLATION_ID")
+				_ = os.Unsetenv("GITHUB_PRIVATE_KEY")
+				_ = os.Unsetenv("FLUX_REPO")
+			},
+			want: Config{
+				Port:                 8080,
+				WebhookSecret:        "test-secret",
+				GitHubAppID:          123456,
+				GitHubInstallationID: 789012,
+				GitHubPrivateKey:     "test-key",
+				LogLevel:             "info",
+				FluxRepo:             "https://github.com/org/fleet",
+				FluxLocalPath:        "/tmp/chart-val-flux",
+				FluxSyncInterval:     time.Hour,
+				FluxEnvPattern:       "clusters/{envName}",
+			},
+		},
+		{
+			name: "flux credentials",
+			setup: func() {
+				_ = os.Setenv("WEBHOOK_SECRET", "test-secret")
+				_ = os.Setenv("GITHUB_APP_ID", "123456")
+				_ = os.Setenv("GITHUB_INSTALLATION_ID", "789012")
+				_ = os.Setenv("GITHUB_PRIVATE_KEY", "test-key")
+				_ = os.Setenv("FLUX_REPO", "git@github.com:org/fleet.git")
+				_ = os.Setenv("FLUX_SSH_KEY_FILE", "/etc/chart-val/fleet-key")
+			},
+			cleanup: func() {
+				_ = os.Unsetenv("WEBHOOK_SECRET")
+				_ = os.Unsetenv("GITHUB_APP_ID")
+				_ = os.Unsetenv("GITHUB_INSTALLATION_ID")
+				_ = os.Unsetenv("GITHUB_PRIVATE_KEY")
+				_ = os.Unsetenv("FLUX_REPO")
+				_ = os.Unsetenv("FLUX_SSH_KEY_FILE")
+			},
+			want: Config{
+				Port:                 8080,
+				WebhookSecret:        "test-secret",
+				GitHubAppID:          123456,
+				GitHubInstallationID: 789012,
+				GitHubPrivateKey:     "test-key",
+				LogLevel:             "info",
+				FluxRepo:             "git@github.com:org/fleet.git",
+				FluxLocalPath:        "/tmp/chart-val-flux",
+				FluxSyncInterval:     time.Hour,
+				FluxEnvPattern:       "clusters/{envName}",
+				FluxSSHKeyFile:       "/etc/chart-val/fleet-key",
+			},
+		},
+		{
+			name: "flux token and ssh key",
+			setup: func() {
+				_ = os.Setenv("WEBHOOK_SECRET", "test-secret")
+				_ = os.Setenv("GITHUB_APP_ID", "123456")
+				_ = os.Setenv("GITHUB_INSTALLATION_ID", "789012")
+				_ = os.Setenv("GITHUB_PRIVATE_KEY", "test-key")
+				_ = os.Setenv("FLUX_REPO", "https://github.com/org/fleet")
+				_ = os.Setenv("FLUX_TOKEN", "t0ken")
+				_ = os.Setenv("FLUX_SSH_KEY_FILE", "/etc/chart-val/fleet-key")
+			},
+			cleanup: func() {
+				_ = os.Unsetenv("WEBHOOK_SECRET")
+				_ = os.Unsetenv("GITHUB_APP_ID")
+				_ = os.Unsetenv("GITHUB_INSTALLATION_ID")
+				_ = os.Unsetenv("GITHUB_PRIVATE_KEY")
+				_ = os.Unsetenv("FLUX_REPO")
+				_ = os.Unsetenv("FLUX_TOKEN")
+				_ = os.Unsetenv("FLUX_SSH_KEY_FILE")
+			},
+			wantErr: true,
+		},
+		{
+			name: "FLUX_ENV_PATTERN without envName",
+			setup: func() {
+				_ = os.Setenv("WEBHOOK_SECRET", "test-secret")
+				_ = os.Setenv("GITHUB_APP_ID", "123456")
+				_ = os.Setenv("GITHUB_INSTALLATION_ID", "789012")
+				_ = os.Setenv("GITHUB_PRIVATE_KEY", "test-key")
+				_ = os.Setenv("FLUX_REPO", "https://github.com/org/fleet")
+				_ = os.Setenv("FLUX_ENV_PATTERN", "clusters/*")
+			},
+			cleanup: func() {
+				_ = os.Unsetenv("WEBHOOK_SECRET")
+				_ = os.Unsetenv("GITHUB_APP_ID")
+				_ = os.Unsetenv("GITHUB_INSTALLATION_ID")
+				_ = os.Unsetenv("GITHUB_PRIVATE_KEY")
+				_ = os.Unsetenv("FLUX_REPO")
+				_ = os.Unsetenv("FLUX_ENV_PATTERN")
+			},
+			wantErr: true,
+			errMsg:  "FLUX_ENV_PATTERN",
+		},
+		{
+			name: "helmfile paths",
+			setup: func() {
+				_ = os.Setenv("WEBHOOK_SECRET", "test-secret")
+				_ = os.Setenv("GITHUB_APP_ID", "123456")
+				_ = os.Setenv("GITHUB_INSTALLATION_ID", "789012")
+				_ = os.Setenv("GITHUB_PRIVATE_KEY", "test-key")
+				_ = os.Setenv("HELMFILE_PATHS", "helmfile.yaml, deploy/helmfile.yaml.gotmpl")
+			},
+			cleanup: func() {
+				_ = os.Unsetenv("WEBHOOK_SECRET")
+				_ = os.Unsetenv("GITHUB_APP_ID")
+				_ = os.Unsetenv("GITHUB_INSTALLATION_ID")
+				_ = os.Unsetenv("GITHUB_PRIVATE_KEY")
+				_ = os.Unsetenv("HELMFILE_PATHS")
+			},
+			want: Config{
+				Port:                 8080,
+				WebhookSecret:        "test-secret",
+				GitHubAppID:          123456,
+				GitHubInstallationID: 789012,
+				GitHubPrivateKey:     "test-key",
+				LogLevel:             "info",
+				HelmfilePaths:        []string{"helmfile.yaml", "deploy/helmfile.yaml.gotmpl"},
+			},
+		},
 	}
 
 	for _, tt := range tests {
@@ -227,6 +639,41 @@ func TestLoad(t *testing.T) {
 			if got.LogLevel != tt.want.LogLevel {
 				t.Errorf("Load().LogLevel = %v, want %v", got.LogLevel, tt.want.LogLevel)
 			}
+			if got.AdminToken != tt.want.AdminToken {
+				t.Errorf("Load().AdminToken = %v, want %v", got.AdminToken, tt.want.AdminToken)
+			}
+			if tt.want.ChartInclude != nil && !reflect.DeepEqual(got.ChartInclude, tt.want.ChartInclude) {
+				t.Errorf("Load().ChartInclude = %v, want %v", got.ChartInclude, tt.want.ChartInclude)
+			}
+			if !reflect.DeepEqual(got.ChartExclude, tt.want.ChartExclude) {
+				t.Errorf("Load().ChartExclude = %v, want %v", got.ChartExclude, tt.want.ChartExclude)
+			}
+			if got.FluxToken != tt.want.FluxToken || got.FluxSSHKeyFile != tt.want.FluxSSHKeyFile {
+				t.Errorf("Load() flux credentials = %q/%q, want %q/%q",
+					got.FluxToken, got.FluxSSHKeyFile, tt.want.FluxToken, tt.want.FluxSSHKeyFile)
+			}
+			if got.FluxRepo != tt.want.FluxRepo || got.FluxLocalPath != tt.want.FluxLocalPath ||
+				got.FluxSyncInterval != tt.want.FluxSyncInterval || got.FluxEnvPattern != tt.want.FluxEnvPattern {
+				t.Errorf("Load() flux = %q/%q/%v/%q, want %q/%q/%v/%q",
+					got.FluxRepo, got.FluxLocalPath, got.FluxSyncInterval, got.FluxEnvPattern,
+					tt.want.FluxRepo, tt.want.FluxLocalPath, tt.want.FluxSyncInterval, tt.want.FluxEnvPattern)
+			}
+			if !reflect.DeepEqual(got.HelmfilePaths, tt.want.HelmfilePaths) {
+				t.Errorf("Load().HelmfilePaths = %v, want %v", got.HelmfilePaths, tt.want.HelmfilePaths)
+			}
+			if got.ArgoAppsEnvKey != tt.want.ArgoAppsEnvKey || got.ArgoAppsEnvFromDest != tt.want.ArgoAppsEnvFromDest {
+				t.Errorf("Load() argo env naming = %q/%v, want %q/%v",
+					got.ArgoAppsEnvKey, got.ArgoAppsEnvFromDest, tt.want.ArgoAppsEnvKey, tt.want.ArgoAppsEnvFromDest)
+			}
+			if got.ArgoAppsPRDiff != tt.want.ArgoAppsPRDiff {
+				t.Errorf("Load().ArgoAppsPRDiff = %v, want %v", got.ArgoAppsPRDiff, tt.want.ArgoAppsPRDiff)
+			}
+			if !reflect.DeepEqual(got.ArgoAppsPRDiffRepos, tt.want.ArgoAppsPRDiffRepos) {
+				t.Errorf("Load().ArgoAppsPRDiffRepos = %v, want %v", got.ArgoAppsPRDiffRepos, tt.want.ArgoAppsPRDiffRepos)
+			}
+			if got.ArgoSourcesFile != tt.want.ArgoSourcesFile {
+				t.Errorf("Load().ArgoSourcesFile = %v, want %v", got.ArgoSourcesFile, tt.want.ArgoSourcesFile)
+			}
 		})
 	}
 }
@@ -244,3 +691,36 @@ func containsInner(s, substr string) bool {
 	}
 	return false
 }
+
+func TestLoad_ArtifactLinkSecret(t *testing.T) {
+	t.Setenv("WEBHOOK_SECRET", "test-secret")
+	t.Setenv("GITHUB_APP_ID", "123456")
+	t.Setenv("GITHUB_INSTALLATION_ID", "789012")
+	t.Setenv("GITHUB_PRIVATE_KEY", "test-key")
+	t.Setenv("ARTIFACT_STORE", "filesystem")
+	t.Setenv("ARTIFACT_DIR", t.TempDir())
+
+	derived, err := Load()
+	if err != nil {
+		t.Fatalf("Load() error: %v", err)
+	}
+	if derived.ArtifactLinkSecret == "" || derived.ArtifactLinkSecret == "test-secret" {
+		t.Errorf("ArtifactLinkSecret = %q, want a key derived from WEBHOOK_SECRET", derived.ArtifactLinkSecret)
+	}
+	again, err := Load()
+	if err != nil {
+		t.Fatalf("Load() error: %v", err)
+	}
+	if again.ArtifactLinkSecret != derived.ArtifactLinkSecret {
+		t.Error("derived ArtifactLinkSecret should be stable across restarts")
+	}
+
+	t.Setenv("ARTIFACT_LINK_SECRET", "link-secret")
+	explicit, err := Load()
+	if err != nil {
+		t.Fatalf("Load() error: %v", err)
+	}
+	if explicit.ArtifactLinkSecret != "link-secret" {
+		t.Errorf("ArtifactLinkSecret = %q, want link-secret", explicit.ArtifactLinkSecret)
+	}
+}
@@ -0,0 +1,142 @@
+// Package gitmirror keeps a local clone of a git repository in step with its
+// remote, for the gitops adapters that index one.
+package gitmirror
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// Sync triggers, passed to the sync function and recorded in logs and metrics.
+const (
+	TriggerInterval = "interval" // Periodic background sync
+	TriggerWebhook  = "webhook"  // Push to the repository
+	TriggerAdmin    = "admin"    // Forced refresh
+)
+
+// Options configures a Mirror.
+type Options struct {
+	URL        string // Git URL to clone
+	Dir        string // Clone directory
+	Token      string // HTTPS access token for URL
+	SSHKeyFile string // SSH private key for URL
+}
+
+// Mirror is a shallow clone of a remote repository. Syncs run one at a time
+// and requested syncs are coalesced.
+type Mirror struct {
+	opts     Options
+	syncing  chan struct{} // Holds a token while a sync runs
+	requests chan string   // Pending sync request (its trigger), coalesced
+}
+
+// New creates a mirror of opts.URL in opts.Dir. Nothing is cloned until Init.
+func New(opts Options) *Mirror {
+	return &Mirror{
+		opts:     opts,
+		syncing:  make(chan struct{}, 1),
+		requests: make(chan string, 1),
+	}
+}
+
+// Init clones the repository, or pulls the latest changes when Dir already
+// holds a clone.
+func (m *Mirror) Init(ctx context.Context) error {
+	if _, err := os.Stat(filepath.Join(m.opts.Dir, ".git")); err == nil {
+		return m.pull(ctx)
+	}
+	output, err := m.Command(ctx, "clone", "--depth=1", m.opts.URL, m.opts.Dir).CombinedOutput()
+	if err != nil {
+		return fmt.Errorf("git clone failed: %w\noutput: %s", err, output)
+	}
+	return nil
+}
+
+// Sync pulls the repository and calls build to index the pulled clone,
+// after a sync already running has finished. Callers keep serving their
+// previous index until build returns and swap the new one in themselves.
+// Waiting and the git pull are both abandoned when ctx is done; build is not
+// called then.
+func (m *Mirror) Sync(ctx context.Context, build func() error) error {
+	select {
+	case m.syncing <- struct{}{}:
+		defer func() { <-m.syncing }()
+	case <-ctx.Done():
+		return fmt.Errorf("waiting for the running sync: %w", ctx.Err())
+	}
+
+	if err := m.pull(ctx); err != nil {
+		return fmt.Errorf("pulling repository: %w", errors.Join(err, ctx.Err()))
+	}
+	if err := build(); err != nil {
+		return fmt.Errorf("rebuilding index: %w", err)
+	}
+	return nil
+}
+
+// Request queues a sync with trigger for Run. It returns false when one is
+// already queued, which the request then shares.
+func (m *Mirror) Request(trigger string) bool {
+	select {
+	case m.requests <- trigger:
+		return true
+	default:
+		return false
+	}
+}
+
+// Run calls sync every interval and for every request until ctx is done,
+// passing ctx so a sync in progress is abandoned on shutdown.
+func (m *Mirror) Run(ctx context.Context, interval time.Duration, sync func(ctx context.Context, trigger string)) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			sync(ctx, TriggerInterval)
+		case trigger := <-m.requests:
+			sync(ctx, trigger)
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// pull fast-forwards the clone to the remote.
+func (m *Mirror) pull(ctx context.Context) error {
+	output, err := m.Command(ctx, "-C", m.opts.Dir, "pull", "--ff-only").CombinedOutput()
+	if err != nil {
+		return fmt.Errorf("git pull failed: %w\noutput: %s", err, output)
+	}
+	return nil
+}
+
+// Command returns a git command carrying the mirror's credentials in its
+// environment, so they are neither stored in the clone nor visible in its
+// arguments. The command is killed when ctx is done.
+func (m *Mirror) Command(ctx context.Context, args ...string) *exec.Cmd {
+	//nolint:gosec // G204: arguments are from trusted config, not user input
+	cmd := exec.CommandContext(ctx, "git", args...)
+	cmd.Env = os.Environ()
+	if m.opts.Token != "" {
+		auth := base64.StdEncoding.EncodeToString([]byte("x-access-token:" + m.opts.Token))
+		cmd.Env = append(cmd.Env,
+			"GIT_CONFIG_COUNT=1",
+			"GIT_CONFIG_KEY_0=http.extraHeader",
+			"GIT_CONFIG_VALUE_0=Authorization: Basic "+auth,
+		)
+	}
+	if m.opts.SSHKeyFile != "" {
+		key := "'" + strings.ReplaceAll(m.opts.SSHKeyFile, "'", `'\''`) + "'"
+		cmd.Env = append(cmd.Env, "GIT_SSH_COMMAND=ssh -i "+key+" -o IdentitiesOnly=yes")
+	}
+	return cmd
+}
@@ -0,0 +1,30 @@
+// Package httpauth guards operator HTTP routes with a shared token.
+package httpauth
+
+import (
+	"crypto/subtle"
+	"net/http"
+	"strings"
+)
+
+// Require returns a handler that serves next only to requests carrying
+// token, and responds 401 to everything else. API clients send the token as
+// a bearer token; browsers send it as the HTTP basic auth password (any user
+// name), which they prompt for on the 401. An empty token rejects every
+// request.
+func Require(token string, next http.HandlerFunc) http.HandlerFunc {
+	want := []byte(token)
+	return func(w http.ResponseWriter, r *http.Request) {
+		got, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if !ok {
+			_, got, ok = r.BasicAuth()
+		}
+		if !ok || len(want) == 0 || subtle.ConstantTimeCompare([]byte(got), want) != 1 {
+			w.Header().Add("WWW-Authenticate", `Bearer realm="chart-val"`)
+			w.Header().Add("WWW-Authenticate", `Basic realm="chart-val", charset="UTF-8"`)
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+		next(w, r)
+	}
+}
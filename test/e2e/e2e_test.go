@@ -34,6 +34,7 @@ import (
 	prfiles "github.com/nathantilsley/chart-val/internal/diff/adapters/pr_files"
 	sourcectrl "github.com/nathantilsley/chart-val/internal/diff/adapters/source_ctrl"
 	"github.com/nathantilsley/chart-val/internal/diff/app"
+	"github.com/nathantilsley/chart-val/internal/diff/domain"
 	ghclient "github.com/nathantilsley/chart-val/internal/platform/github"
 	"github.com/nathantilsley/chart-val/internal/platform/logger"
 )
@@ -867,17 +868,17 @@ func setupTestServer(t *testing.T, appID, installationID int64, privateKey, webh
 
 	// Set up adapters
 	sourceCtrl := sourcectrl.New(githubClient)
-	helmRenderer, err := helmcli.New()
+	helmRenderer, err := helmcli.New(helmcli.Options{})
 	if err != nil {
 		t.Fatalf("creating helm adapter: %v", err)
 	}
-	reporter := githubout.New(githubClient, "chart-val", "")
-	changedCharts := prfiles.New(githubClient, log, "charts")
+	reporter := githubout.New(githubClient, "chart-val", "", githubout.Options{})
+	changedCharts := prfiles.New(githubClient, log, domain.ChartLayout{Include: []string{"charts/*"}}, nil)
 	semanticDiff := dyffdiff.New()
 	unifiedDiff := linediff.New()
 
 	// Environment config: filesystem discovery
-	filesystemEnvConfig := fsenv.New(sourceCtrl, "charts", "env", "-values.yaml")
+	filesystemEnvConfig := fsenv.New(sourceCtrl, "env", "-values.yaml")
 
 	// Use real OTel when OTEL_ENABLED=true (e.g., with local Jaeger),
 	// otherwise noop for zero overhead in normal test runs.
@@ -909,17 +910,18 @@ func setupTestServer(t *testing.T, appID, installationID int64, privateKey, webh
 		filesystemEnvConfig, // Use filesystem discovery
 		helmRenderer,
 		reporter,
+		nil, // No run history in E2E
+		nil, // No artifact store in E2E
 		semanticDiff,
 		unifiedDiff,
 		log,
 		meter,
 		tracer,
-		"charts",
 		"chart_val",
 	)
 
 	// Create webhook handler
-	webhookHandler := githubin.NewWebhookHandler(diffService, webhookSecret, log)
+	webhookHandler := githubin.NewWebhookHandler(diffService, nil, webhookSecret, log)
 
 	// Create test server with webhook handler
 	mux := http.NewServeMux()